@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/service"
+)
+
+// runExport выполняет подкоманду export: пишет архив организации -tenant в файл -o (по умолчанию stdout)
+func runExport(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	tenant := fs.String("tenant", repository.DefaultTenant, "organization to export")
+	out := fs.String("o", "-", "output file, - for stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return errors.New("usage: export [-tenant ID] [-o FILE]")
+	}
+
+	ctx, err := cliContext(db, *tenant)
+	if err != nil {
+		return err
+	}
+	archive, err := service.NewArchiveService(repository.NewUnitOfWork(db)).Export(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := io.Writer(os.Stdout)
+	if *out != "-" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(archive); err != nil {
+		return err
+	}
+	c := archive.Data.Counts
+	fmt.Fprintf(os.Stderr, "exported %d teams, %d users, %d memberships, %d pull requests, %d history events, %d declines\n",
+		c.Teams, c.Users, c.Memberships, c.PullRequests, c.Events, c.Declines)
+	return nil
+}
+
+// runRestore выполняет подкоманду restore: загружает архив из файла (или stdin при «-»)
+// в пустую организацию -tenant
+func runRestore(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	tenant := fs.String("tenant", repository.DefaultTenant, "organization to restore into")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: restore [-tenant ID] FILE")
+	}
+
+	var data []byte
+	var err error
+	if path := fs.Arg(0); path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	var archive model.Archive
+	if err := json.Unmarshal(data, &archive); err != nil {
+		return fmt.Errorf("cannot decode archive: %w", err)
+	}
+
+	ctx, err := cliContext(db, *tenant)
+	if err != nil {
+		return err
+	}
+	c, err := service.NewArchiveService(repository.NewUnitOfWork(db)).Restore(ctx, &archive)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("restored %d teams, %d users, %d memberships, %d pull requests, %d history events, %d declines\n",
+		c.Teams, c.Users, c.Memberships, c.PullRequests, c.Events, c.Declines)
+	return nil
+}
+
+// cliContext проверяет, что организация существует, и возвращает контекст с ней и админом CLI
+func cliContext(db *sql.DB, tenant string) (context.Context, error) {
+	uow := repository.NewUnitOfWork(db)
+	ctx := repository.WithTenant(context.Background(), tenant)
+	if _, err := uow.Repos(ctx).Orgs.Get(tenant); err != nil {
+		if err == repository.ErrNotFound {
+			return nil, fmt.Errorf("organization %q not found", tenant)
+		}
+		return nil, err
+	}
+	return service.WithPrincipal(ctx, &model.Principal{Name: "cli", Role: model.RoleAdmin, TenantID: tenant, AuthMethod: model.AuthMethodCLI}), nil
+}
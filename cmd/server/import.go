@@ -0,0 +1,96 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/roster"
+	"pr-reviewer/internal/service"
+)
+
+// runImport выполняет подкоманду import: загружает реестр команд и пользователей из файла
+// (или stdin при «-») в организацию -tenant. С -dry-run только печатает план изменений.
+func runImport(db *sql.DB, args []string) error {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "roster format: csv or yaml (default: by file extension)")
+	tenant := fs.String("tenant", repository.DefaultTenant, "organization to import into")
+	dryRun := fs.Bool("dry-run", false, "validate and print the plan without applying it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: import [-dry-run] [-format csv|yaml] [-tenant ID] FILE")
+	}
+	path := fs.Arg(0)
+
+	f := roster.DetectFormat(*format)
+	if *format == "" {
+		f = roster.DetectFormat(path)
+	}
+	if f == "" {
+		return roster.ErrUnknownFormat
+	}
+	var data []byte
+	var err error
+	if path == "-" {
+		data, err = io.ReadAll(os.Stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return err
+	}
+	entries, parseErrs, err := roster.Parse(f, data)
+	if err != nil {
+		return err
+	}
+
+	ctx, err := cliContext(db, *tenant)
+	if err != nil {
+		return err
+	}
+	uow := repository.NewUnitOfWork(db)
+	teams := service.NewTeamService(uow, service.NewPRService(uow, service.DefaultAssignmentPolicy()))
+
+	report, err := teams.ImportRoster(ctx, entries, parseErrs, *dryRun)
+	if err == service.ErrInvalidRoster {
+		for _, e := range report.Errors {
+			fmt.Fprintf(os.Stderr, "%s:%d: %s\n", path, e.Line, e.Message)
+		}
+		return fmt.Errorf("%d errors, nothing was imported", len(report.Errors))
+	}
+	if err != nil {
+		return err
+	}
+	printImportPlan(os.Stdout, report)
+	return nil
+}
+
+// printImportPlan печатает изменения импорта построчно, как diff
+func printImportPlan(w io.Writer, report *model.ImportReport) {
+	for _, c := range report.Changes {
+		switch c.Action {
+		case model.ImportCreateTeam:
+			fmt.Fprintf(w, "line %d:\t+ team %s\n", c.Line, c.TeamName)
+		case model.ImportCreateUser:
+			fmt.Fprintf(w, "line %d:\t+ user %s (%s)\n", c.Line, c.UserID, c.New)
+		case model.ImportUpdateUser:
+			fmt.Fprintf(w, "line %d:\t~ user %s %s: %s -> %s\n", c.Line, c.UserID, c.Field, c.Old, c.New)
+		case model.ImportAddMember:
+			fmt.Fprintf(w, "line %d:\t+ member %s of %s as %s\n", c.Line, c.UserID, c.TeamName, c.New)
+		case model.ImportSetRole:
+			fmt.Fprintf(w, "line %d:\t~ member %s of %s role: %s -> %s\n", c.Line, c.UserID, c.TeamName, c.Old, c.New)
+		}
+	}
+	status := "applied"
+	if report.DryRun {
+		status = "dry run, nothing applied"
+	}
+	fmt.Fprintf(w, "%d entries, %d changes, %d unchanged (%s)\n", report.Entries, len(report.Changes), report.Unchanged, status)
+}
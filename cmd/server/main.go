@@ -1,14 +1,19 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
 	"pr-reviewer/internal/handlers"
+	"pr-reviewer/internal/jwt"
+	"pr-reviewer/internal/model"
 	"pr-reviewer/internal/repository"
 	"pr-reviewer/internal/service"
 
@@ -38,26 +43,114 @@ func main() {
 		log.Fatalf("cannot ping db: %v", err)
 	}
 
-	// Создаём репозитории
-	teamRepo := repository.NewTeamRepo(db)
-	userRepo := repository.NewUserRepo(db)
-	prRepo := repository.NewPRRepo(db)
+	// Подкоманды выполняются без запуска HTTP-сервера
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			if err := runMigrate(db, os.Args[2:]); err != nil {
+				log.Fatalf("migrate: %v", err)
+			}
+			return
+		case "import":
+			if err := runImport(db, os.Args[2:]); err != nil {
+				log.Fatalf("import: %v", err)
+			}
+			return
+		case "export":
+			if err := runExport(db, os.Args[2:]); err != nil {
+				log.Fatalf("export: %v", err)
+			}
+			return
+		case "restore":
+			if err := runRestore(db, os.Args[2:]); err != nil {
+				log.Fatalf("restore: %v", err)
+			}
+			return
+		default:
+			log.Fatalf("unknown command %q", os.Args[1])
+		}
+	}
+
+	// Применяем миграции при старте
+	if getEnv("AUTO_MIGRATE", "true") == "true" {
+		if err := migrateUp(db); err != nil {
+			log.Fatalf("cannot apply migrations: %v", err)
+		}
+	}
+
+	// Репозитории доступны сервисам через unit of work
+	uow := repository.NewUnitOfWork(db)
+
+	// Политика назначения ревьюверов
+	policy := service.DefaultAssignmentPolicy()
+	policy.ReviewerCount = getEnvInt("REVIEWER_COUNT", policy.ReviewerCount)
+	policy.MinReviewers = getEnvInt("MIN_REVIEWERS", policy.MinReviewers)
+	policy.MaxReviewers = getEnvInt("MAX_REVIEWERS", policy.MaxReviewers)
+	policy.MaxOpenReviews = getEnvInt("MAX_OPEN_REVIEWS", policy.MaxOpenReviews)
+	policy.Strategy = getEnv("ASSIGNMENT_STRATEGY", policy.Strategy)
+	policy.MergePolicy = getEnv("MERGE_POLICY", policy.MergePolicy)
+	policy.ReviewSLAHours = getEnvInt("REVIEW_SLA_HOURS", policy.ReviewSLAHours)
+	if err := policy.Validate(); err != nil {
+		log.Fatalf("invalid assignment policy: %v", err)
+	}
+
+	// Без секрета seed назначения выводится из одного ID PR, и выбор ревьюверов можно предсказать
+	authEnabled := getEnv("AUTH_ENABLED", "true") == "true"
+	assignmentSecret := getEnv("ASSIGNMENT_SECRET", "")
+	if assignmentSecret == "" {
+		if authEnabled {
+			log.Fatalf("ASSIGNMENT_SECRET is required when AUTH_ENABLED=true: without it reviewer selection is predictable from the PR id")
+		}
+		log.Printf("ASSIGNMENT_SECRET is empty: reviewer selection is predictable from the PR id")
+	}
 
 	// Создаём сервисы
-	teamService := service.NewTeamService(teamRepo, userRepo)
-	userService := service.NewUserService(userRepo, teamRepo)
-	prService := service.NewPRService(prRepo, userRepo, teamRepo)
+	prService := service.NewPRService(uow, policy,
+		service.WithSeedSecret([]byte(assignmentSecret)))
+	teamService := service.NewTeamService(uow, prService)
+	userService := service.NewUserService(uow, prService)
+	idempotencyService := service.NewIdempotencyService(uow,
+		getEnvDuration("IDEMPOTENCY_TTL", service.DefaultIdempotencyTTL),
+		getEnvDuration("IDEMPOTENCY_LOCK_TIMEOUT", service.DefaultIdempotencyLockTimeout))
+	go purgeIdempotencyKeys(idempotencyService, time.Hour)
+	authService := service.NewAuthService(uow, getEnv("ADMIN_TOKEN", ""), jwtOptions()...)
+	auditService := service.NewAuditService(uow)
+	orgService := service.NewOrgService(uow, policy)
+	archiveService := service.NewArchiveService(uow)
+	ldapService := ldapSyncService(teamService)
 
 	// Создаём обработчики
 	teamHandler := handlers.NewTeamHandler(teamService)
 	userHandler := handlers.NewUserHandler(userService, prService)
 	prHandler := handlers.NewPRHandler(prService)
+	tokenHandler := handlers.NewTokenHandler(authService)
+	auditHandler := handlers.NewAuditHandler(auditService)
+	orgHandler := handlers.NewOrgHandler(orgService)
+	archiveHandler := handlers.NewArchiveHandler(archiveService)
+	scimHandler := handlers.NewSCIMHandler(userService, teamService)
+	ldapHandler := handlers.NewLDAPHandler(ldapService)
 
 	// Создаём маршрутизатор
 	r := mux.NewRouter()
+	if authEnabled {
+		if getEnv("ADMIN_TOKEN", "") == "" {
+			log.Printf("ADMIN_TOKEN is empty: only tokens already stored in the database are accepted")
+		}
+		r.Use(handlers.Authenticate(authService, orgService))
+	} else {
+		log.Printf("AUTH_ENABLED=false: all requests run with admin rights")
+		r.Use(handlers.Anonymous(orgService))
+	}
+	r.Use(handlers.Idempotency(idempotencyService))
 	teamHandler.RegisterTeamRoutes(r)
 	userHandler.RegisterUserRoutes(r)
 	prHandler.RegisterPRRoutes(r)
+	tokenHandler.RegisterTokenRoutes(r)
+	auditHandler.RegisterAuditRoutes(r)
+	orgHandler.RegisterOrgRoutes(r)
+	archiveHandler.RegisterArchiveRoutes(r)
+	scimHandler.RegisterSCIMRoutes(r)
+	ldapHandler.RegisterLDAPRoutes(r)
 
 	// Эндпоинт здоровья
 	r.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -87,3 +180,118 @@ func getEnv(key, defaultVal string) string {
 	}
 	return defaultVal
 }
+
+// getEnvInt возвращает целочисленное значение переменной окружения или дефолт
+func getEnvInt(key string, defaultVal int) int {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultVal
+	}
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		log.Fatalf("invalid %s: %v", key, err)
+	}
+	return n
+}
+
+// jwtOptions включает вход по JWT, если задан JWKS (JWT_JWKS_FILE или JWT_JWKS_URL)
+func jwtOptions() []service.AuthOption {
+	refresh := getEnvDuration("JWT_JWKS_REFRESH", 10*time.Minute)
+	var keys *jwt.KeySet
+	switch {
+	case getEnv("JWT_JWKS_FILE", "") != "":
+		keys = jwt.NewFileKeySet(getEnv("JWT_JWKS_FILE", ""), refresh)
+	case getEnv("JWT_JWKS_URL", "") != "":
+		keys = jwt.NewURLKeySet(getEnv("JWT_JWKS_URL", ""), refresh)
+	default:
+		return nil
+	}
+
+	// JWT_ROLE_GROUPS: "platform-admins=admin,leads=team-lead,ci=bot"
+	roleGroups := make(map[string]string)
+	for _, pair := range strings.Split(getEnv("JWT_ROLE_GROUPS", ""), ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, "=")
+		role = strings.TrimSpace(role)
+		if !ok || !model.ValidRole(role) {
+			log.Fatalf("invalid JWT_ROLE_GROUPS entry %q: want group=role", pair)
+		}
+		roleGroups[strings.TrimSpace(group)] = role
+	}
+
+	verifier := jwt.NewVerifier(keys, getEnv("JWT_ISSUER", ""), getEnv("JWT_AUDIENCE", ""))
+	return []service.AuthOption{service.WithJWT(verifier, service.JWTMapping{
+		UserClaim:   getEnv("JWT_USER_CLAIM", "sub"),
+		GroupsClaim: getEnv("JWT_GROUPS_CLAIM", "groups"),
+		TenantClaim: getEnv("JWT_TENANT_CLAIM", ""),
+		RoleGroups:  roleGroups,
+	})}
+}
+
+// getEnvDuration возвращает длительность из переменной окружения (например, "24h") или дефолт
+func getEnvDuration(key string, defaultVal time.Duration) time.Duration {
+	val, ok := os.LookupEnv(key)
+	if !ok {
+		return defaultVal
+	}
+	d, err := time.ParseDuration(val)
+	if err != nil || d <= 0 {
+		log.Fatalf("invalid %s: must be a positive duration like 24h", key)
+	}
+	return d
+}
+
+// purgeIdempotencyKeys периодически удаляет ответы с истёкшим сроком хранения
+func purgeIdempotencyKeys(svc *service.IdempotencyService, every time.Duration) {
+	for range time.Tick(every) {
+		if _, err := svc.PurgeExpired(context.Background()); err != nil {
+			log.Printf("cannot purge idempotency keys: %v", err)
+		}
+	}
+}
+
+// ldapSyncService включает синхронизацию с LDAP, если задан LDAP_URL, и запускает её
+// каждые LDAP_SYNC_INTERVAL. Без LDAP_URL возвращает nil.
+func ldapSyncService(teams *service.TeamService) *service.LDAPSyncService {
+	if getEnv("LDAP_URL", "") == "" {
+		return nil
+	}
+	cfg := service.DefaultLDAPSyncConfig()
+	cfg.URL = getEnv("LDAP_URL", "")
+	cfg.BindDN = getEnv("LDAP_BIND_DN", "")
+	cfg.BindPassword = getEnv("LDAP_BIND_PASSWORD", "")
+	cfg.BaseDN = getEnv("LDAP_BASE_DN", "")
+	cfg.GroupFilter = getEnv("LDAP_GROUP_FILTER", cfg.GroupFilter)
+	cfg.GroupNameAttr = getEnv("LDAP_GROUP_NAME_ATTR", cfg.GroupNameAttr)
+	cfg.MemberAttr = getEnv("LDAP_MEMBER_ATTR", cfg.MemberAttr)
+	cfg.UserBaseDN = getEnv("LDAP_USER_BASE_DN", "")
+	cfg.UserFilter = getEnv("LDAP_USER_FILTER", cfg.UserFilter)
+	cfg.UserIDAttr = getEnv("LDAP_USER_ID_ATTR", cfg.UserIDAttr)
+	cfg.UsernameAttr = getEnv("LDAP_USERNAME_ATTR", cfg.UsernameAttr)
+	cfg.DeactivateMissing = getEnv("LDAP_DEACTIVATE_MISSING", "false") == "true"
+	cfg.Tenant = getEnv("LDAP_SYNC_TENANT", cfg.Tenant)
+	cfg.PageSize = getEnvInt("LDAP_PAGE_SIZE", cfg.PageSize)
+	cfg.Timeout = getEnvDuration("LDAP_TIMEOUT", cfg.Timeout)
+	if err := cfg.Validate(); err != nil {
+		log.Fatalf("invalid ldap sync config: %v", err)
+	}
+	svc := service.NewLDAPSyncService(teams, cfg)
+	go syncLDAP(svc, getEnvDuration("LDAP_SYNC_INTERVAL", time.Hour))
+	return svc
+}
+
+// syncLDAP синхронизирует организацию с каталогом при старте и затем периодически
+func syncLDAP(svc *service.LDAPSyncService, every time.Duration) {
+	ctx := repository.WithTenant(context.Background(), svc.Tenant())
+	ctx = service.WithPrincipal(ctx, &model.Principal{Name: "ldap-sync", Role: model.RoleAdmin, TenantID: svc.Tenant(), AuthMethod: model.AuthMethodJob})
+	for {
+		if report, err := svc.Sync(ctx, false); err != nil {
+			log.Printf("ldap sync failed: %v", err)
+		} else if len(report.Changes) > 0 || len(report.Warnings) > 0 {
+			log.Printf("ldap sync: %d changes, %d warnings", len(report.Changes), len(report.Warnings))
+		}
+		time.Sleep(every)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+
+	"pr-reviewer/internal/migrate"
+	"pr-reviewer/migrations"
+)
+
+// migrateUp применяет все новые миграции
+func migrateUp(db *sql.DB) error {
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	applied, err := m.Up(context.Background())
+	for _, mig := range applied {
+		log.Printf("applied migration %03d_%s", mig.Version, mig.Name)
+	}
+	return err
+}
+
+// runMigrate выполняет подкоманду migrate: up, down [N], status
+func runMigrate(db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New("usage: migrate up | down [N] | status")
+	}
+
+	if args[0] == "up" {
+		return migrateUp(db)
+	}
+
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		reverted, err := m.Down(ctx, steps)
+		for _, mig := range reverted {
+			log.Printf("reverted migration %03d_%s", mig.Version, mig.Name)
+		}
+		return err
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, st := range statuses {
+			applied := "pending"
+			if st.AppliedAt != nil {
+				applied = "applied " + st.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%03d_%s\t%s\n", st.Version, st.Name, applied)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command %q", args[0])
+	}
+}
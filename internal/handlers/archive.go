@@ -0,0 +1,71 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// maxArchiveSize ограничивает размер восстанавливаемого архива
+const maxArchiveSize = 256 << 20
+
+// ArchiveHandler выгружает и восстанавливает данные организации
+type ArchiveHandler struct {
+	archiveService *service.ArchiveService
+}
+
+// NewArchiveHandler создаёт новый обработчик выгрузки
+func NewArchiveHandler(archiveService *service.ArchiveService) *ArchiveHandler {
+	return &ArchiveHandler{archiveService: archiveService}
+}
+
+// RegisterArchiveRoutes регистрирует маршруты выгрузки и восстановления
+func (h *ArchiveHandler) RegisterArchiveRoutes(r *mux.Router) {
+	r.HandleFunc("/admin/export", h.Export).Methods("GET")
+	r.HandleFunc("/admin/restore", h.Restore).Methods("POST")
+}
+
+// Export отдаёт архив организации запроса; download=true добавляет Content-Disposition
+func (h *ArchiveHandler) Export(w http.ResponseWriter, r *http.Request) {
+	archive, err := h.archiveService.Export(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+	if r.URL.Query().Get("download") == "true" {
+		name := fmt.Sprintf("%s-%s.json", archive.TenantID, archive.ExportedAt.Format("20060102-150405"))
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	}
+	writeJSON(w, http.StatusOK, archive)
+}
+
+// Restore загружает архив из тела запроса в пустую организацию запроса
+func (h *ArchiveHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	var archive model.Archive
+	dec := json.NewDecoder(io.LimitReader(r.Body, maxArchiveSize))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&archive); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_ARCHIVE", "cannot decode archive: "+err.Error())
+		return
+	}
+
+	counts, err := h.archiveService.Restore(r.Context(), &archive)
+	switch {
+	case errors.Is(err, service.ErrInvalidArchive):
+		writeError(w, http.StatusBadRequest, "INVALID_ARCHIVE", strings.TrimPrefix(err.Error(), service.ErrInvalidArchive.Error()+": "))
+	case err == service.ErrRestoreNotEmpty:
+		writeError(w, http.StatusConflict, "TARGET_NOT_EMPTY", err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"restored": counts})
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"net/http"
+
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// AuditHandler отдаёт журнал аудита
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+// NewAuditHandler создаёт новый обработчик журнала аудита
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// RegisterAuditRoutes регистрирует маршруты журнала аудита
+func (h *AuditHandler) RegisterAuditRoutes(r *mux.Router) {
+	r.HandleFunc("/admin/audit", h.ListAudit).Methods("GET")
+}
+
+// ListAudit возвращает записи журнала от новых к старым; следующая страница — ?before_id=<next_before_id>
+func (h *AuditHandler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	limit, ok := queryInt(w, r, "limit")
+	if !ok {
+		return
+	}
+	beforeID, ok := queryInt(w, r, "before_id")
+	if !ok {
+		return
+	}
+
+	q := r.URL.Query()
+	events, err := h.auditService.List(r.Context(), repository.AuditFilter{
+		Action:     q.Get("action"),
+		Actor:      q.Get("actor"),
+		TargetType: q.Get("target_type"),
+		TargetID:   q.Get("target_id"),
+		BeforeID:   int64(beforeID),
+		Limit:      limit,
+	})
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+
+	var next int64
+	if len(events) > 0 {
+		next = events[len(events)-1].ID
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"events":         events,
+		"next_before_id": next,
+	})
+}
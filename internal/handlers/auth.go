@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	anyRole     = []string{model.RoleAdmin, model.RoleTeamLead, model.RoleBot, model.RoleMember}
+	managers    = []string{model.RoleAdmin, model.RoleTeamLead}
+	automation  = []string{model.RoleAdmin, model.RoleTeamLead, model.RoleBot}
+	peopleRoles = []string{model.RoleAdmin, model.RoleTeamLead, model.RoleMember}
+	adminOnly   = []string{model.RoleAdmin}
+)
+
+// routeRoles — роли, которым открыт маршрут. Маршруты, которых здесь нет, доступны только админу.
+// Ограничения по команде или пользователю проверяют сами обработчики.
+var routeRoles = map[string][]string{
+	"/team/add": managers,
+	"/team/get": anyRole,
+	"/teams":    anyRole,
+
+	"/team/addMembers":    managers,
+	"/team/removeMember":  managers,
+	"/team/moveUser":      managers,
+	"/team/setMembership": managers,
+	"/team/settings":      managers,
+	"/team/addLead":       managers,
+	"/team/removeLead":    managers,
+	"/team/escalations":   managers,
+
+	"/users/setIsActive":    managers,
+	"/users/getReview":      anyRole,
+	"/users/setSkills":      peopleRoles,
+	"/users/setOutOfOffice": peopleRoles,
+	"/users/declines":       peopleRoles,
+	"/users/declineStats":   managers,
+
+	"/pullRequest/create":            anyRole,
+	"/pullRequest/merge":             automation,
+	"/pullRequest/reassign":          automation,
+	"/pullRequest/decline":           peopleRoles,
+	"/pullRequest/addReviewer":       automation,
+	"/pullRequest/removeReviewer":    automation,
+	"/pullRequests":                  anyRole,
+	"/pullRequest/get":               anyRole,
+	"/pullRequest/history":           anyRole,
+	"/pullRequest/previewAssignment": anyRole,
+	"/pullRequest/addLabels":         anyRole,
+	"/pullRequest/removeLabels":      anyRole,
+
+	"/admin/tokens":        adminOnly,
+	"/admin/audit":         adminOnly,
+	"/admin/tokens/revoke": adminOnly,
+	"/admin/export":        adminOnly,
+	"/admin/restore":       adminOnly,
+	"/admin/ldap/sync":     adminOnly,
+
+	"/organization":          anyRole,
+	"/organization/settings": adminOnly,
+	"/admin/organizations":   adminOnly,
+
+	"/scim/v2/ServiceProviderConfig": adminOnly,
+	"/scim/v2/Users":                 adminOnly,
+	"/scim/v2/Users/{id}":            adminOnly,
+	"/scim/v2/Groups":                adminOnly,
+	"/scim/v2/Groups/{id}":           adminOnly,
+}
+
+// tenantHeader выбирает организацию запроса для глобальных вызывающих
+const tenantHeader = "X-Tenant-ID"
+
+// publicRoutes не требуют аутентификации
+var publicRoutes = map[string]bool{
+	"/health": true,
+}
+
+// Authenticate возвращает middleware, которое проверяет токен из заголовка
+// Authorization: Bearer <token> (API-токен или JWT), роль вызывающего по таблице routeRoles
+// и привязывает запрос к организации вызывающего
+func Authenticate(svc *service.AuthService, orgs *service.OrgService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path := routePath(r)
+			if publicRoutes[path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			p, err := svc.Authenticate(r.Context(), bearerToken(r))
+			if err != nil {
+				if err == service.ErrUnauthenticated {
+					w.Header().Set("WWW-Authenticate", "Bearer")
+					writeError(w, http.StatusUnauthorized, "UNAUTHORIZED", "missing or invalid token")
+					return
+				}
+				writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+				return
+			}
+
+			roles, ok := routeRoles[path]
+			if !ok {
+				roles = adminOnly
+			}
+			if !p.HasRole(roles...) {
+				writeForbidden(w)
+				return
+			}
+			ctx, ok := resolveTenant(w, r, p, orgs)
+			if !ok {
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(service.WithPrincipal(ctx, p)))
+		})
+	}
+}
+
+// Anonymous возвращает middleware для режима без аутентификации: каждый запрос выполняется
+// от имени глобального админа в организации из заголовка X-Tenant-ID
+func Anonymous(orgs *service.OrgService) func(http.Handler) http.Handler {
+	anonymous := &model.Principal{Name: "anonymous", Role: model.RoleAdmin, AuthMethod: model.AuthMethodAnonymous}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if publicRoutes[routePath(r)] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			ctx, ok := resolveTenant(w, r, anonymous, orgs)
+			if !ok {
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(service.WithPrincipal(ctx, anonymous)))
+		})
+	}
+}
+
+// resolveTenant определяет организацию запроса и возвращает привязанный к ней контекст.
+// Организация токена или JWT не переопределяется: чужой X-Tenant-ID — 403.
+// Глобальный вызывающий выбирает организацию заголовком, без него — организацию по умолчанию.
+// При ошибке пишет ответ и возвращает false.
+func resolveTenant(w http.ResponseWriter, r *http.Request, p *model.Principal, orgs *service.OrgService) (context.Context, bool) {
+	header := strings.TrimSpace(r.Header.Get(tenantHeader))
+	tenant := p.TenantID
+	if p.IsGlobal() {
+		tenant = header
+		if tenant == "" {
+			tenant = repository.DefaultTenant
+		}
+	} else if header != "" && header != tenant {
+		writeError(w, http.StatusForbidden, "FORBIDDEN", "token belongs to another organization")
+		return nil, false
+	}
+
+	if !repository.ValidTenantID(tenant) {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", service.ErrInvalidOrgID.Error())
+		return nil, false
+	}
+	if _, err := orgs.GetOrganization(r.Context(), tenant); err != nil {
+		if err == service.ErrOrgNotFound {
+			writeError(w, http.StatusNotFound, "TENANT_NOT_FOUND", "organization not found")
+			return nil, false
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return nil, false
+	}
+	return repository.WithTenant(r.Context(), tenant), true
+}
+
+// routePath возвращает шаблон пути совпавшего маршрута
+func routePath(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tpl, err := route.GetPathTemplate(); err == nil {
+			return tpl
+		}
+	}
+	return r.URL.Path
+}
+
+// bearerToken извлекает токен из заголовка Authorization
+func bearerToken(r *http.Request) string {
+	h := r.Header.Get("Authorization")
+	if len(h) > 7 && strings.EqualFold(h[:7], "Bearer ") {
+		return strings.TrimSpace(h[7:])
+	}
+	return ""
+}
+
+// principal возвращает вызывающего запроса; без middleware — пустого, без прав
+func principal(r *http.Request) *model.Principal {
+	if p := service.PrincipalFrom(r.Context()); p != nil {
+		return p
+	}
+	return &model.Principal{}
+}
+
+// writeForbidden отвечает 403 в общем формате ошибок
+func writeForbidden(w http.ResponseWriter) {
+	writeError(w, http.StatusForbidden, "FORBIDDEN", "not allowed for this token")
+}
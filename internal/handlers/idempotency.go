@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+
+	"pr-reviewer/internal/service"
+)
+
+// IdempotencyKeyHeader — заголовок, которым клиент помечает повторяемый POST-запрос
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// maxIdempotencyKeyLen ограничивает длину ключа
+const maxIdempotencyKeyLen = 255
+
+// secretResponseFields — маршруты, ответы которых содержат секреты, и поля ответа с ними.
+// Эти поля не сохраняются: повтор запроса получает ответ без них.
+var secretResponseFields = map[string][]string{
+	"/admin/tokens": {"token"},
+}
+
+// Idempotency возвращает middleware, которое для POST-запросов с заголовком Idempotency-Key
+// выполняет обработчик один раз, а повторам с тем же ключом и телом отдаёт сохранённый ответ.
+// Ответы 5xx не сохраняются: такой запрос можно повторить с тем же ключом. Паника обработчика
+// тоже освобождает ключ.
+func Idempotency(svc *service.IdempotencyService) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLen {
+				writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "Idempotency-Key is too long")
+				return
+			}
+
+			// ключи разных токенов не пересекаются
+			if p := service.PrincipalFrom(r.Context()); p != nil {
+				key = fmt.Sprintf("%d:%s:%s", p.TokenID, p.Name, key)
+			}
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "cannot read request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			saved, err := svc.Begin(r.Context(), key, requestHash(r, body))
+			switch err {
+			case nil:
+			case service.ErrIdempotencyKeyReused:
+				writeError(w, http.StatusConflict, "IDEMPOTENCY_KEY_REUSED", "Idempotency-Key was already used with a different request")
+				return
+			case service.ErrIdempotencyInProgress:
+				writeError(w, http.StatusConflict, "IDEMPOTENCY_IN_PROGRESS", "request with this Idempotency-Key is still in progress")
+				return
+			default:
+				writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+				return
+			}
+
+			if saved != nil {
+				w.Header().Set("Content-Type", "application/json")
+				w.Header().Set("Idempotent-Replayed", "true")
+				w.WriteHeader(saved.StatusCode)
+				w.Write(saved.Response)
+				return
+			}
+
+			defer func() {
+				if p := recover(); p != nil {
+					if err := svc.Release(r.Context(), key); err != nil {
+						log.Printf("idempotency key %q: cannot release after panic: %v", key, err)
+					}
+					panic(p)
+				}
+			}()
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if rec.status >= http.StatusInternalServerError {
+				err = svc.Release(r.Context(), key)
+			} else {
+				err = svc.Complete(r.Context(), key, rec.status, redactResponse(rec.body.Bytes(), secretResponseFields[routePath(r)]))
+			}
+			if err != nil {
+				log.Printf("idempotency key %q: cannot save response: %v", key, err)
+			}
+		})
+	}
+}
+
+// requestHash отличает запросы с одинаковым ключом: метод, путь с параметрами и тело
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// redactResponse убирает из JSON-ответа поля fields. Ответ, который не удалось разобрать,
+// не сохраняется вовсе.
+func redactResponse(body []byte, fields []string) []byte {
+	if len(fields) == 0 {
+		return body
+	}
+	var obj map[string]json.RawMessage
+	if err := json.Unmarshal(body, &obj); err != nil {
+		return nil
+	}
+	for _, f := range fields {
+		delete(obj, f)
+	}
+	redacted, err := json.Marshal(obj)
+	if err != nil {
+		return nil
+	}
+	return redacted
+}
+
+// responseRecorder пропускает ответ клиенту и запоминает статус и тело
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	r.wroteHeader = true
+	r.body.Write(b)
+	return r.ResponseWriter.Write(b)
+}
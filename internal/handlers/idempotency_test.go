@@ -0,0 +1,93 @@
+package handlers_test
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pr-reviewer/internal/handlers"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/repository/memory"
+	"pr-reviewer/internal/service"
+)
+
+// doWithKey выполняет POST с заголовком Idempotency-Key и возвращает статус, тело и признак повтора
+func (s *testServer) doWithKey(token, path, key, body string) (int, string, bool) {
+	s.t.Helper()
+	req, err := http.NewRequest(http.MethodPost, s.url+path, strings.NewReader(body))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set(handlers.IdempotencyKeyHeader, key)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var buf bytes.Buffer
+	buf.ReadFrom(resp.Body)
+	return resp.StatusCode, buf.String(), resp.Header.Get("Idempotent-Replayed") == "true"
+}
+
+func TestIdempotencyDoesNotStoreTokens(t *testing.T) {
+	s := newTestServer(t)
+	body := `{"name":"ci","role":"bot"}`
+
+	status, first, replayed := s.doWithKey(adminToken, "/admin/tokens", "create-ci", body)
+	if status != http.StatusCreated || replayed || !strings.Contains(first, `"token":"`) {
+		t.Fatalf("first request: %d %s replayed=%v", status, first, replayed)
+	}
+
+	status, second, replayed := s.doWithKey(adminToken, "/admin/tokens", "create-ci", body)
+	if status != http.StatusCreated || !replayed {
+		t.Fatalf("replay: %d %s replayed=%v", status, second, replayed)
+	}
+	if strings.Contains(second, `"token":`) {
+		t.Fatalf("replay contains the token: %s", second)
+	}
+	if !strings.Contains(second, `"token_info":`) {
+		t.Fatalf("replay lost token_info: %s", second)
+	}
+
+	// повтор не выпустил второй токен
+	tokens, err := s.auth.ListTokens(s.ctx(repository.DefaultTenant))
+	if err != nil || len(tokens) != 1 {
+		t.Fatalf("tokens after replay: %d, %v", len(tokens), err)
+	}
+}
+
+func TestIdempotencyReleasesKeyOnPanic(t *testing.T) {
+	st := memory.NewStore()
+	svc := service.NewIdempotencyService(st, service.DefaultIdempotencyTTL, service.DefaultIdempotencyLockTimeout)
+	calls := 0
+	h := handlers.Idempotency(svc)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			panic("boom")
+		}
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	serve := func() (rec *httptest.ResponseRecorder, panicked bool) {
+		req := httptest.NewRequest(http.MethodPost, "/pullRequest/create", strings.NewReader(`{}`))
+		req.Header.Set(handlers.IdempotencyKeyHeader, "k")
+		rec = httptest.NewRecorder()
+		defer func() { panicked = recover() != nil }()
+		h.ServeHTTP(rec, req)
+		return rec, false
+	}
+
+	if _, panicked := serve(); !panicked {
+		t.Fatal("panic was swallowed by the middleware")
+	}
+	rec, panicked := serve()
+	if panicked || rec.Code != http.StatusCreated {
+		t.Fatalf("retry after panic: code %d, panicked=%v, body %s", rec.Code, panicked, rec.Body)
+	}
+	if calls != 2 {
+		t.Fatalf("handler calls: got %d, want 2", calls)
+	}
+}
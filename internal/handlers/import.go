@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pr-reviewer/internal/roster"
+	"pr-reviewer/internal/service"
+)
+
+// maxRosterSize ограничивает размер загружаемого реестра
+const maxRosterSize = 10 << 20
+
+// ImportRoster импортирует команды и пользователей из реестра в теле запроса.
+// Формат задаётся параметром format (csv, yaml) или заголовком Content-Type;
+// dry_run=true только проверяет реестр и возвращает план изменений.
+func (h *TeamHandler) ImportRoster(w http.ResponseWriter, r *http.Request) {
+	format := roster.DetectFormat(r.URL.Query().Get("format"))
+	if format == "" {
+		format = roster.DetectFormat(r.Header.Get("Content-Type"))
+	}
+	if format == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", roster.ErrUnknownFormat.Error())
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "dry_run must be true or false")
+			return
+		}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRosterSize+1))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "cannot read request body")
+		return
+	}
+	if len(data) > maxRosterSize {
+		writeError(w, http.StatusRequestEntityTooLarge, "INVALID_REQUEST", "roster is too large")
+		return
+	}
+
+	entries, parseErrs, err := roster.Parse(format, data)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+		return
+	}
+	report, err := h.teamService.ImportRoster(r.Context(), entries, parseErrs, dryRun)
+	switch {
+	case err == service.ErrInvalidRoster:
+		writeJSON(w, http.StatusBadRequest, map[string]interface{}{
+			"error":  map[string]string{"code": "INVALID_ROSTER", "message": fmt.Sprintf("roster has %d errors, nothing was imported", len(report.Errors))},
+			"report": report,
+		})
+	case err != nil:
+		writeTeamError(w, err)
+	default:
+		writeJSON(w, http.StatusOK, map[string]interface{}{"report": report})
+	}
+}
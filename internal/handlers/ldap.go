@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"pr-reviewer/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// LDAPHandler запускает синхронизацию с каталогом LDAP и показывает её состояние
+type LDAPHandler struct {
+	// ldapService равен nil, если синхронизация не настроена
+	ldapService *service.LDAPSyncService
+}
+
+// NewLDAPHandler создаёт новый обработчик синхронизации; ldapService может быть nil
+func NewLDAPHandler(ldapService *service.LDAPSyncService) *LDAPHandler {
+	return &LDAPHandler{ldapService: ldapService}
+}
+
+// RegisterLDAPRoutes регистрирует маршруты синхронизации
+func (h *LDAPHandler) RegisterLDAPRoutes(r *mux.Router) {
+	r.HandleFunc("/admin/ldap/sync", h.Status).Methods("GET")
+	r.HandleFunc("/admin/ldap/sync", h.Sync).Methods("POST")
+}
+
+// Status возвращает отчёт последней применённой синхронизации и последнюю ошибку
+func (h *LDAPHandler) Status(w http.ResponseWriter, r *http.Request) {
+	if h.ldapService == nil {
+		writeLDAPError(w, service.ErrLDAPSyncNotConfigured)
+		return
+	}
+	status, err := h.ldapService.Status(r.Context())
+	if err != nil {
+		writeLDAPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// Sync синхронизирует организацию с каталогом; dry_run=true только возвращает план изменений
+func (h *LDAPHandler) Sync(w http.ResponseWriter, r *http.Request) {
+	if h.ldapService == nil {
+		writeLDAPError(w, service.ErrLDAPSyncNotConfigured)
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "dry_run must be true or false")
+			return
+		}
+	}
+	report, err := h.ldapService.Sync(r.Context(), dryRun)
+	if err != nil {
+		writeLDAPError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"report": report})
+}
+
+// writeLDAPError переводит ошибки синхронизации в HTTP-ответ
+func writeLDAPError(w http.ResponseWriter, err error) {
+	switch {
+	case err == service.ErrLDAPSyncNotConfigured:
+		writeError(w, http.StatusNotFound, "LDAP_SYNC_NOT_CONFIGURED", err.Error())
+	case errors.Is(err, service.ErrLDAPUnavailable):
+		writeError(w, http.StatusBadGateway, "LDAP_UNAVAILABLE", err.Error())
+	case err == service.ErrConcurrentUpdate:
+		writeError(w, http.StatusConflict, "CONFLICT", "resource was modified concurrently, retry the request")
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// OrgHandler управляет организациями и их настройками
+type OrgHandler struct {
+	orgService *service.OrgService
+}
+
+// NewOrgHandler создаёт новый обработчик организаций
+func NewOrgHandler(orgService *service.OrgService) *OrgHandler {
+	return &OrgHandler{orgService: orgService}
+}
+
+// RegisterOrgRoutes регистрирует маршруты организаций
+func (h *OrgHandler) RegisterOrgRoutes(r *mux.Router) {
+	r.HandleFunc("/admin/organizations", h.CreateOrganization).Methods("POST")
+	r.HandleFunc("/admin/organizations", h.ListOrganizations).Methods("GET")
+	r.HandleFunc("/organization", h.GetOrganization).Methods("GET")
+	r.HandleFunc("/organization/settings", h.UpdateSettings).Methods("POST")
+}
+
+// CreateOrganization создаёт организацию; доступно только глобальному админу
+func (h *OrgHandler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	if !principal(r).IsGlobal() {
+		writeForbidden(w)
+		return
+	}
+	var req struct {
+		ID       string            `json:"id"`
+		Name     string            `json:"name"`
+		Settings model.OrgSettings `json:"settings"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	org, err := h.orgService.CreateOrganization(r.Context(), req.ID, req.Name, req.Settings)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, map[string]interface{}{"organization": org})
+}
+
+// ListOrganizations возвращает все организации; доступно только глобальному админу
+func (h *OrgHandler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	if !principal(r).IsGlobal() {
+		writeForbidden(w)
+		return
+	}
+	orgs, err := h.orgService.ListOrganizations(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"organizations": orgs})
+}
+
+// GetOrganization возвращает организацию запроса и её итоговую политику назначения
+func (h *OrgHandler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	org, policy, err := h.orgService.CurrentOrganization(r.Context())
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"organization":     org,
+		"effective_policy": policy,
+	})
+}
+
+// UpdateSettings заменяет настройки назначения организации запроса
+func (h *OrgHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var settings model.OrgSettings
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	org, policy, err := h.orgService.UpdateSettings(r.Context(), settings)
+	if err != nil {
+		writeOrgError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"organization":     org,
+		"effective_policy": policy,
+	})
+}
+
+// writeOrgError отображает ошибки сервиса организаций в HTTP-ответ
+func writeOrgError(w http.ResponseWriter, err error) {
+	switch {
+	case err == service.ErrInvalidOrgID, errors.Is(err, service.ErrInvalidSettings):
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+	case err == service.ErrOrgExists:
+		writeError(w, http.StatusConflict, "ORG_EXISTS", "organization already exists")
+	case err == service.ErrOrgNotFound:
+		writeError(w, http.StatusNotFound, "TENANT_NOT_FOUND", "organization not found")
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+	}
+}
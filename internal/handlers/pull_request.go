@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
 	"pr-reviewer/internal/service"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -21,40 +25,187 @@ func (h *PRHandler) RegisterPRRoutes(r *mux.Router) {
 	r.HandleFunc("/pullRequest/create", h.CreatePR).Methods("POST")
 	r.HandleFunc("/pullRequest/merge", h.MergePR).Methods("POST")
 	r.HandleFunc("/pullRequest/reassign", h.ReassignReviewer).Methods("POST")
+	r.HandleFunc("/pullRequest/decline", h.DeclineReview).Methods("POST")
+	r.HandleFunc("/pullRequest/addReviewer", h.AddReviewer).Methods("POST")
+	r.HandleFunc("/pullRequest/removeReviewer", h.RemoveReviewer).Methods("POST")
+	r.HandleFunc("/pullRequests", h.ListPRs).Methods("GET")
+	r.HandleFunc("/pullRequest/get", h.GetPR).Methods("GET")
+	r.HandleFunc("/pullRequest/history", h.GetHistory).Methods("GET")
+	r.HandleFunc("/pullRequest/previewAssignment", h.PreviewAssignment).Methods("POST")
+	r.HandleFunc("/pullRequest/addLabels", h.AddLabels).Methods("POST")
+	r.HandleFunc("/pullRequest/removeLabels", h.RemoveLabels).Methods("POST")
 }
 
 // CreatePR создаёт PR и назначает ревьюверов
 func (h *PRHandler) CreatePR(w http.ResponseWriter, r *http.Request) {
 	var req struct {
-		ID     string `json:"pull_request_id"`
-		Name   string `json:"pull_request_name"`
-		Author string `json:"author_id"`
+		ID     string   `json:"pull_request_id"`
+		Name   string   `json:"pull_request_name"`
+		Author string   `json:"author_id"`
+		Labels []string `json:"labels"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, `{"error":{"code":"INVALID_REQUEST","message":"invalid JSON"}}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorizeAuthor(w, r, req.Author) {
+		return
+	}
 
-	pr, err := h.prService.CreatePR(&model.PullRequest{
+	pr, err := h.prService.CreatePR(r.Context(), &model.PullRequest{
 		ID:       req.ID,
 		Name:     req.Name,
 		AuthorID: req.Author,
+		Labels:   req.Labels,
 	})
 	if err != nil {
-		if err.Error() == "PR_EXISTS" {
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error": map[string]string{"code": "PR_EXISTS", "message": "PR id already exists"},
-			})
-			return
-		}
-		http.Error(w, `{"error":{"code":"INTERNAL","message":"internal error"}}`, http.StatusInternalServerError)
+		writePRError(w, err)
 		return
 	}
+	hideExplanation(r, pr)
 	w.WriteHeader(http.StatusCreated)
 	json.NewEncoder(w).Encode(map[string]interface{}{"pr": pr})
 }
 
+// GetPR возвращает PR по ID; объяснение назначения — при ?explain=true
+func (h *PRHandler) GetPR(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id required")
+		return
+	}
+
+	pr, err := h.prService.GetPR(r.Context(), prID)
+	if err != nil {
+		if err == service.ErrPRNotFound {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "PR not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+
+	hideExplanation(r, pr)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pr": pr})
+}
+
+// ListPRs возвращает страницу PR с фильтрами, сортировкой и курсорной пагинацией
+func (h *PRHandler) ListPRs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	filter := repository.PRFilter{
+		Status:     q.Get("status"),
+		AuthorID:   q.Get("author_id"),
+		ReviewerID: q.Get("reviewer_id"),
+		TeamName:   q.Get("team_name"),
+		Label:      q.Get("label"),
+		SortBy:     q.Get("sort"),
+		Cursor:     q.Get("cursor"),
+	}
+	switch q.Get("order") {
+	case "", "desc":
+		filter.Desc = true
+	case "asc":
+	default:
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "order must be asc or desc")
+		return
+	}
+
+	var ok bool
+	if filter.Limit, ok = queryInt(w, r, "limit"); !ok {
+		return
+	}
+	for name, dst := range map[string]**time.Time{
+		"created_from": &filter.CreatedFrom,
+		"created_to":   &filter.CreatedTo,
+		"merged_from":  &filter.MergedFrom,
+		"merged_to":    &filter.MergedTo,
+	} {
+		if *dst, ok = queryTime(w, r, name); !ok {
+			return
+		}
+	}
+
+	prs, next, err := h.prService.ListPRs(r.Context(), filter)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidSort:
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "sort must be created_at, pull_request_id or pull_request_name")
+		case service.ErrInvalidCursor:
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid cursor")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		}
+		return
+	}
+
+	for i := range prs {
+		hideExplanation(r, &prs[i])
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pull_requests": prs,
+		"next_cursor":   next,
+	})
+}
+
+// GetHistory возвращает историю PR вместе с объяснениями назначений
+func (h *PRHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
+	prID := r.URL.Query().Get("pull_request_id")
+	if prID == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "pull_request_id required")
+		return
+	}
+
+	events, err := h.prService.GetHistory(r.Context(), prID)
+	if err != nil {
+		if err == service.ErrPRNotFound {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "PR not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"pull_request_id": prID,
+		"history":         events,
+	})
+}
+
+// PreviewAssignment показывает, кого назначил бы CreatePR, не создавая PR
+func (h *PRHandler) PreviewAssignment(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID       string   `json:"pull_request_id"`
+		Name     string   `json:"pull_request_name"`
+		Author   string   `json:"author_id"`
+		Labels   []string `json:"labels"`
+		Strategy string   `json:"strategy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	preview, err := h.prService.PreviewAssignment(r.Context(), &model.PullRequest{
+		ID:       req.ID,
+		Name:     req.Name,
+		AuthorID: req.Author,
+		Labels:   req.Labels,
+	}, req.Strategy)
+	if err != nil {
+		switch err {
+		case service.ErrUnknownStrategy:
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "unknown strategy")
+		case service.ErrUserNotFound:
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "author not found")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"preview": preview})
+}
+
 // MergePR помечает PR как MERGED
 func (h *PRHandler) MergePR(w http.ResponseWriter, r *http.Request) {
 	var req struct {
@@ -64,20 +215,17 @@ func (h *PRHandler) MergePR(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, `{"error":{"code":"INVALID_REQUEST","message":"invalid JSON"}}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorizePR(w, r, req.ID) {
+		return
+	}
 
-	pr, err := h.prService.MergePR(req.ID)
+	pr, err := h.prService.MergePR(r.Context(), req.ID)
 	if err != nil {
-		if err == service.ErrPRNotFound {
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{
-				"error": map[string]string{"code": "NOT_FOUND", "message": "PR not found"},
-			})
-			return
-		}
-		http.Error(w, `{"error":{"code":"INTERNAL","message":"internal error"}}`, http.StatusInternalServerError)
+		writePRError(w, err)
 		return
 	}
 
+	hideExplanation(r, pr)
 	json.NewEncoder(w).Encode(map[string]interface{}{"pr": pr})
 }
 
@@ -86,32 +234,230 @@ func (h *PRHandler) ReassignReviewer(w http.ResponseWriter, r *http.Request) {
 	var req struct {
 		PRID      string `json:"pull_request_id"`
 		OldUserID string `json:"old_user_id"`
+		NewUserID string `json:"new_user_id"`
+		Strategy  string `json:"strategy"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, `{"error":{"code":"INVALID_REQUEST","message":"invalid JSON"}}`, http.StatusBadRequest)
 		return
 	}
+	if !h.authorizePR(w, r, req.PRID) {
+		return
+	}
 
-	pr, newID, err := h.prService.ReassignReviewer(req.PRID, req.OldUserID)
+	pr, chosen, err := h.prService.ReassignReviewer(r.Context(), req.PRID, req.OldUserID, service.ReassignOptions{
+		NewUserID: req.NewUserID,
+		Strategy:  req.Strategy,
+	})
 	if err != nil {
-		switch err {
-		case service.ErrPRNotFound:
-			w.WriteHeader(http.StatusNotFound)
-			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": "NOT_FOUND", "message": "PR or user not found"}})
-		case service.ErrPRAlreadyMerged:
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": "PR_MERGED", "message": "cannot reassign on merged PR"}})
-		case service.ErrReviewerNotAssigned:
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": "NOT_ASSIGNED", "message": "reviewer is not assigned to this PR"}})
-		case service.ErrNoCandidate:
-			w.WriteHeader(http.StatusConflict)
-			json.NewEncoder(w).Encode(map[string]interface{}{"error": map[string]string{"code": "NO_CANDIDATE", "message": "no active replacement candidate in team"}})
-		default:
-			http.Error(w, `{"error":{"code":"INTERNAL","message":"internal error"}}`, http.StatusInternalServerError)
+		if err == service.ErrUnknownStrategy {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "unknown strategy")
+			return
+		}
+		writePRError(w, err)
+		return
+	}
+
+	hideExplanation(r, pr)
+	json.NewEncoder(w).Encode(map[string]interface{}{"pr": pr, "replaced_by": chosen.UserID, "reason": chosen.Reason})
+}
+
+// DeclineReview фиксирует отказ ревьювера и подбирает замену
+func (h *PRHandler) DeclineReview(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		PRID    string `json:"pull_request_id"`
+		UserID  string `json:"user_id"`
+		Reason  string `json:"reason"`
+		Comment string `json:"comment"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	// от своего ревью может отказаться сам ревьювер, за другого — админ или лид команды автора PR
+	if p := principal(r); !p.IsUser(req.UserID) {
+		if p.HasRole(model.RoleMember) {
+			writeForbidden(w)
+			return
+		}
+		if !h.authorizePR(w, r, req.PRID) {
+			return
 		}
+	}
+
+	pr, chosen, err := h.prService.DeclineReview(r.Context(), req.PRID, req.UserID, req.Reason, req.Comment)
+	if err != nil {
+		if err == service.ErrInvalidDeclineReason {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "reason must be one of no_context, overloaded, conflict_of_interest")
+			return
+		}
+		writePRError(w, err)
+		return
+	}
+
+	hideExplanation(r, pr)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pr": pr, "replaced_by": chosen.UserID, "reason": chosen.Reason})
+}
+
+// reviewerRequest — тело запросов на ручное добавление и снятие ревьювера
+type reviewerRequest struct {
+	PRID   string `json:"pull_request_id"`
+	UserID string `json:"user_id"`
+}
+
+// AddReviewer вручную добавляет ревьювера на PR
+func (h *PRHandler) AddReviewer(w http.ResponseWriter, r *http.Request) {
+	h.changeReviewer(w, r, h.prService.AddReviewer)
+}
+
+// RemoveReviewer вручную снимает ревьювера с PR
+func (h *PRHandler) RemoveReviewer(w http.ResponseWriter, r *http.Request) {
+	h.changeReviewer(w, r, h.prService.RemoveReviewer)
+}
+
+func (h *PRHandler) changeReviewer(w http.ResponseWriter, r *http.Request, apply func(context.Context, string, string) (*model.PullRequest, error)) {
+	var req reviewerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if !h.authorizePR(w, r, req.PRID) {
+		return
+	}
+
+	pr, err := apply(r.Context(), req.PRID, req.UserID)
+	if err != nil {
+		writePRError(w, err)
 		return
 	}
 
-	json.NewEncoder(w).Encode(map[string]interface{}{"pr": pr, "replaced_by": newID})
+	hideExplanation(r, pr)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pr": pr})
+}
+
+// authorizeAuthor проверяет, что вызывающий может действовать с PR автора authorID: админ, сам автор,
+// лид одной из команд автора или бот, чей токен привязан к одной из них либо не привязан к команде.
+// Участник работает только со своими PR. При отказе пишет ответ и возвращает false.
+func (h *PRHandler) authorizeAuthor(w http.ResponseWriter, r *http.Request, authorID string) bool {
+	p := principal(r)
+	if p.HasRole(model.RoleAdmin) || p.IsUser(authorID) {
+		return true
+	}
+	if !p.HasRole(model.RoleTeamLead, model.RoleBot) {
+		writeForbidden(w)
+		return false
+	}
+	if p.HasRole(model.RoleBot) && p.TeamName == "" {
+		return true
+	}
+	teams, err := h.prService.AuthorTeams(r.Context(), authorID)
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "author not found")
+			return false
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return false
+	}
+	for _, team := range teams {
+		if p.CanManageTeam(team) || (p.HasRole(model.RoleBot) && p.TeamName == team) {
+			return true
+		}
+	}
+	writeForbidden(w)
+	return false
+}
+
+// authorizePR проверяет права на существующий PR prID по его автору, как authorizeAuthor
+func (h *PRHandler) authorizePR(w http.ResponseWriter, r *http.Request, prID string) bool {
+	pr, err := h.prService.GetPR(r.Context(), prID)
+	if err != nil {
+		writePRError(w, err)
+		return false
+	}
+	return h.authorizeAuthor(w, r, pr.AuthorID)
+}
+
+// writePRError переводит доменные ошибки PR в HTTP-ответ
+func writePRError(w http.ResponseWriter, err error) {
+	switch err {
+	case service.ErrPRNotFound:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "PR not found")
+	case service.ErrUserNotFound:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+	case service.ErrPRAlreadyMerged:
+		writeError(w, http.StatusConflict, "PR_MERGED", "PR is already merged")
+	case service.ErrReviewerNotAssigned:
+		writeError(w, http.StatusConflict, "NOT_ASSIGNED", "reviewer is not assigned to this PR")
+	case service.ErrReviewerIsAuthor:
+		writeError(w, http.StatusConflict, "REVIEWER_IS_AUTHOR", "author cannot review own PR")
+	case service.ErrReviewerInactive:
+		writeError(w, http.StatusConflict, "REVIEWER_INACTIVE", "reviewer is not active")
+	case service.ErrAlreadyAssigned:
+		writeError(w, http.StatusConflict, "ALREADY_ASSIGNED", "reviewer is already assigned to this PR")
+	case service.ErrReviewerOutOfOffice:
+		writeError(w, http.StatusConflict, "REVIEWER_OUT_OF_OFFICE", "reviewer is out of office")
+	case service.ErrReviewerDeclined:
+		writeError(w, http.StatusConflict, "REVIEWER_DECLINED", "reviewer has declined this PR")
+	case service.ErrReviewerNotInPool:
+		writeError(w, http.StatusConflict, "NOT_IN_POOL", "reviewer is not in the author's teams")
+	case service.ErrTooManyReviewers:
+		writeError(w, http.StatusConflict, "MAX_REVIEWERS", "maximum number of reviewers reached")
+	case service.ErrTooFewReviewers:
+		writeError(w, http.StatusConflict, "MIN_REVIEWERS", "minimum number of reviewers required")
+	case service.ErrMergeBlocked:
+		writeError(w, http.StatusConflict, "MERGE_BLOCKED", "merge policy requires more reviewers")
+	case service.ErrNoCandidate:
+		writeError(w, http.StatusConflict, "NO_CANDIDATE", "no active replacement candidate in team")
+	case service.ErrPRExists:
+		writeError(w, http.StatusConflict, "PR_EXISTS", "PR id already exists")
+	case service.ErrConcurrentUpdate:
+		writeError(w, http.StatusConflict, "CONFLICT", "PR was modified concurrently, retry the request")
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+	}
+}
+
+// labelsRequest — тело запросов на изменение меток PR
+type labelsRequest struct {
+	PRID   string   `json:"pull_request_id"`
+	Labels []string `json:"labels"`
+}
+
+// AddLabels добавляет метки к PR
+func (h *PRHandler) AddLabels(w http.ResponseWriter, r *http.Request) {
+	h.changeLabels(w, r, h.prService.AddLabels)
+}
+
+// RemoveLabels удаляет метки из PR
+func (h *PRHandler) RemoveLabels(w http.ResponseWriter, r *http.Request) {
+	h.changeLabels(w, r, h.prService.RemoveLabels)
+}
+
+func (h *PRHandler) changeLabels(w http.ResponseWriter, r *http.Request, apply func(context.Context, string, []string) (*model.PullRequest, error)) {
+	var req labelsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if !h.authorizePR(w, r, req.PRID) {
+		return
+	}
+
+	pr, err := apply(r.Context(), req.PRID, req.Labels)
+	if err != nil {
+		writePRError(w, err)
+		return
+	}
+
+	hideExplanation(r, pr)
+	writeJSON(w, http.StatusOK, map[string]interface{}{"pr": pr})
+}
+
+// hideExplanation убирает объяснение назначения из ответа, если клиент не передал ?explain=true
+func hideExplanation(r *http.Request, pr *model.PullRequest) {
+	if explain, _ := strconv.ParseBool(r.URL.Query().Get("explain")); !explain {
+		pr.Assignment = nil
+	}
 }
@@ -0,0 +1,102 @@
+package handlers_test
+
+import (
+	"net/http"
+	"testing"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+func TestPRAuthorization(t *testing.T) {
+	s := newTestServer(t)
+	tenant := repository.DefaultTenant
+	s.team(tenant, "backend", "1", "alice", "2", "bob", "3", "carol")
+	s.team(tenant, "frontend", "4", "dave", "5", "erin", "6", "frank")
+
+	alice := s.token(tenant, model.APIToken{Role: model.RoleMember, UserID: "1"})
+	backendLead := s.token(tenant, model.APIToken{Role: model.RoleTeamLead, TeamName: "backend"})
+	frontendBot := s.token(tenant, model.APIToken{Role: model.RoleBot, TeamName: "frontend"})
+	orgBot := s.token(tenant, model.APIToken{Role: model.RoleBot})
+
+	create := func(id, author string) map[string]interface{} {
+		return map[string]interface{}{"pull_request_id": id, "pull_request_name": id, "author_id": author}
+	}
+	steps := []struct {
+		name   string
+		token  string
+		path   string
+		body   interface{}
+		status int
+	}{
+		{"member creates own PR", alice, "/pullRequest/create", create("pr-alice", "1"), http.StatusCreated},
+		{"member creates PR as someone else", alice, "/pullRequest/create", create("pr-fake", "2"), http.StatusForbidden},
+		{"lead creates PR for own team", backendLead, "/pullRequest/create", create("pr-bob", "2"), http.StatusCreated},
+		{"lead creates PR for another team", backendLead, "/pullRequest/create", create("pr-x", "4"), http.StatusForbidden},
+		{"lead creates PR for unknown author", backendLead, "/pullRequest/create", create("pr-x", "99"), http.StatusNotFound},
+		{"scoped bot creates PR for its team", frontendBot, "/pullRequest/create", create("pr-dave", "4"), http.StatusCreated},
+		{"scoped bot creates PR for another team", frontendBot, "/pullRequest/create", create("pr-x", "1"), http.StatusForbidden},
+		{"org bot creates PR for any team", orgBot, "/pullRequest/create", create("pr-erin", "5"), http.StatusCreated},
+
+		{"member labels own PR", alice, "/pullRequest/addLabels", map[string]interface{}{"pull_request_id": "pr-alice", "labels": []string{"db"}}, http.StatusOK},
+		{"member labels someone else's PR", alice, "/pullRequest/addLabels", map[string]interface{}{"pull_request_id": "pr-bob", "labels": []string{"db"}}, http.StatusForbidden},
+		{"member removes labels of someone else's PR", alice, "/pullRequest/removeLabels", map[string]interface{}{"pull_request_id": "pr-bob", "labels": []string{"db"}}, http.StatusForbidden},
+		{"lead labels own team's PR", backendLead, "/pullRequest/addLabels", map[string]interface{}{"pull_request_id": "pr-bob", "labels": []string{"db"}}, http.StatusOK},
+
+		{"lead reassigns on another team's PR", backendLead, "/pullRequest/reassign", map[string]interface{}{"pull_request_id": "pr-dave", "old_user_id": "5"}, http.StatusForbidden},
+		{"lead adds reviewer on another team's PR", backendLead, "/pullRequest/addReviewer", map[string]interface{}{"pull_request_id": "pr-dave", "user_id": "6"}, http.StatusForbidden},
+		{"lead removes reviewer on another team's PR", backendLead, "/pullRequest/removeReviewer", map[string]interface{}{"pull_request_id": "pr-dave", "user_id": "5"}, http.StatusForbidden},
+		{"member declines for someone else", alice, "/pullRequest/decline", map[string]interface{}{"pull_request_id": "pr-bob", "user_id": "3", "reason": "overloaded"}, http.StatusForbidden},
+		{"lead declines on another team's PR", backendLead, "/pullRequest/decline", map[string]interface{}{"pull_request_id": "pr-dave", "user_id": "5", "reason": "overloaded"}, http.StatusForbidden},
+		{"lead declines on another team's PR for own member", backendLead, "/pullRequest/decline", map[string]interface{}{"pull_request_id": "pr-erin", "user_id": "1", "reason": "overloaded"}, http.StatusForbidden},
+		{"lead merges another team's PR", backendLead, "/pullRequest/merge", map[string]interface{}{"pull_request_id": "pr-dave"}, http.StatusForbidden},
+		{"scoped bot merges another team's PR", frontendBot, "/pullRequest/merge", map[string]interface{}{"pull_request_id": "pr-bob"}, http.StatusForbidden},
+		{"lead merges unknown PR", backendLead, "/pullRequest/merge", map[string]interface{}{"pull_request_id": "missing"}, http.StatusNotFound},
+		{"lead merges own team's PR", backendLead, "/pullRequest/merge", map[string]interface{}{"pull_request_id": "pr-bob"}, http.StatusOK},
+		{"scoped bot merges its team's PR", frontendBot, "/pullRequest/merge", map[string]interface{}{"pull_request_id": "pr-dave"}, http.StatusOK},
+		{"member cannot merge even own PR", alice, "/pullRequest/merge", map[string]interface{}{"pull_request_id": "pr-alice"}, http.StatusForbidden},
+	}
+	for _, st := range steps {
+		status, body := s.do(st.token, http.MethodPost, st.path, st.body)
+		if status != st.status {
+			t.Errorf("%s: want %d, got %d %v", st.name, st.status, status, body)
+		}
+		if status == http.StatusForbidden && errorCode(body) != "FORBIDDEN" {
+			t.Errorf("%s: want FORBIDDEN, got %v", st.name, body)
+		}
+	}
+
+	// отказы видят сам пользователь, лиды его команд и админ
+	declines := []struct {
+		name   string
+		token  string
+		user   string
+		status int
+	}{
+		{"member reads own declines", alice, "1", http.StatusOK},
+		{"member reads someone else's declines", alice, "2", http.StatusForbidden},
+		{"lead reads declines of own member", backendLead, "2", http.StatusOK},
+		{"lead reads declines of another team's member", backendLead, "5", http.StatusForbidden},
+		{"bot reads declines", orgBot, "5", http.StatusForbidden},
+		{"admin reads declines", adminToken, "5", http.StatusOK},
+	}
+	for _, d := range declines {
+		if status, body := s.do(d.token, http.MethodGet, "/users/declines?user_id="+d.user, nil); status != d.status {
+			t.Errorf("%s: want %d, got %d %v", d.name, d.status, status, body)
+		}
+	}
+	_, body := s.do(adminToken, http.MethodGet, "/users/declines?user_id=5", nil)
+	if recorded, _ := body["declines"].([]interface{}); len(recorded) != 0 {
+		t.Errorf("forbidden decline was recorded: %v", body)
+	}
+
+	// запрещённые запросы ничего не изменили
+	for _, id := range []string{"pr-fake", "pr-x"} {
+		if _, err := s.prs.GetPR(s.ctx(tenant), id); err == nil {
+			t.Errorf("%s must not be created", id)
+		}
+	}
+	if pr, _ := s.prs.GetPR(s.ctx(tenant), "pr-dave"); pr.Status != "MERGED" {
+		t.Errorf("pr-dave: want MERGED, got %s", pr.Status)
+	}
+}
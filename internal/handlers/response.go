@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// writeJSON отправляет ответ в формате JSON с указанным статусом
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError отправляет ошибку в общем формате {"error":{"code","message"}}
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]interface{}{
+		"error": map[string]string{"code": code, "message": message},
+	})
+}
+
+// queryInt читает необязательный целочисленный параметр запроса; при ошибке отвечает 400
+func queryInt(w http.ResponseWriter, r *http.Request, name string) (int, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return 0, true
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", name+" must be a non-negative integer")
+		return 0, false
+	}
+	return n, true
+}
+
+// queryTime читает необязательный параметр запроса в формате RFC3339; при ошибке отвечает 400
+func queryTime(w http.ResponseWriter, r *http.Request, name string) (*time.Time, bool) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return nil, true
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", name+" must be RFC3339 timestamp")
+		return nil, false
+	}
+	return &t, true
+}
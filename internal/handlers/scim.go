@@ -0,0 +1,393 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"pr-reviewer/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// SCIMHandler реализует провижининг SCIM 2.0: пользователи каталога — пользователи сервиса,
+// группы — команды, active — is_active
+type SCIMHandler struct {
+	userService *service.UserService
+	teamService *service.TeamService
+}
+
+// NewSCIMHandler создаёт новый обработчик SCIM
+func NewSCIMHandler(userService *service.UserService, teamService *service.TeamService) *SCIMHandler {
+	return &SCIMHandler{userService: userService, teamService: teamService}
+}
+
+// RegisterSCIMRoutes регистрирует маршруты SCIM
+func (h *SCIMHandler) RegisterSCIMRoutes(r *mux.Router) {
+	r.HandleFunc(scimBasePath+"/ServiceProviderConfig", h.ServiceProviderConfig).Methods("GET")
+
+	r.HandleFunc(scimBasePath+"/Users", h.ListUsers).Methods("GET")
+	r.HandleFunc(scimBasePath+"/Users", h.CreateUser).Methods("POST")
+	r.HandleFunc(scimBasePath+"/Users/{id}", h.GetUser).Methods("GET")
+	r.HandleFunc(scimBasePath+"/Users/{id}", h.ReplaceUser).Methods("PUT")
+	r.HandleFunc(scimBasePath+"/Users/{id}", h.PatchUser).Methods("PATCH")
+	r.HandleFunc(scimBasePath+"/Users/{id}", h.DeleteUser).Methods("DELETE")
+
+	r.HandleFunc(scimBasePath+"/Groups", h.ListGroups).Methods("GET")
+	r.HandleFunc(scimBasePath+"/Groups", h.CreateGroup).Methods("POST")
+	r.HandleFunc(scimBasePath+"/Groups/{id}", h.GetGroup).Methods("GET")
+	r.HandleFunc(scimBasePath+"/Groups/{id}", h.ReplaceGroup).Methods("PUT")
+	r.HandleFunc(scimBasePath+"/Groups/{id}", h.PatchGroup).Methods("PATCH")
+	r.HandleFunc(scimBasePath+"/Groups/{id}", h.DeleteGroup).Methods("DELETE")
+}
+
+// ServiceProviderConfig описывает поддерживаемые возможности SCIM
+func (h *SCIMHandler) ServiceProviderConfig(w http.ResponseWriter, r *http.Request) {
+	writeSCIM(w, http.StatusOK, map[string]interface{}{
+		"schemas":        []string{scimConfigSchema},
+		"patch":          map[string]bool{"supported": true},
+		"bulk":           map[string]interface{}{"supported": false, "maxOperations": 0, "maxPayloadSize": 0},
+		"filter":         map[string]interface{}{"supported": true, "maxResults": scimMaxResults},
+		"changePassword": map[string]bool{"supported": false},
+		"sort":           map[string]bool{"supported": false},
+		"etag":           map[string]bool{"supported": false},
+		"authenticationSchemes": []map[string]string{{
+			"type":        "oauthbearertoken",
+			"name":        "Bearer token",
+			"description": "Admin API token in the Authorization: Bearer header",
+		}},
+	})
+}
+
+// ListUsers ищет пользователей; поддерживается фильтр userName eq "..."
+func (h *SCIMHandler) ListUsers(w http.ResponseWriter, r *http.Request) {
+	var username string
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		var err error
+		if username, err = parseSCIMFilter(filter, "userName"); err != nil {
+			h.writeErr(w, err)
+			return
+		}
+	}
+	users, err := h.userService.ListUsers(r.Context(), username)
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	start, from, to, err := scimPage(r, len(users))
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	resources := make([]interface{}, 0, to-from)
+	for i := from; i < to; i++ {
+		resources = append(resources, toSCIMUser(&users[i]))
+	}
+	writeSCIM(w, http.StatusOK, scimListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: len(users),
+		StartIndex:   start,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// scimUserInput — тело POST и PUT пользователя; остальные атрибуты профиля игнорируются
+type scimUserInput struct {
+	UserName string          `json:"userName"`
+	Active   json.RawMessage `json:"active"`
+}
+
+// change возвращает изменения пользователя из тела запроса
+func (in *scimUserInput) change() (*scimUserChange, error) {
+	change := &scimUserChange{}
+	if in.UserName == "" {
+		return nil, badSCIM(scimInvalidValue, "userName required")
+	}
+	change.username = &in.UserName
+	if len(in.Active) > 0 && string(in.Active) != "null" {
+		if err := change.set("active", in.Active); err != nil {
+			return nil, err
+		}
+	}
+	return change, nil
+}
+
+// CreateUser создаёт пользователя вне команд; без active пользователь активен
+func (h *SCIMHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
+	var in scimUserInput
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	change, err := in.change()
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	active := true
+	if change.active != nil {
+		active = *change.active
+	}
+	user, err := h.userService.CreateUser(r.Context(), *change.username, active)
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	res := toSCIMUser(user)
+	w.Header().Set("Location", res.Meta.Location)
+	writeSCIM(w, http.StatusCreated, res)
+}
+
+// GetUser возвращает пользователя по ID
+func (h *SCIMHandler) GetUser(w http.ResponseWriter, r *http.Request) {
+	user, err := h.userService.GetUserByID(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(user))
+}
+
+// ReplaceUser заменяет userName и active; без active активность не меняется
+func (h *SCIMHandler) ReplaceUser(w http.ResponseWriter, r *http.Request) {
+	var in scimUserInput
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	change, err := in.change()
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	h.updateUser(w, r, change)
+}
+
+// PatchUser меняет userName и active; деактивация переназначает открытые ревью пользователя
+func (h *SCIMHandler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	var patch scimPatchOp
+	if !decodeSCIM(w, r, &patch) {
+		return
+	}
+	change, err := parseUserPatch(&patch)
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	h.updateUser(w, r, change)
+}
+
+// DeleteUser деактивирует пользователя: его PR и история сохраняются, открытые ревью
+// переназначаются. Пользователь остаётся доступен с active=false.
+func (h *SCIMHandler) DeleteUser(w http.ResponseWriter, r *http.Request) {
+	inactive := false
+	if _, _, err := h.userService.UpdateUser(r.Context(), mux.Vars(r)["id"], nil, &inactive); err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateUser применяет изменения пользователя и отвечает его новым состоянием
+func (h *SCIMHandler) updateUser(w http.ResponseWriter, r *http.Request, change *scimUserChange) {
+	user, _, err := h.userService.UpdateUser(r.Context(), mux.Vars(r)["id"], change.username, change.active)
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMUser(user))
+}
+
+// ListGroups ищет группы; поддерживается фильтр displayName eq "..." и
+// excludedAttributes=members для списка без участников
+func (h *SCIMHandler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	names, err := h.teamService.TeamNames(r.Context())
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	if filter := r.URL.Query().Get("filter"); filter != "" {
+		name, err := parseSCIMFilter(filter, "displayName")
+		if err != nil {
+			h.writeErr(w, err)
+			return
+		}
+		matched := []string{}
+		for _, n := range names {
+			if n == name {
+				matched = append(matched, n)
+			}
+		}
+		names = matched
+	}
+	start, from, to, err := scimPage(r, len(names))
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	withMembers := !excludesMembers(r)
+	resources := make([]interface{}, 0, to-from)
+	for _, name := range names[from:to] {
+		team, err := h.teamService.GetTeam(r.Context(), name)
+		if err != nil {
+			h.writeErr(w, err)
+			return
+		}
+		resources = append(resources, toSCIMGroup(team, withMembers))
+	}
+	writeSCIM(w, http.StatusOK, scimListResponse{
+		Schemas:      []string{scimListSchema},
+		TotalResults: len(names),
+		StartIndex:   start,
+		ItemsPerPage: len(resources),
+		Resources:    resources,
+	})
+}
+
+// excludesMembers сообщает, попросил ли клиент не возвращать участников групп
+func excludesMembers(r *http.Request) bool {
+	for _, attr := range strings.Split(r.URL.Query().Get("excludedAttributes"), ",") {
+		if strings.EqualFold(strings.TrimSpace(attr), "members") {
+			return true
+		}
+	}
+	return false
+}
+
+// scimGroupInput — тело POST и PUT группы
+type scimGroupInput struct {
+	DisplayName string          `json:"displayName"`
+	Members     json.RawMessage `json:"members"`
+}
+
+// CreateGroup создаёт команду с участниками — существующими пользователями
+func (h *SCIMHandler) CreateGroup(w http.ResponseWriter, r *http.Request) {
+	var in scimGroupInput
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	if in.DisplayName == "" {
+		h.writeErr(w, badSCIM(scimInvalidValue, "displayName required"))
+		return
+	}
+	members, err := parseSCIMMembers(in.Members)
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	team, err := h.teamService.ProvisionTeam(r.Context(), in.DisplayName, members)
+	if err != nil {
+		h.writeGroupErr(w, err)
+		return
+	}
+	res := toSCIMGroup(team, true)
+	w.Header().Set("Location", res.Meta.Location)
+	writeSCIM(w, http.StatusCreated, res)
+}
+
+// GetGroup возвращает команду по имени
+func (h *SCIMHandler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	team, err := h.teamService.GetTeam(r.Context(), mux.Vars(r)["id"])
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMGroup(team, !excludesMembers(r)))
+}
+
+// ReplaceGroup переименовывает команду, если изменился displayName, и задаёт её состав целиком
+func (h *SCIMHandler) ReplaceGroup(w http.ResponseWriter, r *http.Request) {
+	var in scimGroupInput
+	if !decodeSCIM(w, r, &in) {
+		return
+	}
+	if in.DisplayName == "" {
+		h.writeErr(w, badSCIM(scimInvalidValue, "displayName required"))
+		return
+	}
+	members, err := parseSCIMMembers(in.Members)
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	h.updateGroup(w, r, &scimGroupChange{displayName: &in.DisplayName, replace: members})
+}
+
+// PatchGroup переименовывает команду и меняет её состав; убранные участники теряют
+// открытые ревью, как при /team/removeMember
+func (h *SCIMHandler) PatchGroup(w http.ResponseWriter, r *http.Request) {
+	var patch scimPatchOp
+	if !decodeSCIM(w, r, &patch) {
+		return
+	}
+	change, err := parseGroupPatch(&patch)
+	if err != nil {
+		h.writeErr(w, err)
+		return
+	}
+	h.updateGroup(w, r, change)
+}
+
+// DeleteGroup убирает из команды всех участников и удаляет её
+func (h *SCIMHandler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.teamService.DisbandTeam(r.Context(), mux.Vars(r)["id"]); err != nil {
+		h.writeGroupErr(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// updateGroup применяет изменения группы и отвечает её новым состоянием. При переименовании
+// меняется и id группы: он совпадает с именем команды.
+func (h *SCIMHandler) updateGroup(w http.ResponseWriter, r *http.Request, change *scimGroupChange) {
+	var update service.GroupChange
+	if change.displayName != nil {
+		update.NewName = *change.displayName
+	}
+	if change.replace != nil {
+		update.Members = change.members()
+	} else {
+		update.Add, update.Remove = change.add, change.remove
+	}
+	team, _, err := h.teamService.UpdateGroup(r.Context(), mux.Vars(r)["id"], update)
+	if err != nil {
+		h.writeGroupErr(w, err)
+		return
+	}
+	writeSCIM(w, http.StatusOK, toSCIMGroup(team, true))
+}
+
+// writeGroupErr отвечает на ошибку изменения группы: неизвестный участник — ошибка значения запроса
+func (h *SCIMHandler) writeGroupErr(w http.ResponseWriter, err error) {
+	if errors.Is(err, service.ErrUserNotFound) {
+		writeSCIMError(w, http.StatusBadRequest, scimInvalidValue, "member "+err.Error())
+		return
+	}
+	h.writeErr(w, err)
+}
+
+// writeErr отвечает на ошибку в формате SCIM
+func (h *SCIMHandler) writeErr(w http.ResponseWriter, err error) {
+	if se, ok := asSCIMError(err); ok {
+		writeSCIMError(w, se.status, se.scimType, se.detail)
+		return
+	}
+	switch {
+	case errors.Is(err, service.ErrUserNotFound):
+		writeSCIMError(w, http.StatusNotFound, "", "user not found")
+	case err == service.ErrTeamNotFound:
+		writeSCIMError(w, http.StatusNotFound, "", "group not found")
+	case err == service.ErrUsernameTaken:
+		writeSCIMError(w, http.StatusConflict, scimUniqueness, "userName already exists")
+	case err == service.ErrTeamExists:
+		writeSCIMError(w, http.StatusConflict, scimUniqueness, "group with this displayName already exists")
+	case err == service.ErrInvalidUsername, err == service.ErrInvalidTeamName:
+		writeSCIMError(w, http.StatusBadRequest, scimInvalidValue, err.Error())
+	case err == service.ErrTeamHasChildren:
+		writeSCIMError(w, http.StatusConflict, "", "move or delete child teams first")
+	case err == service.ErrConcurrentUpdate:
+		writeSCIMError(w, http.StatusConflict, "", "resource was modified concurrently, retry the request")
+	default:
+		writeSCIMError(w, http.StatusInternalServerError, "", "internal error")
+	}
+}
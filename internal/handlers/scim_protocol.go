@@ -0,0 +1,495 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"pr-reviewer/internal/model"
+)
+
+// Схемы и формат сообщений SCIM 2.0 (RFC 7643, RFC 7644)
+const (
+	scimUserSchema   = "urn:ietf:params:scim:schemas:core:2.0:User"
+	scimGroupSchema  = "urn:ietf:params:scim:schemas:core:2.0:Group"
+	scimConfigSchema = "urn:ietf:params:scim:schemas:core:2.0:ServiceProviderConfig"
+	scimListSchema   = "urn:ietf:params:scim:api:messages:2.0:ListResponse"
+	scimPatchSchema  = "urn:ietf:params:scim:api:messages:2.0:PatchOp"
+	scimErrorSchema  = "urn:ietf:params:scim:api:messages:2.0:Error"
+
+	scimBasePath    = "/scim/v2"
+	scimContentType = "application/scim+json"
+	// scimMaxResults — наибольший размер страницы списка
+	scimMaxResults = 200
+)
+
+// Значения scimType ошибок SCIM
+const (
+	scimInvalidFilter = "invalidFilter"
+	scimInvalidSyntax = "invalidSyntax"
+	scimInvalidPath   = "invalidPath"
+	scimInvalidValue  = "invalidValue"
+	scimUniqueness    = "uniqueness"
+	scimMutability    = "mutability"
+)
+
+// scimMeta — метаданные ресурса
+type scimMeta struct {
+	ResourceType string `json:"resourceType"`
+	Location     string `json:"location"`
+}
+
+// scimRef — ссылка на пользователя или группу: участник группы или группа пользователя
+type scimRef struct {
+	Value   string `json:"value"`
+	Display string `json:"display,omitempty"`
+	Ref     string `json:"$ref,omitempty"`
+}
+
+// scimUser — пользователь SCIM; userName — имя пользователя, active — is_active
+type scimUser struct {
+	Schemas  []string  `json:"schemas"`
+	ID       string    `json:"id"`
+	UserName string    `json:"userName"`
+	Active   bool      `json:"active"`
+	Groups   []scimRef `json:"groups"`
+	Meta     scimMeta  `json:"meta"`
+}
+
+// scimGroup — группа SCIM; группа — это команда, id и displayName — имя команды
+type scimGroup struct {
+	Schemas     []string  `json:"schemas"`
+	ID          string    `json:"id"`
+	DisplayName string    `json:"displayName"`
+	Members     []scimRef `json:"members,omitempty"`
+	Meta        scimMeta  `json:"meta"`
+}
+
+// scimListResponse — страница результатов поиска
+type scimListResponse struct {
+	Schemas      []string      `json:"schemas"`
+	TotalResults int           `json:"totalResults"`
+	StartIndex   int           `json:"startIndex"`
+	ItemsPerPage int           `json:"itemsPerPage"`
+	Resources    []interface{} `json:"Resources"`
+}
+
+// scimPatchOp — запрос PATCH: список операций add, replace и remove
+type scimPatchOp struct {
+	Schemas    []string `json:"schemas"`
+	Operations []struct {
+		Op    string          `json:"op"`
+		Path  string          `json:"path"`
+		Value json.RawMessage `json:"value"`
+	} `json:"Operations"`
+}
+
+// validate проверяет схему запроса и наличие операций
+func (p *scimPatchOp) validate() error {
+	if len(p.Schemas) > 0 && !containsFold(p.Schemas, scimPatchSchema) {
+		return badSCIM(scimInvalidSyntax, "schemas must contain %s", scimPatchSchema)
+	}
+	if len(p.Operations) == 0 {
+		return badSCIM(scimInvalidSyntax, "Operations required")
+	}
+	return nil
+}
+
+// containsFold сообщает, есть ли v в срезе без учёта регистра
+func containsFold(values []string, v string) bool {
+	for _, x := range values {
+		if strings.EqualFold(x, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// scimError — ошибка запроса SCIM с HTTP-статусом и scimType
+type scimError struct {
+	status   int
+	scimType string
+	detail   string
+}
+
+func (e *scimError) Error() string { return e.detail }
+
+// badSCIM создаёт ошибку 400 с типом scimType
+func badSCIM(scimType, format string, args ...interface{}) *scimError {
+	return &scimError{status: http.StatusBadRequest, scimType: scimType, detail: fmt.Sprintf(format, args...)}
+}
+
+// writeSCIM отправляет ресурс SCIM с указанным статусом
+func writeSCIM(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", scimContentType)
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeSCIMError отправляет ошибку в формате SCIM
+func writeSCIMError(w http.ResponseWriter, status int, scimType, detail string) {
+	body := map[string]interface{}{
+		"schemas": []string{scimErrorSchema},
+		"status":  strconv.Itoa(status),
+		"detail":  detail,
+	}
+	if scimType != "" {
+		body["scimType"] = scimType
+	}
+	writeSCIM(w, status, body)
+}
+
+// decodeSCIM читает тело запроса; при ошибке отвечает 400 invalidSyntax
+func decodeSCIM(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil {
+		writeSCIMError(w, http.StatusBadRequest, scimInvalidSyntax, "invalid JSON: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// toSCIMUser представляет пользователя ресурсом SCIM
+func toSCIMUser(u *model.User) scimUser {
+	id := strconv.FormatInt(u.ID, 10)
+	groups := make([]scimRef, 0, len(u.Teams))
+	for _, m := range u.Teams {
+		groups = append(groups, scimRef{Value: m.TeamName, Display: m.TeamName, Ref: scimGroupLocation(m.TeamName)})
+	}
+	return scimUser{
+		Schemas:  []string{scimUserSchema},
+		ID:       id,
+		UserName: u.Username,
+		Active:   u.IsActive,
+		Groups:   groups,
+		Meta:     scimMeta{ResourceType: "User", Location: scimUserLocation(id)},
+	}
+}
+
+// toSCIMGroup представляет команду ресурсом SCIM; withMembers=false опускает участников
+func toSCIMGroup(team *model.Team, withMembers bool) scimGroup {
+	g := scimGroup{
+		Schemas:     []string{scimGroupSchema},
+		ID:          team.Name,
+		DisplayName: team.Name,
+		Meta:        scimMeta{ResourceType: "Group", Location: scimGroupLocation(team.Name)},
+	}
+	if withMembers {
+		g.Members = make([]scimRef, 0, len(team.Members))
+		for _, m := range team.Members {
+			g.Members = append(g.Members, scimRef{Value: m.UserID, Display: m.Username, Ref: scimUserLocation(m.UserID)})
+		}
+	}
+	return g
+}
+
+func scimUserLocation(id string) string    { return scimBasePath + "/Users/" + url.PathEscape(id) }
+func scimGroupLocation(name string) string { return scimBasePath + "/Groups/" + url.PathEscape(name) }
+
+// scimFilterRe — поддерживаемые фильтры: `<атрибут> eq "<значение>"`
+var scimFilterRe = regexp.MustCompile(`(?i)^\s*([a-z][a-z0-9._]*)\s+eq\s+("(?:[^"\\]|\\.)*")\s*$`)
+
+// parseSCIMFilter разбирает фильтр поиска; допускается только равенство атрибута attr
+func parseSCIMFilter(filter, attr string) (string, error) {
+	m := scimFilterRe.FindStringSubmatch(filter)
+	if m == nil || !strings.EqualFold(m[1], attr) {
+		return "", badSCIM(scimInvalidFilter, "only filter %s eq \"value\" is supported", attr)
+	}
+	value, err := strconv.Unquote(m[2])
+	if err != nil {
+		return "", badSCIM(scimInvalidFilter, "invalid filter value %s", m[2])
+	}
+	return value, nil
+}
+
+// scimPage читает startIndex (с 1) и count и возвращает границы страницы среди total результатов
+func scimPage(r *http.Request, total int) (start, from, to int, err error) {
+	start, count := 1, scimMaxResults
+	q := r.URL.Query()
+	if v := q.Get("startIndex"); v != "" {
+		if start, err = strconv.Atoi(v); err != nil {
+			return 0, 0, 0, badSCIM(scimInvalidValue, "startIndex must be an integer")
+		}
+		if start < 1 {
+			start = 1
+		}
+	}
+	if v := q.Get("count"); v != "" {
+		if count, err = strconv.Atoi(v); err != nil {
+			return 0, 0, 0, badSCIM(scimInvalidValue, "count must be an integer")
+		}
+		if count < 0 {
+			count = 0
+		}
+		if count > scimMaxResults {
+			count = scimMaxResults
+		}
+	}
+	from = start - 1
+	if from > total {
+		from = total
+	}
+	to = from + count
+	if to > total {
+		to = total
+	}
+	return start, from, to, nil
+}
+
+// parseSCIMBool читает логическое значение; часть провайдеров присылает его строкой "True"/"False"
+func parseSCIMBool(raw json.RawMessage) (bool, error) {
+	var b bool
+	if err := json.Unmarshal(raw, &b); err == nil {
+		return b, nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b, nil
+		}
+	}
+	return false, badSCIM(scimInvalidValue, "active must be a boolean")
+}
+
+// parseSCIMString читает непустую строку атрибута attr
+func parseSCIMString(raw json.RawMessage, attr string) (string, error) {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil || s == "" {
+		return "", badSCIM(scimInvalidValue, "%s must be a non-empty string", attr)
+	}
+	return s, nil
+}
+
+// parseSCIMMembers читает список участников группы [{"value":"<user id>"}]
+func parseSCIMMembers(raw json.RawMessage) ([]string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return []string{}, nil
+	}
+	var refs []scimRef
+	if err := json.Unmarshal(raw, &refs); err != nil {
+		return nil, badSCIM(scimInvalidValue, "members must be a list of {\"value\": user id}")
+	}
+	ids := make([]string, 0, len(refs))
+	for _, ref := range refs {
+		if ref.Value == "" {
+			return nil, badSCIM(scimInvalidValue, "member value required")
+		}
+		ids = append(ids, ref.Value)
+	}
+	return ids, nil
+}
+
+// scimUserChange — изменения пользователя из PUT или PATCH; nil — без изменений
+type scimUserChange struct {
+	username *string
+	active   *bool
+}
+
+// set применяет значение атрибута пользователя. Атрибуты, которые сервис не хранит
+// (name, emails, externalId, расширения), игнорируются, чтобы провайдер мог присылать профиль целиком.
+func (c *scimUserChange) set(attr string, raw json.RawMessage) error {
+	switch strings.ToLower(attr) {
+	case "active":
+		b, err := parseSCIMBool(raw)
+		if err != nil {
+			return err
+		}
+		c.active = &b
+	case "username":
+		s, err := parseSCIMString(raw, "userName")
+		if err != nil {
+			return err
+		}
+		c.username = &s
+	}
+	return nil
+}
+
+// parseUserPatch разбирает PATCH пользователя
+func parseUserPatch(patch *scimPatchOp) (*scimUserChange, error) {
+	if err := patch.validate(); err != nil {
+		return nil, err
+	}
+	change := &scimUserChange{}
+	for _, op := range patch.Operations {
+		switch strings.ToLower(op.Op) {
+		case "add", "replace":
+			if op.Path != "" {
+				if err := change.set(op.Path, op.Value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var attrs map[string]json.RawMessage
+			if err := json.Unmarshal(op.Value, &attrs); err != nil {
+				return nil, badSCIM(scimInvalidValue, "operation without path needs an object value")
+			}
+			for attr, raw := range attrs {
+				if err := change.set(attr, raw); err != nil {
+					return nil, err
+				}
+			}
+		case "remove":
+			switch strings.ToLower(op.Path) {
+			case "":
+				return nil, badSCIM(scimInvalidPath, "remove needs a path")
+			case "active", "username":
+				return nil, badSCIM(scimMutability, "%s cannot be removed", op.Path)
+			}
+		default:
+			return nil, badSCIM(scimInvalidSyntax, "unsupported operation %q", op.Op)
+		}
+	}
+	return change, nil
+}
+
+// scimMemberPathRe — путь к одному участнику: members[value eq "<user id>"]
+var scimMemberPathRe = regexp.MustCompile(`(?i)^members\[\s*value\s+eq\s+("(?:[^"\\]|\\.)*")\s*\]$`)
+
+// scimGroupChange — изменения группы из PATCH
+type scimGroupChange struct {
+	displayName *string
+	// replace — новый состав целиком; nil — состав меняется только add и remove
+	replace []string
+	add     []string
+	remove  []string
+}
+
+// members возвращает итоговый состав при замене: replace с учётом последующих add и remove
+func (c *scimGroupChange) members() []string {
+	removed := make(map[string]bool, len(c.remove))
+	for _, id := range c.remove {
+		removed[id] = true
+	}
+	ids := []string{}
+	for _, id := range append(append([]string{}, c.replace...), c.add...) {
+		if !removed[id] {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// addMembers, removeMembers и replaceMembers накапливают изменения состава в порядке операций
+func (c *scimGroupChange) addMembers(ids []string) {
+	c.add = append(c.add, ids...)
+	c.remove = withoutIDs(c.remove, ids)
+}
+
+func (c *scimGroupChange) removeMembers(ids []string) {
+	c.remove = append(c.remove, ids...)
+	c.add = withoutIDs(c.add, ids)
+	if c.replace != nil {
+		c.replace = withoutIDs(c.replace, ids)
+	}
+}
+
+func (c *scimGroupChange) replaceMembers(ids []string) {
+	c.replace = ids
+	c.add, c.remove = nil, nil
+}
+
+// withoutIDs возвращает ids без значений drop
+func withoutIDs(ids, drop []string) []string {
+	skip := make(map[string]bool, len(drop))
+	for _, id := range drop {
+		skip[id] = true
+	}
+	kept := []string{}
+	for _, id := range ids {
+		if !skip[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// set применяет операцию op к атрибуту группы attr
+func (c *scimGroupChange) set(op, attr string, raw json.RawMessage) error {
+	switch strings.ToLower(attr) {
+	case "displayname":
+		s, err := parseSCIMString(raw, "displayName")
+		if err != nil {
+			return err
+		}
+		c.displayName = &s
+	case "members":
+		ids, err := parseSCIMMembers(raw)
+		if err != nil {
+			return err
+		}
+		if op == "add" {
+			c.addMembers(ids)
+		} else {
+			c.replaceMembers(ids)
+		}
+	default:
+		return badSCIM(scimInvalidPath, "unsupported attribute %q", attr)
+	}
+	return nil
+}
+
+// parseGroupPatch разбирает PATCH группы: переименование и изменение состава
+func parseGroupPatch(patch *scimPatchOp) (*scimGroupChange, error) {
+	if err := patch.validate(); err != nil {
+		return nil, err
+	}
+	change := &scimGroupChange{}
+	for _, o := range patch.Operations {
+		op := strings.ToLower(o.Op)
+		switch op {
+		case "add", "replace":
+			if o.Path != "" {
+				if err := change.set(op, o.Path, o.Value); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			var attrs map[string]json.RawMessage
+			if err := json.Unmarshal(o.Value, &attrs); err != nil {
+				return nil, badSCIM(scimInvalidValue, "operation without path needs an object value")
+			}
+			for attr, raw := range attrs {
+				if err := change.set(op, attr, raw); err != nil {
+					return nil, err
+				}
+			}
+		case "remove":
+			if m := scimMemberPathRe.FindStringSubmatch(o.Path); m != nil {
+				id, err := strconv.Unquote(m[1])
+				if err != nil {
+					return nil, badSCIM(scimInvalidPath, "invalid member path %q", o.Path)
+				}
+				change.removeMembers([]string{id})
+				continue
+			}
+			if !strings.EqualFold(o.Path, "members") {
+				return nil, badSCIM(scimInvalidPath, "only members can be removed")
+			}
+			// без значения удаляются все участники, со значением — перечисленные
+			if len(o.Value) == 0 || string(o.Value) == "null" {
+				change.replaceMembers([]string{})
+				continue
+			}
+			ids, err := parseSCIMMembers(o.Value)
+			if err != nil {
+				return nil, err
+			}
+			change.removeMembers(ids)
+		default:
+			return nil, badSCIM(scimInvalidSyntax, "unsupported operation %q", o.Op)
+		}
+	}
+	return change, nil
+}
+
+// asSCIMError возвращает ошибку запроса SCIM, если err — она
+func asSCIMError(err error) (*scimError, bool) {
+	var se *scimError
+	ok := errors.As(err, &se)
+	return se, ok
+}
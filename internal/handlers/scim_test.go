@@ -0,0 +1,100 @@
+package handlers_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/service"
+)
+
+// memberIDs возвращает ID участников команды организации по умолчанию
+func (s *testServer) memberIDs(name string) []string {
+	s.t.Helper()
+	team, err := s.teams.GetTeam(s.ctx(repository.DefaultTenant), name)
+	if err != nil {
+		s.t.Fatalf("get team %s: %v", name, err)
+	}
+	var ids []string
+	for _, m := range team.Members {
+		ids = append(ids, m.UserID)
+	}
+	return ids
+}
+
+func TestSCIMGroupUpdateIsAtomic(t *testing.T) {
+	s := newTestServer(t)
+	s.team(repository.DefaultTenant, "backend", "1", "alice", "2", "bob", "3", "carol")
+
+	members := func(ids ...string) []map[string]string {
+		out := []map[string]string{}
+		for _, id := range ids {
+			out = append(out, map[string]string{"value": id})
+		}
+		return out
+	}
+	unchanged := func(t *testing.T) {
+		t.Helper()
+		if _, err := s.teams.GetTeam(s.ctx(repository.DefaultTenant), "platform"); err != service.ErrTeamNotFound {
+			t.Fatalf("team renamed despite failed member change: %v", err)
+		}
+		if got := s.memberIDs("backend"); !reflect.DeepEqual(got, []string{"1", "2", "3"}) {
+			t.Fatalf("backend members changed: %v", got)
+		}
+	}
+
+	t.Run("replace with unknown member", func(t *testing.T) {
+		status, body := s.do(adminToken, "PUT", "/scim/v2/Groups/backend", map[string]interface{}{
+			"displayName": "platform", "members": members("1", "99"),
+		})
+		if status != http.StatusBadRequest {
+			t.Fatalf("got %d %v", status, body)
+		}
+		unchanged(t)
+	})
+
+	t.Run("patch with unknown member", func(t *testing.T) {
+		status, body := s.do(adminToken, "PATCH", "/scim/v2/Groups/backend", map[string]interface{}{
+			"schemas": []string{"urn:ietf:params:scim:api:messages:2.0:PatchOp"},
+			"Operations": []map[string]interface{}{
+				{"op": "replace", "path": "displayName", "value": "platform"},
+				{"op": "add", "path": "members", "value": members("99")},
+			},
+		})
+		if status != http.StatusBadRequest {
+			t.Fatalf("got %d %v", status, body)
+		}
+		unchanged(t)
+	})
+
+	t.Run("rename and replace", func(t *testing.T) {
+		status, body := s.do(adminToken, "PUT", "/scim/v2/Groups/backend", map[string]interface{}{
+			"displayName": "platform", "members": members("1", "3"),
+		})
+		if status != http.StatusOK || body["id"] != "platform" {
+			t.Fatalf("got %d %v", status, body)
+		}
+		if _, err := s.teams.GetTeam(s.ctx(repository.DefaultTenant), "backend"); err != service.ErrTeamNotFound {
+			t.Fatalf("old name still resolves: %v", err)
+		}
+		if got := s.memberIDs("platform"); !reflect.DeepEqual(got, []string{"1", "3"}) {
+			t.Fatalf("platform members: %v", got)
+		}
+	})
+
+	t.Run("rename and remove", func(t *testing.T) {
+		status, body := s.do(adminToken, "PATCH", "/scim/v2/Groups/platform", map[string]interface{}{
+			"Operations": []map[string]interface{}{
+				{"op": "replace", "value": map[string]string{"displayName": "core"}},
+				{"op": "remove", "path": `members[value eq "3"]`},
+			},
+		})
+		if status != http.StatusOK || body["id"] != "core" {
+			t.Fatalf("got %d %v", status, body)
+		}
+		if got := s.memberIDs("core"); !reflect.DeepEqual(got, []string{"1"}) {
+			t.Fatalf("core members: %v", got)
+		}
+	})
+}
@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pr-reviewer/internal/handlers"
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/repository/memory"
+	"pr-reviewer/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// adminToken — токен глобального админа тестового сервера
+const adminToken = "test-admin-token"
+
+// testServer — сервис целиком поверх хранилища в памяти, с аутентификацией по токенам
+type testServer struct {
+	t      *testing.T
+	url    string
+	store  *memory.Store
+	auth   *service.AuthService
+	teams  *service.TeamService
+	prs    *service.PRService
+	orgs   *service.OrgService
+	client *http.Client
+}
+
+func newTestServer(t *testing.T) *testServer {
+	t.Helper()
+	st := memory.NewStore()
+	policy := service.DefaultAssignmentPolicy()
+	prs := service.NewPRService(st, policy)
+	teams := service.NewTeamService(st, prs)
+	users := service.NewUserService(st, prs)
+	auth := service.NewAuthService(st, adminToken)
+	orgs := service.NewOrgService(st, policy)
+	idem := service.NewIdempotencyService(st, service.DefaultIdempotencyTTL, service.DefaultIdempotencyLockTimeout)
+
+	r := mux.NewRouter()
+	r.Use(handlers.Authenticate(auth, orgs))
+	r.Use(handlers.Idempotency(idem))
+	handlers.NewTeamHandler(teams).RegisterTeamRoutes(r)
+	handlers.NewUserHandler(users, prs).RegisterUserRoutes(r)
+	handlers.NewPRHandler(prs).RegisterPRRoutes(r)
+	handlers.NewTokenHandler(auth).RegisterTokenRoutes(r)
+	handlers.NewAuditHandler(service.NewAuditService(st)).RegisterAuditRoutes(r)
+	handlers.NewOrgHandler(orgs).RegisterOrgRoutes(r)
+	handlers.NewArchiveHandler(service.NewArchiveService(st)).RegisterArchiveRoutes(r)
+	handlers.NewSCIMHandler(users, teams).RegisterSCIMRoutes(r)
+
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return &testServer{t: t, url: srv.URL, store: st, auth: auth, teams: teams, prs: prs, orgs: orgs, client: srv.Client()}
+}
+
+// ctx возвращает контекст админа организации tenant для подготовки данных через сервисы
+func (s *testServer) ctx(tenant string) context.Context {
+	ctx := repository.WithTenant(context.Background(), tenant)
+	return service.WithPrincipal(ctx, &model.Principal{Name: "test", Role: model.RoleAdmin, TenantID: tenant, AuthMethod: model.AuthMethodCLI})
+}
+
+// team создаёт команду с активными участниками: ID и имя через пару аргументов
+func (s *testServer) team(tenant, name string, members ...string) {
+	s.t.Helper()
+	team := &model.Team{Name: name}
+	for i := 0; i+1 < len(members); i += 2 {
+		team.Members = append(team.Members, model.TeamMember{UserID: members[i], Username: members[i+1], IsActive: true})
+	}
+	if _, err := s.teams.CreateOrUpdateTeam(s.ctx(tenant), team); err != nil {
+		s.t.Fatalf("create team %s: %v", name, err)
+	}
+}
+
+// token выпускает API-токен в организации tenant
+func (s *testServer) token(tenant string, t model.APIToken) string {
+	s.t.Helper()
+	if t.Name == "" {
+		t.Name = t.Role
+	}
+	raw, err := s.auth.CreateToken(s.ctx(tenant), &t)
+	if err != nil {
+		s.t.Fatalf("create token: %v", err)
+	}
+	return raw
+}
+
+// do выполняет запрос с токеном и возвращает статус и разобранный JSON ответа
+func (s *testServer) do(token, method, path string, body interface{}) (int, map[string]interface{}) {
+	s.t.Helper()
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			s.t.Fatal(err)
+		}
+	}
+	req, err := http.NewRequest(method, s.url+path, bytes.NewReader(data))
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	var out map[string]interface{}
+	_ = json.NewDecoder(resp.Body).Decode(&out)
+	return resp.StatusCode, out
+}
+
+// errorCode возвращает error.code из ответа
+func errorCode(body map[string]interface{}) string {
+	if e, ok := body["error"].(map[string]interface{}); ok {
+		code, _ := e["code"].(string)
+		return code
+	}
+	return ""
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"pr-reviewer/internal/model"
@@ -24,6 +25,19 @@ func NewTeamHandler(teamService *service.TeamService) *TeamHandler {
 func (h *TeamHandler) RegisterTeamRoutes(r *mux.Router) {
 	r.HandleFunc("/team/add", h.AddTeam).Methods("POST")
 	r.HandleFunc("/team/get", h.GetTeam).Methods("GET")
+	r.HandleFunc("/teams", h.ListTeams).Methods("GET")
+	r.HandleFunc("/team/addMembers", h.AddMembers).Methods("POST")
+	r.HandleFunc("/team/removeMember", h.RemoveMember).Methods("POST")
+	r.HandleFunc("/team/moveUser", h.MoveUser).Methods("POST")
+	r.HandleFunc("/team/setMembership", h.SetMembership).Methods("POST")
+	r.HandleFunc("/team/addLead", h.AddLead).Methods("POST")
+	r.HandleFunc("/team/removeLead", h.RemoveLead).Methods("POST")
+	r.HandleFunc("/team/escalations", h.Escalations).Methods("GET")
+	r.HandleFunc("/team/import", h.ImportRoster).Methods("POST")
+	r.HandleFunc("/team/setParent", h.SetParent).Methods("POST")
+	r.HandleFunc("/team/settings", h.UpdateSettings).Methods("POST")
+	r.HandleFunc("/team/rename", h.RenameTeam).Methods("POST")
+	r.HandleFunc("/team/delete", h.DeleteTeam).Methods("POST")
 }
 
 // AddTeam создаёт команду с участниками
@@ -34,7 +48,12 @@ func (h *TeamHandler) AddTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	createdTeam, err := h.teamService.CreateOrUpdateTeam(&team)
+	if !principal(r).CanManageTeam(team.Name) {
+		writeForbidden(w)
+		return
+	}
+
+	createdTeam, err := h.teamService.CreateOrUpdateTeam(r.Context(), &team)
 	if err != nil {
 		if err == service.ErrTeamExists {
 			w.WriteHeader(http.StatusBadRequest)
@@ -46,7 +65,7 @@ func (h *TeamHandler) AddTeam(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
-		http.Error(w, `{"error": {"code": "INTERNAL", "message": "internal error"}}`, http.StatusInternalServerError)
+		writeTeamError(w, err)
 		return
 	}
 
@@ -64,7 +83,7 @@ func (h *TeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	team, err := h.teamService.GetTeam(teamName)
+	team, err := h.teamService.GetTeam(r.Context(), teamName)
 	if err != nil {
 		if err == service.ErrTeamNotFound {
 			w.WriteHeader(http.StatusNotFound)
@@ -82,3 +101,315 @@ func (h *TeamHandler) GetTeam(w http.ResponseWriter, r *http.Request) {
 
 	json.NewEncoder(w).Encode(team)
 }
+
+// ListTeams возвращает страницу команд с курсорной пагинацией
+func (h *TeamHandler) ListTeams(w http.ResponseWriter, r *http.Request) {
+	limit, ok := queryInt(w, r, "limit")
+	if !ok {
+		return
+	}
+
+	teams, next, err := h.teamService.ListTeams(r.Context(), limit, r.URL.Query().Get("cursor"))
+	if err != nil {
+		if err == service.ErrInvalidCursor {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid cursor")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"teams":       teams,
+		"next_cursor": next,
+	})
+}
+
+// AddMembers добавляет участников в существующую команду; членства в других командах сохраняются
+func (h *TeamHandler) AddMembers(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string             `json:"team_name"`
+		Members  []model.TeamMember `json:"members"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if !principal(r).CanManageTeam(req.TeamName) {
+		writeForbidden(w)
+		return
+	}
+
+	team, err := h.teamService.AddMembers(r.Context(), req.TeamName, req.Members)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"team": team})
+}
+
+// authorizeMove проверяет, что вызывающий управляет командой teamName и командами, которые
+// покидает пользователь: fromTeam, а при пустом fromTeam — всеми его командами.
+// При отказе пишет ответ и возвращает false.
+func (h *TeamHandler) authorizeMove(w http.ResponseWriter, r *http.Request, userID, fromTeam, teamName string) bool {
+	p := principal(r)
+	leaving := []string{fromTeam}
+	if fromTeam == "" {
+		var err error
+		leaving, err = h.teamService.UserTeams(r.Context(), userID)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+			return false
+		}
+	}
+	for _, team := range append(leaving, teamName) {
+		if !p.CanManageTeam(team) {
+			writeForbidden(w)
+			return false
+		}
+	}
+	return true
+}
+
+// RemoveMember убирает пользователя из команды
+func (h *TeamHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string `json:"team_name"`
+		UserID   string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if !principal(r).CanManageTeam(req.TeamName) {
+		writeForbidden(w)
+		return
+	}
+
+	handoffs, err := h.teamService.RemoveMember(r.Context(), req.TeamName, req.UserID)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"team_name":  req.TeamName,
+		"user_id":    req.UserID,
+		"reassigned": handoffs,
+	})
+}
+
+// MoveUser переводит пользователя в другую команду из from_team или, без него, из всех его команд
+func (h *TeamHandler) MoveUser(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID   string `json:"user_id"`
+		FromTeam string `json:"from_team"`
+		TeamName string `json:"team_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if !h.authorizeMove(w, r, req.UserID, req.FromTeam, req.TeamName) {
+		return
+	}
+
+	user, handoffs, err := h.teamService.MoveUser(r.Context(), req.UserID, req.FromTeam, req.TeamName)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user":       user,
+		"reassigned": handoffs,
+	})
+}
+
+// SetMembership меняет флаг активности и вес членства пользователя в команде
+func (h *TeamHandler) SetMembership(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string `json:"team_name"`
+		UserID   string `json:"user_id"`
+		IsActive *bool  `json:"is_active"`
+		Weight   *int   `json:"weight"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if !principal(r).CanManageTeam(req.TeamName) {
+		writeForbidden(w)
+		return
+	}
+
+	user, err := h.teamService.SetMembership(r.Context(), req.TeamName, req.UserID, req.IsActive, req.Weight)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"user": user})
+}
+
+// AddLead назначает участника команды лидом
+func (h *TeamHandler) AddLead(w http.ResponseWriter, r *http.Request) {
+	h.setLead(w, r, true)
+}
+
+// RemoveLead возвращает лиду роль обычного участника команды
+func (h *TeamHandler) RemoveLead(w http.ResponseWriter, r *http.Request) {
+	h.setLead(w, r, false)
+}
+
+// setLead меняет роль участника команды
+func (h *TeamHandler) setLead(w http.ResponseWriter, r *http.Request, lead bool) {
+	var req struct {
+		TeamName string `json:"team_name"`
+		UserID   string `json:"user_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if !principal(r).CanManageTeam(req.TeamName) {
+		writeForbidden(w)
+		return
+	}
+
+	team, err := h.teamService.SetLead(r.Context(), req.TeamName, req.UserID, lead)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"team": team})
+}
+
+// Escalations возвращает открытые PR команды, ревью которых не уложилось в SLA
+func (h *TeamHandler) Escalations(w http.ResponseWriter, r *http.Request) {
+	teamName := r.URL.Query().Get("team_name")
+	if teamName == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "team_name required")
+		return
+	}
+	if !principal(r).CanManageTeam(teamName) {
+		writeForbidden(w)
+		return
+	}
+
+	escalations, err := h.teamService.Escalations(r.Context(), teamName)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"team_name": teamName, "escalations": escalations})
+}
+
+// SetParent переносит команду под другую родительскую команду; пустой parent_team делает её корневой
+func (h *TeamHandler) SetParent(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string `json:"team_name"`
+		Parent   string `json:"parent_team"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	team, err := h.teamService.SetParent(r.Context(), req.TeamName, req.Parent)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"team": team})
+}
+
+// UpdateSettings заменяет собственные настройки команды
+func (h *TeamHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string             `json:"team_name"`
+		Settings model.TeamSettings `json:"settings"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if !principal(r).CanManageTeam(req.TeamName) {
+		writeForbidden(w)
+		return
+	}
+
+	team, err := h.teamService.UpdateSettings(r.Context(), req.TeamName, req.Settings)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"team": team})
+}
+
+// RenameTeam переименовывает команду
+func (h *TeamHandler) RenameTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName    string `json:"team_name"`
+		NewTeamName string `json:"new_team_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+	if req.NewTeamName == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "new_team_name required")
+		return
+	}
+
+	team, err := h.teamService.RenameTeam(r.Context(), req.TeamName, req.NewTeamName)
+	if err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"team": team})
+}
+
+// DeleteTeam удаляет пустую команду
+func (h *TeamHandler) DeleteTeam(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		TeamName string `json:"team_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	if err := h.teamService.DeleteTeam(r.Context(), req.TeamName); err != nil {
+		writeTeamError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"team_name": req.TeamName})
+}
+
+// writeTeamError отображает ошибки управления составом команд в HTTP-ответ
+func writeTeamError(w http.ResponseWriter, err error) {
+	switch {
+	case err == service.ErrTeamNotFound:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "team not found")
+	case err == service.ErrUserNotFound:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+	case err == service.ErrTeamExists:
+		writeError(w, http.StatusConflict, "TEAM_EXISTS", "team_name already exists")
+	case err == service.ErrNotTeamMember:
+		writeError(w, http.StatusConflict, "NOT_TEAM_MEMBER", "user is not a member of the team")
+	case err == service.ErrTeamNotEmpty:
+		writeError(w, http.StatusConflict, "TEAM_NOT_EMPTY", "remove or move team members first")
+	case err == service.ErrTeamHasChildren:
+		writeError(w, http.StatusConflict, "TEAM_HAS_CHILDREN", "move or delete child teams first")
+	case err == service.ErrParentNotFound:
+		writeError(w, http.StatusNotFound, "NOT_FOUND", "parent team not found")
+	case err == service.ErrTeamCycle:
+		writeError(w, http.StatusConflict, "TEAM_CYCLE", "team cannot be nested under itself or its descendant")
+	case errors.Is(err, service.ErrInvalidTeamSettings):
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+	case err == service.ErrConcurrentUpdate:
+		writeError(w, http.StatusConflict, "CONFLICT", "resource was modified concurrently, retry the request")
+	case errors.Is(err, service.ErrInvalidUserID), err == service.ErrInvalidWeight, err == service.ErrInvalidTeamRole:
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+	}
+}
@@ -0,0 +1,163 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"testing"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// createOrg создаёт организацию id
+func (s *testServer) createOrg(id string) {
+	s.t.Helper()
+	if _, err := s.orgs.CreateOrganization(s.ctx(repository.DefaultTenant), id, id, model.OrgSettings{}); err != nil {
+		s.t.Fatalf("create organization %s: %v", id, err)
+	}
+}
+
+// doTenant выполняет запрос с заголовком X-Tenant-ID
+func (s *testServer) doTenant(token, tenant, method, path string) int {
+	s.t.Helper()
+	req, err := http.NewRequest(method, s.url+path, nil)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("X-Tenant-ID", tenant)
+	resp, err := s.client.Do(req)
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	resp.Body.Close()
+	return resp.StatusCode
+}
+
+// names возвращает значения поля field элементов списка list из ответа
+func names(body map[string]interface{}, list, field string) []string {
+	items, _ := body[list].([]interface{})
+	var out []string
+	for _, it := range items {
+		if m, ok := it.(map[string]interface{}); ok {
+			v, _ := m[field].(string)
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func TestTenantIsolation(t *testing.T) {
+	s := newTestServer(t)
+	s.createOrg("acme")
+	s.createOrg("globex")
+
+	// у организаций совпадают имена команд и ID пользователей, но не данные
+	s.team("acme", "backend", "1", "alice", "2", "bob", "3", "carol")
+	s.team("globex", "backend", "1", "xavier", "2", "yan", "3", "zoe")
+	s.team("globex", "platform", "7", "walt", "8", "una", "9", "vic")
+	if _, err := s.prs.CreatePR(s.ctx("acme"), &model.PullRequest{ID: "pr-a", Name: "acme change", AuthorID: "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.prs.CreatePR(s.ctx("globex"), &model.PullRequest{ID: "pr-g", Name: "globex change", AuthorID: "7"}); err != nil {
+		t.Fatal(err)
+	}
+
+	acme := s.token("acme", model.APIToken{Role: model.RoleAdmin})
+	globexBot := &model.APIToken{Name: "globex-ci", Role: model.RoleBot}
+	globex, err := s.auth.CreateToken(s.ctx("globex"), globexBot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("teams", func(t *testing.T) {
+		if status, _ := s.do(acme, "GET", "/team/get?team_name=platform", nil); status != http.StatusNotFound {
+			t.Fatalf("get team of another tenant: got %d", status)
+		}
+		status, body := s.do(acme, "GET", "/team/get?team_name=backend", nil)
+		if status != http.StatusOK {
+			t.Fatalf("get own team: got %d %v", status, body)
+		}
+		if got := names(body, "members", "username"); len(got) != 3 || got[0] != "alice" {
+			t.Fatalf("own team members: got %v", got)
+		}
+		_, body = s.do(acme, "GET", "/teams", nil)
+		if got := names(body, "teams", "team_name"); len(got) != 1 || got[0] != "backend" {
+			t.Fatalf("team list: got %v", got)
+		}
+		status, _ = s.do(acme, "POST", "/team/rename", map[string]string{"team_name": "platform", "new_team_name": "stolen"})
+		if status != http.StatusNotFound {
+			t.Fatalf("rename team of another tenant: got %d", status)
+		}
+		if _, err := s.teams.GetTeam(s.ctx("globex"), "platform"); err != nil {
+			t.Fatalf("team of globex changed: %v", err)
+		}
+	})
+
+	t.Run("users", func(t *testing.T) {
+		status, _ := s.do(acme, "POST", "/users/setIsActive", map[string]interface{}{"user_id": "7", "is_active": false})
+		if status != http.StatusNotFound {
+			t.Fatalf("deactivate user of another tenant: got %d", status)
+		}
+		if status, _ := s.do(acme, "POST", "/users/setIsActive", map[string]interface{}{"user_id": "2", "is_active": false}); status != http.StatusOK {
+			t.Fatalf("deactivate own user: got %d", status)
+		}
+		globexUsers := s.store.Repos(repository.WithTenant(context.Background(), "globex")).Users
+		for _, id := range []int{2, 7} {
+			u, err := globexUsers.GetByID(strconv.Itoa(id))
+			if err != nil || !u.IsActive {
+				t.Fatalf("globex user %d changed: %+v, %v", id, u, err)
+			}
+		}
+	})
+
+	t.Run("pull requests", func(t *testing.T) {
+		if status, _ := s.do(acme, "GET", "/pullRequest/get?pull_request_id=pr-g", nil); status != http.StatusNotFound {
+			t.Fatalf("get PR of another tenant: got %d", status)
+		}
+		if status, _ := s.do(acme, "POST", "/pullRequest/merge", map[string]string{"pull_request_id": "pr-g"}); status != http.StatusNotFound {
+			t.Fatalf("merge PR of another tenant: got %d", status)
+		}
+		if pr, err := s.prs.GetPR(s.ctx("globex"), "pr-g"); err != nil || pr.Status != "OPEN" {
+			t.Fatalf("globex PR changed: %+v, %v", pr, err)
+		}
+		_, body := s.do(acme, "GET", "/pullRequests", nil)
+		if got := names(body, "pull_requests", "pull_request_id"); len(got) != 1 || got[0] != "pr-a" {
+			t.Fatalf("acme PR list: got %v", got)
+		}
+		_, body = s.do(globex, "GET", "/pullRequests", nil)
+		if got := names(body, "pull_requests", "pull_request_id"); len(got) != 1 || got[0] != "pr-g" {
+			t.Fatalf("globex PR list: got %v", got)
+		}
+	})
+
+	t.Run("tokens", func(t *testing.T) {
+		_, body := s.do(acme, "GET", "/admin/tokens", nil)
+		listed := names(body, "tokens", "name")
+		if len(listed) != 1 {
+			t.Fatalf("acme tokens: got %v", listed)
+		}
+		for _, name := range listed {
+			if name == globexBot.Name {
+				t.Fatalf("token of another tenant listed: %v", body)
+			}
+		}
+		status, _ := s.do(acme, "POST", "/admin/tokens/revoke", map[string]int64{"id": globexBot.ID})
+		if status != http.StatusNotFound {
+			t.Fatalf("revoke token of another tenant: got %d", status)
+		}
+		if status, _ := s.do(globex, "GET", "/pullRequests", nil); status != http.StatusOK {
+			t.Fatalf("globex token after foreign revoke: got %d", status)
+		}
+	})
+
+	t.Run("tenant header", func(t *testing.T) {
+		if status := s.doTenant(acme, "globex", "GET", "/teams"); status != http.StatusForbidden {
+			t.Fatalf("tenant token with another X-Tenant-ID: got %d", status)
+		}
+		if status := s.doTenant(adminToken, "globex", "GET", "/team/get?team_name=platform"); status != http.StatusOK {
+			t.Fatalf("global admin with X-Tenant-ID: got %d", status)
+		}
+	})
+}
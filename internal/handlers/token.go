@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/service"
+
+	"github.com/gorilla/mux"
+)
+
+// TokenHandler управляет API-токенами
+type TokenHandler struct {
+	authService *service.AuthService
+}
+
+// NewTokenHandler создаёт новый обработчик токенов
+func NewTokenHandler(authService *service.AuthService) *TokenHandler {
+	return &TokenHandler{authService: authService}
+}
+
+// RegisterTokenRoutes регистрирует маршруты управления токенами
+func (h *TokenHandler) RegisterTokenRoutes(r *mux.Router) {
+	r.HandleFunc("/admin/tokens", h.CreateToken).Methods("POST")
+	r.HandleFunc("/admin/tokens", h.ListTokens).Methods("GET")
+	r.HandleFunc("/admin/tokens/revoke", h.RevokeToken).Methods("POST")
+}
+
+// CreateToken выпускает токен; его значение возвращается только в этом ответе
+func (h *TokenHandler) CreateToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name     string `json:"name"`
+		Role     string `json:"role"`
+		UserID   string `json:"user_id"`
+		TeamName string `json:"team_name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	t := &model.APIToken{Name: req.Name, Role: req.Role, UserID: req.UserID, TeamName: req.TeamName}
+	token, err := h.authService.CreateToken(r.Context(), t)
+	if err != nil {
+		switch err {
+		case service.ErrInvalidRole:
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "role must be one of admin, team-lead, bot, member")
+		case service.ErrTokenScope:
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "team-lead tokens need team_name, member tokens need user_id")
+		default:
+			writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, map[string]interface{}{
+		"token":      token,
+		"token_info": t,
+	})
+}
+
+// ListTokens возвращает выданные токены без их значений
+func (h *TokenHandler) ListTokens(w http.ResponseWriter, r *http.Request) {
+	tokens, err := h.authService.ListTokens(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"tokens": tokens})
+}
+
+// RevokeToken отзывает токен
+func (h *TokenHandler) RevokeToken(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		ID int64 `json:"id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	t, err := h.authService.RevokeToken(r.Context(), req.ID)
+	if err != nil {
+		if err == service.ErrTokenNotFound {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "token not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"token_info": t})
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 	"pr-reviewer/internal/service"
+	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -23,6 +25,10 @@ func NewUserHandler(userService *service.UserService, prService *service.PRServi
 func (h *UserHandler) RegisterUserRoutes(r *mux.Router) {
 	r.HandleFunc("/users/setIsActive", h.SetIsActive).Methods("POST")
 	r.HandleFunc("/users/getReview", h.GetReviewPRs).Methods("GET")
+	r.HandleFunc("/users/setSkills", h.SetSkills).Methods("POST")
+	r.HandleFunc("/users/setOutOfOffice", h.SetOutOfOffice).Methods("POST")
+	r.HandleFunc("/users/declines", h.GetDeclines).Methods("GET")
+	r.HandleFunc("/users/declineStats", h.GetDeclineStats).Methods("GET")
 }
 
 // SetIsActive обновляет флаг активности
@@ -36,7 +42,11 @@ func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	user, err := h.userService.SetIsActive(req.UserID, req.IsActive)
+	if !h.authorizeUser(w, r, req.UserID, false) {
+		return
+	}
+
+	user, handoffs, err := h.userService.SetIsActive(r.Context(), req.UserID, req.IsActive)
 	if err != nil {
 		if err == service.ErrUserNotFound {
 			w.WriteHeader(http.StatusNotFound)
@@ -48,15 +58,48 @@ func (h *UserHandler) SetIsActive(w http.ResponseWriter, r *http.Request) {
 			})
 			return
 		}
+		if err == service.ErrConcurrentUpdate {
+			writeError(w, http.StatusConflict, "CONFLICT", "resource was modified concurrently, retry the request")
+			return
+		}
 		http.Error(w, `{"error":{"code":"INTERNAL","message":"internal error"}}`, http.StatusInternalServerError)
 		return
 	}
 
 	json.NewEncoder(w).Encode(map[string]interface{}{
-		"user": user,
+		"user":       user,
+		"reassigned": handoffs,
 	})
 }
 
+// authorizeUser проверяет, что вызывающий может менять пользователя userID: админ, лид одной из его команд
+// или, при allowSelf, сам пользователь. При отказе пишет ответ и возвращает false.
+func (h *UserHandler) authorizeUser(w http.ResponseWriter, r *http.Request, userID string, allowSelf bool) bool {
+	p := principal(r)
+	if allowSelf && p.IsUser(userID) {
+		return true
+	}
+	user, err := h.userService.GetUserByID(r.Context(), userID)
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+			return false
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return false
+	}
+	for _, team := range user.TeamNames() {
+		if p.CanManageTeam(team) {
+			return true
+		}
+	}
+	if p.CanManageTeam("") {
+		return true
+	}
+	writeForbidden(w)
+	return false
+}
+
 // GetReviewPRs возвращает PR, где пользователь назначен ревьювером
 func (h *UserHandler) GetReviewPRs(w http.ResponseWriter, r *http.Request) {
 	userID := r.URL.Query().Get("user_id")
@@ -65,7 +108,13 @@ func (h *UserHandler) GetReviewPRs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	prs, err := h.prService.GetPRsForReviewer(userID)
+	status := r.URL.Query().Get("status")
+	if status != "" && status != "OPEN" && status != "MERGED" {
+		http.Error(w, `{"error":{"code":"INVALID_REQUEST","message":"status must be OPEN or MERGED"}}`, http.StatusBadRequest)
+		return
+	}
+
+	prs, err := h.prService.GetPRsForReviewer(r.Context(), userID, status)
 	if err != nil {
 		if err == service.ErrUserNotFound {
 			w.WriteHeader(http.StatusNotFound)
@@ -81,8 +130,137 @@ func (h *UserHandler) GetReviewPRs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	for i := range prs {
+		hideExplanation(r, &prs[i])
+	}
 	json.NewEncoder(w).Encode(map[string]interface{}{
 		"user_id":       userID,
 		"pull_requests": prs,
 	})
 }
+
+// SetSkills заменяет навыки пользователя
+func (h *UserHandler) SetSkills(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID string   `json:"user_id"`
+		Skills []string `json:"skills"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	if !h.authorizeUser(w, r, req.UserID, true) {
+		return
+	}
+
+	user, err := h.userService.SetSkills(r.Context(), req.UserID, req.Skills)
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"user": user})
+}
+
+// SetOutOfOffice отмечает пользователя отсутствующим до указанного момента
+func (h *UserHandler) SetOutOfOffice(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID string     `json:"user_id"`
+		Until  *time.Time `json:"until"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "invalid JSON")
+		return
+	}
+
+	if !h.authorizeUser(w, r, req.UserID, true) {
+		return
+	}
+
+	user, err := h.userService.SetOutOfOffice(r.Context(), req.UserID, req.Until)
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"user": user})
+}
+
+// GetDeclines возвращает отказы пользователя от ревью; их видят сам пользователь, лиды его команд и админ
+func (h *UserHandler) GetDeclines(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "user_id required")
+		return
+	}
+	if !h.authorizeUser(w, r, userID, true) {
+		return
+	}
+	since, ok := parseSince(w, r)
+	if !ok {
+		return
+	}
+
+	declines, err := h.prService.GetDeclines(r.Context(), userID, since)
+	if err != nil {
+		if err == service.ErrUserNotFound {
+			writeError(w, http.StatusNotFound, "NOT_FOUND", "user not found")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user_id":  userID,
+		"declines": declines,
+	})
+}
+
+// GetDeclineStats возвращает сводку отказов по пользователям; ?min=N оставляет тех, у кого не меньше N отказов
+func (h *UserHandler) GetDeclineStats(w http.ResponseWriter, r *http.Request) {
+	since, ok := parseSince(w, r)
+	if !ok {
+		return
+	}
+	minTotal := 1
+	if v := r.URL.Query().Get("min"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "min must be a positive integer")
+			return
+		}
+		minTotal = n
+	}
+
+	stats, err := h.prService.GetDeclineStats(r.Context(), since, minTotal)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "INTERNAL", "internal error")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{"stats": stats})
+}
+
+// parseSince читает необязательный параметр ?since в формате RFC3339
+func parseSince(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	v := r.URL.Query().Get("since")
+	if v == "" {
+		return time.Time{}, true
+	}
+	since, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "INVALID_REQUEST", "since must be RFC3339 timestamp")
+		return time.Time{}, false
+	}
+	return since, true
+}
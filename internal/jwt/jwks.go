@@ -0,0 +1,265 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey возвращается, если в JWKS нет ключа с kid из заголовка токена
+var ErrUnknownKey = fmt.Errorf("%w: unknown signing key", ErrInvalid)
+
+// jwk — открытый ключ в формате RFC 7517; поддерживаются RSA и EC P-256
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS разбирает набор ключей {"keys":[...]}. Ключи не для подписи и неподдерживаемых типов пропускаются.
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("jwt: invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		var (
+			key crypto.PublicKey
+			err error
+		)
+		switch k.Kty {
+		case "RSA":
+			key, err = k.rsaKey()
+		case "EC":
+			key, err = k.ecKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("jwt: key %q: %w", k.Kid, err)
+		}
+		keys[k.Kid] = key
+	}
+	return keys, nil
+}
+
+func (k jwk) rsaKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jwk) ecKey() (*ecdsa.PublicKey, error) {
+	if k.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+	if !key.Curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on curve")
+	}
+	return key, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) == 0 {
+		return nil, errors.New("invalid base64url integer")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// Паузы между попытками загрузить JWKS после ошибки: удваиваются от retryBackoff до maxRetryBackoff
+const (
+	retryBackoff    = 5 * time.Second
+	maxRetryBackoff = 5 * time.Minute
+)
+
+// KeySet — кэш ключей JWKS из файла или по URL. Ключи перечитываются раз в refresh,
+// а при неизвестном kid — не чаще раза в minReload, чтобы подхватить ротацию ключей.
+// Загрузка идёт без блокировки и не более одной одновременно; после ошибки следующая
+// попытка откладывается, а запросы до неё обслуживаются прежними ключами.
+type KeySet struct {
+	load      func() ([]byte, error)
+	refresh   time.Duration
+	minReload time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	loadedAt  time.Time // последняя успешная загрузка
+	attemptAt time.Time // последняя попытка загрузки
+	retryAt   time.Time // до этого момента после ошибки новых попыток нет
+	failures  int
+	lastErr   error
+	// loading не nil, пока идёт загрузка; закрывается по её окончании
+	loading chan struct{}
+}
+
+// NewFileKeySet читает JWKS из файла
+func NewFileKeySet(path string, refresh time.Duration) *KeySet {
+	return newKeySet(func() ([]byte, error) { return os.ReadFile(path) }, refresh)
+}
+
+// NewURLKeySet загружает JWKS по HTTP(S)
+func NewURLKeySet(url string, refresh time.Duration) *KeySet {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return newKeySet(func() ([]byte, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return nil, fmt.Errorf("jwt: JWKS endpoint returned %s", resp.Status)
+		}
+		return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	}, refresh)
+}
+
+func newKeySet(load func() ([]byte, error), refresh time.Duration) *KeySet {
+	return &KeySet{load: load, refresh: refresh, minReload: time.Minute, now: time.Now}
+}
+
+// Key возвращает ключ по kid. Пустой kid допустим, если в наборе ровно один ключ.
+func (s *KeySet) Key(kid string) (crypto.PublicKey, error) {
+	s.mu.Lock()
+	stale := s.keys == nil || s.now().Sub(s.loadedAt) >= s.refresh
+	empty := s.keys == nil
+	s.mu.Unlock()
+	if stale {
+		// с прежними ключами не ждём чужую загрузку: устаревшие ключи лучше задержки
+		err := s.reload(empty, func(now time.Time) bool {
+			return s.keys == nil || now.Sub(s.loadedAt) >= s.refresh
+		})
+		if err != nil && empty {
+			return nil, err
+		}
+	}
+
+	s.mu.Lock()
+	key, ok := s.lookup(kid)
+	empty, lastErr := s.keys == nil, s.lastErr
+	s.mu.Unlock()
+	switch {
+	case ok:
+		return key, nil
+	case empty && lastErr != nil:
+		return nil, lastErr
+	}
+
+	// неизвестный kid: возможно, ключи сменились
+	err := s.reload(true, func(now time.Time) bool { return now.Sub(s.attemptAt) >= s.minReload })
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, ErrUnknownKey
+}
+
+// reload загружает набор, если due (вызывается под s.mu) разрешает и пауза после ошибки прошла.
+// Если загрузка уже идёт, при wait дожидается её и возвращает её результат, иначе сразу nil.
+// При ошибке остаются прежние ключи.
+func (s *KeySet) reload(wait bool, due func(now time.Time) bool) error {
+	s.mu.Lock()
+	if loading := s.loading; loading != nil {
+		s.mu.Unlock()
+		if !wait {
+			return nil
+		}
+		<-loading
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return s.lastErr
+	}
+	now := s.now()
+	if now.Before(s.retryAt) || !due(now) {
+		s.mu.Unlock()
+		return nil
+	}
+	loading := make(chan struct{})
+	s.loading, s.attemptAt = loading, now
+	s.mu.Unlock()
+
+	keys, err := s.fetch()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		s.failures++
+		backoff := maxRetryBackoff
+		if s.failures <= 10 {
+			backoff = min(retryBackoff<<(s.failures-1), maxRetryBackoff)
+		}
+		s.retryAt = s.now().Add(backoff)
+	} else {
+		s.keys, s.loadedAt, s.failures, s.retryAt = keys, s.now(), 0, time.Time{}
+	}
+	s.lastErr = err
+	s.loading = nil
+	close(loading)
+	return err
+}
+
+// fetch загружает и разбирает набор ключей
+func (s *KeySet) fetch() (map[string]crypto.PublicKey, error) {
+	data, err := s.load()
+	if err != nil {
+		return nil, fmt.Errorf("jwt: cannot load JWKS: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+func (s *KeySet) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
@@ -0,0 +1,221 @@
+// Package jwt проверяет подписанные JWT (RS256, ES256) по открытым ключам из JWKS.
+// Реализован на стандартной библиотеке и поддерживает только то, что нужно для входа через SSO.
+package jwt
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+)
+
+// ErrInvalid оборачивают все ошибки, вызванные самим токеном, в отличие от сбоев загрузки ключей
+var ErrInvalid = errors.New("jwt: invalid token")
+
+var (
+	ErrMalformed      = fmt.Errorf("%w: malformed", ErrInvalid)
+	ErrUnsupportedAlg = fmt.Errorf("%w: unsupported algorithm", ErrInvalid)
+	ErrSignature      = fmt.Errorf("%w: bad signature", ErrInvalid)
+	ErrExpired        = fmt.Errorf("%w: expired", ErrInvalid)
+	ErrNotYetValid    = fmt.Errorf("%w: not valid yet", ErrInvalid)
+	ErrIssuer         = fmt.Errorf("%w: unexpected issuer", ErrInvalid)
+	ErrAudience       = fmt.Errorf("%w: unexpected audience", ErrInvalid)
+)
+
+// Claims — полезная нагрузка токена
+type Claims map[string]interface{}
+
+// String возвращает строковый claim или пустую строку
+func (c Claims) String(name string) string {
+	switch v := c[name].(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	}
+	return ""
+}
+
+// Strings возвращает claim-массив строк; одиночная строка считается массивом из одного элемента
+func (c Claims) Strings(name string) []string {
+	switch v := c[name].(type) {
+	case string:
+		return []string{v}
+	case []interface{}:
+		out := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// time возвращает числовой claim (NumericDate) как время
+func (c Claims) time(name string) (time.Time, bool, error) {
+	v, ok := c[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+	n, ok := v.(json.Number)
+	if !ok {
+		return time.Time{}, false, ErrMalformed
+	}
+	f, err := n.Float64()
+	if err != nil {
+		return time.Time{}, false, ErrMalformed
+	}
+	return time.Unix(int64(f), 0), true, nil
+}
+
+// KeySource выдаёт открытый ключ по kid
+type KeySource interface {
+	Key(kid string) (crypto.PublicKey, error)
+}
+
+// Verifier проверяет подпись и стандартные claims: exp, nbf, iss, aud
+type Verifier struct {
+	keys     KeySource
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// NewVerifier создаёт Verifier. Пустые issuer и audience не проверяются.
+func NewVerifier(keys KeySource, issuer, audience string) *Verifier {
+	return &Verifier{keys: keys, issuer: issuer, audience: audience, leeway: time.Minute, now: time.Now}
+}
+
+// LooksLikeJWT отличает JWT от непрозрачных API-токенов
+func LooksLikeJWT(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// Verify проверяет токен и возвращает его claims
+func (v *Verifier) Verify(token string) (Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrMalformed
+	}
+
+	var header struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, err
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrMalformed
+	}
+
+	key, err := v.keys.Key(header.Kid)
+	if err != nil {
+		return nil, err
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := verifySignature(header.Alg, key, digest[:], sig); err != nil {
+		return nil, err
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, err
+	}
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// verifySignature проверяет подпись; алгоритм должен соответствовать типу ключа
+func verifySignature(alg string, key crypto.PublicKey, digest, sig []byte) error {
+	switch alg {
+	case "RS256":
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		if rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest, sig) != nil {
+			return ErrSignature
+		}
+		return nil
+	case "ES256":
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return ErrUnsupportedAlg
+		}
+		// подпись JWS для ES256 — r||s по 32 байта, а не DER
+		if len(sig) != 64 {
+			return ErrSignature
+		}
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, digest, r, s) {
+			return ErrSignature
+		}
+		return nil
+	}
+	return ErrUnsupportedAlg
+}
+
+// validate проверяет срок действия, издателя и аудиторию
+func (v *Verifier) validate(c Claims) error {
+	now := v.now()
+	exp, ok, err := c.time("exp")
+	if err != nil {
+		return err
+	}
+	if !ok || now.After(exp.Add(v.leeway)) {
+		return ErrExpired
+	}
+	nbf, ok, err := c.time("nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.leeway).Before(nbf) {
+		return ErrNotYetValid
+	}
+	if v.issuer != "" && c.String("iss") != v.issuer {
+		return ErrIssuer
+	}
+	if v.audience != "" {
+		found := false
+		for _, aud := range c.Strings("aud") {
+			if aud == v.audience {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return ErrAudience
+		}
+	}
+	return nil
+}
+
+// decodeSegment декодирует base64url-сегмент JSON, сохраняя числа как json.Number
+func decodeSegment(seg string, out interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(seg)
+	if err != nil {
+		return ErrMalformed
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(out); err != nil {
+		return ErrMalformed
+	}
+	return nil
+}
@@ -0,0 +1,428 @@
+package jwt
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var (
+	testNow = time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	rsaKey = mustRSAKey()
+	ecKey  = mustECKey()
+)
+
+func mustRSAKey() *rsa.PrivateKey {
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func mustECKey() *ecdsa.PrivateKey {
+	k, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		panic(err)
+	}
+	return k
+}
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func b64JSON(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return b64(data)
+}
+
+// jwksJSON собирает JWKS из открытых ключей по kid
+func jwksJSON(keys map[string]crypto.PublicKey) []byte {
+	var set struct {
+		Keys []map[string]string `json:"keys"`
+	}
+	for kid, key := range keys {
+		switch k := key.(type) {
+		case *rsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "RSA", "kid": kid, "use": "sig", "alg": "RS256",
+				"n": b64(k.N.Bytes()), "e": b64(big.NewInt(int64(k.E)).Bytes()),
+			})
+		case *ecdsa.PublicKey:
+			set.Keys = append(set.Keys, map[string]string{
+				"kty": "EC", "kid": kid, "crv": "P-256",
+				"x": b64(k.X.FillBytes(make([]byte, 32))), "y": b64(k.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+	}
+	data, err := json.Marshal(set)
+	if err != nil {
+		panic(err)
+	}
+	return data
+}
+
+// sign выпускает токен; key — *rsa.PrivateKey, *ecdsa.PrivateKey, []byte для HS256 или nil для alg=none
+func sign(t *testing.T, alg, kid string, key interface{}, claims map[string]interface{}) string {
+	t.Helper()
+	header := map[string]string{"alg": alg, "typ": "JWT"}
+	if kid != "" {
+		header["kid"] = kid
+	}
+	input := b64JSON(header) + "." + b64JSON(claims)
+	digest := sha256.Sum256([]byte(input))
+
+	var sig []byte
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		var err error
+		if sig, err = rsa.SignPKCS1v15(rand.Reader, k, crypto.SHA256, digest[:]); err != nil {
+			t.Fatal(err)
+		}
+	case *ecdsa.PrivateKey:
+		r, s, err := ecdsa.Sign(rand.Reader, k, digest[:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		sig = append(r.FillBytes(make([]byte, 32)), s.FillBytes(make([]byte, 32))...)
+	case []byte:
+		mac := hmac.New(sha256.New, k)
+		mac.Write([]byte(input))
+		sig = mac.Sum(nil)
+	}
+	return input + "." + b64(sig)
+}
+
+func claims(overrides map[string]interface{}) map[string]interface{} {
+	c := map[string]interface{}{
+		"sub": "42",
+		"iss": "https://idp.example.com",
+		"aud": "pr-reviewer",
+		"exp": testNow.Add(time.Hour).Unix(),
+		"iat": testNow.Unix(),
+	}
+	for k, v := range overrides {
+		if v == nil {
+			delete(c, k)
+			continue
+		}
+		c[k] = v
+	}
+	return c
+}
+
+func newTestVerifier(keys map[string]crypto.PublicKey) *Verifier {
+	data := jwksJSON(keys)
+	ks := newKeySet(func() ([]byte, error) { return data, nil }, time.Hour)
+	ks.now = func() time.Time { return testNow }
+	v := NewVerifier(ks, "https://idp.example.com", "pr-reviewer")
+	v.now = func() time.Time { return testNow }
+	return v
+}
+
+func TestVerify(t *testing.T) {
+	v := newTestVerifier(map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey, "ec": &ecKey.PublicKey})
+	rsaDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := sign(t, "RS256", "rsa", rsaKey, claims(nil))
+
+	tests := []struct {
+		name  string
+		token string
+		want  error
+	}{
+		{"RS256", valid, nil},
+		{"ES256", sign(t, "ES256", "ec", ecKey, claims(nil)), nil},
+		{"audience in array", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": []string{"other", "pr-reviewer"}})), nil},
+		{"expired within leeway", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": testNow.Add(-30 * time.Second).Unix()})), nil},
+		{"expired", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": testNow.Add(-2 * time.Minute).Unix()})), ErrExpired},
+		{"no exp", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": nil})), ErrExpired},
+		{"exp not a number", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"exp": "tomorrow"})), ErrMalformed},
+		{"nbf in future", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"nbf": testNow.Add(5 * time.Minute).Unix()})), ErrNotYetValid},
+		{"nbf within leeway", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"nbf": testNow.Add(30 * time.Second).Unix()})), nil},
+		{"wrong issuer", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": "https://evil.example.com"})), ErrIssuer},
+		{"no issuer", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"iss": nil})), ErrIssuer},
+		{"wrong audience", sign(t, "RS256", "rsa", rsaKey, claims(map[string]interface{}{"aud": "other"})), ErrAudience},
+		{"alg none", sign(t, "none", "rsa", nil, claims(nil)), ErrUnsupportedAlg},
+		{"HS256 with public key as secret", sign(t, "HS256", "rsa", rsaDER, claims(nil)), ErrUnsupportedAlg},
+		{"RS256 header with EC key", sign(t, "RS256", "ec", ecKey, claims(nil)), ErrUnsupportedAlg},
+		{"ES256 header with RSA key", sign(t, "ES256", "rsa", rsaKey, claims(nil)), ErrUnsupportedAlg},
+		{"signed by another key", sign(t, "RS256", "rsa", otherRSAKey(t), claims(nil)), ErrSignature},
+		{"tampered payload", tamper(valid), ErrSignature},
+		{"unknown kid", sign(t, "RS256", "missing", rsaKey, claims(nil)), ErrUnknownKey},
+		{"empty kid with several keys", sign(t, "RS256", "", rsaKey, claims(nil)), ErrUnknownKey},
+		{"two segments", "a.b", ErrMalformed},
+		{"bad header", "!!!." + b64JSON(claims(nil)) + ".sig", ErrMalformed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := v.Verify(tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("Verify: want %v, got %v", tt.want, err)
+			}
+			if tt.want != nil {
+				if !errors.Is(err, ErrInvalid) {
+					t.Fatalf("token error must wrap ErrInvalid: %v", err)
+				}
+				return
+			}
+			if c.String("sub") != "42" {
+				t.Fatalf("claims: got %v", c)
+			}
+		})
+	}
+}
+
+// otherRSAKey возвращает RSA-ключ, которого нет в JWKS
+func otherRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	k, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+// tamper подменяет полезную нагрузку, сохраняя подпись
+func tamper(token string) string {
+	parts := strings.Split(token, ".")
+	return parts[0] + "." + b64JSON(claims(map[string]interface{}{"sub": "1"})) + "." + parts[2]
+}
+
+func TestVerifySingleKeyWithoutKid(t *testing.T) {
+	v := newTestVerifier(map[string]crypto.PublicKey{"": &ecKey.PublicKey})
+	if _, err := v.Verify(sign(t, "ES256", "", ecKey, claims(nil))); err != nil {
+		t.Fatalf("empty kid with one key: %v", err)
+	}
+	v = newTestVerifier(map[string]crypto.PublicKey{"only": &ecKey.PublicKey})
+	if _, err := v.Verify(sign(t, "ES256", "", ecKey, claims(nil))); err != nil {
+		t.Fatalf("token without kid, single keyed JWKS: %v", err)
+	}
+}
+
+func TestParseJWKS(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		keys    int
+		wantErr bool
+	}{
+		{"encryption key skipped", `{"keys":[{"kty":"RSA","kid":"a","use":"enc","n":"AQAB","e":"AQAB"}]}`, 0, false},
+		{"unknown type skipped", `{"keys":[{"kty":"oct","kid":"a","k":"c2VjcmV0"}]}`, 0, false},
+		{"bad exponent", `{"keys":[{"kty":"RSA","kid":"a","n":"AQAB","e":"AQ"}]}`, 0, true},
+		{"unsupported curve", `{"keys":[{"kty":"EC","kid":"a","crv":"P-384","x":"AQ","y":"AQ"}]}`, 0, true},
+		{"point not on curve", `{"keys":[{"kty":"EC","kid":"a","crv":"P-256","x":"AQ","y":"AQ"}]}`, 0, true},
+		{"not json", `keys`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, err := ParseJWKS([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseJWKS: wantErr %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && len(keys) != tt.keys {
+				t.Fatalf("ParseJWKS: want %d keys, got %d", tt.keys, len(keys))
+			}
+		})
+	}
+}
+
+// fakeClock — управляемое время для KeySet
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestKeySetBacksOffAfterFailedRefresh(t *testing.T) {
+	clock := &fakeClock{now: testNow}
+	data := jwksJSON(map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey})
+	var loads int32
+	var failing atomic.Bool
+	ks := newKeySet(func() ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		if failing.Load() {
+			return nil, errors.New("idp is down")
+		}
+		return data, nil
+	}, 10*time.Minute)
+	ks.now = clock.Now
+
+	if _, err := ks.Key("rsa"); err != nil {
+		t.Fatalf("first Key: %v", err)
+	}
+	failing.Store(true)
+	clock.Advance(11 * time.Minute)
+
+	// ключи устарели, загрузка не удалась: отдаём прежний ключ
+	for i := 0; i < 5; i++ {
+		if _, err := ks.Key("rsa"); err != nil {
+			t.Fatalf("Key during outage: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&loads); n != 2 {
+		t.Fatalf("want one refresh attempt during backoff, got %d loads", n-1)
+	}
+	// неизвестный kid во время паузы не вызывает загрузку
+	if _, err := ks.Key("other"); !errors.Is(err, ErrUnknownKey) {
+		t.Fatalf("unknown kid during backoff: want ErrUnknownKey, got %v", err)
+	}
+	if n := atomic.LoadInt32(&loads); n != 2 {
+		t.Fatalf("unknown kid must respect backoff, got %d loads", n)
+	}
+
+	clock.Advance(retryBackoff)
+	ks.Key("rsa")
+	if n := atomic.LoadInt32(&loads); n != 3 {
+		t.Fatalf("want retry after backoff, got %d loads", n)
+	}
+	// вторая ошибка подряд удваивает паузу
+	clock.Advance(retryBackoff)
+	ks.Key("rsa")
+	if n := atomic.LoadInt32(&loads); n != 3 {
+		t.Fatalf("backoff must double after second failure, got %d loads", n)
+	}
+
+	failing.Store(false)
+	clock.Advance(retryBackoff)
+	ks.Key("rsa")
+	if n := atomic.LoadInt32(&loads); n != 4 {
+		t.Fatalf("want retry after doubled backoff, got %d loads", n)
+	}
+	ks.Key("rsa")
+	if n := atomic.LoadInt32(&loads); n != 4 {
+		t.Fatalf("fresh keys must not be reloaded, got %d loads", n)
+	}
+}
+
+func TestKeySetInitialLoadFailure(t *testing.T) {
+	clock := &fakeClock{now: testNow}
+	var loads int32
+	ks := newKeySet(func() ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		return nil, errors.New("idp is down")
+	}, time.Hour)
+	ks.now = clock.Now
+
+	for i := 0; i < 3; i++ {
+		if _, err := ks.Key("rsa"); err == nil || errors.Is(err, ErrInvalid) {
+			t.Fatalf("Key without keys: want load error, got %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("want one attempt before backoff expires, got %d", n)
+	}
+}
+
+func TestKeySetSingleFlight(t *testing.T) {
+	data := jwksJSON(map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey})
+	release := make(chan struct{})
+	var loads int32
+	ks := newKeySet(func() ([]byte, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return data, nil
+	}, time.Hour)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := ks.Key("rsa")
+			errs <- err
+		}()
+	}
+	// ждём, пока первая загрузка начнётся, и отпускаем её
+	for atomic.LoadInt32(&loads) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Key: %v", err)
+		}
+	}
+	if n := atomic.LoadInt32(&loads); n != 1 {
+		t.Fatalf("want a single load for concurrent callers, got %d", n)
+	}
+}
+
+func TestKeySetStaleKeysDoNotWaitForRefresh(t *testing.T) {
+	clock := &fakeClock{now: testNow}
+	data := jwksJSON(map[string]crypto.PublicKey{"rsa": &rsaKey.PublicKey})
+	var blocking atomic.Bool
+	release := make(chan struct{})
+	started := make(chan struct{})
+	ks := newKeySet(func() ([]byte, error) {
+		if blocking.Load() {
+			close(started)
+			<-release
+		}
+		return data, nil
+	}, time.Minute)
+	ks.now = clock.Now
+	if _, err := ks.Key("rsa"); err != nil {
+		t.Fatal(err)
+	}
+
+	blocking.Store(true)
+	clock.Advance(2 * time.Minute)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ks.Key("rsa")
+	}()
+	<-started
+
+	// загрузка висит, но запросы обслуживаются прежними ключами без ожидания
+	got := make(chan error, 1)
+	go func() {
+		_, err := ks.Key("rsa")
+		got <- err
+	}()
+	select {
+	case err := <-got:
+		if err != nil {
+			t.Fatalf("Key with stale keys: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Key blocked on a refresh in progress")
+	}
+	close(release)
+	<-done
+}
@@ -0,0 +1,272 @@
+// Package ber кодирует и разбирает подмножество BER (X.690), достаточное для протокола LDAP:
+// определённая длина, номера тегов до 30.
+package ber
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Class — класс тега
+type Class byte
+
+// Классы тегов
+const (
+	ClassUniversal   Class = 0x00
+	ClassApplication Class = 0x40
+	ClassContext     Class = 0x80
+)
+
+// Универсальные теги
+const (
+	TagBoolean     = 1
+	TagInteger     = 2
+	TagOctetString = 4
+	TagNull        = 5
+	TagEnumerated  = 10
+	TagSequence    = 16
+	TagSet         = 17
+)
+
+const (
+	constructedBit = 0x20
+	// MaxPacketSize ограничивает размер одного сообщения
+	MaxPacketSize = 16 << 20
+)
+
+// ErrMalformed — данные не являются корректным BER
+var ErrMalformed = errors.New("ber: malformed data")
+
+// Packet — элемент BER: примитивный со значением Value или составной с дочерними элементами
+type Packet struct {
+	Class       Class
+	Constructed bool
+	Tag         int
+	Value       []byte
+	Children    []*Packet
+}
+
+// NewConstructed создаёт составной элемент
+func NewConstructed(class Class, tag int, children ...*Packet) *Packet {
+	return &Packet{Class: class, Constructed: true, Tag: tag, Children: children}
+}
+
+// NewSequence создаёт SEQUENCE
+func NewSequence(children ...*Packet) *Packet {
+	return NewConstructed(ClassUniversal, TagSequence, children...)
+}
+
+// NewSet создаёт SET
+func NewSet(children ...*Packet) *Packet {
+	return NewConstructed(ClassUniversal, TagSet, children...)
+}
+
+// NewPrimitive создаёт примитивный элемент с готовым значением
+func NewPrimitive(class Class, tag int, value []byte) *Packet {
+	return &Packet{Class: class, Tag: tag, Value: value}
+}
+
+// NewString создаёт строку с тегом tag класса class
+func NewString(class Class, tag int, s string) *Packet {
+	return NewPrimitive(class, tag, []byte(s))
+}
+
+// NewOctetString создаёт OCTET STRING
+func NewOctetString(s string) *Packet {
+	return NewString(ClassUniversal, TagOctetString, s)
+}
+
+// NewInteger создаёт INTEGER
+func NewInteger(v int64) *Packet {
+	return NewPrimitive(ClassUniversal, TagInteger, encodeInt(v))
+}
+
+// NewEnumerated создаёт ENUMERATED
+func NewEnumerated(v int64) *Packet {
+	return NewPrimitive(ClassUniversal, TagEnumerated, encodeInt(v))
+}
+
+// NewBoolean создаёт BOOLEAN
+func NewBoolean(v bool) *Packet {
+	if v {
+		return NewPrimitive(ClassUniversal, TagBoolean, []byte{0xff})
+	}
+	return NewPrimitive(ClassUniversal, TagBoolean, []byte{0x00})
+}
+
+// Add добавляет дочерние элементы и возвращает p
+func (p *Packet) Add(children ...*Packet) *Packet {
+	p.Children = append(p.Children, children...)
+	return p
+}
+
+// Is сообщает, что элемент имеет класс class и тег tag
+func (p *Packet) Is(class Class, tag int) bool {
+	return p != nil && p.Class == class && p.Tag == tag
+}
+
+// Str возвращает значение примитивного элемента строкой
+func (p *Packet) Str() string {
+	return string(p.Value)
+}
+
+// Int возвращает значение INTEGER или ENUMERATED
+func (p *Packet) Int() (int64, error) {
+	if p.Constructed || len(p.Value) == 0 || len(p.Value) > 8 {
+		return 0, ErrMalformed
+	}
+	v := int64(int8(p.Value[0]))
+	for _, b := range p.Value[1:] {
+		v = v<<8 | int64(b)
+	}
+	return v, nil
+}
+
+// Bool возвращает значение BOOLEAN
+func (p *Packet) Bool() (bool, error) {
+	if p.Constructed || len(p.Value) != 1 {
+		return false, ErrMalformed
+	}
+	return p.Value[0] != 0, nil
+}
+
+// Bytes кодирует элемент
+func (p *Packet) Bytes() []byte {
+	value := p.Value
+	if p.Constructed {
+		value = nil
+		for _, c := range p.Children {
+			value = append(value, c.Bytes()...)
+		}
+	}
+	id := byte(p.Class) | byte(p.Tag)
+	if p.Constructed {
+		id |= constructedBit
+	}
+	out := append([]byte{id}, encodeLength(len(value))...)
+	return append(out, value...)
+}
+
+// Parse разбирает ровно один элемент из data
+func Parse(data []byte) (*Packet, error) {
+	p, rest, err := parse(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: %d trailing bytes", ErrMalformed, len(rest))
+	}
+	return p, nil
+}
+
+// Read читает из r один элемент верхнего уровня
+func Read(r *bufio.Reader) (*Packet, error) {
+	id, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	first, err := r.ReadByte()
+	if err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	header := []byte{id, first}
+	length := int(first)
+	if first&0x80 != 0 {
+		n := int(first & 0x7f)
+		if n == 0 || n > 4 {
+			return nil, fmt.Errorf("%w: unsupported length encoding", ErrMalformed)
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		header = append(header, buf...)
+		length = 0
+		for _, b := range buf {
+			length = length<<8 | int(b)
+		}
+	}
+	if length > MaxPacketSize {
+		return nil, fmt.Errorf("%w: packet of %d bytes is too large", ErrMalformed, length)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return Parse(append(header, body...))
+}
+
+// parse разбирает элемент в начале data и возвращает остаток
+func parse(data []byte) (*Packet, []byte, error) {
+	if len(data) < 2 {
+		return nil, nil, ErrMalformed
+	}
+	id := data[0]
+	if id&0x1f == 0x1f {
+		return nil, nil, fmt.Errorf("%w: high tag numbers are not supported", ErrMalformed)
+	}
+	p := &Packet{Class: Class(id & 0xc0), Constructed: id&constructedBit != 0, Tag: int(id & 0x1f)}
+
+	length, pos := int(data[1]), 2
+	if data[1]&0x80 != 0 {
+		n := int(data[1] & 0x7f)
+		if n == 0 || n > 4 || len(data) < 2+n {
+			return nil, nil, fmt.Errorf("%w: unsupported length encoding", ErrMalformed)
+		}
+		length = 0
+		for _, b := range data[2 : 2+n] {
+			length = length<<8 | int(b)
+		}
+		pos += n
+	}
+	if length < 0 || len(data)-pos < length {
+		return nil, nil, fmt.Errorf("%w: truncated element", ErrMalformed)
+	}
+	value, rest := data[pos:pos+length], data[pos+length:]
+
+	if !p.Constructed {
+		p.Value = value
+		return p, rest, nil
+	}
+	for len(value) > 0 {
+		child, tail, err := parse(value)
+		if err != nil {
+			return nil, nil, err
+		}
+		p.Children = append(p.Children, child)
+		value = tail
+	}
+	return p, rest, nil
+}
+
+// encodeLength кодирует длину в короткой или длинной форме
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var buf []byte
+	for v := n; v > 0; v >>= 8 {
+		buf = append([]byte{byte(v)}, buf...)
+	}
+	return append([]byte{0x80 | byte(len(buf))}, buf...)
+}
+
+// encodeInt кодирует целое минимальным числом байт в дополнительном коде
+func encodeInt(v int64) []byte {
+	buf := []byte{byte(v)}
+	for v > 127 || v < -128 {
+		v >>= 8
+		buf = append([]byte{byte(v)}, buf...)
+	}
+	return buf
+}
+
+// unexpectedEOF превращает EOF посреди элемента в io.ErrUnexpectedEOF
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
@@ -0,0 +1,138 @@
+package ber
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Packet
+	}{
+		{"octet string", NewOctetString("dc=example,dc=org")},
+		{"long string", NewOctetString(strings.Repeat("x", 200))},
+		{"string over 64k", NewOctetString(strings.Repeat("y", 70000))},
+		{"boolean", NewBoolean(true)},
+		{"enumerated", NewEnumerated(2)},
+		{"context string", NewString(ClassContext, 3, "secret")},
+		{"application", NewConstructed(ClassApplication, 3, NewOctetString("base"), NewInteger(0))},
+		{"nested", NewSequence(
+			NewInteger(7),
+			NewSet(NewOctetString("a"), NewOctetString("b")),
+			NewSequence(NewSequence(NewBoolean(false))),
+		)},
+		{"empty sequence", NewSequence()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Parse(tt.p.Bytes())
+			if err != nil {
+				t.Fatalf("Parse: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.p) {
+				t.Fatalf("round trip:\n got %+v\nwant %+v", got, tt.p)
+			}
+		})
+	}
+}
+
+func TestIntegers(t *testing.T) {
+	for _, v := range []int64{0, 1, -1, 127, 128, -128, -129, 255, 256, 65535, 1 << 40, math.MaxInt64, math.MinInt64} {
+		p, err := Parse(NewInteger(v).Bytes())
+		if err != nil {
+			t.Fatalf("%d: Parse: %v", v, err)
+		}
+		got, err := p.Int()
+		if err != nil || got != v {
+			t.Fatalf("%d: got %d, %v", v, got, err)
+		}
+	}
+	// минимальная длина в дополнительном коде
+	for v, want := range map[int64]int{0: 1, 127: 1, 128: 2, -128: 1, -129: 2, 32767: 2, 32768: 3} {
+		if n := len(NewInteger(v).Value); n != want {
+			t.Errorf("%d: encoded in %d bytes, want %d", v, n, want)
+		}
+	}
+}
+
+func TestLengthEncoding(t *testing.T) {
+	tests := []struct {
+		n    int
+		want []byte
+	}{
+		{0, []byte{0x00}},
+		{127, []byte{0x7f}},
+		{128, []byte{0x81, 0x80}},
+		{255, []byte{0x81, 0xff}},
+		{256, []byte{0x82, 0x01, 0x00}},
+		{70000, []byte{0x83, 0x01, 0x11, 0x70}},
+	}
+	for _, tt := range tests {
+		if got := encodeLength(tt.n); !bytes.Equal(got, tt.want) {
+			t.Errorf("encodeLength(%d) = % x, want % x", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRead(t *testing.T) {
+	first := NewSequence(NewInteger(1), NewOctetString(strings.Repeat("z", 300)))
+	second := NewSequence(NewInteger(2))
+	r := bufio.NewReader(bytes.NewReader(append(first.Bytes(), second.Bytes()...)))
+
+	for i, want := range []*Packet{first, second} {
+		got, err := Read(r)
+		if err != nil {
+			t.Fatalf("packet %d: %v", i, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("packet %d: got %+v", i, got)
+		}
+	}
+	if _, err := Read(r); err != io.EOF {
+		t.Fatalf("after last packet: want io.EOF, got %v", err)
+	}
+
+	truncated := first.Bytes()[:10]
+	if _, err := Read(bufio.NewReader(bytes.NewReader(truncated))); err != io.ErrUnexpectedEOF {
+		t.Fatalf("truncated packet: want io.ErrUnexpectedEOF, got %v", err)
+	}
+	huge := []byte{0x30, 0x84, 0x7f, 0xff, 0xff, 0xff}
+	if _, err := Read(bufio.NewReader(bytes.NewReader(huge))); !errors.Is(err, ErrMalformed) {
+		t.Fatalf("oversized packet: want ErrMalformed, got %v", err)
+	}
+}
+
+func TestMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"only tag", []byte{0x04}},
+		{"truncated value", []byte{0x04, 0x05, 'a', 'b'}},
+		{"trailing bytes", []byte{0x04, 0x01, 'a', 0x00}},
+		{"high tag number", []byte{0x1f, 0x81, 0x00}},
+		{"indefinite length", []byte{0x30, 0x80, 0x00, 0x00}},
+		{"length of five bytes", []byte{0x04, 0x85, 0, 0, 0, 0, 1, 'a'}},
+		{"truncated child", []byte{0x30, 0x03, 0x04, 0x05, 'a'}},
+	}
+	for _, tt := range tests {
+		if _, err := Parse(tt.data); !errors.Is(err, ErrMalformed) {
+			t.Errorf("%s: want ErrMalformed, got %v", tt.name, err)
+		}
+	}
+
+	if _, err := NewPrimitive(ClassUniversal, TagInteger, make([]byte, 9)).Int(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("9-byte integer: want ErrMalformed, got %v", err)
+	}
+	if _, err := NewPrimitive(ClassUniversal, TagBoolean, []byte{1, 1}).Bool(); !errors.Is(err, ErrMalformed) {
+		t.Errorf("2-byte boolean: want ErrMalformed, got %v", err)
+	}
+}
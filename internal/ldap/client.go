@@ -0,0 +1,195 @@
+package ldap
+
+import (
+	"bufio"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"pr-reviewer/internal/ldap/ber"
+)
+
+// ErrUnexpectedResponse — сервер ответил не той операцией или не на тот запрос
+var ErrUnexpectedResponse = errors.New("ldap: unexpected response")
+
+// Conn — соединение с сервером LDAP. Запросы выполняются последовательно;
+// Conn не предназначен для одновременного использования из нескольких горутин.
+type Conn struct {
+	conn    net.Conn
+	br      *bufio.Reader
+	nextID  int64
+	timeout time.Duration
+}
+
+// Dial подключается по адресу вида ldap://host[:389] или ldaps://host[:636].
+// timeout ограничивает подключение и каждую операцию; 0 — без ограничения.
+func Dial(rawURL string, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("ldap: parse url: %w", err)
+	}
+	host := u.Host
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch strings.ToLower(u.Scheme) {
+	case "ldap":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "389")
+		}
+		conn, err = dialer.Dial("tcp", host)
+	case "ldaps":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "636")
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", host, &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("ldap: unsupported scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ldap: dial %s: %w", host, err)
+	}
+	return &Conn{conn: conn, br: bufio.NewReader(conn), timeout: timeout}, nil
+}
+
+// Close отправляет UnbindRequest и закрывает соединение
+func (c *Conn) Close() error {
+	c.nextID++
+	_ = c.send(EncodeMessage(c.nextID, ber.NewPrimitive(ber.ClassApplication, AppUnbindRequest, nil)))
+	return c.conn.Close()
+}
+
+// Bind выполняет простую аутентификацию
+func (c *Conn) Bind(dn, password string) error {
+	c.nextID++
+	id := c.nextID
+	op := ber.NewConstructed(ber.ClassApplication, AppBindRequest,
+		ber.NewInteger(3),
+		ber.NewOctetString(dn),
+		ber.NewString(ber.ClassContext, 0, password),
+	)
+	if err := c.send(EncodeMessage(id, op)); err != nil {
+		return err
+	}
+	resp, _, err := c.receive(id)
+	if err != nil {
+		return err
+	}
+	if !resp.Is(ber.ClassApplication, AppBindResponse) {
+		return ErrUnexpectedResponse
+	}
+	return decodeResult(resp)
+}
+
+// SearchRequest — параметры поиска
+type SearchRequest struct {
+	BaseDN     string
+	Scope      Scope
+	Filter     string
+	Attributes []string
+	// SizeLimit — ограничение числа записей на стороне сервера; 0 — без ограничения
+	SizeLimit int
+	// PageSize — размер страницы (RFC 2696); 0 — без постраничной выдачи
+	PageSize int
+}
+
+// Search выполняет поиск и возвращает все найденные записи, при необходимости
+// запрашивая страницы по очереди. Ссылки на другие серверы пропускаются.
+func (c *Conn) Search(req *SearchRequest) ([]*Entry, error) {
+	filter, err := ParseFilter(req.Filter)
+	if err != nil {
+		return nil, err
+	}
+	attrs := ber.NewSequence()
+	for _, a := range req.Attributes {
+		attrs.Add(ber.NewOctetString(a))
+	}
+
+	var entries []*Entry
+	cookie := ""
+	for {
+		c.nextID++
+		id := c.nextID
+		op := ber.NewConstructed(ber.ClassApplication, AppSearchRequest,
+			ber.NewOctetString(req.BaseDN),
+			ber.NewEnumerated(int64(req.Scope)),
+			ber.NewEnumerated(0), // neverDerefAliases
+			ber.NewInteger(int64(req.SizeLimit)),
+			ber.NewInteger(0),
+			ber.NewBoolean(false),
+			filter.Encode(),
+			attrs,
+		)
+		var controls []*ber.Packet
+		if req.PageSize > 0 {
+			controls = append(controls, EncodePagingControl(req.PageSize, cookie))
+		}
+		if err := c.send(EncodeMessage(id, op, controls...)); err != nil {
+			return nil, err
+		}
+
+		for {
+			resp, respControls, err := c.receive(id)
+			if err != nil {
+				return nil, err
+			}
+			switch {
+			case resp.Is(ber.ClassApplication, AppSearchEntry):
+				e, err := decodeEntry(resp)
+				if err != nil {
+					return nil, err
+				}
+				entries = append(entries, e)
+				continue
+			case resp.Is(ber.ClassApplication, AppSearchReference):
+				continue
+			case !resp.Is(ber.ClassApplication, AppSearchDone):
+				return nil, ErrUnexpectedResponse
+			}
+			if err := decodeResult(resp); err != nil {
+				return nil, err
+			}
+			cookie = ""
+			if req.PageSize > 0 {
+				_, cookie, _ = DecodePagingControl(respControls)
+			}
+			break
+		}
+		if cookie == "" {
+			return entries, nil
+		}
+	}
+}
+
+// send записывает сообщение
+func (c *Conn) send(msg *ber.Packet) error {
+	if c.timeout > 0 {
+		_ = c.conn.SetWriteDeadline(time.Now().Add(c.timeout))
+	}
+	if _, err := c.conn.Write(msg.Bytes()); err != nil {
+		return fmt.Errorf("ldap: write: %w", err)
+	}
+	return nil
+}
+
+// receive читает ответ на запрос id
+func (c *Conn) receive(id int64) (op, controls *ber.Packet, err error) {
+	if c.timeout > 0 {
+		_ = c.conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	p, err := ber.Read(c.br)
+	if err != nil {
+		return nil, nil, fmt.Errorf("ldap: read: %w", err)
+	}
+	gotID, op, controls, err := DecodeMessage(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if gotID != id {
+		return nil, nil, ErrUnexpectedResponse
+	}
+	return op, controls, nil
+}
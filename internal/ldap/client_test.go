@@ -0,0 +1,165 @@
+package ldap_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/ldap"
+	"pr-reviewer/internal/ldap/ldaptest"
+)
+
+const (
+	bindDN   = "cn=sync,dc=example,dc=org"
+	password = "secret"
+)
+
+// people возвращает n пользователей в ou=people и одну группу
+func people(n int) []*ldap.Entry {
+	entries := []*ldap.Entry{
+		ldap.NewEntry("dc=example,dc=org", map[string][]string{"objectClass": {"domain"}}),
+		ldap.NewEntry("ou=people,dc=example,dc=org", map[string][]string{"objectClass": {"organizationalUnit"}}),
+	}
+	for i := 1; i <= n; i++ {
+		entries = append(entries, ldap.NewEntry(fmt.Sprintf("uid=user%d,ou=people,dc=example,dc=org", i), map[string][]string{
+			"objectClass": {"inetOrgPerson"},
+			"uid":         {fmt.Sprintf("user%d", i)},
+			"uidNumber":   {fmt.Sprint(1000 + i)},
+			"mail":        {fmt.Sprintf("user%d@example.org", i)},
+		}))
+	}
+	return append(entries, ldap.NewEntry("cn=dev,dc=example,dc=org", map[string][]string{
+		"objectClass": {"groupOfNames"},
+		"cn":          {"dev"},
+		"member":      {"uid=user1,ou=people,dc=example,dc=org"},
+	}))
+}
+
+func dial(t *testing.T, srv *ldaptest.Server) *ldap.Conn {
+	t.Helper()
+	conn, err := ldap.Dial(srv.URL(), 5*time.Second)
+	if err != nil {
+		t.Fatalf("Dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := conn.Bind(bindDN, password); err != nil {
+		t.Fatalf("Bind: %v", err)
+	}
+	return conn
+}
+
+func newServer(t *testing.T, entries ...*ldap.Entry) *ldaptest.Server {
+	t.Helper()
+	srv, err := ldaptest.NewServer(bindDN, password, entries...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { srv.Close() })
+	return srv
+}
+
+func TestSearchPaged(t *testing.T) {
+	tests := []struct {
+		pageSize int
+		searches int
+	}{
+		{0, 1},
+		{10, 3},
+		{25, 1},
+		{24, 2},
+		{1, 25},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("page %d", tt.pageSize), func(t *testing.T) {
+			srv := newServer(t, people(25)...)
+			entries, err := dial(t, srv).Search(&ldap.SearchRequest{
+				BaseDN:     "ou=people,dc=example,dc=org",
+				Scope:      ldap.ScopeWholeSubtree,
+				Filter:     "(objectClass=inetOrgPerson)",
+				Attributes: []string{"uid", "uidNumber"},
+				PageSize:   tt.pageSize,
+			})
+			if err != nil {
+				t.Fatalf("Search: %v", err)
+			}
+			if len(entries) != 25 {
+				t.Fatalf("entries: got %d, want 25", len(entries))
+			}
+			seen := make(map[string]bool)
+			for _, e := range entries {
+				if seen[e.DN] {
+					t.Fatalf("entry %s returned twice", e.DN)
+				}
+				seen[e.DN] = true
+				if e.Value("uid") == "" || e.Value("uidNumber") == "" || e.Value("mail") != "" {
+					t.Fatalf("entry %s: unexpected attributes %+v", e.DN, e.Attributes)
+				}
+			}
+			if got := srv.Searches(); got != tt.searches {
+				t.Fatalf("search requests: got %d, want %d", got, tt.searches)
+			}
+		})
+	}
+}
+
+func TestSearch(t *testing.T) {
+	srv := newServer(t, people(3)...)
+	conn := dial(t, srv)
+
+	tests := []struct {
+		name  string
+		req   ldap.SearchRequest
+		count int
+	}{
+		{"escaped value", ldap.SearchRequest{BaseDN: "dc=example,dc=org", Scope: ldap.ScopeWholeSubtree,
+			Filter: "(uid=" + ldap.EscapeValue("user2") + ")"}, 1},
+		{"substring", ldap.SearchRequest{BaseDN: "dc=example,dc=org", Scope: ldap.ScopeWholeSubtree,
+			Filter: "(&(objectClass=inetOrgPerson)(uid=user*))"}, 3},
+		{"single level", ldap.SearchRequest{BaseDN: "dc=example,dc=org", Scope: ldap.ScopeSingleLevel,
+			Filter: "(objectClass=*)"}, 2},
+		{"base object", ldap.SearchRequest{BaseDN: "cn=dev,dc=example,dc=org", Scope: ldap.ScopeBaseObject,
+			Filter: "(objectClass=*)"}, 1},
+		{"no match", ldap.SearchRequest{BaseDN: "dc=example,dc=org", Scope: ldap.ScopeWholeSubtree,
+			Filter: "(uid=" + ldap.EscapeValue("user*") + ")"}, 0},
+	}
+	for _, tt := range tests {
+		entries, err := conn.Search(&tt.req)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if len(entries) != tt.count {
+			t.Fatalf("%s: got %d entries, want %d", tt.name, len(entries), tt.count)
+		}
+	}
+
+	_, err := conn.Search(&ldap.SearchRequest{BaseDN: "ou=missing,dc=example,dc=org", Scope: ldap.ScopeWholeSubtree, Filter: "(objectClass=*)"})
+	var lerr *ldap.Error
+	if !errors.As(err, &lerr) || lerr.Code != ldap.ResultNoSuchObject {
+		t.Fatalf("missing base: want result %d, got %v", ldap.ResultNoSuchObject, err)
+	}
+	if _, err := conn.Search(&ldap.SearchRequest{BaseDN: "dc=example,dc=org", Filter: "(uid=user1"}); !errors.Is(err, ldap.ErrInvalidFilter) {
+		t.Fatalf("invalid filter: want ErrInvalidFilter, got %v", err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	srv := newServer(t, people(1)...)
+	conn, err := ldap.Dial(srv.URL(), 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	var lerr *ldap.Error
+	if err := conn.Bind(bindDN, "wrong"); !errors.As(err, &lerr) || lerr.Code != ldap.ResultInvalidCredentials {
+		t.Fatalf("wrong password: want result %d, got %v", ldap.ResultInvalidCredentials, err)
+	}
+	_, err = conn.Search(&ldap.SearchRequest{BaseDN: "dc=example,dc=org", Scope: ldap.ScopeWholeSubtree, Filter: "(objectClass=*)"})
+	if !errors.As(err, &lerr) || lerr.Code != ldap.ResultInsufficientAccessRight {
+		t.Fatalf("search without bind: want result %d, got %v", ldap.ResultInsufficientAccessRight, err)
+	}
+	if err := conn.Bind("CN=Sync, DC=Example, DC=Org", password); err != nil {
+		t.Fatalf("bind with differently written DN: %v", err)
+	}
+}
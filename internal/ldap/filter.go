@@ -0,0 +1,431 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pr-reviewer/internal/ldap/ber"
+)
+
+// ErrInvalidFilter — строка фильтра не соответствует RFC 4515
+var ErrInvalidFilter = errors.New("ldap: invalid filter")
+
+// Правила сравнения Active Directory для битовых масок
+const (
+	MatchingRuleBitAnd = "1.2.840.113556.1.4.803"
+	MatchingRuleBitOr  = "1.2.840.113556.1.4.804"
+)
+
+// FilterKind — вид условия фильтра; значение совпадает с тегом в протоколе
+type FilterKind int
+
+// Виды условий
+const (
+	FilterAnd        FilterKind = 0
+	FilterOr         FilterKind = 1
+	FilterNot        FilterKind = 2
+	FilterEqual      FilterKind = 3
+	FilterSubstrings FilterKind = 4
+	FilterGreater    FilterKind = 5
+	FilterLess       FilterKind = 6
+	FilterPresent    FilterKind = 7
+	FilterApprox     FilterKind = 8
+	FilterExtensible FilterKind = 9
+)
+
+// Filter — условие поиска
+type Filter struct {
+	Kind     FilterKind
+	Children []*Filter // and, or, not
+	Attr     string
+	Value    string
+
+	// подстроки: Initial*Any[0]*...*Final
+	Initial string
+	Any     []string
+	Final   string
+
+	// расширенное сравнение
+	Rule    string
+	DNAttrs bool
+}
+
+// ParseFilter разбирает строку фильтра, например (&(objectClass=group)(cn=dev-*))
+func ParseFilter(s string) (*Filter, error) {
+	s = strings.TrimSpace(s)
+	f, rest, err := parseFilter(s)
+	if err != nil {
+		return nil, err
+	}
+	if rest != "" {
+		return nil, fmt.Errorf("%w: unexpected %q after filter", ErrInvalidFilter, rest)
+	}
+	return f, nil
+}
+
+// parseFilter разбирает один фильтр в скобках в начале s и возвращает остаток
+func parseFilter(s string) (*Filter, string, error) {
+	if !strings.HasPrefix(s, "(") {
+		return nil, "", fmt.Errorf("%w: expected '(' in %q", ErrInvalidFilter, s)
+	}
+	s = s[1:]
+	if s == "" {
+		return nil, "", fmt.Errorf("%w: unexpected end", ErrInvalidFilter)
+	}
+
+	switch s[0] {
+	case '&', '|':
+		f := &Filter{Kind: FilterAnd}
+		if s[0] == '|' {
+			f.Kind = FilterOr
+		}
+		s = s[1:]
+		for strings.HasPrefix(s, "(") {
+			child, rest, err := parseFilter(s)
+			if err != nil {
+				return nil, "", err
+			}
+			f.Children = append(f.Children, child)
+			s = rest
+		}
+		return closeFilter(f, s)
+	case '!':
+		child, rest, err := parseFilter(s[1:])
+		if err != nil {
+			return nil, "", err
+		}
+		return closeFilter(&Filter{Kind: FilterNot, Children: []*Filter{child}}, rest)
+	}
+
+	end := strings.IndexByte(s, ')')
+	if end < 0 {
+		return nil, "", fmt.Errorf("%w: missing ')'", ErrInvalidFilter)
+	}
+	f, err := parseItem(s[:end])
+	if err != nil {
+		return nil, "", err
+	}
+	return f, s[end+1:], nil
+}
+
+// closeFilter проверяет закрывающую скобку составного фильтра
+func closeFilter(f *Filter, s string) (*Filter, string, error) {
+	if !strings.HasPrefix(s, ")") {
+		return nil, "", fmt.Errorf("%w: missing ')'", ErrInvalidFilter)
+	}
+	return f, s[1:], nil
+}
+
+// parseItem разбирает простое условие без скобок
+func parseItem(s string) (*Filter, error) {
+	eq := strings.IndexByte(s, '=')
+	if eq <= 0 {
+		return nil, fmt.Errorf("%w: %q has no attribute", ErrInvalidFilter, s)
+	}
+	attr, raw := s[:eq], s[eq+1:]
+	f := &Filter{}
+	switch attr[len(attr)-1] {
+	case '~':
+		f.Kind, attr = FilterApprox, attr[:len(attr)-1]
+	case '>':
+		f.Kind, attr = FilterGreater, attr[:len(attr)-1]
+	case '<':
+		f.Kind, attr = FilterLess, attr[:len(attr)-1]
+	case ':':
+		return parseExtensible(attr[:len(attr)-1], raw)
+	default:
+		if raw == "*" {
+			return &Filter{Kind: FilterPresent, Attr: attr}, nil
+		}
+		if strings.Contains(raw, "*") {
+			return parseSubstrings(attr, raw)
+		}
+		f.Kind = FilterEqual
+	}
+	if attr == "" {
+		return nil, fmt.Errorf("%w: %q has no attribute", ErrInvalidFilter, s)
+	}
+	value, err := unescapeValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	f.Attr, f.Value = attr, value
+	return f, nil
+}
+
+// parseSubstrings разбирает условие с подстановкой: attr=ini*any*fin
+func parseSubstrings(attr, raw string) (*Filter, error) {
+	parts := strings.Split(raw, "*")
+	f := &Filter{Kind: FilterSubstrings, Attr: attr}
+	for i, part := range parts {
+		value, err := unescapeValue(part)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case i == 0:
+			f.Initial = value
+		case i == len(parts)-1:
+			f.Final = value
+		case value != "":
+			f.Any = append(f.Any, value)
+		}
+	}
+	return f, nil
+}
+
+// parseExtensible разбирает расширенное сравнение: attr[:dn][:rule]:=value
+func parseExtensible(left, raw string) (*Filter, error) {
+	f := &Filter{Kind: FilterExtensible}
+	parts := strings.Split(left, ":")
+	f.Attr = parts[0]
+	for _, p := range parts[1:] {
+		switch {
+		case strings.EqualFold(p, "dn"):
+			f.DNAttrs = true
+		case p != "":
+			f.Rule = p
+		}
+	}
+	if f.Attr == "" && f.Rule == "" {
+		return nil, fmt.Errorf("%w: extensible match needs an attribute or a rule", ErrInvalidFilter)
+	}
+	value, err := unescapeValue(raw)
+	if err != nil {
+		return nil, err
+	}
+	f.Value = value
+	return f, nil
+}
+
+// unescapeValue раскрывает экранирование \XX
+func unescapeValue(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' {
+			b.WriteByte(s[i])
+			continue
+		}
+		if i+2 >= len(s) {
+			return "", fmt.Errorf("%w: bad escape in %q", ErrInvalidFilter, s)
+		}
+		decoded, err := hex.DecodeString(s[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("%w: bad escape in %q", ErrInvalidFilter, s)
+		}
+		b.Write(decoded)
+		i += 2
+	}
+	return b.String(), nil
+}
+
+// EscapeValue экранирует значение для подстановки в строку фильтра
+func EscapeValue(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case '*', '(', ')', '\\', 0:
+			fmt.Fprintf(&b, `\%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// Encode кодирует фильтр для SearchRequest
+func (f *Filter) Encode() *ber.Packet {
+	tag := int(f.Kind)
+	switch f.Kind {
+	case FilterAnd, FilterOr, FilterNot:
+		p := ber.NewConstructed(ber.ClassContext, tag)
+		for _, c := range f.Children {
+			p.Add(c.Encode())
+		}
+		return p
+	case FilterPresent:
+		return ber.NewString(ber.ClassContext, tag, f.Attr)
+	case FilterSubstrings:
+		subs := ber.NewSequence()
+		if f.Initial != "" {
+			subs.Add(ber.NewString(ber.ClassContext, 0, f.Initial))
+		}
+		for _, a := range f.Any {
+			subs.Add(ber.NewString(ber.ClassContext, 1, a))
+		}
+		if f.Final != "" {
+			subs.Add(ber.NewString(ber.ClassContext, 2, f.Final))
+		}
+		return ber.NewConstructed(ber.ClassContext, tag, ber.NewOctetString(f.Attr), subs)
+	case FilterExtensible:
+		p := ber.NewConstructed(ber.ClassContext, tag)
+		if f.Rule != "" {
+			p.Add(ber.NewString(ber.ClassContext, 1, f.Rule))
+		}
+		if f.Attr != "" {
+			p.Add(ber.NewString(ber.ClassContext, 2, f.Attr))
+		}
+		p.Add(ber.NewString(ber.ClassContext, 3, f.Value))
+		if f.DNAttrs {
+			b := ber.NewBoolean(true)
+			b.Class, b.Tag = ber.ClassContext, 4
+			p.Add(b)
+		}
+		return p
+	default:
+		return ber.NewConstructed(ber.ClassContext, tag, ber.NewOctetString(f.Attr), ber.NewOctetString(f.Value))
+	}
+}
+
+// DecodeFilter разбирает фильтр из SearchRequest
+func DecodeFilter(p *ber.Packet) (*Filter, error) {
+	if p.Class != ber.ClassContext || p.Tag > int(FilterExtensible) {
+		return nil, ber.ErrMalformed
+	}
+	f := &Filter{Kind: FilterKind(p.Tag)}
+	switch f.Kind {
+	case FilterAnd, FilterOr, FilterNot:
+		if f.Kind == FilterNot && len(p.Children) != 1 {
+			return nil, ber.ErrMalformed
+		}
+		for _, c := range p.Children {
+			child, err := DecodeFilter(c)
+			if err != nil {
+				return nil, err
+			}
+			f.Children = append(f.Children, child)
+		}
+	case FilterPresent:
+		f.Attr = p.Str()
+	case FilterSubstrings:
+		if len(p.Children) != 2 {
+			return nil, ber.ErrMalformed
+		}
+		f.Attr = p.Children[0].Str()
+		for _, s := range p.Children[1].Children {
+			switch s.Tag {
+			case 0:
+				f.Initial = s.Str()
+			case 1:
+				f.Any = append(f.Any, s.Str())
+			case 2:
+				f.Final = s.Str()
+			}
+		}
+	case FilterExtensible:
+		for _, c := range p.Children {
+			switch c.Tag {
+			case 1:
+				f.Rule = c.Str()
+			case 2:
+				f.Attr = c.Str()
+			case 3:
+				f.Value = c.Str()
+			case 4:
+				f.DNAttrs, _ = c.Bool()
+			}
+		}
+	default:
+		if len(p.Children) != 2 {
+			return nil, ber.ErrMalformed
+		}
+		f.Attr, f.Value = p.Children[0].Str(), p.Children[1].Str()
+	}
+	return f, nil
+}
+
+// Match проверяет запись по фильтру. Строки сравниваются без учёта регистра, порядок — как чисел,
+// если оба значения целые. Из расширенных правил поддерживаются битовые маски Active Directory.
+func (f *Filter) Match(e *Entry) bool {
+	switch f.Kind {
+	case FilterAnd:
+		for _, c := range f.Children {
+			if !c.Match(e) {
+				return false
+			}
+		}
+		return true
+	case FilterOr:
+		for _, c := range f.Children {
+			if c.Match(e) {
+				return true
+			}
+		}
+		return false
+	case FilterNot:
+		return !f.Children[0].Match(e)
+	case FilterPresent:
+		return len(e.Values(f.Attr)) > 0
+	}
+
+	for _, v := range e.Values(f.Attr) {
+		if f.matchValue(v) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchValue проверяет одно значение атрибута
+func (f *Filter) matchValue(v string) bool {
+	switch f.Kind {
+	case FilterEqual, FilterApprox:
+		return strings.EqualFold(v, f.Value)
+	case FilterGreater:
+		return compareValues(v, f.Value) >= 0
+	case FilterLess:
+		return compareValues(v, f.Value) <= 0
+	case FilterSubstrings:
+		v = strings.ToLower(v)
+		if !strings.HasPrefix(v, strings.ToLower(f.Initial)) {
+			return false
+		}
+		v = v[len(f.Initial):]
+		for _, a := range f.Any {
+			i := strings.Index(v, strings.ToLower(a))
+			if i < 0 {
+				return false
+			}
+			v = v[i+len(a):]
+		}
+		return strings.HasSuffix(v, strings.ToLower(f.Final))
+	case FilterExtensible:
+		switch f.Rule {
+		case "":
+			return strings.EqualFold(v, f.Value)
+		case MatchingRuleBitAnd, MatchingRuleBitOr:
+			value, err1 := strconv.ParseInt(v, 10, 64)
+			mask, err2 := strconv.ParseInt(f.Value, 10, 64)
+			if err1 != nil || err2 != nil {
+				return false
+			}
+			if f.Rule == MatchingRuleBitAnd {
+				return value&mask == mask
+			}
+			return value&mask != 0
+		}
+	}
+	return false
+}
+
+// compareValues сравнивает значения как целые числа, а если это не числа — как строки
+func compareValues(a, b string) int {
+	x, err1 := strconv.ParseInt(a, 10, 64)
+	y, err2 := strconv.ParseInt(b, 10, 64)
+	if err1 == nil && err2 == nil {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
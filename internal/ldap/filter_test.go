@@ -0,0 +1,159 @@
+package ldap
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/ldap/ber"
+)
+
+func TestParseFilter(t *testing.T) {
+	tests := []struct {
+		in   string
+		want *Filter
+	}{
+		{"(cn=dev)", &Filter{Kind: FilterEqual, Attr: "cn", Value: "dev"}},
+		{" (cn=dev) ", &Filter{Kind: FilterEqual, Attr: "cn", Value: "dev"}},
+		{"(cn=*)", &Filter{Kind: FilterPresent, Attr: "cn"}},
+		{"(cn=dev-*)", &Filter{Kind: FilterSubstrings, Attr: "cn", Initial: "dev-"}},
+		{"(cn=*ops)", &Filter{Kind: FilterSubstrings, Attr: "cn", Final: "ops"}},
+		{"(cn=a*b**c*d)", &Filter{Kind: FilterSubstrings, Attr: "cn", Initial: "a", Any: []string{"b", "c"}, Final: "d"}},
+		{"(uidNumber>=1000)", &Filter{Kind: FilterGreater, Attr: "uidNumber", Value: "1000"}},
+		{"(uidNumber<=99)", &Filter{Kind: FilterLess, Attr: "uidNumber", Value: "99"}},
+		{"(sn~=smith)", &Filter{Kind: FilterApprox, Attr: "sn", Value: "smith"}},
+		{"(cn=a\\2ab\\28c\\29\\5c)", &Filter{Kind: FilterEqual, Attr: "cn", Value: "a*b(c)\\"}},
+		{"(cn=\\d0\\9f\\d1\\80\\d0\\be)", &Filter{Kind: FilterEqual, Attr: "cn", Value: "Про"}},
+		{"(userAccountControl:1.2.840.113556.1.4.803:=2)", &Filter{Kind: FilterExtensible, Attr: "userAccountControl", Rule: MatchingRuleBitAnd, Value: "2"}},
+		{"(ou:dn:=people)", &Filter{Kind: FilterExtensible, Attr: "ou", DNAttrs: true, Value: "people"}},
+		{"(:1.2.3:=x)", &Filter{Kind: FilterExtensible, Rule: "1.2.3", Value: "x"}},
+		{"(!(cn=dev))", &Filter{Kind: FilterNot, Children: []*Filter{{Kind: FilterEqual, Attr: "cn", Value: "dev"}}}},
+		{"(&(objectClass=group)(|(cn=dev)(cn=ops)))", &Filter{Kind: FilterAnd, Children: []*Filter{
+			{Kind: FilterEqual, Attr: "objectClass", Value: "group"},
+			{Kind: FilterOr, Children: []*Filter{
+				{Kind: FilterEqual, Attr: "cn", Value: "dev"},
+				{Kind: FilterEqual, Attr: "cn", Value: "ops"},
+			}},
+		}}},
+		{"(&)", &Filter{Kind: FilterAnd}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseFilter(tt.in)
+			if err != nil {
+				t.Fatalf("ParseFilter: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+
+			// фильтр передаётся серверу в BER и должен разбираться обратно без потерь
+			p, err := ber.Parse(got.Encode().Bytes())
+			if err != nil {
+				t.Fatalf("parse encoded filter: %v", err)
+			}
+			decoded, err := DecodeFilter(p)
+			if err != nil {
+				t.Fatalf("DecodeFilter: %v", err)
+			}
+			if !reflect.DeepEqual(decoded, tt.want) {
+				t.Fatalf("decoded %+v, want %+v", decoded, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"cn=dev",
+		"(cn=dev",
+		"(cn=dev))",
+		"(=dev)",
+		"(cn)",
+		"(>=1)",
+		"(&(cn=dev)",
+		"(!(cn=dev)(cn=ops))",
+		"(cn=\\2)",
+		"(cn=\\zz)",
+		"(cn=dev\\)",
+		"(:=x)",
+		"(cn=a)(cn=b)",
+	} {
+		if f, err := ParseFilter(in); !errors.Is(err, ErrInvalidFilter) {
+			t.Errorf("ParseFilter(%q): want ErrInvalidFilter, got %+v, %v", in, f, err)
+		}
+	}
+}
+
+func TestEscapeValue(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"dev", "dev"},
+		{"a*b", `a\2ab`},
+		{"(admin)", `\28admin\29`},
+		{`dom\user`, `dom\5cuser`},
+		{"nul\x00", `nul\00`},
+		{"Про", "Про"},
+	}
+	for _, tt := range tests {
+		got := EscapeValue(tt.in)
+		if got != tt.want {
+			t.Errorf("EscapeValue(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+		// экранированное значение подставляется в фильтр как одно значение
+		f, err := ParseFilter("(cn=" + got + ")")
+		if err != nil {
+			t.Fatalf("ParseFilter with %q: %v", got, err)
+		}
+		if f.Kind != FilterEqual || f.Value != tt.in {
+			t.Errorf("round trip of %q: got %+v", tt.in, f)
+		}
+	}
+}
+
+func TestFilterMatch(t *testing.T) {
+	e := NewEntry("uid=alice,ou=people,dc=example,dc=org", map[string][]string{
+		"objectClass":        {"top", "inetOrgPerson"},
+		"uid":                {"alice"},
+		"cn":                 {"Alice Liddell"},
+		"uidNumber":          {"1001"},
+		"userAccountControl": {"514"}, // 512 | 2: обычная отключённая учётная запись
+	})
+	tests := []struct {
+		filter string
+		want   bool
+	}{
+		{"(uid=alice)", true},
+		{"(UID=ALICE)", true},
+		{"(uid=bob)", false},
+		{"(objectClass=inetOrgPerson)", true},
+		{"(mail=*)", false},
+		{"(cn=*)", true},
+		{"(cn=alice*)", true},
+		{"(cn=*liddell)", true},
+		{"(cn=a*e*l*l)", true},
+		{"(cn=*bob*)", false},
+		{"(uidNumber>=1000)", true},
+		{"(uidNumber>=1002)", false},
+		{"(uidNumber<=1001)", true},
+		{"(uidNumber>=999)", true}, // числа, а не строки: "1001" < "999"
+		{"(userAccountControl:1.2.840.113556.1.4.803:=2)", true},
+		{"(userAccountControl:1.2.840.113556.1.4.803:=3)", false},
+		{"(userAccountControl:1.2.840.113556.1.4.804:=3)", true},
+		{"(&(objectClass=inetOrgPerson)(!(userAccountControl:1.2.840.113556.1.4.803:=2)))", false},
+		{"(|(uid=bob)(uid=alice))", true},
+		{"(&)", true},
+		{"(|)", false},
+	}
+	for _, tt := range tests {
+		f, err := ParseFilter(tt.filter)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q): %v", tt.filter, err)
+		}
+		if got := f.Match(e); got != tt.want {
+			t.Errorf("%s: got %v, want %v", tt.filter, got, tt.want)
+		}
+	}
+}
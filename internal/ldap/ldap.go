@@ -0,0 +1,244 @@
+// Package ldap — минимальный клиент LDAPv3 (RFC 4511): простая аутентификация и поиск
+// с постраничной выдачей. Кодирование сообщений общее с тестовым сервером ldaptest.
+package ldap
+
+import (
+	"fmt"
+	"strings"
+
+	"pr-reviewer/internal/ldap/ber"
+)
+
+// Теги операций протокола (APPLICATION)
+const (
+	AppBindRequest     = 0
+	AppBindResponse    = 1
+	AppUnbindRequest   = 2
+	AppSearchRequest   = 3
+	AppSearchEntry     = 4
+	AppSearchDone      = 5
+	AppSearchReference = 19
+)
+
+// Коды результата операций
+const (
+	ResultSuccess                 = 0
+	ResultOperationsError         = 1
+	ResultProtocolError           = 2
+	ResultSizeLimitExceeded       = 4
+	ResultNoSuchObject            = 32
+	ResultInvalidCredentials      = 49
+	ResultInsufficientAccessRight = 50
+	ResultUnwillingToPerform      = 53
+)
+
+// PagedResultsOID — управляющий элемент постраничной выдачи (RFC 2696)
+const PagedResultsOID = "1.2.840.113556.1.4.319"
+
+// Scope — область поиска
+type Scope int
+
+// Области поиска
+const (
+	ScopeBaseObject   Scope = 0
+	ScopeSingleLevel  Scope = 1
+	ScopeWholeSubtree Scope = 2
+)
+
+// Error — неуспешный результат операции LDAP
+type Error struct {
+	Code    int
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ldap: result code %d", e.Code)
+	}
+	return fmt.Sprintf("ldap: result code %d: %s", e.Code, e.Message)
+}
+
+// Attribute — атрибут записи со значениями
+type Attribute struct {
+	Name   string
+	Values []string
+}
+
+// Entry — запись каталога
+type Entry struct {
+	DN         string
+	Attributes []Attribute
+}
+
+// NewEntry создаёт запись; атрибуты упорядочиваются по имени
+func NewEntry(dn string, attrs map[string][]string) *Entry {
+	e := &Entry{DN: dn}
+	for name, values := range attrs {
+		e.Attributes = append(e.Attributes, Attribute{Name: name, Values: values})
+	}
+	for i := 1; i < len(e.Attributes); i++ {
+		for j := i; j > 0 && e.Attributes[j].Name < e.Attributes[j-1].Name; j-- {
+			e.Attributes[j], e.Attributes[j-1] = e.Attributes[j-1], e.Attributes[j]
+		}
+	}
+	return e
+}
+
+// Values возвращает значения атрибута name без учёта регистра имени
+func (e *Entry) Values(name string) []string {
+	for _, a := range e.Attributes {
+		if strings.EqualFold(a.Name, name) {
+			return a.Values
+		}
+	}
+	return nil
+}
+
+// Value возвращает первое значение атрибута name или пустую строку
+func (e *Entry) Value(name string) string {
+	if v := e.Values(name); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// NormalizeDN приводит DN к виду для сравнения: нижний регистр, без пробелов вокруг «,» и «=»
+func NormalizeDN(dn string) string {
+	parts := strings.Split(strings.ToLower(dn), ",")
+	for i, part := range parts {
+		if k, v, ok := strings.Cut(part, "="); ok {
+			parts[i] = strings.TrimSpace(k) + "=" + strings.TrimSpace(v)
+		} else {
+			parts[i] = strings.TrimSpace(part)
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// EncodeResult кодирует LDAPResult операции tag
+func EncodeResult(tag, code int, message string) *ber.Packet {
+	return ber.NewConstructed(ber.ClassApplication, tag,
+		ber.NewEnumerated(int64(code)),
+		ber.NewOctetString(""),
+		ber.NewOctetString(message),
+	)
+}
+
+// EncodeEntry кодирует SearchResultEntry с атрибутами attrs (пустой список — все атрибуты)
+func EncodeEntry(e *Entry, attrs []string) *ber.Packet {
+	list := ber.NewSequence()
+	for _, a := range e.Attributes {
+		if !wanted(a.Name, attrs) {
+			continue
+		}
+		values := ber.NewSet()
+		for _, v := range a.Values {
+			values.Add(ber.NewOctetString(v))
+		}
+		list.Add(ber.NewSequence(ber.NewOctetString(a.Name), values))
+	}
+	return ber.NewConstructed(ber.ClassApplication, AppSearchEntry, ber.NewOctetString(e.DN), list)
+}
+
+// wanted сообщает, запрошен ли атрибут name
+func wanted(name string, attrs []string) bool {
+	if len(attrs) == 0 {
+		return true
+	}
+	for _, a := range attrs {
+		if a == "*" || strings.EqualFold(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// decodeEntry разбирает SearchResultEntry
+func decodeEntry(p *ber.Packet) (*Entry, error) {
+	if len(p.Children) != 2 {
+		return nil, ber.ErrMalformed
+	}
+	e := &Entry{DN: p.Children[0].Str()}
+	for _, attr := range p.Children[1].Children {
+		if len(attr.Children) != 2 {
+			return nil, ber.ErrMalformed
+		}
+		a := Attribute{Name: attr.Children[0].Str()}
+		for _, v := range attr.Children[1].Children {
+			a.Values = append(a.Values, v.Str())
+		}
+		e.Attributes = append(e.Attributes, a)
+	}
+	return e, nil
+}
+
+// decodeResult разбирает LDAPResult и возвращает *Error для неуспешного кода
+func decodeResult(p *ber.Packet) error {
+	if len(p.Children) < 3 {
+		return ber.ErrMalformed
+	}
+	code, err := p.Children[0].Int()
+	if err != nil {
+		return err
+	}
+	if code != ResultSuccess {
+		return &Error{Code: int(code), Message: p.Children[2].Str()}
+	}
+	return nil
+}
+
+// EncodePagingControl кодирует управляющий элемент постраничной выдачи
+func EncodePagingControl(size int, cookie string) *ber.Packet {
+	value := ber.NewSequence(ber.NewInteger(int64(size)), ber.NewOctetString(cookie))
+	return ber.NewSequence(
+		ber.NewOctetString(PagedResultsOID),
+		ber.NewOctetString(string(value.Bytes())),
+	)
+}
+
+// DecodePagingControl ищет среди управляющих элементов постраничную выдачу и возвращает
+// размер страницы и cookie
+func DecodePagingControl(controls *ber.Packet) (size int, cookie string, ok bool) {
+	if controls == nil {
+		return 0, "", false
+	}
+	for _, c := range controls.Children {
+		if len(c.Children) < 2 || c.Children[0].Str() != PagedResultsOID {
+			continue
+		}
+		value := c.Children[len(c.Children)-1]
+		p, err := ber.Parse(value.Value)
+		if err != nil || len(p.Children) != 2 {
+			return 0, "", false
+		}
+		n, err := p.Children[0].Int()
+		if err != nil {
+			return 0, "", false
+		}
+		return int(n), p.Children[1].Str(), true
+	}
+	return 0, "", false
+}
+
+// EncodeMessage оборачивает операцию в LDAPMessage; controls может быть nil
+func EncodeMessage(id int64, op *ber.Packet, controls ...*ber.Packet) *ber.Packet {
+	msg := ber.NewSequence(ber.NewInteger(id), op)
+	if len(controls) > 0 {
+		msg.Add(ber.NewConstructed(ber.ClassContext, 0, controls...))
+	}
+	return msg
+}
+
+// DecodeMessage разбирает LDAPMessage: ID, операцию и управляющие элементы (nil, если их нет)
+func DecodeMessage(p *ber.Packet) (id int64, op, controls *ber.Packet, err error) {
+	if !p.Is(ber.ClassUniversal, ber.TagSequence) || len(p.Children) < 2 {
+		return 0, nil, nil, ber.ErrMalformed
+	}
+	if id, err = p.Children[0].Int(); err != nil {
+		return 0, nil, nil, err
+	}
+	if len(p.Children) > 2 && p.Children[2].Is(ber.ClassContext, 0) {
+		controls = p.Children[2]
+	}
+	return id, p.Children[1], controls, nil
+}
@@ -0,0 +1,240 @@
+// Package ldaptest — сервер LDAP в памяти процесса для проверки синхронизации без внешнего каталога.
+// Поддерживает простую аутентификацию, поиск с фильтрами и постраничную выдачу.
+package ldaptest
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"pr-reviewer/internal/ldap"
+	"pr-reviewer/internal/ldap/ber"
+)
+
+// Server — тестовый сервер LDAP на 127.0.0.1 со случайным портом
+type Server struct {
+	ln       net.Listener
+	bindDN   string
+	password string
+
+	mu       sync.Mutex
+	entries  []*ldap.Entry
+	searches int
+	conns    map[net.Conn]struct{}
+	wg       sync.WaitGroup
+}
+
+// NewServer запускает сервер с учётной записью bindDN/password и набором записей
+func NewServer(bindDN, password string, entries ...*ldap.Entry) (*Server, error) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{
+		ln:       ln,
+		bindDN:   bindDN,
+		password: password,
+		entries:  entries,
+		conns:    make(map[net.Conn]struct{}),
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s, nil
+}
+
+// URL возвращает адрес сервера вида ldap://127.0.0.1:port
+func (s *Server) URL() string {
+	return "ldap://" + s.ln.Addr().String()
+}
+
+// SetEntries заменяет содержимое каталога
+func (s *Server) SetEntries(entries ...*ldap.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = entries
+}
+
+// Searches возвращает число обработанных запросов поиска (каждая страница — отдельный запрос)
+func (s *Server) Searches() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.searches
+}
+
+// Close останавливает сервер и закрывает открытые соединения
+func (s *Server) Close() error {
+	err := s.ln.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		_ = c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+	return err
+}
+
+// serve принимает соединения до закрытия сервера
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.ln.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[conn] = struct{}{}
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go s.handle(conn)
+	}
+}
+
+// handle обслуживает одно соединение
+func (s *Server) handle(conn net.Conn) {
+	defer s.wg.Done()
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
+		_ = conn.Close()
+	}()
+
+	br := bufio.NewReader(conn)
+	bound := false
+	for {
+		p, err := ber.Read(br)
+		if err != nil {
+			return
+		}
+		id, op, controls, err := ldap.DecodeMessage(p)
+		if err != nil {
+			return
+		}
+		var replies []*ber.Packet
+		switch {
+		case op.Is(ber.ClassApplication, ldap.AppBindRequest):
+			code := s.bind(op)
+			bound = code == ldap.ResultSuccess
+			replies = append(replies, ldap.EncodeMessage(id, ldap.EncodeResult(ldap.AppBindResponse, code, "")))
+		case op.Is(ber.ClassApplication, ldap.AppUnbindRequest):
+			return
+		case op.Is(ber.ClassApplication, ldap.AppSearchRequest):
+			if !bound {
+				replies = append(replies, ldap.EncodeMessage(id,
+					ldap.EncodeResult(ldap.AppSearchDone, ldap.ResultInsufficientAccessRight, "bind required")))
+				break
+			}
+			replies = s.search(id, op, controls)
+		default:
+			return
+		}
+		for _, r := range replies {
+			if _, err := conn.Write(r.Bytes()); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// bind проверяет учётные данные простой аутентификации
+func (s *Server) bind(op *ber.Packet) int {
+	if len(op.Children) != 3 || op.Children[2].Tag != 0 {
+		return ldap.ResultProtocolError
+	}
+	if ldap.NormalizeDN(op.Children[1].Str()) != ldap.NormalizeDN(s.bindDN) || op.Children[2].Str() != s.password {
+		return ldap.ResultInvalidCredentials
+	}
+	return ldap.ResultSuccess
+}
+
+// search отвечает на SearchRequest записями и итоговым SearchResultDone
+func (s *Server) search(id int64, op, controls *ber.Packet) []*ber.Packet {
+	done := func(code int, msg string, ctrl ...*ber.Packet) []*ber.Packet {
+		return []*ber.Packet{ldap.EncodeMessage(id, ldap.EncodeResult(ldap.AppSearchDone, code, msg), ctrl...)}
+	}
+	if len(op.Children) != 8 {
+		return done(ldap.ResultProtocolError, "malformed search request")
+	}
+	base := ldap.NormalizeDN(op.Children[0].Str())
+	scope, err1 := op.Children[1].Int()
+	sizeLimit, err2 := op.Children[3].Int()
+	filter, err3 := ldap.DecodeFilter(op.Children[6])
+	if err1 != nil || err2 != nil || err3 != nil {
+		return done(ldap.ResultProtocolError, "malformed search request")
+	}
+	var attrs []string
+	for _, a := range op.Children[7].Children {
+		attrs = append(attrs, a.Str())
+	}
+
+	s.mu.Lock()
+	s.searches++
+	var matched []*ldap.Entry
+	baseFound := base == ""
+	for _, e := range s.entries {
+		dn := ldap.NormalizeDN(e.DN)
+		if dn == base {
+			baseFound = true
+		}
+		if inScope(dn, base, ldap.Scope(scope)) && filter.Match(e) {
+			matched = append(matched, e)
+		}
+	}
+	s.mu.Unlock()
+	if !baseFound {
+		return done(ldap.ResultNoSuchObject, "base object not found")
+	}
+
+	pageSize, cookie, paged := ldap.DecodePagingControl(controls)
+	offset := 0
+	if paged && cookie != "" {
+		n, err := strconv.Atoi(cookie)
+		if err != nil || n < 0 || n > len(matched) {
+			return done(ldap.ResultUnwillingToPerform, "invalid paging cookie")
+		}
+		offset = n
+	}
+	matched = matched[offset:]
+
+	var replies []*ber.Packet
+	next := ""
+	if paged && pageSize > 0 && len(matched) > pageSize {
+		matched = matched[:pageSize]
+		next = strconv.Itoa(offset + pageSize)
+	}
+	for i, e := range matched {
+		if sizeLimit > 0 && i == int(sizeLimit) {
+			replies = append(replies, done(ldap.ResultSizeLimitExceeded, "")...)
+			return replies
+		}
+		replies = append(replies, ldap.EncodeMessage(id, ldap.EncodeEntry(e, attrs)))
+	}
+	var ctrl []*ber.Packet
+	if paged {
+		ctrl = append(ctrl, ldap.EncodePagingControl(0, next))
+	}
+	return append(replies, done(ldap.ResultSuccess, "", ctrl...)...)
+}
+
+// inScope проверяет, что нормализованный dn попадает в область поиска от base
+func inScope(dn, base string, scope ldap.Scope) bool {
+	if dn == base {
+		return scope != ldap.ScopeSingleLevel
+	}
+	if base != "" && !strings.HasSuffix(dn, ","+base) {
+		return false
+	}
+	switch scope {
+	case ldap.ScopeBaseObject:
+		return false
+	case ldap.ScopeSingleLevel:
+		rdn := dn
+		if base != "" {
+			rdn = strings.TrimSuffix(dn, ","+base)
+		}
+		return !strings.Contains(rdn, ",")
+	}
+	return true
+}
@@ -0,0 +1,81 @@
+package model
+
+import "time"
+
+// Формат архива выгрузки. Версия увеличивается при несовместимом изменении структуры.
+const (
+	ArchiveFormat  = "pr-reviewer-archive"
+	ArchiveVersion = 1
+)
+
+// Archive — полная выгрузка данных организации
+type Archive struct {
+	Format     string    `json:"format"`
+	Version    int       `json:"version"`
+	ExportedAt time.Time `json:"exported_at"`
+	// TenantID — организация, из которой сделана выгрузка; восстанавливать можно в любую
+	TenantID string `json:"tenant_id"`
+	// Checksum — SHA-256 от JSON-представления Data, проверяется при восстановлении
+	Checksum string      `json:"checksum"`
+	Data     ArchiveData `json:"data"`
+}
+
+// ArchiveData — содержимое архива
+type ArchiveData struct {
+	Settings     OrgSettings   `json:"organization_settings"`
+	Teams        []ArchiveTeam `json:"teams"`
+	Users        []ArchiveUser `json:"users"`
+	PullRequests []ArchivePR   `json:"pull_requests"`
+	Counts       ArchiveCounts `json:"counts"`
+}
+
+// ArchiveTeam — команда в архиве; родитель всегда идёт раньше дочерних команд
+type ArchiveTeam struct {
+	Name     string       `json:"team_name"`
+	Parent   string       `json:"parent_team,omitempty"`
+	Settings TeamSettings `json:"settings"`
+}
+
+// ArchiveUser — пользователь в архиве вместе с членствами в командах
+type ArchiveUser struct {
+	ID               int64            `json:"user_id"`
+	Username         string           `json:"username"`
+	IsActive         bool             `json:"is_active"`
+	Skills           []string         `json:"skills"`
+	OutOfOfficeUntil *time.Time       `json:"out_of_office_until,omitempty"`
+	Teams            []TeamMembership `json:"teams"`
+}
+
+// ArchivePR — PR в архиве с историей и отказами ревьюверов в хронологическом порядке
+type ArchivePR struct {
+	PullRequest
+	History  []ArchiveEvent   `json:"history"`
+	Declines []ArchiveDecline `json:"declines"`
+}
+
+// ArchiveEvent — событие истории PR; ID назначается заново при восстановлении
+type ArchiveEvent struct {
+	Event      string                 `json:"event"`
+	Assignment *AssignmentExplanation `json:"assignment,omitempty"`
+	Data       map[string]string      `json:"data,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
+
+// ArchiveDecline — отказ от ревью PR; ID назначается заново при восстановлении
+type ArchiveDecline struct {
+	UserID     string    `json:"user_id"`
+	Reason     string    `json:"reason"`
+	Comment    string    `json:"comment,omitempty"`
+	ReplacedBy string    `json:"replaced_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// ArchiveCounts — число записей каждого вида в архиве
+type ArchiveCounts struct {
+	Teams        int `json:"teams"`
+	Users        int `json:"users"`
+	Memberships  int `json:"memberships"`
+	PullRequests int `json:"pull_requests"`
+	Events       int `json:"history_events"`
+	Declines     int `json:"declines"`
+}
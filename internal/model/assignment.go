@@ -0,0 +1,62 @@
+package model
+
+import "time"
+
+// Причины, по которым пользователь не попал в пул кандидатов
+const (
+	ExcludedAuthor             = "author"
+	ExcludedInactive           = "inactive"
+	ExcludedAtCapacity         = "at_capacity"
+	ExcludedOutOfOffice        = "out_of_office"
+	ExcludedCurrentReviewer    = "current_reviewer"
+	ExcludedDeclined           = "declined"
+	ExcludedMembershipInactive = "membership_inactive"
+	ExcludedTeamLead           = "team_lead"
+)
+
+// ExcludedCandidate описывает пользователя, исключённого из пула кандидатов
+type ExcludedCandidate struct {
+	UserID string `json:"user_id"`
+	Reason string `json:"reason"`
+}
+
+// AssignmentPreview — результат пробного назначения ревьюверов без записи в БД
+type AssignmentPreview struct {
+	Strategy   string              `json:"strategy"`
+	Seed       int64               `json:"seed,string"`
+	Candidates []string            `json:"candidates"`
+	Excluded   []ExcludedCandidate `json:"excluded"`
+	Reviewers  []string            `json:"reviewers"`
+	PoolTeams  []string            `json:"pool_teams,omitempty"`
+}
+
+// Действия, при которых принимается решение о назначении
+const (
+	AssignmentActionCreate   = "create"
+	AssignmentActionReassign = "reassign"
+)
+
+// ChosenReviewer описывает выбранного ревьювера и причину выбора
+type ChosenReviewer struct {
+	UserID string `json:"user_id"`
+	Score  int    `json:"score"`
+	Reason string `json:"reason"`
+}
+
+// AssignmentExplanation объясняет, как были выбраны ревьюверы
+type AssignmentExplanation struct {
+	Action   string `json:"action"`
+	Strategy string `json:"strategy"`
+	// Seed генератора случайных чисел; вместе с Candidates позволяет воспроизвести решение
+	Seed       int64               `json:"seed,string"`
+	PoolSize   int                 `json:"pool_size"`
+	Candidates []string            `json:"candidates"`
+	Filters    []string            `json:"filters"`
+	Excluded   []ExcludedCandidate `json:"excluded"`
+	Chosen     []ChosenReviewer    `json:"chosen"`
+	// Weights — веса членства кандидатов, если хотя бы у одного он меньше 100 %
+	Weights map[string]int `json:"weights,omitempty"`
+	// PoolTeams — команды, из которых собран пул, если он расширялся на родительские команды
+	PoolTeams []string  `json:"pool_teams,omitempty"`
+	DecidedAt time.Time `json:"decided_at"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Действия, которые пишутся в журнал аудита
+const (
+	AuditPRMerged         = "pr_merged"
+	AuditReviewerReassign = "reviewer_reassigned"
+	AuditUserActivity     = "user_is_active_set"
+	AuditOrgSettings      = "org_settings_updated"
+	AuditMemberAdded      = "team_member_added"
+	AuditMemberRemoved    = "team_member_removed"
+	AuditMemberUpdated    = "team_membership_updated"
+	AuditTeamRenamed      = "team_renamed"
+	AuditTeamDeleted      = "team_deleted"
+	AuditTeamParent       = "team_parent_changed"
+	AuditTeamSettings     = "team_settings_updated"
+	AuditLeadAdded        = "team_lead_added"
+	AuditLeadRemoved      = "team_lead_removed"
+	AuditRosterImported   = "roster_imported"
+	AuditDataRestored     = "data_restored"
+	AuditUserCreated      = "user_created"
+	AuditUserRenamed      = "user_renamed"
+	AuditLDAPSynced       = "ldap_synced"
+)
+
+// Типы объектов в журнале аудита
+const (
+	AuditTargetPR   = "pull_request"
+	AuditTargetUser = "user"
+	AuditTargetOrg  = "organization"
+	AuditTargetTeam = "team"
+)
+
+// AuditEvent — запись журнала аудита: кто, что и над чем сделал
+type AuditEvent struct {
+	ID         int64             `json:"id"`
+	Action     string            `json:"action"`
+	Actor      string            `json:"actor"`
+	ActorRole  string            `json:"actor_role,omitempty"`
+	AuthMethod string            `json:"auth_method,omitempty"`
+	TargetType string            `json:"target_type"`
+	TargetID   string            `json:"target_id"`
+	Data       map[string]string `json:"data"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
@@ -0,0 +1,106 @@
+package model
+
+import "time"
+
+// Роли вызывающих API
+const (
+	RoleAdmin    = "admin"
+	RoleTeamLead = "team-lead"
+	RoleBot      = "bot"
+	RoleMember   = "member"
+)
+
+// ValidRole сообщает, что роль поддерживается
+func ValidRole(role string) bool {
+	switch role {
+	case RoleAdmin, RoleTeamLead, RoleBot, RoleMember:
+		return true
+	}
+	return false
+}
+
+// Способы аутентификации вызывающего
+const (
+	AuthMethodToken     = "api_token"
+	AuthMethodJWT       = "jwt"
+	AuthMethodBootstrap = "bootstrap"
+	AuthMethodAnonymous = "anonymous"
+	AuthMethodCLI       = "cli"
+	AuthMethodJob       = "job"
+)
+
+// APIToken — выданный API-токен. Сам токен не хранится, только его SHA-256.
+type APIToken struct {
+	ID        int64      `json:"id"`
+	TenantID  string     `json:"tenant_id"`
+	Name      string     `json:"name"`
+	Role      string     `json:"role"`
+	UserID    string     `json:"user_id,omitempty"`
+	TeamName  string     `json:"team_name,omitempty"`
+	TokenHash string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Principal — аутентифицированный вызывающий запроса
+type Principal struct {
+	TokenID  int64  `json:"token_id"`
+	Name     string `json:"name"`
+	Role     string `json:"role"`
+	UserID   string `json:"user_id,omitempty"`
+	TeamName string `json:"team_name,omitempty"`
+	// LeadTeams — команды, в которых пользователь вызывающего назначен лидом
+	LeadTeams []string `json:"lead_teams,omitempty"`
+	// TenantID — организация, к которой привязан вызывающий; пусто только у глобального админа
+	TenantID string `json:"tenant_id,omitempty"`
+	// AuthMethod — чем вызывающий подтвердил личность: API-токен, JWT и т.д.
+	AuthMethod string `json:"auth_method"`
+}
+
+// Actor возвращает идентификатор вызывающего для журнала: ID пользователя, если он известен, иначе имя
+func (p *Principal) Actor() string {
+	if p.UserID != "" {
+		return p.UserID
+	}
+	return p.Name
+}
+
+// HasRole сообщает, что у вызывающего одна из ролей roles
+func (p *Principal) HasRole(roles ...string) bool {
+	for _, r := range roles {
+		if p.Role == r {
+			return true
+		}
+	}
+	return false
+}
+
+// CanManageTeam сообщает, что вызывающий — админ или лид команды teamName: по команде токена
+// или по роли lead в самой команде
+func (p *Principal) CanManageTeam(teamName string) bool {
+	if p.Role == RoleAdmin {
+		return true
+	}
+	if p.Role != RoleTeamLead || teamName == "" {
+		return false
+	}
+	if p.TeamName == teamName {
+		return true
+	}
+	for _, t := range p.LeadTeams {
+		if t == teamName {
+			return true
+		}
+	}
+	return false
+}
+
+// IsGlobal сообщает, что вызывающий не привязан к организации и может выбрать её заголовком
+func (p *Principal) IsGlobal() bool {
+	return p.TenantID == ""
+}
+
+// IsUser сообщает, что вызывающий действует от имени пользователя userID
+func (p *Principal) IsUser(userID string) bool {
+	return p.UserID != "" && p.UserID == userID
+}
@@ -0,0 +1,37 @@
+package model
+
+import "time"
+
+// Причины отказа от ревью
+const (
+	DeclineNoContext          = "no_context"
+	DeclineOverloaded         = "overloaded"
+	DeclineConflictOfInterest = "conflict_of_interest"
+)
+
+// ValidDeclineReason проверяет, что причина отказа поддерживается
+func ValidDeclineReason(reason string) bool {
+	switch reason {
+	case DeclineNoContext, DeclineOverloaded, DeclineConflictOfInterest:
+		return true
+	}
+	return false
+}
+
+// ReviewDecline описывает отказ ревьювера от PR
+type ReviewDecline struct {
+	ID         int64     `json:"id"`
+	PRID       string    `json:"pull_request_id"`
+	UserID     string    `json:"user_id"`
+	Reason     string    `json:"reason"`
+	Comment    string    `json:"comment,omitempty"`
+	ReplacedBy string    `json:"replaced_by"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// DeclineStats — сводка отказов одного пользователя
+type DeclineStats struct {
+	UserID   string         `json:"user_id"`
+	Total    int            `json:"total"`
+	ByReason map[string]int `json:"by_reason"`
+}
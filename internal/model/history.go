@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// События истории PR
+const (
+	PREventCreated    = "created"
+	PREventReassigned = "reassigned"
+	PREventMerged     = "merged"
+	PREventAdded      = "reviewer_added"
+	PREventRemoved    = "reviewer_removed"
+	PREventDeclined   = "declined"
+)
+
+// PREvent — запись в истории PR
+type PREvent struct {
+	ID         int64                  `json:"id"`
+	PRID       string                 `json:"pull_request_id"`
+	Event      string                 `json:"event"`
+	Assignment *AssignmentExplanation `json:"assignment,omitempty"`
+	Data       map[string]string      `json:"data,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// IdempotencyRecord — сохранённый результат запроса с заголовком Idempotency-Key.
+// Пока StatusCode равен 0, запрос ещё выполняется.
+type IdempotencyRecord struct {
+	Key         string
+	RequestHash string
+	StatusCode  int
+	Response    []byte
+	CreatedAt   time.Time
+	ExpiresAt   time.Time
+}
+
+// Completed сообщает, что ответ на запрос уже сохранён
+func (r *IdempotencyRecord) Completed() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,50 @@
+package model
+
+// RosterEntry — строка импортируемого реестра: членство пользователя в команде
+type RosterEntry struct {
+	// Line — номер строки исходного файла, к которому относятся ошибки и изменения
+	Line     int    `json:"line"`
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	IsActive bool   `json:"is_active"`
+	// Role — роль в команде; пустая сохраняет роль существующего участника, новый становится member
+	Role string `json:"role,omitempty"`
+}
+
+// ImportLineError — ошибка в строке реестра
+type ImportLineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Действия, из которых состоит план импорта
+const (
+	ImportCreateTeam = "create_team"
+	ImportCreateUser = "create_user"
+	ImportUpdateUser = "update_user"
+	ImportAddMember  = "add_member"
+	ImportSetRole    = "set_role"
+)
+
+// ImportChange — одно изменение, которое вносит импорт
+type ImportChange struct {
+	Line     int    `json:"line"`
+	Action   string `json:"action"`
+	TeamName string `json:"team_name,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+}
+
+// ImportReport — результат проверки или применения реестра
+type ImportReport struct {
+	DryRun  bool `json:"dry_run"`
+	Applied bool `json:"applied"`
+	// Entries — число строк реестра, Unchanged — строк, не требующих изменений
+	Entries   int               `json:"entries"`
+	Unchanged int               `json:"unchanged"`
+	Changes   []ImportChange    `json:"changes"`
+	Errors    []ImportLineError `json:"errors"`
+}
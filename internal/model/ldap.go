@@ -0,0 +1,46 @@
+package model
+
+import "time"
+
+// Действия, из которых состоит план синхронизации с LDAP
+const (
+	LDAPCreateTeam     = "create_team"
+	LDAPCreateUser     = "create_user"
+	LDAPUpdateUser     = "update_user"
+	LDAPActivateUser   = "activate_user"
+	LDAPDeactivateUser = "deactivate_user"
+	LDAPAddMember      = "add_member"
+	LDAPRemoveMember   = "remove_member"
+)
+
+// LDAPChange — одно изменение, которое вносит синхронизация
+type LDAPChange struct {
+	Action   string `json:"action"`
+	TeamName string `json:"team_name,omitempty"`
+	UserID   string `json:"user_id,omitempty"`
+	Field    string `json:"field,omitempty"`
+	Old      string `json:"old,omitempty"`
+	New      string `json:"new,omitempty"`
+}
+
+// LDAPSyncReport — результат проверки или применения синхронизации
+type LDAPSyncReport struct {
+	DryRun    bool      `json:"dry_run"`
+	Applied   bool      `json:"applied"`
+	StartedAt time.Time `json:"started_at"`
+	// Groups и Users — число групп и пользователей, прочитанных из каталога
+	Groups  int          `json:"groups"`
+	Users   int          `json:"users"`
+	Changes []LDAPChange `json:"changes"`
+	// Warnings — записи каталога, пропущенные при синхронизации, с причиной
+	Warnings   []string        `json:"warnings"`
+	Reassigned []ReviewHandoff `json:"reassigned"`
+}
+
+// LDAPSyncStatus — состояние периодической синхронизации
+type LDAPSyncStatus struct {
+	// LastReport — отчёт последней применённой синхронизации
+	LastReport  *LDAPSyncReport `json:"last_report"`
+	LastError   string          `json:"last_error,omitempty"`
+	LastErrorAt *time.Time      `json:"last_error_at,omitempty"`
+}
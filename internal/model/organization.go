@@ -0,0 +1,23 @@
+package model
+
+import "time"
+
+// OrgSettings — настройки организации, переопределяющие политику назначения сервиса.
+// Незаданные поля берутся из политики по умолчанию.
+type OrgSettings struct {
+	ReviewerCount  *int   `json:"reviewer_count,omitempty"`
+	MinReviewers   *int   `json:"min_reviewers,omitempty"`
+	MaxReviewers   *int   `json:"max_reviewers,omitempty"`
+	MaxOpenReviews *int   `json:"max_open_reviews,omitempty"`
+	Strategy       string `json:"strategy,omitempty"`
+	MergePolicy    string `json:"merge_policy,omitempty"`
+	ReviewSLAHours *int   `json:"review_sla_hours,omitempty"`
+}
+
+// Organization — организация (тенант): отдел со своими командами, пользователями и PR
+type Organization struct {
+	ID        string      `json:"id"`
+	Name      string      `json:"name"`
+	Settings  OrgSettings `json:"settings"`
+	CreatedAt time.Time   `json:"created_at"`
+}
@@ -8,6 +8,13 @@ type PullRequest struct {
 	AuthorID          string     `db:"author_id" json:"author_id"`
 	Status            string     `db:"status" json:"status"` // OPEN|MERGED
 	AssignedReviewers []string   `db:"assigned_reviewers" json:"assigned_reviewers"`
+	Labels            []string   `db:"labels" json:"labels"`
 	CreatedAt         time.Time  `db:"created_at" json:"createdAt"`
 	MergedAt          *time.Time `db:"merged_at" json:"mergedAt,omitempty"`
+
+	// Version увеличивается при каждом обновлении и защищает от потерянных изменений
+	Version int64 `db:"version" json:"version"`
+
+	// Assignment объясняет последнее решение о назначении ревьюверов
+	Assignment *AssignmentExplanation `db:"assignment" json:"assignment_explanation,omitempty"`
 }
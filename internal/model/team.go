@@ -1,14 +1,119 @@
 package model
 
+import "time"
+
 // TeamMember описывает участника команды
 type TeamMember struct {
-	UserID   string `json:"user_id"`
-	Username string `json:"username"`
+	UserID   string   `json:"user_id"`
+	Username string   `json:"username"`
+	IsActive bool     `json:"is_active"`
+	Skills   []string `json:"skills"`
+	// MembershipActive — участвует ли пользователь в ревью этой команды; по умолчанию да
+	MembershipActive *bool `json:"membership_active,omitempty"`
+	// Weight — доля времени пользователя в этой команде, 1–100 %; по умолчанию 100
+	Weight int `json:"weight,omitempty"`
+	// Role — роль в команде: member или lead; по умолчанию member
+	Role string `json:"role,omitempty"`
+}
+
+// Вес членства по умолчанию: пользователь целиком работает в команде
+const DefaultMembershipWeight = 100
+
+// Роли участника в команде
+const (
+	// TeamRoleMember — обычный участник
+	TeamRoleMember = "member"
+	// TeamRoleLead — лид: управляет составом и настройками команды и получает эскалации
+	TeamRoleLead = "lead"
+)
+
+// ValidTeamRole проверяет, что роль в команде поддерживается
+func ValidTeamRole(role string) bool {
+	return role == TeamRoleMember || role == TeamRoleLead
+}
+
+// TeamMembership — членство пользователя в команде. Пользователь может состоять в нескольких
+// командах и получать ревью в каждой; неактивное членство исключает его только из пула этой команды.
+type TeamMembership struct {
+	TeamName string `json:"team_name"`
+	UserID   string `json:"user_id,omitempty"`
 	IsActive bool   `json:"is_active"`
+	// Weight — доля времени в команде, 1–100 %: снижает лимит открытых ревью и шанс выбора
+	Weight int    `json:"weight"`
+	Role   string `json:"role"`
 }
 
-// Team описывает команду
+// ValidMembershipWeight проверяет, что вес членства в допустимых пределах
+func ValidMembershipWeight(w int) bool {
+	return w >= 1 && w <= DefaultMembershipWeight
+}
+
+// Team описывает команду. Команды образуют иерархию (отдел → команда → группа):
+// незаданные настройки наследуются от родительской команды, затем от организации.
 type Team struct {
-	Name    string       `json:"team_name"`
-	Members []TeamMember `json:"members"`
+	Name     string       `json:"team_name"`
+	Parent   string       `json:"parent_team,omitempty"`
+	Settings TeamSettings `json:"settings"`
+	Members  []TeamMember `json:"members"`
+	// Leads — ID лидов команды; заполняется при чтении команды
+	Leads []string `json:"leads"`
+	// Effective — итоговые настройки с источником каждой; заполняется при чтении команды
+	Effective *EffectiveTeamSettings `json:"effective_settings,omitempty"`
+}
+
+// TeamSettings — собственные настройки команды; незаданные поля наследуются
+type TeamSettings struct {
+	ReviewerCount  *int   `json:"reviewer_count,omitempty"`
+	Strategy       string `json:"strategy,omitempty"`
+	MergePolicy    string `json:"merge_policy,omitempty"`
+	ReviewSLAHours *int   `json:"review_sla_hours,omitempty"`
+	// ExpandPool — добирать ревьюверов из родительских команд, если в команде не хватает кандидатов
+	ExpandPool *bool `json:"expand_pool,omitempty"`
+	// LeadsReview — назначать ли лидов команды ревьюверами наравне с участниками
+	LeadsReview *bool `json:"leads_review,omitempty"`
+}
+
+// Источники настроек, кроме команд: организация и политика сервиса
+const (
+	SettingSourceOrganization = "organization"
+	SettingSourceService      = "service"
+)
+
+// EffectiveTeamSettings — итоговые настройки команды с учётом наследования
+type EffectiveTeamSettings struct {
+	ReviewerCount  int    `json:"reviewer_count"`
+	Strategy       string `json:"strategy"`
+	MergePolicy    string `json:"merge_policy"`
+	ReviewSLAHours int    `json:"review_sla_hours"`
+	ExpandPool     bool   `json:"expand_pool"`
+	LeadsReview    bool   `json:"leads_review"`
+	// Sources — откуда взята каждая настройка: "team:<имя>", "organization" или "service"
+	Sources map[string]string `json:"sources"`
+}
+
+// TeamSummary — краткие сведения о команде для списка команд
+type TeamSummary struct {
+	Name        string `json:"team_name"`
+	Parent      string `json:"parent_team,omitempty"`
+	MemberCount int    `json:"member_count"`
+	ActiveCount int    `json:"active_count"`
+}
+
+// ReviewHandoff описывает, что стало с открытым ревью пользователя, покинувшего команду
+type ReviewHandoff struct {
+	PRID       string `json:"pull_request_id"`
+	UserID     string `json:"old_user_id"`
+	ReplacedBy string `json:"replaced_by,omitempty"` // пусто — замены не нашлось, ревьювер снят
+}
+
+// Escalation — открытый PR команды, ревью которого не уложилось в SLA
+type Escalation struct {
+	PRID              string    `json:"pull_request_id"`
+	Name              string    `json:"pull_request_name"`
+	AuthorID          string    `json:"author_id"`
+	AssignedReviewers []string  `json:"assigned_reviewers"`
+	CreatedAt         time.Time `json:"created_at"`
+	DueAt             time.Time `json:"due_at"`
+	// Leads — кому эскалируется PR: лиды команды, а если их нет — лиды ближайшей родительской команды
+	Leads []string `json:"leads"`
 }
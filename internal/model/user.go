@@ -1,9 +1,40 @@
 package model
 
+import "time"
+
 // User описывает пользователя
 type User struct {
 	ID       int64  `json:"user_id"`
 	Username string `json:"username"`
-	TeamName string `json:"team_name"`
-	IsActive bool   `json:"is_active"`
+	// TeamName — основная команда: членство с наибольшим весом; пусто, если пользователь вне команд.
+	// Задаётся членствами, при сохранении пользователя не используется.
+	TeamName         string           `json:"team_name"`
+	Teams            []TeamMembership `json:"teams"`
+	IsActive         bool             `json:"is_active"`
+	Skills           []string         `json:"skills"`
+	OutOfOfficeUntil *time.Time       `json:"out_of_office_until,omitempty"`
+}
+
+// Membership возвращает членство пользователя в команде teamName
+func (u *User) Membership(teamName string) (TeamMembership, bool) {
+	for _, m := range u.Teams {
+		if m.TeamName == teamName {
+			return m, true
+		}
+	}
+	return TeamMembership{}, false
+}
+
+// TeamNames возвращает команды пользователя, начиная с основной
+func (u *User) TeamNames() []string {
+	names := make([]string, 0, len(u.Teams))
+	for _, m := range u.Teams {
+		names = append(names, m.TeamName)
+	}
+	return names
+}
+
+// IsOutOfOffice сообщает, отсутствует ли пользователь в момент now
+func (u *User) IsOutOfOffice(now time.Time) bool {
+	return u.OutOfOfficeUntil != nil && now.Before(*u.OutOfOfficeUntil)
 }
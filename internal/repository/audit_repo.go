@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"encoding/json"
+	"pr-reviewer/internal/model"
+)
+
+// AuditFilter — условия выборки журнала аудита; пустые поля не фильтруют
+type AuditFilter struct {
+	Action     string
+	Actor      string
+	TargetType string
+	TargetID   string
+	// BeforeID — вернуть записи старше указанной, для постраничного просмотра
+	BeforeID int64
+	Limit    int
+}
+
+// AuditRepo хранит журнал аудита
+type AuditRepo struct {
+	db     DBTX
+	tenant string
+}
+
+// NewAuditRepo создаёт новый AuditRepo
+func NewAuditRepo(db DBTX, tenant string) *AuditRepo {
+	return &AuditRepo{db: db, tenant: tenant}
+}
+
+// Add добавляет запись и присваивает ей ID
+func (r *AuditRepo) Add(e *model.AuditEvent) error {
+	data := e.Data
+	if data == nil {
+		data = map[string]string{}
+	}
+	dataJSON, _ := json.Marshal(data)
+	query := `
+	INSERT INTO audit_events (action, actor, actor_role, auth_method, target_type, target_id, data, created_at, tenant_id)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9)
+	RETURNING id
+	`
+	return r.db.QueryRow(query, e.Action, e.Actor, e.ActorRole, e.AuthMethod, e.TargetType, e.TargetID, dataJSON, e.CreatedAt, r.tenant).Scan(&e.ID)
+}
+
+// List возвращает записи от новых к старым
+func (r *AuditRepo) List(f AuditFilter) ([]model.AuditEvent, error) {
+	query := `
+	SELECT id, action, actor, actor_role, auth_method, target_type, target_id, data, created_at
+	FROM audit_events
+	WHERE ($1 = '' OR action = $1)
+	  AND ($2 = '' OR actor = $2)
+	  AND ($3 = '' OR target_type = $3)
+	  AND ($4 = '' OR target_id = $4)
+	  AND ($5 = 0 OR id < $5)
+	  AND tenant_id = $7
+	ORDER BY id DESC
+	LIMIT $6
+	`
+	rows, err := r.db.Query(query, f.Action, f.Actor, f.TargetType, f.TargetID, f.BeforeID, f.Limit, r.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []model.AuditEvent{}
+	for rows.Next() {
+		var e model.AuditEvent
+		var dataJSON []byte
+		if err := rows.Scan(&e.ID, &e.Action, &e.Actor, &e.ActorRole, &e.AuthMethod, &e.TargetType, &e.TargetID, &dataJSON, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		json.Unmarshal(dataJSON, &e.Data)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
@@ -0,0 +1,122 @@
+package repository
+
+import (
+	"pr-reviewer/internal/model"
+	"sort"
+	"time"
+)
+
+// DeclineRepo хранит отказы ревьюверов
+type DeclineRepo struct {
+	db     DBTX
+	tenant string
+}
+
+// NewDeclineRepo создаёт новый DeclineRepo
+func NewDeclineRepo(db DBTX, tenant string) *DeclineRepo {
+	return &DeclineRepo{db: db, tenant: tenant}
+}
+
+// Add сохраняет отказ
+func (r *DeclineRepo) Add(d *model.ReviewDecline) error {
+	query := `
+	INSERT INTO review_declines (pull_request_id, user_id, reason, comment, replaced_by, created_at, tenant_id)
+	VALUES ($1,$2,$3,$4,$5,$6,$7)
+	RETURNING id
+	`
+	return r.db.QueryRow(query, d.PRID, d.UserID, d.Reason, d.Comment, d.ReplacedBy, d.CreatedAt, r.tenant).Scan(&d.ID)
+}
+
+// DeclinedUsers возвращает пользователей, отказавшихся от PR
+func (r *DeclineRepo) DeclinedUsers(prID string) ([]string, error) {
+	query := `SELECT DISTINCT user_id FROM review_declines WHERE pull_request_id=$1 AND tenant_id=$2`
+	rows, err := r.db.Query(query, prID, r.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		users = append(users, id)
+	}
+	return users, rows.Err()
+}
+
+// ListByUser возвращает отказы пользователя, начиная с since, от новых к старым
+func (r *DeclineRepo) ListByUser(userID string, since time.Time) ([]model.ReviewDecline, error) {
+	query := `
+	SELECT id, pull_request_id, user_id, reason, comment, replaced_by, created_at
+	FROM review_declines
+	WHERE user_id=$1 AND created_at >= $2 AND tenant_id = $3
+	ORDER BY created_at DESC, id DESC
+	`
+	rows, err := r.db.Query(query, userID, since, r.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	declines := []model.ReviewDecline{}
+	for rows.Next() {
+		var d model.ReviewDecline
+		if err := rows.Scan(&d.ID, &d.PRID, &d.UserID, &d.Reason, &d.Comment, &d.ReplacedBy, &d.CreatedAt); err != nil {
+			return nil, err
+		}
+		declines = append(declines, d)
+	}
+	return declines, rows.Err()
+}
+
+// Stats возвращает число отказов по пользователям начиная с since;
+// в отчёт попадают пользователи минимум с minTotal отказами
+func (r *DeclineRepo) Stats(since time.Time, minTotal int) ([]model.DeclineStats, error) {
+	query := `
+	SELECT user_id, reason, COUNT(*)
+	FROM review_declines
+	WHERE created_at >= $1 AND tenant_id = $2
+	GROUP BY user_id, reason
+	`
+	rows, err := r.db.Query(query, since, r.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	byUser := make(map[string]*model.DeclineStats)
+	for rows.Next() {
+		var userID, reason string
+		var n int
+		if err := rows.Scan(&userID, &reason, &n); err != nil {
+			return nil, err
+		}
+		st, ok := byUser[userID]
+		if !ok {
+			st = &model.DeclineStats{UserID: userID, ByReason: map[string]int{}}
+			byUser[userID] = st
+		}
+		st.ByReason[reason] = n
+		st.Total += n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	stats := []model.DeclineStats{}
+	for _, st := range byUser {
+		if st.Total >= minTotal {
+			stats = append(stats, *st)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		return stats[i].UserID < stats[j].UserID
+	})
+	return stats, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"encoding/json"
+	"pr-reviewer/internal/model"
+)
+
+// HistoryRepo хранит историю изменений PR
+type HistoryRepo struct {
+	db     DBTX
+	tenant string
+}
+
+// NewHistoryRepo создаёт новый HistoryRepo
+func NewHistoryRepo(db DBTX, tenant string) *HistoryRepo {
+	return &HistoryRepo{db: db, tenant: tenant}
+}
+
+// Add добавляет событие в историю PR
+func (r *HistoryRepo) Add(event *model.PREvent) error {
+	data := event.Data
+	if data == nil {
+		data = map[string]string{}
+	}
+	dataJSON, _ := json.Marshal(data)
+	query := `
+	INSERT INTO pr_history (pull_request_id, event, assignment, data, created_at, tenant_id)
+	VALUES ($1,$2,$3,$4,$5,$6)
+	RETURNING id
+	`
+	return r.db.QueryRow(query, event.PRID, event.Event, marshalAssignment(event.Assignment), dataJSON, event.CreatedAt, r.tenant).Scan(&event.ID)
+}
+
+// ListByPR возвращает историю PR в хронологическом порядке
+func (r *HistoryRepo) ListByPR(prID string) ([]model.PREvent, error) {
+	query := `SELECT id, pull_request_id, event, assignment, data, created_at FROM pr_history WHERE pull_request_id=$1 AND tenant_id=$2 ORDER BY id`
+	rows, err := r.db.Query(query, prID, r.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []model.PREvent{}
+	for rows.Next() {
+		var e model.PREvent
+		var assignmentJSON, dataJSON []byte
+		if err := rows.Scan(&e.ID, &e.PRID, &e.Event, &assignmentJSON, &dataJSON, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		if len(assignmentJSON) > 0 {
+			json.Unmarshal(assignmentJSON, &e.Assignment)
+		}
+		json.Unmarshal(dataJSON, &e.Data)
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
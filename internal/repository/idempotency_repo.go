@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"pr-reviewer/internal/model"
+	"time"
+)
+
+// IdempotencyRepo хранит ответы на запросы с Idempotency-Key
+type IdempotencyRepo struct {
+	db     DBTX
+	tenant string
+}
+
+// NewIdempotencyRepo создаёт новый IdempotencyRepo
+func NewIdempotencyRepo(db DBTX, tenant string) *IdempotencyRepo {
+	return &IdempotencyRepo{db: db, tenant: tenant}
+}
+
+// Reserve занимает ключ под выполняющийся запрос. Запись с истёкшим сроком
+// (expires_at не позже rec.CreatedAt) перезаписывается; действующая — ErrAlreadyExists.
+func (r *IdempotencyRepo) Reserve(rec *model.IdempotencyRecord) error {
+	query := `
+	INSERT INTO idempotency_keys (idempotency_key, request_hash, status_code, response, created_at, expires_at, tenant_id)
+	VALUES ($1,$2,0,NULL,$3,$4,$5)
+	ON CONFLICT (tenant_id, idempotency_key) DO UPDATE
+	SET request_hash=EXCLUDED.request_hash, status_code=0, response=NULL,
+	    created_at=EXCLUDED.created_at, expires_at=EXCLUDED.expires_at
+	WHERE idempotency_keys.expires_at <= EXCLUDED.created_at
+	`
+	res, err := r.db.Exec(query, rec.Key, rec.RequestHash, rec.CreatedAt, rec.ExpiresAt, r.tenant)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAlreadyExists
+	}
+	return nil
+}
+
+// Get возвращает запись по ключу
+func (r *IdempotencyRepo) Get(key string) (*model.IdempotencyRecord, error) {
+	query := `
+	SELECT idempotency_key, request_hash, status_code, response, created_at, expires_at
+	FROM idempotency_keys WHERE idempotency_key=$1 AND tenant_id=$2
+	`
+	var rec model.IdempotencyRecord
+	err := r.db.QueryRow(query, key, r.tenant).Scan(&rec.Key, &rec.RequestHash, &rec.StatusCode, &rec.Response, &rec.CreatedAt, &rec.ExpiresAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &rec, nil
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ, и продлевает запись до expiresAt
+func (r *IdempotencyRepo) Complete(key string, statusCode int, response []byte, expiresAt time.Time) error {
+	res, err := r.db.Exec(`UPDATE idempotency_keys SET status_code=$1, response=$2, expires_at=$3 WHERE idempotency_key=$4 AND tenant_id=$5`, statusCode, response, expiresAt, key, r.tenant)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete освобождает ключ
+func (r *IdempotencyRepo) Delete(key string) error {
+	_, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE idempotency_key=$1 AND tenant_id=$2`, key, r.tenant)
+	return err
+}
+
+// DeleteExpired удаляет записи всех организаций, срок которых истёк к моменту now
+func (r *IdempotencyRepo) DeleteExpired(now time.Time) (int64, error) {
+	res, err := r.db.Exec(`DELETE FROM idempotency_keys WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
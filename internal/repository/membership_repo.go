@@ -0,0 +1,41 @@
+package repository
+
+import "pr-reviewer/internal/model"
+
+// MembershipRepo хранит членство пользователей в командах
+type MembershipRepo struct {
+	db     DBTX
+	tenant string
+}
+
+// NewMembershipRepo создаёт новый MembershipRepo
+func NewMembershipRepo(db DBTX, tenant string) *MembershipRepo {
+	return &MembershipRepo{db: db, tenant: tenant}
+}
+
+// Upsert добавляет членство или обновляет его флаг активности, вес и роль
+func (r *MembershipRepo) Upsert(m *model.TeamMembership) error {
+	query := `
+	INSERT INTO team_memberships (tenant_id, team_name, user_id, is_active, weight, role)
+	VALUES ($1, $2, $3, $4, $5, $6)
+	ON CONFLICT (tenant_id, team_name, user_id) DO UPDATE SET is_active=$4, weight=$5, role=$6
+	`
+	role := m.Role
+	if role == "" {
+		role = model.TeamRoleMember
+	}
+	_, err := r.db.Exec(query, r.tenant, m.TeamName, m.UserID, m.IsActive, m.Weight, role)
+	return err
+}
+
+// Remove удаляет членство пользователя в команде
+func (r *MembershipRepo) Remove(teamName, userID string) error {
+	res, err := r.db.Exec(`DELETE FROM team_memberships WHERE tenant_id=$1 AND team_name=$2 AND user_id=$3`, r.tenant, teamName, userID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
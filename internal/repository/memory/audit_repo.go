@@ -0,0 +1,63 @@
+package memory
+
+import (
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// AuditRepo хранит журнал аудита в памяти
+type AuditRepo struct {
+	s  *Store
+	d  *tenantData // данные организации репозитория
+	tx bool        // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewAuditRepo создаёт AuditRepo, работающий с данными организации tenant
+func NewAuditRepo(s *Store, tenant string) *AuditRepo {
+	return &AuditRepo{s: s, d: s.tenant(tenant)}
+}
+
+// Add добавляет запись и присваивает ей ID
+func (r *AuditRepo) Add(e *model.AuditEvent) error {
+	defer r.s.write(r.tx)()
+
+	r.s.nextAuditID++
+	e.ID = r.s.nextAuditID
+	r.d.audit = append(r.d.audit, cloneAudit(*e))
+	return nil
+}
+
+// List возвращает записи от новых к старым
+func (r *AuditRepo) List(f repository.AuditFilter) ([]model.AuditEvent, error) {
+	defer r.s.read(r.tx)()
+
+	events := []model.AuditEvent{}
+	for i := len(r.d.audit) - 1; i >= 0 && len(events) < f.Limit; i-- {
+		e := r.d.audit[i]
+		if (f.Action != "" && e.Action != f.Action) ||
+			(f.Actor != "" && e.Actor != f.Actor) ||
+			(f.TargetType != "" && e.TargetType != f.TargetType) ||
+			(f.TargetID != "" && e.TargetID != f.TargetID) ||
+			(f.BeforeID != 0 && e.ID >= f.BeforeID) {
+			continue
+		}
+		e = cloneAudit(e)
+		if e.Data == nil {
+			e.Data = map[string]string{}
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// cloneAudit возвращает независимую копию записи аудита
+func cloneAudit(e model.AuditEvent) model.AuditEvent {
+	if e.Data != nil {
+		data := make(map[string]string, len(e.Data))
+		for k, v := range e.Data {
+			data[k] = v
+		}
+		e.Data = data
+	}
+	return e
+}
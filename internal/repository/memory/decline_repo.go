@@ -0,0 +1,98 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"pr-reviewer/internal/model"
+)
+
+// DeclineRepo хранит отказы ревьюверов в памяти
+type DeclineRepo struct {
+	s  *Store
+	d  *tenantData // данные организации репозитория
+	tx bool        // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewDeclineRepo создаёт DeclineRepo, работающий с данными организации tenant
+func NewDeclineRepo(s *Store, tenant string) *DeclineRepo {
+	return &DeclineRepo{s: s, d: s.tenant(tenant)}
+}
+
+// Add сохраняет отказ и присваивает ему ID
+func (r *DeclineRepo) Add(d *model.ReviewDecline) error {
+	defer r.s.write(r.tx)()
+
+	r.s.nextDeclineID++
+	d.ID = r.s.nextDeclineID
+	r.d.declines = append(r.d.declines, *d)
+	return nil
+}
+
+// DeclinedUsers возвращает пользователей, отказавшихся от PR
+func (r *DeclineRepo) DeclinedUsers(prID string) ([]string, error) {
+	defer r.s.read(r.tx)()
+
+	seen := make(map[string]bool)
+	var users []string
+	for _, d := range r.d.declines {
+		if d.PRID == prID && !seen[d.UserID] {
+			seen[d.UserID] = true
+			users = append(users, d.UserID)
+		}
+	}
+	return users, nil
+}
+
+// ListByUser возвращает отказы пользователя, начиная с since, от новых к старым
+func (r *DeclineRepo) ListByUser(userID string, since time.Time) ([]model.ReviewDecline, error) {
+	defer r.s.read(r.tx)()
+
+	declines := []model.ReviewDecline{}
+	for _, d := range r.d.declines {
+		if d.UserID == userID && !d.CreatedAt.Before(since) {
+			declines = append(declines, d)
+		}
+	}
+	sort.Slice(declines, func(i, j int) bool {
+		if !declines[i].CreatedAt.Equal(declines[j].CreatedAt) {
+			return declines[i].CreatedAt.After(declines[j].CreatedAt)
+		}
+		return declines[i].ID > declines[j].ID
+	})
+	return declines, nil
+}
+
+// Stats возвращает число отказов по пользователям начиная с since;
+// в отчёт попадают пользователи минимум с minTotal отказами
+func (r *DeclineRepo) Stats(since time.Time, minTotal int) ([]model.DeclineStats, error) {
+	defer r.s.read(r.tx)()
+
+	byUser := make(map[string]*model.DeclineStats)
+	for _, d := range r.d.declines {
+		if d.CreatedAt.Before(since) {
+			continue
+		}
+		st, ok := byUser[d.UserID]
+		if !ok {
+			st = &model.DeclineStats{UserID: d.UserID, ByReason: map[string]int{}}
+			byUser[d.UserID] = st
+		}
+		st.ByReason[d.Reason]++
+		st.Total++
+	}
+
+	stats := []model.DeclineStats{}
+	for _, st := range byUser {
+		if st.Total >= minTotal {
+			stats = append(stats, *st)
+		}
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Total != stats[j].Total {
+			return stats[i].Total > stats[j].Total
+		}
+		return stats[i].UserID < stats[j].UserID
+	})
+	return stats, nil
+}
@@ -0,0 +1,42 @@
+package memory
+
+import "pr-reviewer/internal/model"
+
+// HistoryRepo хранит историю PR в памяти
+type HistoryRepo struct {
+	s  *Store
+	d  *tenantData // данные организации репозитория
+	tx bool        // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewHistoryRepo создаёт HistoryRepo, работающий с данными организации tenant
+func NewHistoryRepo(s *Store, tenant string) *HistoryRepo {
+	return &HistoryRepo{s: s, d: s.tenant(tenant)}
+}
+
+// Add добавляет событие в историю PR и присваивает ему ID
+func (r *HistoryRepo) Add(event *model.PREvent) error {
+	defer r.s.write(r.tx)()
+
+	r.s.nextHistoryID++
+	event.ID = r.s.nextHistoryID
+	r.d.history = append(r.d.history, cloneEvent(*event))
+	return nil
+}
+
+// ListByPR возвращает историю PR в хронологическом порядке
+func (r *HistoryRepo) ListByPR(prID string) ([]model.PREvent, error) {
+	defer r.s.read(r.tx)()
+
+	events := []model.PREvent{}
+	for _, e := range r.d.history {
+		if e.PRID == prID {
+			e = cloneEvent(e)
+			if e.Data == nil {
+				e.Data = map[string]string{}
+			}
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
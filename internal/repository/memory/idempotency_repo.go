@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// IdempotencyRepo хранит ответы на запросы с Idempotency-Key в памяти
+type IdempotencyRepo struct {
+	s  *Store
+	d  *tenantData // данные организации репозитория
+	tx bool        // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewIdempotencyRepo создаёт IdempotencyRepo, работающий с данными организации tenant
+func NewIdempotencyRepo(s *Store, tenant string) *IdempotencyRepo {
+	return &IdempotencyRepo{s: s, d: s.tenant(tenant)}
+}
+
+// Reserve занимает ключ; действующая запись даёт ErrAlreadyExists, истёкшая перезаписывается
+func (r *IdempotencyRepo) Reserve(rec *model.IdempotencyRecord) error {
+	defer r.s.write(r.tx)()
+
+	if old, ok := r.d.idempotency[rec.Key]; ok && old.ExpiresAt.After(rec.CreatedAt) {
+		return repository.ErrAlreadyExists
+	}
+	stored := *rec
+	stored.StatusCode = 0
+	stored.Response = nil
+	r.d.idempotency[rec.Key] = stored
+	return nil
+}
+
+// Get возвращает запись по ключу
+func (r *IdempotencyRepo) Get(key string) (*model.IdempotencyRecord, error) {
+	defer r.s.read(r.tx)()
+
+	rec, ok := r.d.idempotency[key]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	rec.Response = append([]byte(nil), rec.Response...)
+	return &rec, nil
+}
+
+// Complete сохраняет ответ на запрос, занявший ключ, и продлевает запись до expiresAt
+func (r *IdempotencyRepo) Complete(key string, statusCode int, response []byte, expiresAt time.Time) error {
+	defer r.s.write(r.tx)()
+
+	rec, ok := r.d.idempotency[key]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	rec.StatusCode = statusCode
+	rec.Response = append([]byte(nil), response...)
+	rec.ExpiresAt = expiresAt
+	r.d.idempotency[key] = rec
+	return nil
+}
+
+// Delete освобождает ключ
+func (r *IdempotencyRepo) Delete(key string) error {
+	defer r.s.write(r.tx)()
+
+	delete(r.d.idempotency, key)
+	return nil
+}
+
+// DeleteExpired удаляет записи всех организаций, срок которых истёк к моменту now
+func (r *IdempotencyRepo) DeleteExpired(now time.Time) (int64, error) {
+	defer r.s.write(r.tx)()
+
+	var n int64
+	for _, d := range r.s.tenants {
+		for key, rec := range d.idempotency {
+			if !rec.ExpiresAt.After(now) {
+				delete(d.idempotency, key)
+				n++
+			}
+		}
+	}
+	return n, nil
+}
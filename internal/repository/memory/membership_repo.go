@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"sort"
+	"strconv"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// membershipKey — ключ членства: команда и пользователь
+type membershipKey struct {
+	team string
+	user string
+}
+
+// MembershipRepo хранит членство пользователей в командах в памяти
+type MembershipRepo struct {
+	s  *Store
+	d  *tenantData // данные организации репозитория
+	tx bool        // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewMembershipRepo создаёт MembershipRepo, работающий с данными организации tenant
+func NewMembershipRepo(s *Store, tenant string) *MembershipRepo {
+	return &MembershipRepo{s: s, d: s.tenant(tenant)}
+}
+
+// Upsert добавляет членство или обновляет его флаг активности, вес и роль
+func (r *MembershipRepo) Upsert(m *model.TeamMembership) error {
+	defer r.s.write(r.tx)()
+
+	saved := *m
+	if saved.Role == "" {
+		saved.Role = model.TeamRoleMember
+	}
+	r.d.memberships[membershipKey{team: m.TeamName, user: m.UserID}] = saved
+	return nil
+}
+
+// Remove удаляет членство пользователя в команде
+func (r *MembershipRepo) Remove(teamName, userID string) error {
+	defer r.s.write(r.tx)()
+
+	key := membershipKey{team: teamName, user: userID}
+	if _, ok := r.d.memberships[key]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.d.memberships, key)
+	return nil
+}
+
+// withTeams возвращает копию пользователя с его членствами: по убыванию веса, затем по имени
+// команды, как в PostgreSQL. Вызывается под блокировкой.
+func (d *tenantData) withTeams(u model.User) model.User {
+	u = cloneUser(u)
+	id := strconv.FormatInt(u.ID, 10)
+	u.Teams = []model.TeamMembership{}
+	for key, m := range d.memberships {
+		if key.user == id {
+			m.UserID = ""
+			u.Teams = append(u.Teams, m)
+		}
+	}
+	sort.Slice(u.Teams, func(i, j int) bool {
+		if u.Teams[i].Weight != u.Teams[j].Weight {
+			return u.Teams[i].Weight > u.Teams[j].Weight
+		}
+		return u.Teams[i].TeamName < u.Teams[j].TeamName
+	})
+	u.TeamName = ""
+	if len(u.Teams) > 0 {
+		u.TeamName = u.Teams[0].TeamName
+	}
+	return u
+}
@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"sort"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// OrganizationRepo хранит организации в памяти
+type OrganizationRepo struct {
+	s  *Store
+	tx bool // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewOrganizationRepo создаёт новый OrganizationRepo
+func NewOrganizationRepo(s *Store) *OrganizationRepo {
+	return &OrganizationRepo{s: s}
+}
+
+// Create создаёт организацию
+func (r *OrganizationRepo) Create(o *model.Organization) error {
+	defer r.s.write(r.tx)()
+
+	if _, ok := r.s.orgs[o.ID]; ok {
+		return repository.ErrAlreadyExists
+	}
+	r.s.orgs[o.ID] = cloneOrganization(*o)
+	return nil
+}
+
+// Get возвращает организацию по ID
+func (r *OrganizationRepo) Get(id string) (*model.Organization, error) {
+	defer r.s.read(r.tx)()
+
+	o, ok := r.s.orgs[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	o = cloneOrganization(o)
+	return &o, nil
+}
+
+// List возвращает все организации по ID
+func (r *OrganizationRepo) List() ([]model.Organization, error) {
+	defer r.s.read(r.tx)()
+
+	orgs := make([]model.Organization, 0, len(r.s.orgs))
+	for _, o := range r.s.orgs {
+		orgs = append(orgs, cloneOrganization(o))
+	}
+	sort.Slice(orgs, func(i, j int) bool { return orgs[i].ID < orgs[j].ID })
+	return orgs, nil
+}
+
+// UpdateSettings заменяет настройки организации
+func (r *OrganizationRepo) UpdateSettings(id string, settings model.OrgSettings) (*model.Organization, error) {
+	defer r.s.write(r.tx)()
+
+	o, ok := r.s.orgs[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	o.Settings = settings
+	o = cloneOrganization(o)
+	r.s.orgs[id] = o
+	o = cloneOrganization(o)
+	return &o, nil
+}
+
+// cloneOrganization возвращает независимую копию организации
+func cloneOrganization(o model.Organization) model.Organization {
+	o.Settings = model.OrgSettings{
+		ReviewerCount:  cloneInt(o.Settings.ReviewerCount),
+		MinReviewers:   cloneInt(o.Settings.MinReviewers),
+		MaxReviewers:   cloneInt(o.Settings.MaxReviewers),
+		MaxOpenReviews: cloneInt(o.Settings.MaxOpenReviews),
+		Strategy:       o.Settings.Strategy,
+		MergePolicy:    o.Settings.MergePolicy,
+		ReviewSLAHours: cloneInt(o.Settings.ReviewSLAHours),
+	}
+	return o
+}
+
+// cloneInt копирует необязательное число
+func cloneInt(p *int) *int {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
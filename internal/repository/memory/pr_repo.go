@@ -0,0 +1,257 @@
+package memory
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// PRRepo хранит PR в памяти
+type PRRepo struct {
+	s  *Store
+	d  *tenantData // данные организации репозитория
+	tx bool        // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewPRRepo создаёт PRRepo, работающий с данными организации tenant
+func NewPRRepo(s *Store, tenant string) *PRRepo {
+	return &PRRepo{s: s, d: s.tenant(tenant)}
+}
+
+// Create создаёт PR
+func (r *PRRepo) Create(pr *model.PullRequest) error {
+	defer r.s.write(r.tx)()
+
+	if _, ok := r.d.prs[pr.ID]; ok {
+		return repository.ErrAlreadyExists
+	}
+	pr.Version = 1
+	stored := clonePR(*pr)
+	if stored.Labels == nil {
+		stored.Labels = []string{}
+	}
+	r.d.prs[pr.ID] = stored
+	return nil
+}
+
+// Restore сохраняет PR из архива как есть: со статусом, временем merge и версией
+func (r *PRRepo) Restore(pr *model.PullRequest) error {
+	defer r.s.write(r.tx)()
+
+	if _, ok := r.d.prs[pr.ID]; ok {
+		return repository.ErrAlreadyExists
+	}
+	stored := clonePR(*pr)
+	if stored.AssignedReviewers == nil {
+		stored.AssignedReviewers = []string{}
+	}
+	if stored.Labels == nil {
+		stored.Labels = []string{}
+	}
+	r.d.prs[pr.ID] = stored
+	return nil
+}
+
+// GetByID возвращает PR по ID
+func (r *PRRepo) GetByID(prID string) (*model.PullRequest, error) {
+	defer r.s.read(r.tx)()
+
+	pr, ok := r.d.prs[prID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	pr = clonePR(pr)
+	return &pr, nil
+}
+
+// Update обновляет изменяемые поля PR при совпадении версии и увеличивает её
+func (r *PRRepo) Update(pr *model.PullRequest) error {
+	defer r.s.write(r.tx)()
+
+	stored, ok := r.d.prs[pr.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if stored.Version != pr.Version {
+		return repository.ErrConflict
+	}
+	updated := clonePR(*pr)
+	stored.Status = updated.Status
+	stored.AssignedReviewers = updated.AssignedReviewers
+	stored.Labels = updated.Labels
+	if stored.Labels == nil {
+		stored.Labels = []string{}
+	}
+	stored.MergedAt = updated.MergedAt
+	stored.Assignment = updated.Assignment
+	stored.Version++
+	r.d.prs[pr.ID] = stored
+	pr.Version = stored.Version
+	return nil
+}
+
+// GetByReviewer возвращает PR, где пользователь ревьювер; пустой status — PR в любом статусе
+func (r *PRRepo) GetByReviewer(userID, status string) ([]model.PullRequest, error) {
+	defer r.s.read(r.tx)()
+
+	var prs []model.PullRequest
+	for _, pr := range r.d.prs {
+		if (status == "" || pr.Status == status) && contains(pr.AssignedReviewers, userID) {
+			prs = append(prs, clonePR(pr))
+		}
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		if !prs[i].CreatedAt.Equal(prs[j].CreatedAt) {
+			return prs[i].CreatedAt.Before(prs[j].CreatedAt)
+		}
+		return prs[i].ID < prs[j].ID
+	})
+	return prs, nil
+}
+
+// CountOpenReviews возвращает число открытых PR, назначенных на каждого ревьювера
+func (r *PRRepo) CountOpenReviews() (map[string]int, error) {
+	defer r.s.read(r.tx)()
+
+	counts := make(map[string]int)
+	for _, pr := range r.d.prs {
+		if pr.Status != "OPEN" {
+			continue
+		}
+		for _, reviewer := range pr.AssignedReviewers {
+			counts[reviewer]++
+		}
+	}
+	return counts, nil
+}
+
+// List возвращает страницу PR, удовлетворяющих фильтру, и курсор следующей страницы
+func (r *PRRepo) List(f repository.PRFilter) ([]model.PullRequest, string, error) {
+	if !repository.ValidPRSort(f.SortBy) {
+		return nil, "", fmt.Errorf("unsupported sort field %q", f.SortBy)
+	}
+	var after *repository.Cursor
+	if f.Cursor != "" {
+		c, err := repository.DecodeCursor(f.Cursor, f.SortKey())
+		if err != nil {
+			return nil, "", err
+		}
+		if f.SortBy == repository.PRSortCreatedAt {
+			if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil {
+				return nil, "", repository.ErrInvalidCursor
+			}
+		}
+		after = c
+	}
+
+	defer r.s.read(r.tx)()
+
+	prs := []model.PullRequest{}
+	for _, pr := range r.d.prs {
+		if r.matches(pr, f) && (after == nil || compare(pr, after, f.SortBy)*direction(f) > 0) {
+			prs = append(prs, clonePR(pr))
+		}
+	}
+	sort.Slice(prs, func(i, j int) bool {
+		return compare(prs[i], keyOf(prs[j], f.SortBy), f.SortBy)*direction(f) < 0
+	})
+
+	var next string
+	if len(prs) > f.Limit {
+		prs = prs[:f.Limit]
+		next = f.NextCursor(prs[len(prs)-1])
+	}
+	return prs, next, nil
+}
+
+// matches проверяет PR по всем фильтрам; вызывается под блокировкой чтения
+func (r *PRRepo) matches(pr model.PullRequest, f repository.PRFilter) bool {
+	switch {
+	case f.Status != "" && pr.Status != f.Status:
+		return false
+	case f.AuthorID != "" && pr.AuthorID != f.AuthorID:
+		return false
+	case f.ReviewerID != "" && !contains(pr.AssignedReviewers, f.ReviewerID):
+		return false
+	case f.Label != "" && !contains(pr.Labels, f.Label):
+		return false
+	case f.TeamName != "" && !r.hasMember(f.TeamName, pr.AuthorID):
+		return false
+	case f.CreatedFrom != nil && pr.CreatedAt.Before(*f.CreatedFrom):
+		return false
+	case f.CreatedTo != nil && !pr.CreatedAt.Before(*f.CreatedTo):
+		return false
+	case f.MergedFrom != nil && (pr.MergedAt == nil || pr.MergedAt.Before(*f.MergedFrom)):
+		return false
+	case f.MergedTo != nil && (pr.MergedAt == nil || !pr.MergedAt.Before(*f.MergedTo)):
+		return false
+	}
+	return true
+}
+
+// keyOf строит позицию PR в порядке сортировки
+func keyOf(pr model.PullRequest, sortBy string) *repository.Cursor {
+	c := &repository.Cursor{ID: pr.ID}
+	switch sortBy {
+	case repository.PRSortCreatedAt:
+		c.Value = pr.CreatedAt.Format(time.RFC3339Nano)
+	case repository.PRSortName:
+		c.Value = pr.Name
+	}
+	return c
+}
+
+// compare сравнивает PR с позицией c по полю сортировки, затем по ID: -1, 0 или 1
+func compare(pr model.PullRequest, c *repository.Cursor, sortBy string) int {
+	switch sortBy {
+	case repository.PRSortCreatedAt:
+		ts, _ := time.Parse(time.RFC3339Nano, c.Value)
+		if pr.CreatedAt.Before(ts) {
+			return -1
+		}
+		if pr.CreatedAt.After(ts) {
+			return 1
+		}
+	case repository.PRSortName:
+		if pr.Name < c.Value {
+			return -1
+		}
+		if pr.Name > c.Value {
+			return 1
+		}
+	}
+	switch {
+	case pr.ID < c.ID:
+		return -1
+	case pr.ID > c.ID:
+		return 1
+	}
+	return 0
+}
+
+// direction возвращает 1 для сортировки по возрастанию и -1 по убыванию
+func direction(f repository.PRFilter) int {
+	if f.Desc {
+		return -1
+	}
+	return 1
+}
+
+// contains сообщает, есть ли значение в срезе
+func contains(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// hasMember сообщает, состоит ли пользователь в команде; вызывается под блокировкой
+func (r *PRRepo) hasMember(teamName, userID string) bool {
+	_, ok := r.d.memberships[membershipKey{team: teamName, user: userID}]
+	return ok
+}
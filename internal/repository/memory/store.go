@@ -0,0 +1,359 @@
+// Package memory — потокобезопасная реализация репозиториев в памяти.
+// Повторяет семантику PostgreSQL-реализации (ErrNotFound, ErrAlreadyExists, порядок выдачи)
+// и предназначена для тестов сервисов без PostgreSQL.
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// Store — общее состояние всех репозиториев в памяти
+type Store struct {
+	mu sync.RWMutex
+
+	tenants map[string]*tenantData
+	orgs    map[string]model.Organization
+
+	tokens      map[int64]model.APIToken
+	nextTokenID int64
+
+	nextHistoryID int64
+	nextDeclineID int64
+	nextAuditID   int64
+
+	// version увеличивается при каждом изменении; по ней Do узнаёт о параллельных изменениях
+	version int64
+}
+
+// tenantData — данные одной организации. Репозитории организации видят только их,
+// поэтому обратиться к чужим данным через них невозможно.
+type tenantData struct {
+	teams       map[string]model.Team
+	users       map[string]model.User
+	memberships map[membershipKey]model.TeamMembership
+	prs         map[string]model.PullRequest
+	history     []model.PREvent
+	declines    []model.ReviewDecline
+
+	idempotency map[string]model.IdempotencyRecord
+	audit       []model.AuditEvent
+}
+
+// NewStore создаёт пустое хранилище с организацией по умолчанию
+func NewStore() *Store {
+	return &Store{
+		tenants: make(map[string]*tenantData),
+		orgs: map[string]model.Organization{
+			repository.DefaultTenant: {ID: repository.DefaultTenant, Name: "Default"},
+		},
+		tokens: make(map[int64]model.APIToken),
+	}
+}
+
+// newTenantData создаёт пустые данные организации
+func newTenantData() *tenantData {
+	return &tenantData{
+		teams:       make(map[string]model.Team),
+		users:       make(map[string]model.User),
+		memberships: make(map[membershipKey]model.TeamMembership),
+		prs:         make(map[string]model.PullRequest),
+		idempotency: make(map[string]model.IdempotencyRecord),
+	}
+}
+
+// tenant возвращает данные организации, создавая их при первом обращении
+func (s *Store) tenant(id string) *tenantData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tenantLocked(id)
+}
+
+// tenantLocked — tenant для вызова под уже захваченной блокировкой
+func (s *Store) tenantLocked(id string) *tenantData {
+	d, ok := s.tenants[id]
+	if !ok {
+		d = newTenantData()
+		s.tenants[id] = d
+	}
+	return d
+}
+
+// Repos возвращает набор репозиториев организации из ctx
+func (s *Store) Repos(ctx context.Context) repository.Repos {
+	tenant := repository.TenantFrom(ctx)
+	return repository.Repos{
+		Teams:       NewTeamRepo(s, tenant),
+		Users:       NewUserRepo(s, tenant),
+		Memberships: NewMembershipRepo(s, tenant),
+		PRs:         NewPRRepo(s, tenant),
+		History:     NewHistoryRepo(s, tenant),
+		Declines:    NewDeclineRepo(s, tenant),
+		Idempotency: NewIdempotencyRepo(s, tenant),
+		Tokens:      NewTokenRepo(s, tenant),
+		Audit:       NewAuditRepo(s, tenant),
+		Orgs:        NewOrganizationRepo(s),
+	}
+}
+
+// Do выполняет fn атомарно. Как и транзакция PostgreSQL, fn работает без блокировки
+// хранилища — с собственной копией данных, которая заменяет общее состояние при фиксации.
+// Если за время fn другая операция обновила PR, который обновила и fn, возвращается
+// repository.ErrConflict; при прочих параллельных изменениях fn выполняется заново на свежих данных.
+// При ошибке fn её изменения отбрасываются.
+func (s *Store) Do(ctx context.Context, fn func(r repository.Repos) error) error {
+	tenant := repository.TenantFrom(ctx)
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		tx, base := s.begin()
+		d := tx.tenantLocked(tenant)
+		prVersions := make(map[string]int64, len(d.prs))
+		for id, pr := range d.prs {
+			prVersions[id] = pr.Version
+		}
+		if err := fn(txRepos(tx, d, tenant)); err != nil {
+			return err
+		}
+
+		s.mu.Lock()
+		if s.version == base {
+			s.install(tx)
+			s.version++
+			s.mu.Unlock()
+			return nil
+		}
+		conflict := s.prConflict(tenant, d, prVersions)
+		s.mu.Unlock()
+		if conflict {
+			return repository.ErrConflict
+		}
+	}
+}
+
+// View выполняет fn над снимком хранилища на момент вызова; изменения fn отбрасываются
+func (s *Store) View(ctx context.Context, fn func(r repository.Repos) error) error {
+	tenant := repository.TenantFrom(ctx)
+	tx, _ := s.begin()
+	return fn(txRepos(tx, tx.tenantLocked(tenant), tenant))
+}
+
+// txRepos возвращает репозитории организации tenant над копией хранилища tx
+func txRepos(tx *Store, d *tenantData, tenant string) repository.Repos {
+	return repository.Repos{
+		Teams:       &TeamRepo{s: tx, d: d, tx: true},
+		Users:       &UserRepo{s: tx, d: d, tx: true},
+		Memberships: &MembershipRepo{s: tx, d: d, tx: true},
+		PRs:         &PRRepo{s: tx, d: d, tx: true},
+		History:     &HistoryRepo{s: tx, d: d, tx: true},
+		Declines:    &DeclineRepo{s: tx, d: d, tx: true},
+		Idempotency: &IdempotencyRepo{s: tx, d: d, tx: true},
+		Tokens:      &TokenRepo{s: tx, tenant: tenant, tx: true},
+		Audit:       &AuditRepo{s: tx, d: d, tx: true},
+		Orgs:        &OrganizationRepo{s: tx, tx: true},
+	}
+}
+
+// write захватывает блокировку на запись, если вызов не внутри Do; возвращает функцию освобождения
+func (s *Store) write(inTx bool) func() {
+	if inTx {
+		return func() {}
+	}
+	s.mu.Lock()
+	return func() {
+		s.version++
+		s.mu.Unlock()
+	}
+}
+
+// read захватывает блокировку на чтение, если вызов не внутри Do; возвращает функцию освобождения
+func (s *Store) read(inTx bool) func() {
+	if inTx {
+		return func() {}
+	}
+	s.mu.RLock()
+	return s.mu.RUnlock
+}
+
+// begin копирует состояние хранилища для транзакции и возвращает копию и версию, с которой она снята.
+// Репозитории никогда не изменяют сохранённые значения на месте, поэтому достаточно копий карт и срезов.
+func (s *Store) begin() (*Store, int64) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tx := &Store{
+		tenants:       make(map[string]*tenantData, len(s.tenants)),
+		orgs:          make(map[string]model.Organization, len(s.orgs)),
+		tokens:        make(map[int64]model.APIToken, len(s.tokens)),
+		nextTokenID:   s.nextTokenID,
+		nextHistoryID: s.nextHistoryID,
+		nextDeclineID: s.nextDeclineID,
+		nextAuditID:   s.nextAuditID,
+	}
+	for id, d := range s.tenants {
+		c := d.clone()
+		tx.tenants[id] = &c
+	}
+	for k, v := range s.orgs {
+		tx.orgs[k] = v
+	}
+	for k, v := range s.tokens {
+		tx.tokens[k] = v
+	}
+	return tx, s.version
+}
+
+// install заменяет состояние хранилища состоянием транзакции tx. Вызывается под блокировкой.
+// Репозитории держат указатели на данные организаций, поэтому данные заменяются на месте.
+func (s *Store) install(tx *Store) {
+	for id, d := range tx.tenants {
+		if live, ok := s.tenants[id]; ok {
+			*live = *d
+		} else {
+			s.tenants[id] = d
+		}
+	}
+	s.orgs = tx.orgs
+	s.tokens = tx.tokens
+	s.nextTokenID = tx.nextTokenID
+	s.nextHistoryID = tx.nextHistoryID
+	s.nextDeclineID = tx.nextDeclineID
+	s.nextAuditID = tx.nextAuditID
+}
+
+// prConflict сообщает, что транзакция обновила PR организации tenant, версию которого
+// с момента её начала изменил кто-то другой. prVersions — версии PR на начало транзакции,
+// d — данные организации в транзакции. Вызывается под блокировкой.
+func (s *Store) prConflict(tenant string, d *tenantData, prVersions map[string]int64) bool {
+	live, ok := s.tenants[tenant]
+	if !ok {
+		return false
+	}
+	for id, pr := range d.prs {
+		read, existed := prVersions[id]
+		if !existed || pr.Version == read {
+			continue
+		}
+		if cur, ok := live.prs[id]; ok && cur.Version != read {
+			return true
+		}
+	}
+	return false
+}
+
+// clone копирует данные организации
+func (d *tenantData) clone() tenantData {
+	c := tenantData{
+		teams:       make(map[string]model.Team, len(d.teams)),
+		users:       make(map[string]model.User, len(d.users)),
+		memberships: make(map[membershipKey]model.TeamMembership, len(d.memberships)),
+		prs:         make(map[string]model.PullRequest, len(d.prs)),
+		history:     d.history[:len(d.history):len(d.history)],
+		declines:    d.declines[:len(d.declines):len(d.declines)],
+		idempotency: make(map[string]model.IdempotencyRecord, len(d.idempotency)),
+		audit:       d.audit[:len(d.audit):len(d.audit)],
+	}
+	for k, v := range d.teams {
+		c.teams[k] = v
+	}
+	for k, v := range d.users {
+		c.users[k] = v
+	}
+	for k, v := range d.memberships {
+		c.memberships[k] = v
+	}
+	for k, v := range d.prs {
+		c.prs[k] = v
+	}
+	for k, v := range d.idempotency {
+		c.idempotency[k] = v
+	}
+	return c
+}
+
+// cloneStrings копирует срез, чтобы вызывающий код не мог изменить состояние хранилища
+func cloneStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	out := make([]string, len(s))
+	copy(out, s)
+	return out
+}
+
+// cloneUser возвращает независимую копию пользователя
+func cloneUser(u model.User) model.User {
+	u.Skills = cloneStrings(u.Skills)
+	if u.OutOfOfficeUntil != nil {
+		t := *u.OutOfOfficeUntil
+		u.OutOfOfficeUntil = &t
+	}
+	return u
+}
+
+// clonePR возвращает независимую копию PR
+func clonePR(pr model.PullRequest) model.PullRequest {
+	pr.AssignedReviewers = cloneStrings(pr.AssignedReviewers)
+	pr.Labels = cloneStrings(pr.Labels)
+	if pr.MergedAt != nil {
+		t := *pr.MergedAt
+		pr.MergedAt = &t
+	}
+	pr.Assignment = cloneAssignment(pr.Assignment)
+	return pr
+}
+
+// cloneAssignment возвращает независимую копию объяснения назначения
+func cloneAssignment(a *model.AssignmentExplanation) *model.AssignmentExplanation {
+	if a == nil {
+		return nil
+	}
+	c := *a
+	c.Filters = cloneStrings(a.Filters)
+	c.Candidates = cloneStrings(a.Candidates)
+	c.Excluded = append([]model.ExcludedCandidate(nil), a.Excluded...)
+	c.Chosen = append([]model.ChosenReviewer(nil), a.Chosen...)
+	c.PoolTeams = cloneStrings(a.PoolTeams)
+	if a.Weights != nil {
+		c.Weights = make(map[string]int, len(a.Weights))
+		for k, v := range a.Weights {
+			c.Weights[k] = v
+		}
+	}
+	return &c
+}
+
+// cloneEvent возвращает независимую копию события истории
+func cloneEvent(e model.PREvent) model.PREvent {
+	e.Assignment = cloneAssignment(e.Assignment)
+	if e.Data != nil {
+		data := make(map[string]string, len(e.Data))
+		for k, v := range e.Data {
+			data[k] = v
+		}
+		e.Data = data
+	}
+	return e
+}
+
+// Проверяем на этапе компиляции, что реализации в памяти удовлетворяют интерфейсам
+var (
+	_ repository.TeamRepository    = (*TeamRepo)(nil)
+	_ repository.UserRepository    = (*UserRepo)(nil)
+	_ repository.PRRepository      = (*PRRepo)(nil)
+	_ repository.HistoryRepository = (*HistoryRepo)(nil)
+	_ repository.DeclineRepository = (*DeclineRepo)(nil)
+	_ repository.UnitOfWork        = (*Store)(nil)
+
+	_ repository.IdempotencyRepository = (*IdempotencyRepo)(nil)
+	_ repository.TokenRepository       = (*TokenRepo)(nil)
+	_ repository.AuditRepository       = (*AuditRepo)(nil)
+
+	_ repository.OrganizationRepository = (*OrganizationRepo)(nil)
+	_ repository.MembershipRepository   = (*MembershipRepo)(nil)
+)
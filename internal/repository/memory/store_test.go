@@ -0,0 +1,27 @@
+package memory
+
+import (
+	"context"
+	"testing"
+
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/repository/repotest"
+)
+
+func TestConformance(t *testing.T) {
+	repotest.Run(t, func(t *testing.T) repository.UnitOfWork {
+		return NewStore()
+	})
+}
+
+func BenchmarkGetByReviewer(b *testing.B) {
+	st := NewStore()
+	repotest.Seed(b, st.Repos(context.Background()))
+	repotest.BenchGetByReviewer(b, st.Repos(context.Background()))
+}
+
+func BenchmarkListPRs(b *testing.B) {
+	st := NewStore()
+	repotest.Seed(b, st.Repos(context.Background()))
+	repotest.BenchList(b, st.Repos(context.Background()))
+}
@@ -0,0 +1,164 @@
+package memory
+
+import (
+	"sort"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// TeamRepo хранит команды в памяти
+type TeamRepo struct {
+	s  *Store
+	d  *tenantData // данные организации репозитория
+	tx bool        // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewTeamRepo создаёт TeamRepo, работающий с данными организации tenant
+func NewTeamRepo(s *Store, tenant string) *TeamRepo {
+	return &TeamRepo{s: s, d: s.tenant(tenant)}
+}
+
+// Create создаёт новую команду с родителем и настройками
+func (r *TeamRepo) Create(team *model.Team) error {
+	defer r.s.write(r.tx)()
+
+	if _, ok := r.d.teams[team.Name]; ok {
+		return repository.ErrAlreadyExists
+	}
+	r.d.teams[team.Name] = cloneTeam(*team)
+	return nil
+}
+
+// GetByName возвращает команду по имени без участников
+func (r *TeamRepo) GetByName(name string) (*model.Team, error) {
+	defer r.s.read(r.tx)()
+
+	t, ok := r.d.teams[name]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	t = cloneTeam(t)
+	return &t, nil
+}
+
+// Update сохраняет родителя и настройки команды
+func (r *TeamRepo) Update(team *model.Team) error {
+	defer r.s.write(r.tx)()
+
+	if _, ok := r.d.teams[team.Name]; !ok {
+		return repository.ErrNotFound
+	}
+	r.d.teams[team.Name] = cloneTeam(*team)
+	return nil
+}
+
+// Children возвращает имена дочерних команд, упорядоченные по имени
+func (r *TeamRepo) Children(name string) ([]string, error) {
+	defer r.s.read(r.tx)()
+
+	var names []string
+	for _, t := range r.d.teams {
+		if t.Parent == name {
+			names = append(names, t.Name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Delete удаляет команду
+func (r *TeamRepo) Delete(name string) error {
+	defer r.s.write(r.tx)()
+
+	delete(r.d.teams, name)
+	return nil
+}
+
+// Rename переименовывает команду и переводит её участников и дочерние команды к newName
+func (r *TeamRepo) Rename(oldName, newName string) error {
+	defer r.s.write(r.tx)()
+
+	t, ok := r.d.teams[oldName]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if _, ok := r.d.teams[newName]; ok {
+		return repository.ErrAlreadyExists
+	}
+	t.Name = newName
+	r.d.teams[newName] = t
+	delete(r.d.teams, oldName)
+	for name, child := range r.d.teams {
+		if child.Parent == oldName {
+			child.Parent = newName
+			r.d.teams[name] = child
+		}
+	}
+	for key, m := range r.d.memberships {
+		if key.team == oldName {
+			delete(r.d.memberships, key)
+			m.TeamName = newName
+			r.d.memberships[membershipKey{team: newName, user: key.user}] = m
+		}
+	}
+	return nil
+}
+
+// List возвращает страницу команд, упорядоченных по имени, и курсор следующей страницы
+func (r *TeamRepo) List(limit int, after string) ([]model.TeamSummary, string, error) {
+	var afterName string
+	if after != "" {
+		c, err := repository.DecodeCursor(after, "team_name")
+		if err != nil {
+			return nil, "", err
+		}
+		afterName = c.ID
+	}
+
+	defer r.s.read(r.tx)()
+
+	byName := make(map[string]*model.TeamSummary)
+	teams := []model.TeamSummary{}
+	for name, t := range r.d.teams {
+		if name > afterName {
+			teams = append(teams, model.TeamSummary{Name: name, Parent: t.Parent})
+		}
+	}
+	sort.Slice(teams, func(i, j int) bool { return teams[i].Name < teams[j].Name })
+	for i := range teams {
+		byName[teams[i].Name] = &teams[i]
+	}
+	for key, m := range r.d.memberships {
+		if t, ok := byName[key.team]; ok {
+			t.MemberCount++
+			if m.IsActive && r.d.users[key.user].IsActive {
+				t.ActiveCount++
+			}
+		}
+	}
+
+	var next string
+	if len(teams) > limit {
+		teams = teams[:limit]
+		next = repository.EncodeCursor(repository.Cursor{Sort: "team_name", ID: teams[limit-1].Name})
+	}
+	return teams, next, nil
+}
+
+// cloneTeam копирует команду вместе с настройками, чтобы хранилище не делило указатели с вызывающим
+func cloneTeam(t model.Team) model.Team {
+	t.Members = nil
+	t.Effective = nil
+	t.Settings.ReviewerCount = cloneInt(t.Settings.ReviewerCount)
+	t.Settings.ReviewSLAHours = cloneInt(t.Settings.ReviewSLAHours)
+	if t.Settings.ExpandPool != nil {
+		v := *t.Settings.ExpandPool
+		t.Settings.ExpandPool = &v
+	}
+	if t.Settings.LeadsReview != nil {
+		v := *t.Settings.LeadsReview
+		t.Settings.LeadsReview = &v
+	}
+	return t
+}
@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// TokenRepo хранит API-токены в памяти
+type TokenRepo struct {
+	s      *Store
+	tenant string
+	tx     bool // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewTokenRepo создаёт TokenRepo, работающий с токенами организации tenant
+func NewTokenRepo(s *Store, tenant string) *TokenRepo {
+	return &TokenRepo{s: s, tenant: tenant}
+}
+
+// Create сохраняет токен и присваивает ему ID
+func (r *TokenRepo) Create(t *model.APIToken) error {
+	defer r.s.write(r.tx)()
+
+	for _, existing := range r.s.tokens {
+		if existing.TokenHash == t.TokenHash {
+			return repository.ErrAlreadyExists
+		}
+	}
+	r.s.nextTokenID++
+	t.ID = r.s.nextTokenID
+	t.TenantID = r.tenant
+	r.s.tokens[t.ID] = cloneToken(*t)
+	return nil
+}
+
+// GetByHash возвращает токен по SHA-256, в том числе отозванный. Поиск идёт по всем
+// организациям: организацию запроса определяет сам токен.
+func (r *TokenRepo) GetByHash(hash string) (*model.APIToken, error) {
+	defer r.s.read(r.tx)()
+
+	for _, t := range r.s.tokens {
+		if t.TokenHash == hash {
+			t = cloneToken(t)
+			return &t, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+// List возвращает токены организации по возрастанию ID
+func (r *TokenRepo) List() ([]model.APIToken, error) {
+	defer r.s.read(r.tx)()
+
+	tokens := make([]model.APIToken, 0, len(r.s.tokens))
+	for _, t := range r.s.tokens {
+		if t.TenantID == r.tenant {
+			tokens = append(tokens, cloneToken(t))
+		}
+	}
+	sort.Slice(tokens, func(i, j int) bool { return tokens[i].ID < tokens[j].ID })
+	return tokens, nil
+}
+
+// Revoke отзывает токен; повторный отзыв сохраняет исходное время
+func (r *TokenRepo) Revoke(id int64, at time.Time) (*model.APIToken, error) {
+	defer r.s.write(r.tx)()
+
+	t, ok := r.s.tokens[id]
+	if !ok || t.TenantID != r.tenant {
+		return nil, repository.ErrNotFound
+	}
+	if t.RevokedAt == nil {
+		t = cloneToken(t)
+		t.RevokedAt = &at
+		r.s.tokens[id] = t
+	}
+	t = cloneToken(t)
+	return &t, nil
+}
+
+// RenameTeam переносит токены лидов команды oldName на команду newName
+func (r *TokenRepo) RenameTeam(oldName, newName string) error {
+	defer r.s.write(r.tx)()
+
+	for id, t := range r.s.tokens {
+		if t.TenantID == r.tenant && t.TeamName == oldName {
+			t = cloneToken(t)
+			t.TeamName = newName
+			r.s.tokens[id] = t
+		}
+	}
+	return nil
+}
+
+// cloneToken возвращает независимую копию токена
+func cloneToken(t model.APIToken) model.APIToken {
+	if t.RevokedAt != nil {
+		at := *t.RevokedAt
+		t.RevokedAt = &at
+	}
+	return t
+}
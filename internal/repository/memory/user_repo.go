@@ -0,0 +1,129 @@
+package memory
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// UserRepo хранит пользователей в памяти
+type UserRepo struct {
+	s  *Store
+	d  *tenantData // данные организации репозитория
+	tx bool        // вызывается внутри Store.Do, блокировка уже захвачена
+}
+
+// NewUserRepo создаёт UserRepo, работающий с данными организации tenant
+func NewUserRepo(s *Store, tenant string) *UserRepo {
+	return &UserRepo{s: s, d: s.tenant(tenant)}
+}
+
+// CreateOrUpdate создаёт или обновляет пользователя; навыки, отсутствие и членства сохраняются
+func (r *UserRepo) CreateOrUpdate(user *model.User) error {
+	defer r.s.write(r.tx)()
+
+	id := strconv.FormatInt(user.ID, 10)
+	u, ok := r.d.users[id]
+	if !ok {
+		u = model.User{ID: user.ID, Skills: []string{}}
+	}
+	u.Username = user.Username
+	u.IsActive = user.IsActive
+	r.d.users[id] = u
+	return nil
+}
+
+// Create создаёт нового пользователя; занятый ID — ErrAlreadyExists
+func (r *UserRepo) Create(user *model.User) error {
+	defer r.s.write(r.tx)()
+
+	id := strconv.FormatInt(user.ID, 10)
+	if _, ok := r.d.users[id]; ok {
+		return repository.ErrAlreadyExists
+	}
+	r.d.users[id] = model.User{ID: user.ID, Username: user.Username, IsActive: user.IsActive, Skills: []string{}}
+	return nil
+}
+
+// GetByID возвращает пользователя по ID
+func (r *UserRepo) GetByID(userID string) (*model.User, error) {
+	defer r.s.read(r.tx)()
+
+	u, ok := r.d.users[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	u = r.d.withTeams(u)
+	return &u, nil
+}
+
+// GetByTeam возвращает всех участников команды, в том числе с неактивным членством, упорядоченных по ID
+func (r *UserRepo) GetByTeam(teamName string) ([]model.User, error) {
+	defer r.s.read(r.tx)()
+
+	var ids []string
+	for key := range r.d.memberships {
+		if key.team == teamName {
+			ids = append(ids, key.user)
+		}
+	}
+	sort.Strings(ids)
+
+	var users []model.User
+	for _, id := range ids {
+		users = append(users, r.d.withTeams(r.d.users[id]))
+	}
+	return users, nil
+}
+
+// List возвращает всех пользователей организации, упорядоченных по ID
+func (r *UserRepo) List() ([]model.User, error) {
+	defer r.s.read(r.tx)()
+
+	var users []model.User
+	for _, u := range r.d.users {
+		users = append(users, r.d.withTeams(u))
+	}
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+	return users, nil
+}
+
+// SetIsActive обновляет флаг активности пользователя
+func (r *UserRepo) SetIsActive(userID string, isActive bool) (*model.User, error) {
+	return r.update(userID, func(u *model.User) { u.IsActive = isActive })
+}
+
+// SetSkills заменяет набор навыков пользователя
+func (r *UserRepo) SetSkills(userID string, skills []string) (*model.User, error) {
+	skills = cloneStrings(skills)
+	if skills == nil {
+		skills = []string{}
+	}
+	return r.update(userID, func(u *model.User) { u.Skills = skills })
+}
+
+// SetOutOfOffice задаёт момент, до которого пользователь отсутствует (nil — снять отметку)
+func (r *UserRepo) SetOutOfOffice(userID string, until *time.Time) (*model.User, error) {
+	if until != nil {
+		t := *until
+		until = &t
+	}
+	return r.update(userID, func(u *model.User) { u.OutOfOfficeUntil = until })
+}
+
+// update изменяет пользователя под блокировкой и возвращает его копию
+func (r *UserRepo) update(userID string, apply func(u *model.User)) (*model.User, error) {
+	defer r.s.write(r.tx)()
+
+	u, ok := r.d.users[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	apply(&u)
+	r.d.users[userID] = u
+	u = r.d.withTeams(u)
+	return &u, nil
+}
@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"pr-reviewer/internal/model"
+)
+
+// OrganizationRepo хранит организации. В отличие от остальных репозиториев он
+// не привязан к организации: им пользуются для создания организаций и поиска их настроек.
+type OrganizationRepo struct {
+	db DBTX
+}
+
+// NewOrganizationRepo создаёт новый OrganizationRepo
+func NewOrganizationRepo(db DBTX) *OrganizationRepo {
+	return &OrganizationRepo{db: db}
+}
+
+// scanOrganization читает организацию из строки результата
+func scanOrganization(row rowScanner) (*model.Organization, error) {
+	var o model.Organization
+	var settingsJSON []byte
+	if err := row.Scan(&o.ID, &o.Name, &settingsJSON, &o.CreatedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	json.Unmarshal(settingsJSON, &o.Settings)
+	return &o, nil
+}
+
+// Create создаёт организацию
+func (r *OrganizationRepo) Create(o *model.Organization) error {
+	settingsJSON, _ := json.Marshal(o.Settings)
+	query := `INSERT INTO organizations (id, name, settings, created_at) VALUES ($1,$2,$3,$4)`
+	_, err := r.db.Exec(query, o.ID, o.Name, settingsJSON, o.CreatedAt)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// Get возвращает организацию по ID
+func (r *OrganizationRepo) Get(id string) (*model.Organization, error) {
+	query := `SELECT id, name, settings, created_at FROM organizations WHERE id=$1`
+	return scanOrganization(r.db.QueryRow(query, id))
+}
+
+// List возвращает все организации по ID
+func (r *OrganizationRepo) List() ([]model.Organization, error) {
+	rows, err := r.db.Query(`SELECT id, name, settings, created_at FROM organizations ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []model.Organization{}
+	for rows.Next() {
+		o, err := scanOrganization(rows)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, *o)
+	}
+	return orgs, rows.Err()
+}
+
+// UpdateSettings заменяет настройки организации
+func (r *OrganizationRepo) UpdateSettings(id string, settings model.OrgSettings) (*model.Organization, error) {
+	settingsJSON, _ := json.Marshal(settings)
+	query := `UPDATE organizations SET settings=$1 WHERE id=$2 RETURNING id, name, settings, created_at`
+	return scanOrganization(r.db.QueryRow(query, settingsJSON, id))
+}
@@ -0,0 +1,73 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"testing"
+
+	"pr-reviewer/internal/migrate"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/repository/repotest"
+	"pr-reviewer/migrations"
+
+	_ "github.com/lib/pq"
+)
+
+// openTestDB подключается к базе из TEST_DATABASE_URL и применяет миграции; без переменной тест пропускается.
+// Все таблицы базы очищаются, поэтому нужна отдельная тестовая база.
+func openTestDB(t testing.TB) *sql.DB {
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	m, err := migrate.New(db, migrations.FS)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// resetTestDB очищает данные и оставляет одну организацию repository.DefaultTenant
+func resetTestDB(t testing.TB, db *sql.DB) {
+	_, err := db.Exec(`TRUNCATE teams, users, team_memberships, pull_requests, pr_history, review_declines,
+		idempotency_keys, api_tokens, audit_events, organizations RESTART IDENTITY CASCADE`)
+	if err == nil {
+		_, err = db.Exec(`INSERT INTO organizations (id, name) VALUES ($1, 'Default')`, repository.DefaultTenant)
+	}
+	if err != nil {
+		t.Fatalf("reset db: %v", err)
+	}
+}
+
+func TestPostgresConformance(t *testing.T) {
+	db := openTestDB(t)
+	repotest.Run(t, func(t *testing.T) repository.UnitOfWork {
+		resetTestDB(t, db)
+		return repository.NewUnitOfWork(db)
+	})
+}
+
+func BenchmarkPostgresGetByReviewer(b *testing.B) {
+	db := openTestDB(b)
+	resetTestDB(b, db)
+	repos := repository.NewUnitOfWork(db).Repos(context.Background())
+	repotest.Seed(b, repos)
+	repotest.BenchGetByReviewer(b, repos)
+}
+
+func BenchmarkPostgresListPRs(b *testing.B) {
+	db := openTestDB(b)
+	resetTestDB(b, db)
+	repos := repository.NewUnitOfWork(db).Repos(context.Background())
+	repotest.Seed(b, repos)
+	repotest.BenchList(b, repos)
+}
@@ -0,0 +1,166 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+	"pr-reviewer/internal/model"
+	"strings"
+	"time"
+)
+
+// Поля сортировки списка PR
+const (
+	PRSortCreatedAt = "created_at"
+	PRSortID        = "pull_request_id"
+	PRSortName      = "pull_request_name"
+)
+
+// PRFilter задаёт фильтры, сортировку и пагинацию списка PR
+type PRFilter struct {
+	Status      string
+	AuthorID    string
+	ReviewerID  string
+	TeamName    string // команда автора
+	Label       string
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	MergedFrom  *time.Time
+	MergedTo    *time.Time
+
+	SortBy string // created_at|pull_request_id|pull_request_name
+	Desc   bool
+	Limit  int
+	Cursor string
+}
+
+// ValidPRSort проверяет, что поле сортировки поддерживается
+func ValidPRSort(sortBy string) bool {
+	switch sortBy {
+	case PRSortCreatedAt, PRSortID, PRSortName:
+		return true
+	}
+	return false
+}
+
+// List возвращает страницу PR, удовлетворяющих фильтру, и курсор следующей страницы.
+// Пагинация keyset: курсор хранит значение поля сортировки и ID последнего PR страницы.
+func (r *PRRepo) List(f PRFilter) ([]model.PullRequest, string, error) {
+	if !ValidPRSort(f.SortBy) {
+		return nil, "", fmt.Errorf("unsupported sort field %q", f.SortBy)
+	}
+
+	var conds []string
+	var args []interface{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	conds = append(conds, "tenant_id = "+arg(r.tenant))
+
+	if f.Status != "" {
+		conds = append(conds, "status = "+arg(f.Status))
+	}
+	if f.AuthorID != "" {
+		conds = append(conds, "author_id = "+arg(f.AuthorID))
+	}
+	if f.ReviewerID != "" {
+		reviewerJSON, _ := json.Marshal([]string{f.ReviewerID})
+		conds = append(conds, "assigned_reviewers @> "+arg(string(reviewerJSON))+"::jsonb")
+	}
+	if f.Label != "" {
+		labelJSON, _ := json.Marshal([]string{f.Label})
+		conds = append(conds, "labels @> "+arg(string(labelJSON))+"::jsonb")
+	}
+	if f.TeamName != "" {
+		conds = append(conds, "author_id IN (SELECT user_id FROM team_memberships WHERE tenant_id = pull_requests.tenant_id AND team_name = "+arg(f.TeamName)+")")
+	}
+	if f.CreatedFrom != nil {
+		conds = append(conds, "created_at >= "+arg(*f.CreatedFrom))
+	}
+	if f.CreatedTo != nil {
+		conds = append(conds, "created_at < "+arg(*f.CreatedTo))
+	}
+	if f.MergedFrom != nil {
+		conds = append(conds, "merged_at >= "+arg(*f.MergedFrom))
+	}
+	if f.MergedTo != nil {
+		conds = append(conds, "merged_at < "+arg(*f.MergedTo))
+	}
+
+	cmp, dir := ">", "ASC"
+	if f.Desc {
+		cmp, dir = "<", "DESC"
+	}
+	if f.Cursor != "" {
+		c, err := DecodeCursor(f.Cursor, f.SortKey())
+		if err != nil {
+			return nil, "", err
+		}
+		switch f.SortBy {
+		case PRSortID:
+			conds = append(conds, "pull_request_id "+cmp+" "+arg(c.ID))
+		case PRSortCreatedAt:
+			ts, err := time.Parse(time.RFC3339Nano, c.Value)
+			if err != nil {
+				return nil, "", ErrInvalidCursor
+			}
+			conds = append(conds, fmt.Sprintf("(created_at, pull_request_id) %s (%s, %s)", cmp, arg(ts), arg(c.ID)))
+		case PRSortName:
+			conds = append(conds, fmt.Sprintf("(pull_request_name, pull_request_id) %s (%s, %s)", cmp, arg(c.Value), arg(c.ID)))
+		}
+	}
+
+	query := `SELECT ` + prColumns + ` FROM pull_requests WHERE ` + strings.Join(conds, " AND ")
+	if f.SortBy == PRSortID {
+		query += fmt.Sprintf(` ORDER BY pull_request_id %s`, dir)
+	} else {
+		query += fmt.Sprintf(` ORDER BY %s %s, pull_request_id %s`, f.SortBy, dir, dir)
+	}
+	query += ` LIMIT ` + arg(f.Limit+1)
+
+	rows, err := r.db.Query(query, args...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	prs := []model.PullRequest{}
+	for rows.Next() {
+		pr, err := scanPR(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		prs = append(prs, *pr)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(prs) > f.Limit {
+		prs = prs[:f.Limit]
+		next = f.NextCursor(prs[len(prs)-1])
+	}
+	return prs, next, nil
+}
+
+// NextCursor возвращает курсор, указывающий на last как на последний PR страницы
+func (f PRFilter) NextCursor(last model.PullRequest) string {
+	c := Cursor{Sort: f.SortKey(), ID: last.ID}
+	switch f.SortBy {
+	case PRSortCreatedAt:
+		c.Value = last.CreatedAt.Format(time.RFC3339Nano)
+	case PRSortName:
+		c.Value = last.Name
+	}
+	return EncodeCursor(c)
+}
+
+// SortKey описывает сортировку, для которой выдан курсор
+func (f PRFilter) SortKey() string {
+	if f.Desc {
+		return f.SortBy + ":desc"
+	}
+	return f.SortBy + ":asc"
+}
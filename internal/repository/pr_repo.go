@@ -7,58 +7,121 @@ import (
 	"pr-reviewer/internal/model"
 )
 
+// prColumns — список колонок, читаемых scanPR
+const prColumns = `pull_request_id, pull_request_name, author_id, status, assigned_reviewers, labels, created_at, merged_at, assignment, version`
+
 type PRRepo struct {
-	db *sql.DB
+	db     DBTX
+	tenant string
+}
+
+func NewPRRepo(db DBTX, tenant string) *PRRepo {
+	return &PRRepo{db: db, tenant: tenant}
+}
+
+// scanPR читает PR из строки результата
+func scanPR(row rowScanner) (*model.PullRequest, error) {
+	var pr model.PullRequest
+	var reviewersJSON, labelsJSON, assignmentJSON []byte
+	if err := row.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &reviewersJSON, &labelsJSON, &pr.CreatedAt, &pr.MergedAt, &assignmentJSON, &pr.Version); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	json.Unmarshal(reviewersJSON, &pr.AssignedReviewers)
+	json.Unmarshal(labelsJSON, &pr.Labels)
+	if len(assignmentJSON) > 0 {
+		json.Unmarshal(assignmentJSON, &pr.Assignment)
+	}
+	return &pr, nil
 }
 
-func NewPRRepo(db *sql.DB) *PRRepo {
-	return &PRRepo{db: db}
+// marshalAssignment сериализует объяснение назначения; nil сохраняется как NULL
+func marshalAssignment(a *model.AssignmentExplanation) []byte {
+	if a == nil {
+		return nil
+	}
+	data, _ := json.Marshal(a)
+	return data
 }
 
-// Create создает PR
+// Create создает PR с версией 1
 func (r *PRRepo) Create(pr *model.PullRequest) error {
 	reviewersJSON, _ := json.Marshal(pr.AssignedReviewers)
+	labelsJSON, _ := json.Marshal(nonNil(pr.Labels))
 	query := `
-	INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at)
-	VALUES ($1,$2,$3,$4,$5,$6)
+	INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, assigned_reviewers, labels, created_at, assignment, version, tenant_id)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,1,$9)
 	`
-	_, err := r.db.Exec(query, pr.ID, pr.Name, pr.AuthorID, pr.Status, reviewersJSON, pr.CreatedAt)
+	_, err := r.db.Exec(query, pr.ID, pr.Name, pr.AuthorID, pr.Status, reviewersJSON, labelsJSON, pr.CreatedAt, marshalAssignment(pr.Assignment), r.tenant)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+	pr.Version = 1
+	return nil
+}
+
+// Restore сохраняет PR из архива как есть: со статусом, временем merge и версией
+func (r *PRRepo) Restore(pr *model.PullRequest) error {
+	reviewersJSON, _ := json.Marshal(nonNil(pr.AssignedReviewers))
+	labelsJSON, _ := json.Marshal(nonNil(pr.Labels))
+	query := `
+	INSERT INTO pull_requests (pull_request_id, pull_request_name, author_id, status, assigned_reviewers, labels, created_at, merged_at, assignment, version, tenant_id)
+	VALUES ($1,$2,$3,$4,$5,$6,$7,$8,$9,$10,$11)
+	`
+	_, err := r.db.Exec(query, pr.ID, pr.Name, pr.AuthorID, pr.Status, reviewersJSON, labelsJSON, pr.CreatedAt, pr.MergedAt, marshalAssignment(pr.Assignment), pr.Version, r.tenant)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
 	return err
 }
 
 // GetByID возвращает PR по ID
 func (r *PRRepo) GetByID(prID string) (*model.PullRequest, error) {
-	query := `SELECT pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, merged_at FROM pull_requests WHERE pull_request_id=$1`
-	row := r.db.QueryRow(query, prID)
-
-	var pr model.PullRequest
-	var reviewersJSON []byte
-	if err := row.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &reviewersJSON, &pr.CreatedAt, &pr.MergedAt); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
-		}
-		return nil, err
-	}
-	json.Unmarshal(reviewersJSON, &pr.AssignedReviewers)
-	return &pr, nil
+	query := `SELECT ` + prColumns + ` FROM pull_requests WHERE pull_request_id=$1 AND tenant_id=$2`
+	return scanPR(r.db.QueryRow(query, prID, r.tenant))
 }
 
-// Update обновляет PR
+// Update обновляет PR, если его версия в БД совпадает с pr.Version, и увеличивает версию.
+// Если PR успели изменить после чтения, возвращает ErrConflict.
 func (r *PRRepo) Update(pr *model.PullRequest) error {
 	reviewersJSON, _ := json.Marshal(pr.AssignedReviewers)
+	labelsJSON, _ := json.Marshal(nonNil(pr.Labels))
 	query := `
 	UPDATE pull_requests
-	SET status=$1, assigned_reviewers=$2, merged_at=$3
-	WHERE pull_request_id=$4
+	SET status=$1, assigned_reviewers=$2, labels=$3, merged_at=$4, assignment=$5, version=version+1
+	WHERE pull_request_id=$6 AND version=$7 AND tenant_id=$8
+	RETURNING version
 	`
-	_, err := r.db.Exec(query, pr.Status, reviewersJSON, pr.MergedAt, pr.ID)
+	err := r.db.QueryRow(query, pr.Status, reviewersJSON, labelsJSON, pr.MergedAt, marshalAssignment(pr.Assignment), pr.ID, pr.Version, r.tenant).Scan(&pr.Version)
+	if errors.Is(err, sql.ErrNoRows) {
+		var exists bool
+		if err := r.db.QueryRow(`SELECT EXISTS(SELECT 1 FROM pull_requests WHERE pull_request_id=$1 AND tenant_id=$2)`, pr.ID, r.tenant).Scan(&exists); err != nil {
+			return err
+		}
+		if exists {
+			return ErrConflict
+		}
+		return ErrNotFound
+	}
 	return err
 }
 
-// GetByReviewer возвращает PR, где пользователь ревьювер
-func (r *PRRepo) GetByReviewer(userID string) ([]model.PullRequest, error) {
-	query := `SELECT pull_request_id, pull_request_name, author_id, status, assigned_reviewers, created_at, merged_at FROM pull_requests`
-	rows, err := r.db.Query(query)
+// GetByReviewer возвращает PR, где пользователь ревьювер; пустой status — PR в любом статусе.
+// Поиск идёт по GIN-индексу assigned_reviewers через оператор @>.
+func (r *PRRepo) GetByReviewer(userID, status string) ([]model.PullRequest, error) {
+	reviewerJSON, _ := json.Marshal([]string{userID})
+	query := `
+	SELECT ` + prColumns + `
+	FROM pull_requests
+	WHERE assigned_reviewers @> $1::jsonb AND ($2 = '' OR status = $2) AND tenant_id = $3
+	ORDER BY created_at, pull_request_id
+	`
+	rows, err := r.db.Query(query, string(reviewerJSON), status, r.tenant)
 	if err != nil {
 		return nil, err
 	}
@@ -66,18 +129,45 @@ func (r *PRRepo) GetByReviewer(userID string) ([]model.PullRequest, error) {
 
 	var prs []model.PullRequest
 	for rows.Next() {
-		var pr model.PullRequest
-		var reviewersJSON []byte
-		if err := rows.Scan(&pr.ID, &pr.Name, &pr.AuthorID, &pr.Status, &reviewersJSON, &pr.CreatedAt, &pr.MergedAt); err != nil {
+		pr, err := scanPR(rows)
+		if err != nil {
 			return nil, err
 		}
-		json.Unmarshal(reviewersJSON, &pr.AssignedReviewers)
-		for _, rID := range pr.AssignedReviewers {
-			if rID == userID {
-				prs = append(prs, pr)
-				break
-			}
+		prs = append(prs, *pr)
+	}
+	return prs, rows.Err()
+}
+
+// CountOpenReviews возвращает число открытых PR, назначенных на каждого ревьювера
+func (r *PRRepo) CountOpenReviews() (map[string]int, error) {
+	query := `
+	SELECT reviewer, COUNT(*)
+	FROM pull_requests, jsonb_array_elements_text(assigned_reviewers) AS reviewer
+	WHERE status='OPEN' AND tenant_id=$1
+	GROUP BY reviewer
+	`
+	rows, err := r.db.Query(query, r.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[string]int)
+	for rows.Next() {
+		var reviewer string
+		var n int
+		if err := rows.Scan(&reviewer, &n); err != nil {
+			return nil, err
 		}
+		counts[reviewer] = n
+	}
+	return counts, rows.Err()
+}
+
+// nonNil заменяет nil-срез пустым, чтобы в JSONB писался [] вместо null
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
 	}
-	return prs, nil
+	return s
 }
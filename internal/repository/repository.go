@@ -0,0 +1,228 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"pr-reviewer/internal/model"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// DBTX — общее подмножество *sql.DB и *sql.Tx, с которым работают репозитории
+type DBTX interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+// ErrAlreadyExists возвращается при попытке создать запись с существующим ключом
+var ErrAlreadyExists = errors.New("record already exists")
+
+// ErrConflict возвращается, когда запись изменили после того, как её прочитал вызывающий
+var ErrConflict = errors.New("record was modified concurrently")
+
+// TeamRepository хранит команды
+type TeamRepository interface {
+	Create(team *model.Team) error
+	GetByName(name string) (*model.Team, error)
+	Delete(name string) error
+	List(limit int, after string) ([]model.TeamSummary, string, error)
+	// Rename переименовывает команду вместе с членствами и ссылками дочерних команд
+	Rename(oldName, newName string) error
+	// Update сохраняет родителя и настройки команды
+	Update(team *model.Team) error
+	// Children возвращает имена дочерних команд
+	Children(name string) ([]string, error)
+}
+
+// UserRepository хранит пользователей
+type UserRepository interface {
+	CreateOrUpdate(user *model.User) error
+	// Create создаёт нового пользователя; занятый ID — ErrAlreadyExists
+	Create(user *model.User) error
+	GetByID(userID string) (*model.User, error)
+	GetByTeam(teamName string) ([]model.User, error)
+	// List возвращает всех пользователей организации, упорядоченных по ID
+	List() ([]model.User, error)
+	SetIsActive(userID string, isActive bool) (*model.User, error)
+	SetSkills(userID string, skills []string) (*model.User, error)
+	SetOutOfOffice(userID string, until *time.Time) (*model.User, error)
+}
+
+// MembershipRepository хранит членство пользователей в командах
+type MembershipRepository interface {
+	// Upsert добавляет членство или обновляет его флаг активности, вес и роль
+	Upsert(m *model.TeamMembership) error
+	// Remove удаляет членство; ErrNotFound, если его нет
+	Remove(teamName, userID string) error
+}
+
+// PRRepository хранит pull request'ы
+type PRRepository interface {
+	Create(pr *model.PullRequest) error
+	// Restore сохраняет PR из архива как есть: со статусом, временем merge и версией
+	Restore(pr *model.PullRequest) error
+	GetByID(prID string) (*model.PullRequest, error)
+	Update(pr *model.PullRequest) error
+	GetByReviewer(userID, status string) ([]model.PullRequest, error)
+	CountOpenReviews() (map[string]int, error)
+	List(f PRFilter) ([]model.PullRequest, string, error)
+}
+
+// HistoryRepository хранит историю PR
+type HistoryRepository interface {
+	Add(event *model.PREvent) error
+	ListByPR(prID string) ([]model.PREvent, error)
+}
+
+// DeclineRepository хранит отказы ревьюверов
+type DeclineRepository interface {
+	Add(d *model.ReviewDecline) error
+	DeclinedUsers(prID string) ([]string, error)
+	ListByUser(userID string, since time.Time) ([]model.ReviewDecline, error)
+	Stats(since time.Time, minTotal int) ([]model.DeclineStats, error)
+}
+
+// IdempotencyRepository хранит ответы на запросы с Idempotency-Key
+type IdempotencyRepository interface {
+	Reserve(rec *model.IdempotencyRecord) error
+	Get(key string) (*model.IdempotencyRecord, error)
+	Complete(key string, statusCode int, response []byte, expiresAt time.Time) error
+	Delete(key string) error
+	DeleteExpired(now time.Time) (int64, error)
+}
+
+// TokenRepository хранит API-токены
+type TokenRepository interface {
+	Create(t *model.APIToken) error
+	GetByHash(hash string) (*model.APIToken, error)
+	RenameTeam(oldName, newName string) error
+	List() ([]model.APIToken, error)
+	Revoke(id int64, at time.Time) (*model.APIToken, error)
+}
+
+// AuditRepository хранит журнал аудита
+type AuditRepository interface {
+	Add(e *model.AuditEvent) error
+	List(f AuditFilter) ([]model.AuditEvent, error)
+}
+
+// OrganizationRepository хранит организации; не привязан к организации контекста
+type OrganizationRepository interface {
+	Create(o *model.Organization) error
+	Get(id string) (*model.Organization, error)
+	List() ([]model.Organization, error)
+	UpdateSettings(id string, settings model.OrgSettings) (*model.Organization, error)
+}
+
+// Repos объединяет репозитории одного хранилища. Все репозитории, кроме Orgs,
+// привязаны к одной организации и не видят данных других.
+type Repos struct {
+	Teams       TeamRepository
+	Users       UserRepository
+	Memberships MembershipRepository
+	PRs         PRRepository
+	History     HistoryRepository
+	Declines    DeclineRepository
+	Idempotency IdempotencyRepository
+	Tokens      TokenRepository
+	Audit       AuditRepository
+	Orgs        OrganizationRepository
+}
+
+// UnitOfWork даёт сервисам доступ к репозиториям и выполняет группы операций атомарно
+type UnitOfWork interface {
+	// Repos возвращает репозитории организации из ctx для одиночных операций вне транзакции
+	Repos(ctx context.Context) Repos
+	// Do выполняет fn над репозиториями организации из ctx в одной транзакции: commit,
+	// если fn вернула nil, иначе rollback. Вложенные вызовы Do не поддерживаются.
+	Do(ctx context.Context, fn func(r Repos) error) error
+	// View выполняет fn над репозиториями организации из ctx в транзакции только для чтения:
+	// все запросы fn видят один снимок данных на начало транзакции
+	View(ctx context.Context, fn func(r Repos) error) error
+}
+
+// Проверяем на этапе компиляции, что PostgreSQL-реализации удовлетворяют интерфейсам
+var (
+	_ TeamRepository    = (*TeamRepo)(nil)
+	_ UserRepository    = (*UserRepo)(nil)
+	_ PRRepository      = (*PRRepo)(nil)
+	_ HistoryRepository = (*HistoryRepo)(nil)
+	_ DeclineRepository = (*DeclineRepo)(nil)
+	_ UnitOfWork        = (*TxUnitOfWork)(nil)
+
+	_ IdempotencyRepository = (*IdempotencyRepo)(nil)
+	_ TokenRepository       = (*TokenRepo)(nil)
+	_ AuditRepository       = (*AuditRepo)(nil)
+
+	_ OrganizationRepository = (*OrganizationRepo)(nil)
+	_ MembershipRepository   = (*MembershipRepo)(nil)
+)
+
+// isUniqueViolation сообщает, что PostgreSQL отклонил запись из-за дубликата ключа
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// NewRepos создаёт набор PostgreSQL-репозиториев организации tenant поверх подключения или транзакции
+func NewRepos(db DBTX, tenant string) Repos {
+	return Repos{
+		Teams:       NewTeamRepo(db, tenant),
+		Users:       NewUserRepo(db, tenant),
+		Memberships: NewMembershipRepo(db, tenant),
+		PRs:         NewPRRepo(db, tenant),
+		History:     NewHistoryRepo(db, tenant),
+		Declines:    NewDeclineRepo(db, tenant),
+		Idempotency: NewIdempotencyRepo(db, tenant),
+		Tokens:      NewTokenRepo(db, tenant),
+		Audit:       NewAuditRepo(db, tenant),
+		Orgs:        NewOrganizationRepo(db),
+	}
+}
+
+// TxUnitOfWork — UnitOfWork поверх транзакций PostgreSQL
+type TxUnitOfWork struct {
+	db *sql.DB
+}
+
+// NewUnitOfWork создаёт UnitOfWork поверх пула подключений
+func NewUnitOfWork(db *sql.DB) *TxUnitOfWork {
+	return &TxUnitOfWork{db: db}
+}
+
+// Repos возвращает репозитории организации из ctx поверх пула подключений
+func (u *TxUnitOfWork) Repos(ctx context.Context) Repos {
+	return NewRepos(u.db, TenantFrom(ctx))
+}
+
+// Do выполняет fn в одной транзакции
+func (u *TxUnitOfWork) Do(ctx context.Context, fn func(r Repos) error) error {
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(NewRepos(tx, TenantFrom(ctx))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// View выполняет fn в транзакции REPEATABLE READ только для чтения: в отличие от READ COMMITTED
+// в Do, каждый запрос видит тот же снимок, что и первый
+func (u *TxUnitOfWork) View(ctx context.Context, fn func(r Repos) error) error {
+	tx, err := u.db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true})
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(NewRepos(tx, TenantFrom(ctx))); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
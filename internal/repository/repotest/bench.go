@@ -0,0 +1,141 @@
+package repotest
+
+import (
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// Размер набора данных бенчмарков: benchTeams команд по benchTeamSize участников и benchPRs PR
+const (
+	benchTeams    = 10
+	benchTeamSize = 20
+	benchPRs      = 5000
+)
+
+// benchLabels — метки, которые по очереди получают PR набора
+var benchLabels = [][]string{nil, {"backend"}, {"bug"}, {"backend", "urgent"}}
+
+// Seed заполняет пустое хранилище набором данных бенчмарков. Каждый третий PR смержен,
+// у каждого PR два ревьювера из команды автора.
+func Seed(tb testing.TB, r repository.Repos) {
+	tb.Helper()
+	users := benchTeams * benchTeamSize
+	for t := 0; t < benchTeams; t++ {
+		team := fmt.Sprintf("team-%d", t)
+		if err := r.Teams.Create(&model.Team{Name: team}); err != nil {
+			tb.Fatalf("create team %s: %v", team, err)
+		}
+		for i := 0; i < benchTeamSize; i++ {
+			id := int64(t*benchTeamSize + i + 1)
+			if err := r.Users.CreateOrUpdate(&model.User{ID: id, Username: "user" + strconv.FormatInt(id, 10), TeamName: team, IsActive: true}); err != nil {
+				tb.Fatalf("create user %d: %v", id, err)
+			}
+			m := &model.TeamMembership{TeamName: team, UserID: strconv.FormatInt(id, 10), IsActive: true, Weight: model.DefaultMembershipWeight}
+			if err := r.Memberships.Upsert(m); err != nil {
+				tb.Fatalf("add membership %s/%d: %v", team, id, err)
+			}
+		}
+	}
+
+	for i := 0; i < benchPRs; i++ {
+		author := i % users
+		team := author / benchTeamSize * benchTeamSize
+		reviewer := func(shift int) string {
+			return strconv.Itoa(team + (author-team+shift)%benchTeamSize + 1)
+		}
+		status := "OPEN"
+		if i%3 == 0 {
+			status = "MERGED"
+		}
+		pr := &model.PullRequest{
+			ID:                fmt.Sprintf("pr-%05d", i),
+			Name:              fmt.Sprintf("change %d", i),
+			AuthorID:          strconv.Itoa(author + 1),
+			Status:            status,
+			AssignedReviewers: []string{reviewer(1), reviewer(2)},
+			Labels:            benchLabels[i%len(benchLabels)],
+			CreatedAt:         base.Add(time.Duration(i) * time.Minute),
+		}
+		if err := r.PRs.Create(pr); err != nil {
+			tb.Fatalf("create PR %s: %v", pr.ID, err)
+		}
+	}
+}
+
+// BenchGetByReviewer измеряет выборку открытых PR ревьювера на наборе Seed
+func BenchGetByReviewer(b *testing.B, r repository.Repos) {
+	users := benchTeams * benchTeamSize
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		prs, err := r.PRs.GetByReviewer(strconv.Itoa(i%users+1), "OPEN")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(prs) == 0 {
+			b.Fatal("reviewer has no open PRs")
+		}
+	}
+}
+
+// BenchList измеряет первую страницу списка PR на наборе Seed с разными фильтрами
+// и полный обход списка по курсору
+func BenchList(b *testing.B, r repository.Repos) {
+	filters := []struct {
+		name string
+		f    repository.PRFilter
+	}{
+		{"all", repository.PRFilter{}},
+		{"status", repository.PRFilter{Status: "OPEN"}},
+		{"team", repository.PRFilter{TeamName: "team-3"}},
+		{"reviewer", repository.PRFilter{ReviewerID: "42"}},
+		{"label", repository.PRFilter{Label: "urgent", Status: "OPEN"}},
+		{"name desc", repository.PRFilter{SortBy: repository.PRSortName, Desc: true}},
+	}
+	for _, tt := range filters {
+		b.Run(tt.name, func(b *testing.B) {
+			f := tt.f
+			if f.SortBy == "" {
+				f.SortBy = repository.PRSortCreatedAt
+			}
+			f.Limit = 50
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				prs, _, err := r.PRs.List(f)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if len(prs) == 0 {
+					b.Fatal("empty page")
+				}
+			}
+		})
+	}
+
+	b.Run("pages", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			f := repository.PRFilter{SortBy: repository.PRSortCreatedAt, Limit: 500}
+			total := 0
+			for {
+				prs, next, err := r.PRs.List(f)
+				if err != nil {
+					b.Fatal(err)
+				}
+				total += len(prs)
+				if next == "" {
+					break
+				}
+				f.Cursor = next
+			}
+			if total != benchPRs {
+				b.Fatalf("pages: got %d PRs, want %d", total, benchPRs)
+			}
+		}
+	})
+}
@@ -0,0 +1,1045 @@
+// Package repotest содержит общий набор проверок, который должна проходить
+// любая реализация репозиториев (PostgreSQL, в памяти).
+//
+// Использование в тестах пакета реализации:
+//
+//	func TestConformance(t *testing.T) {
+//		repotest.Run(t, func(t *testing.T) repository.UnitOfWork {
+//			return memory.NewStore()
+//		})
+//	}
+//
+// Фабрика должна возвращать пустое хранилище с одной организацией repository.DefaultTenant:
+// для PostgreSQL — после очистки таблиц.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// Factory создаёт пустое хранилище для одной проверки
+type Factory func(t *testing.T) repository.UnitOfWork
+
+// base — опорный момент времени; PostgreSQL хранит TIMESTAMP с точностью до микросекунд без зоны
+var base = time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+
+// Run запускает все проверки
+func Run(t *testing.T, newUoW Factory) {
+	newRepos := func(t *testing.T) repository.Repos {
+		return newUoW(t).Repos(context.Background())
+	}
+	t.Run("Teams", func(t *testing.T) { testTeams(t, newRepos(t)) })
+	t.Run("Users", func(t *testing.T) { testUsers(t, newRepos(t)) })
+	t.Run("Memberships", func(t *testing.T) { testMemberships(t, newRepos(t)) })
+	t.Run("TeamHierarchy", func(t *testing.T) { testTeamHierarchy(t, newRepos(t)) })
+	t.Run("PullRequests", func(t *testing.T) { testPRs(t, newRepos(t)) })
+	t.Run("PullRequestList", func(t *testing.T) { testPRList(t, newRepos(t)) })
+	t.Run("PullRequestVersion", func(t *testing.T) { testPRVersion(t, newRepos(t)) })
+	t.Run("TransactionConflict", func(t *testing.T) { testTxConflict(t, newUoW(t)) })
+	t.Run("ReadOnlySnapshot", func(t *testing.T) { testView(t, newUoW(t)) })
+	t.Run("PullRequestRestore", func(t *testing.T) { testPRRestore(t, newRepos(t)) })
+	t.Run("History", func(t *testing.T) { testHistory(t, newRepos(t)) })
+	t.Run("Declines", func(t *testing.T) { testDeclines(t, newRepos(t)) })
+	t.Run("Idempotency", func(t *testing.T) { testIdempotency(t, newRepos(t)) })
+	t.Run("Tokens", func(t *testing.T) { testTokens(t, newRepos(t)) })
+	t.Run("Audit", func(t *testing.T) { testAudit(t, newRepos(t)) })
+	t.Run("Organizations", func(t *testing.T) { testOrganizations(t, newRepos(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, newUoW(t)) })
+}
+
+func testTeams(t *testing.T, r repository.Repos) {
+	mustCreateTeam(t, r, "backend")
+	if err := r.Teams.Create(&model.Team{Name: "backend"}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Fatalf("duplicate Create: want ErrAlreadyExists, got %v", err)
+	}
+	if _, err := r.Teams.GetByName("missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetByName missing: want ErrNotFound, got %v", err)
+	}
+	team, err := r.Teams.GetByName("backend")
+	if err != nil || team.Name != "backend" {
+		t.Fatalf("GetByName: got %+v, %v", team, err)
+	}
+
+	mustCreateTeam(t, r, "frontend")
+	mustCreateTeam(t, r, "data")
+	mustCreateUser(t, r, model.User{ID: 1, Username: "alice", TeamName: "backend", IsActive: true})
+	mustCreateUser(t, r, model.User{ID: 2, Username: "bob", TeamName: "backend", IsActive: false})
+
+	page, next, err := r.Teams.List(2, "")
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	want := []model.TeamSummary{{Name: "backend", MemberCount: 2, ActiveCount: 1}, {Name: "data"}}
+	if !reflect.DeepEqual(page, want) || next == "" {
+		t.Fatalf("List page 1: got %+v next=%q, want %+v", page, next, want)
+	}
+	page, next, err = r.Teams.List(2, next)
+	if err != nil || len(page) != 1 || page[0].Name != "frontend" || next != "" {
+		t.Fatalf("List page 2: got %+v next=%q err=%v", page, next, err)
+	}
+	if _, _, err := r.Teams.List(2, "not-a-cursor"); !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Fatalf("List bad cursor: want ErrInvalidCursor, got %v", err)
+	}
+
+	if err := r.Teams.Delete("data"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := r.Teams.GetByName("data"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetByName after Delete: want ErrNotFound, got %v", err)
+	}
+}
+
+func testUsers(t *testing.T, r repository.Repos) {
+	mustCreateTeam(t, r, "backend")
+	mustCreateUser(t, r, model.User{ID: 2, Username: "bob", TeamName: "backend", IsActive: true})
+	mustCreateUser(t, r, model.User{ID: 1, Username: "alice", TeamName: "backend", IsActive: true})
+
+	if _, err := r.Users.GetByID("42"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetByID missing: want ErrNotFound, got %v", err)
+	}
+
+	u, err := r.Users.SetSkills("1", []string{"go", "sql"})
+	if err != nil || !reflect.DeepEqual(u.Skills, []string{"go", "sql"}) {
+		t.Fatalf("SetSkills: got %+v, %v", u, err)
+	}
+	// повторный upsert не должен сбрасывать навыки
+	mustCreateUser(t, r, model.User{ID: 1, Username: "alice2", TeamName: "backend", IsActive: true})
+	u, err = r.Users.GetByID("1")
+	if err != nil || u.Username != "alice2" || !reflect.DeepEqual(u.Skills, []string{"go", "sql"}) {
+		t.Fatalf("GetByID after upsert: got %+v, %v", u, err)
+	}
+
+	u, err = r.Users.SetIsActive("2", false)
+	if err != nil || u.IsActive {
+		t.Fatalf("SetIsActive: got %+v, %v", u, err)
+	}
+	if _, err := r.Users.SetIsActive("42", false); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("SetIsActive missing: want ErrNotFound, got %v", err)
+	}
+	if _, err := r.Users.SetSkills("42", nil); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("SetSkills missing: want ErrNotFound, got %v", err)
+	}
+
+	until := base.Add(48 * time.Hour)
+	u, err = r.Users.SetOutOfOffice("2", &until)
+	if err != nil || u.OutOfOfficeUntil == nil || !u.OutOfOfficeUntil.Equal(until) {
+		t.Fatalf("SetOutOfOffice: got %+v, %v", u, err)
+	}
+	u, err = r.Users.SetOutOfOffice("2", nil)
+	if err != nil || u.OutOfOfficeUntil != nil {
+		t.Fatalf("SetOutOfOffice clear: got %+v, %v", u, err)
+	}
+
+	users, err := r.Users.GetByTeam("backend")
+	if err != nil || len(users) != 2 || users[0].ID != 1 || users[1].ID != 2 {
+		t.Fatalf("GetByTeam: got %+v, %v", users, err)
+	}
+	users, err = r.Users.GetByTeam("nobody")
+	if err != nil || len(users) != 0 {
+		t.Fatalf("GetByTeam empty: got %+v, %v", users, err)
+	}
+
+	if err := r.Users.Create(&model.User{ID: 3, Username: "carol", IsActive: true}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := r.Users.Create(&model.User{ID: 3, Username: "dave", IsActive: true}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Fatalf("Create duplicate: want ErrAlreadyExists, got %v", err)
+	}
+	users, err = r.Users.List()
+	if err != nil || len(users) != 3 || users[0].ID != 1 || users[2].ID != 3 || users[2].Username != "carol" || users[0].TeamName != "backend" || len(users[2].Teams) != 0 {
+		t.Fatalf("List: got %+v, %v", users, err)
+	}
+}
+
+func testMemberships(t *testing.T, r repository.Repos) {
+	mustCreateTeam(t, r, "backend")
+	mustCreateTeam(t, r, "platform")
+	mustCreateUser(t, r, model.User{ID: 1, Username: "alice", TeamName: "backend", IsActive: true})
+	mustCreateUser(t, r, model.User{ID: 2, Username: "bob", IsActive: true})
+	mustAddMembership(t, r, "backend", "2", true, 30)
+	mustAddMembership(t, r, "platform", "2", false, 70)
+
+	u, err := r.Users.GetByID("2")
+	want := []model.TeamMembership{
+		{TeamName: "platform", IsActive: false, Weight: 70, Role: model.TeamRoleMember},
+		{TeamName: "backend", IsActive: true, Weight: 30, Role: model.TeamRoleMember},
+	}
+	if err != nil || u.TeamName != "platform" || !reflect.DeepEqual(u.Teams, want) {
+		t.Fatalf("GetByID: got %+v, %v; want primary platform and %+v", u, err, want)
+	}
+	// повторный upsert пользователя не трогает членства
+	mustCreateUser(t, r, model.User{ID: 2, Username: "bob2", IsActive: true})
+	if u, _ := r.Users.GetByID("2"); len(u.Teams) != 2 {
+		t.Fatalf("memberships after user upsert: got %+v", u.Teams)
+	}
+
+	users, err := r.Users.GetByTeam("backend")
+	if err != nil || len(users) != 2 || users[0].ID != 1 || users[1].ID != 2 {
+		t.Fatalf("GetByTeam backend: got %+v, %v", users, err)
+	}
+	page, _, err := r.Teams.List(10, "")
+	wantTeams := []model.TeamSummary{{Name: "backend", MemberCount: 2, ActiveCount: 2}, {Name: "platform", MemberCount: 1}}
+	if err != nil || !reflect.DeepEqual(page, wantTeams) {
+		t.Fatalf("Teams.List: got %+v, %v; want %+v", page, err, wantTeams)
+	}
+
+	// обновление членства
+	mustAddMembership(t, r, "backend", "2", true, 100)
+	if u, _ := r.Users.GetByID("2"); u.TeamName != "backend" {
+		t.Fatalf("primary team after weight change: got %+v", u)
+	}
+
+	// роль лида сохраняется вместе с членством
+	if err := r.Memberships.Upsert(&model.TeamMembership{TeamName: "backend", UserID: "2", IsActive: true, Weight: 100, Role: model.TeamRoleLead}); err != nil {
+		t.Fatalf("Upsert lead: %v", err)
+	}
+	if u, _ := r.Users.GetByID("2"); u.Teams[0].Role != model.TeamRoleLead || u.Teams[1].Role != model.TeamRoleMember {
+		t.Fatalf("roles after lead upsert: got %+v", u.Teams)
+	}
+
+	if err := r.Teams.Rename("platform", "infra"); err != nil {
+		t.Fatalf("Rename: %v", err)
+	}
+	if users, _ := r.Users.GetByTeam("infra"); len(users) != 1 || users[0].ID != 2 {
+		t.Fatalf("GetByTeam after Rename: got %+v", users)
+	}
+	if users, _ := r.Users.GetByTeam("platform"); len(users) != 0 {
+		t.Fatalf("old team after Rename: got %+v", users)
+	}
+
+	if err := r.Memberships.Remove("infra", "2"); err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if err := r.Memberships.Remove("infra", "2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Remove missing: want ErrNotFound, got %v", err)
+	}
+	if err := r.Memberships.Remove("backend", "1"); err != nil {
+		t.Fatalf("Remove alice: %v", err)
+	}
+	u, err = r.Users.GetByID("1")
+	if err != nil || u.TeamName != "" || len(u.Teams) != 0 {
+		t.Fatalf("user without teams: got %+v, %v", u, err)
+	}
+}
+
+func testTeamHierarchy(t *testing.T, r repository.Repos) {
+	two, yes := 2, true
+	mustCreateTeam(t, r, "eng")
+	squad := &model.Team{Name: "payments", Parent: "eng", Settings: model.TeamSettings{ReviewerCount: &two, Strategy: "random"}}
+	if err := r.Teams.Create(squad); err != nil {
+		t.Fatalf("Create with parent: %v", err)
+	}
+	got, err := r.Teams.GetByName("payments")
+	if err != nil || got.Parent != "eng" || !reflect.DeepEqual(got.Settings, squad.Settings) {
+		t.Fatalf("GetByName: got %+v, %v", got, err)
+	}
+	root, _ := r.Teams.GetByName("eng")
+	if root.Parent != "" || !reflect.DeepEqual(root.Settings, model.TeamSettings{}) {
+		t.Fatalf("root team: got %+v", root)
+	}
+
+	got.Settings = model.TeamSettings{ExpandPool: &yes, MergePolicy: "require_reviewers"}
+	got.Parent = ""
+	if err := r.Teams.Update(got); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if got, _ := r.Teams.GetByName("payments"); got.Parent != "" || got.Settings.ExpandPool == nil || got.Settings.ReviewerCount != nil {
+		t.Fatalf("GetByName after Update: got %+v", got)
+	}
+	if err := r.Teams.Update(&model.Team{Name: "nobody"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Update missing: want ErrNotFound, got %v", err)
+	}
+
+	got.Parent = "eng"
+	if err := r.Teams.Update(got); err != nil {
+		t.Fatalf("Update parent: %v", err)
+	}
+	if err := r.Teams.Rename("eng", "engineering"); err != nil {
+		t.Fatalf("Rename parent: %v", err)
+	}
+	children, err := r.Teams.Children("engineering")
+	if err != nil || !reflect.DeepEqual(children, []string{"payments"}) {
+		t.Fatalf("Children after Rename: got %v, %v", children, err)
+	}
+	if children, _ := r.Teams.Children("payments"); len(children) != 0 {
+		t.Fatalf("Children of leaf: got %v", children)
+	}
+	page, _, err := r.Teams.List(10, "")
+	want := []model.TeamSummary{{Name: "engineering"}, {Name: "payments", Parent: "engineering"}}
+	if err != nil || !reflect.DeepEqual(page, want) {
+		t.Fatalf("List: got %+v, %v; want %+v", page, err, want)
+	}
+}
+
+func testPRs(t *testing.T, r repository.Repos) {
+	seedAuthors(t, r)
+
+	pr := &model.PullRequest{
+		ID:                "pr-1",
+		Name:              "Add feature",
+		AuthorID:          "1",
+		Status:            "OPEN",
+		AssignedReviewers: []string{"2", "3"},
+		Labels:            []string{"go"},
+		CreatedAt:         base,
+		Assignment: &model.AssignmentExplanation{
+			Action:   model.AssignmentActionCreate,
+			Strategy: "skills",
+			PoolSize: 2,
+			Chosen:   []model.ChosenReviewer{{UserID: "2", Score: 1, Reason: "match"}},
+		},
+	}
+	if err := r.PRs.Create(pr); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := r.PRs.Create(pr); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Fatalf("duplicate Create: want ErrAlreadyExists, got %v", err)
+	}
+	if _, err := r.PRs.GetByID("missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetByID missing: want ErrNotFound, got %v", err)
+	}
+
+	got, err := r.PRs.GetByID("pr-1")
+	if err != nil {
+		t.Fatalf("GetByID: %v", err)
+	}
+	if got.Name != pr.Name || !reflect.DeepEqual(got.AssignedReviewers, pr.AssignedReviewers) ||
+		!reflect.DeepEqual(got.Labels, pr.Labels) || !got.CreatedAt.Equal(base) ||
+		got.Assignment == nil || got.Assignment.Chosen[0].Reason != "match" {
+		t.Fatalf("GetByID: got %+v", got)
+	}
+
+	// изменение полученной копии не должно менять хранилище
+	got.AssignedReviewers[0] = "9"
+	again, _ := r.PRs.GetByID("pr-1")
+	if again.AssignedReviewers[0] != "2" {
+		t.Fatalf("returned PR shares state with repository")
+	}
+
+	merged := base.Add(time.Hour)
+	again.Status = "MERGED"
+	again.MergedAt = &merged
+	again.Labels = []string{"go", "sql"}
+	if err := r.PRs.Update(again); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	got, _ = r.PRs.GetByID("pr-1")
+	if got.Status != "MERGED" || got.MergedAt == nil || !got.MergedAt.Equal(merged) || len(got.Labels) != 2 {
+		t.Fatalf("GetByID after Update: got %+v", got)
+	}
+	if err := r.PRs.Update(&model.PullRequest{ID: "missing", Status: "OPEN"}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Update missing: want ErrNotFound, got %v", err)
+	}
+
+	mustCreatePR(t, r, "pr-2", "1", "OPEN", []string{"2"}, nil, base.Add(2*time.Hour))
+	mustCreatePR(t, r, "pr-3", "1", "OPEN", []string{"3"}, nil, base.Add(3*time.Hour))
+
+	prs, err := r.PRs.GetByReviewer("2", "")
+	if err != nil || len(prs) != 2 || prs[0].ID != "pr-1" || prs[1].ID != "pr-2" {
+		t.Fatalf("GetByReviewer: got %+v, %v", prs, err)
+	}
+	prs, err = r.PRs.GetByReviewer("2", "OPEN")
+	if err != nil || len(prs) != 1 || prs[0].ID != "pr-2" {
+		t.Fatalf("GetByReviewer OPEN: got %+v, %v", prs, err)
+	}
+
+	counts, err := r.PRs.CountOpenReviews()
+	if err != nil || !reflect.DeepEqual(counts, map[string]int{"2": 1, "3": 1}) {
+		t.Fatalf("CountOpenReviews: got %v, %v", counts, err)
+	}
+}
+
+func testPRList(t *testing.T, r repository.Repos) {
+	seedAuthors(t, r)
+	mustCreateTeam(t, r, "frontend")
+	mustCreateUser(t, r, model.User{ID: 4, Username: "dave", TeamName: "frontend", IsActive: true})
+
+	mustCreatePR(t, r, "pr-a", "1", "OPEN", []string{"2"}, []string{"go"}, base)
+	mustCreatePR(t, r, "pr-b", "4", "OPEN", []string{"3"}, []string{"ui"}, base.Add(time.Hour))
+	mustCreatePR(t, r, "pr-c", "1", "OPEN", []string{"2", "3"}, []string{"go", "sql"}, base.Add(2*time.Hour))
+	mustCreatePR(t, r, "pr-d", "2", "OPEN", []string{"3"}, nil, base.Add(3*time.Hour))
+
+	list := func(f repository.PRFilter) ([]string, string) {
+		t.Helper()
+		if f.SortBy == "" {
+			f.SortBy = repository.PRSortCreatedAt
+		}
+		if f.Limit == 0 {
+			f.Limit = 10
+		}
+		prs, next, err := r.PRs.List(f)
+		if err != nil {
+			t.Fatalf("List(%+v): %v", f, err)
+		}
+		ids := []string{}
+		for _, pr := range prs {
+			ids = append(ids, pr.ID)
+		}
+		return ids, next
+	}
+	expect := func(name string, got, want []string) {
+		t.Helper()
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s: got %v, want %v", name, got, want)
+		}
+	}
+
+	ids, _ := list(repository.PRFilter{})
+	expect("all", ids, []string{"pr-a", "pr-b", "pr-c", "pr-d"})
+	ids, _ = list(repository.PRFilter{Desc: true})
+	expect("desc", ids, []string{"pr-d", "pr-c", "pr-b", "pr-a"})
+	ids, _ = list(repository.PRFilter{AuthorID: "1"})
+	expect("author", ids, []string{"pr-a", "pr-c"})
+	ids, _ = list(repository.PRFilter{ReviewerID: "3"})
+	expect("reviewer", ids, []string{"pr-b", "pr-c", "pr-d"})
+	ids, _ = list(repository.PRFilter{Label: "go"})
+	expect("label", ids, []string{"pr-a", "pr-c"})
+	ids, _ = list(repository.PRFilter{TeamName: "frontend"})
+	expect("team", ids, []string{"pr-b"})
+	from, to := base.Add(time.Hour), base.Add(3*time.Hour)
+	ids, _ = list(repository.PRFilter{CreatedFrom: &from, CreatedTo: &to})
+	expect("created range", ids, []string{"pr-b", "pr-c"})
+
+	merged := base.Add(5 * time.Hour)
+	pr, _ := r.PRs.GetByID("pr-c")
+	pr.Status = "MERGED"
+	pr.MergedAt = &merged
+	if err := r.PRs.Update(pr); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	ids, _ = list(repository.PRFilter{Status: "MERGED"})
+	expect("status", ids, []string{"pr-c"})
+	ids, _ = list(repository.PRFilter{MergedFrom: &merged})
+	expect("merged range", ids, []string{"pr-c"})
+
+	for _, sortBy := range []string{repository.PRSortCreatedAt, repository.PRSortID, repository.PRSortName} {
+		for _, desc := range []bool{false, true} {
+			var all []string
+			cursor := ""
+			for {
+				page, next := list(repository.PRFilter{SortBy: sortBy, Desc: desc, Limit: 3, Cursor: cursor})
+				all = append(all, page...)
+				if next == "" {
+					break
+				}
+				cursor = next
+			}
+			full, _ := list(repository.PRFilter{SortBy: sortBy, Desc: desc})
+			expect("pagination "+sortBy, all, full)
+		}
+	}
+
+	_, next := list(repository.PRFilter{Limit: 1})
+	if _, _, err := r.PRs.List(repository.PRFilter{SortBy: repository.PRSortID, Limit: 1, Cursor: next}); !errors.Is(err, repository.ErrInvalidCursor) {
+		t.Fatalf("cursor from another sort: want ErrInvalidCursor, got %v", err)
+	}
+}
+
+func testPRVersion(t *testing.T, r repository.Repos) {
+	seedAuthors(t, r)
+	mustCreatePR(t, r, "pr-v", "1", "OPEN", []string{"2"}, nil, time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	first, _ := r.PRs.GetByID("pr-v")
+	stale, _ := r.PRs.GetByID("pr-v")
+	if first.Version != 1 {
+		t.Fatalf("new PR version: want 1, got %d", first.Version)
+	}
+
+	first.AssignedReviewers = []string{"3"}
+	if err := r.PRs.Update(first); err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+	if first.Version != 2 {
+		t.Fatalf("Update must bump version of the passed PR: got %d", first.Version)
+	}
+
+	// запись по устаревшей версии не должна затирать чужое изменение
+	stale.AssignedReviewers = []string{"4"}
+	if err := r.PRs.Update(stale); !errors.Is(err, repository.ErrConflict) {
+		t.Fatalf("stale Update: want ErrConflict, got %v", err)
+	}
+	got, _ := r.PRs.GetByID("pr-v")
+	if got.Version != 2 || !reflect.DeepEqual(got.AssignedReviewers, []string{"3"}) {
+		t.Fatalf("after stale Update: got %+v", got)
+	}
+
+	// из параллельных обновлений одной версии проходит ровно одно
+	const writers = 8
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	succeeded := 0
+	for i := 0; i < writers; i++ {
+		pr := *got
+		pr.AssignedReviewers = []string{fmt.Sprint(i)}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := r.PRs.Update(&pr)
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case !errors.Is(err, repository.ErrConflict):
+				t.Errorf("concurrent Update: want nil or ErrConflict, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("concurrent Update: want exactly one winner, got %d", succeeded)
+	}
+
+	// параллельное создание PR с одним ID: остальные получают ErrAlreadyExists
+	succeeded = 0
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := r.PRs.Create(&model.PullRequest{ID: "pr-dup", Name: "dup", AuthorID: "1", Status: "OPEN", AssignedReviewers: []string{}})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				succeeded++
+			case !errors.Is(err, repository.ErrAlreadyExists):
+				t.Errorf("concurrent Create: want nil or ErrAlreadyExists, got %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+	if succeeded != 1 {
+		t.Fatalf("concurrent Create: want exactly one winner, got %d", succeeded)
+	}
+}
+
+// testTxConflict проверяет транзакции, одновременно читающие и обновляющие PR: из обновивших
+// один PR фиксируется ровно одна, остальные получают ErrConflict; обновления разных PR не мешают друг другу
+// testView проверяет, что View читает один снимок и ничего не записывает
+func testView(t *testing.T, uow repository.UnitOfWork) {
+	ctx := context.Background()
+	r := uow.Repos(ctx)
+	if err := r.Teams.Create(&model.Team{Name: "backend"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := uow.View(ctx, func(tx repository.Repos) error {
+		before, _, err := tx.Teams.List(100, "")
+		if err != nil {
+			return err
+		}
+		// изменение вне транзакции после первого чтения не видно внутри неё
+		if err := r.Teams.Create(&model.Team{Name: "frontend"}); err != nil {
+			return err
+		}
+		after, _, err := tx.Teams.List(100, "")
+		if err != nil {
+			return err
+		}
+		if len(before) != 1 || len(after) != 1 {
+			return fmt.Errorf("snapshot changed: %d teams, then %d", len(before), len(after))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("View: %v", err)
+	}
+	if teams, _, _ := r.Teams.List(100, ""); len(teams) != 2 {
+		t.Fatalf("after View: %d teams, want 2", len(teams))
+	}
+
+	// запись внутри View не сохраняется: PostgreSQL отклоняет её, память отбрасывает
+	_ = uow.View(ctx, func(tx repository.Repos) error {
+		return tx.Teams.Create(&model.Team{Name: "ghost"})
+	})
+	if _, err := r.Teams.GetByName("ghost"); err != repository.ErrNotFound {
+		t.Fatalf("write inside View persisted: %v", err)
+	}
+}
+
+func testTxConflict(t *testing.T, uow repository.UnitOfWork) {
+	ctx := context.Background()
+	r := uow.Repos(ctx)
+	seedAuthors(t, r)
+	mustCreatePR(t, r, "pr-a", "1", "OPEN", []string{"2"}, nil, base)
+	mustCreatePR(t, r, "pr-b", "1", "OPEN", []string{"2"}, nil, base)
+
+	// run выполняет транзакции параллельно; каждая читает свой PR и обновляет его,
+	// только когда все остальные тоже его прочитали
+	run := func(prIDs ...string) []error {
+		var read, wg sync.WaitGroup
+		read.Add(len(prIDs))
+		errs := make([]error, len(prIDs))
+		for i, id := range prIDs {
+			wg.Add(1)
+			go func(i int, id string) {
+				defer wg.Done()
+				once := sync.Once{}
+				errs[i] = uow.Do(ctx, func(r repository.Repos) error {
+					pr, err := r.PRs.GetByID(id)
+					once.Do(func() {
+						read.Done()
+						read.Wait()
+					})
+					if err != nil {
+						return err
+					}
+					pr.AssignedReviewers = []string{fmt.Sprint(3 + i%2)}
+					return r.PRs.Update(pr)
+				})
+			}(i, id)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	winners := 0
+	for _, err := range run("pr-a", "pr-a", "pr-a", "pr-a") {
+		switch {
+		case err == nil:
+			winners++
+		case !errors.Is(err, repository.ErrConflict):
+			t.Errorf("Do on the same PR: want nil or ErrConflict, got %v", err)
+		}
+	}
+	if winners != 1 {
+		t.Fatalf("Do on the same PR: want exactly one winner, got %d", winners)
+	}
+	if pr, _ := r.PRs.GetByID("pr-a"); pr.Version != 2 {
+		t.Fatalf("pr-a version: want 2, got %d", pr.Version)
+	}
+
+	for _, err := range run("pr-a", "pr-b") {
+		if err != nil {
+			t.Fatalf("Do on different PRs: %v", err)
+		}
+	}
+}
+
+func testPRRestore(t *testing.T, r repository.Repos) {
+	seedAuthors(t, r)
+	merged := base.Add(5 * time.Hour)
+	pr := &model.PullRequest{
+		ID:                "pr-r",
+		Name:              "restored",
+		AuthorID:          "1",
+		Status:            "MERGED",
+		AssignedReviewers: []string{"2"},
+		Labels:            []string{"backend"},
+		CreatedAt:         base,
+		MergedAt:          &merged,
+		Version:           7,
+		Assignment:        &model.AssignmentExplanation{Action: model.AssignmentActionCreate, Strategy: "random", Seed: 42, DecidedAt: base},
+	}
+	if err := r.PRs.Restore(pr); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	got, err := r.PRs.GetByID("pr-r")
+	if err != nil || got.Version != 7 || got.Status != "MERGED" || got.MergedAt == nil || !got.MergedAt.Equal(merged) ||
+		!got.CreatedAt.Equal(base) || got.Assignment == nil || got.Assignment.Seed != 42 {
+		t.Fatalf("GetByID after Restore: got %+v, %v", got, err)
+	}
+	if err := r.PRs.Restore(pr); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Fatalf("duplicate Restore: want ErrAlreadyExists, got %v", err)
+	}
+}
+
+func testHistory(t *testing.T, r repository.Repos) {
+	seedAuthors(t, r)
+	mustCreatePR(t, r, "pr-1", "1", "OPEN", []string{"2"}, nil, base)
+
+	events, err := r.History.ListByPR("pr-1")
+	if err != nil || events == nil || len(events) != 0 {
+		t.Fatalf("ListByPR empty: got %#v, %v", events, err)
+	}
+
+	first := &model.PREvent{PRID: "pr-1", Event: model.PREventCreated, CreatedAt: base}
+	second := &model.PREvent{
+		PRID:      "pr-1",
+		Event:     model.PREventReassigned,
+		Data:      map[string]string{"old_user_id": "2", "new_user_id": "3"},
+		CreatedAt: base.Add(time.Minute),
+	}
+	for _, e := range []*model.PREvent{first, second} {
+		if err := r.History.Add(e); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if first.ID == 0 || second.ID <= first.ID {
+		t.Fatalf("Add must assign increasing IDs, got %d and %d", first.ID, second.ID)
+	}
+
+	events, err = r.History.ListByPR("pr-1")
+	if err != nil || len(events) != 2 || events[0].Event != model.PREventCreated || events[1].Data["new_user_id"] != "3" {
+		t.Fatalf("ListByPR: got %+v, %v", events, err)
+	}
+}
+
+func testDeclines(t *testing.T, r repository.Repos) {
+	seedAuthors(t, r)
+	mustCreatePR(t, r, "pr-1", "1", "OPEN", []string{"2"}, nil, base)
+	mustCreatePR(t, r, "pr-2", "1", "OPEN", []string{"2"}, nil, base)
+
+	for i, d := range []model.ReviewDecline{
+		{PRID: "pr-1", UserID: "2", Reason: model.DeclineOverloaded, ReplacedBy: "3"},
+		{PRID: "pr-2", UserID: "2", Reason: model.DeclineNoContext, ReplacedBy: "3"},
+		{PRID: "pr-2", UserID: "3", Reason: model.DeclineOverloaded, ReplacedBy: "2"},
+	} {
+		d.CreatedAt = base.Add(time.Duration(i) * time.Hour)
+		if err := r.Declines.Add(&d); err != nil || d.ID == 0 {
+			t.Fatalf("Add: id=%d err=%v", d.ID, err)
+		}
+	}
+
+	users, err := r.Declines.DeclinedUsers("pr-2")
+	if err != nil || len(users) != 2 {
+		t.Fatalf("DeclinedUsers: got %v, %v", users, err)
+	}
+
+	declines, err := r.Declines.ListByUser("2", base.Add(time.Minute))
+	if err != nil || len(declines) != 1 || declines[0].PRID != "pr-2" {
+		t.Fatalf("ListByUser since: got %+v, %v", declines, err)
+	}
+
+	stats, err := r.Declines.Stats(time.Time{}, 2)
+	want := []model.DeclineStats{{UserID: "2", Total: 2, ByReason: map[string]int{
+		model.DeclineOverloaded: 1,
+		model.DeclineNoContext:  1,
+	}}}
+	if err != nil || !reflect.DeepEqual(stats, want) {
+		t.Fatalf("Stats: got %+v, %v", stats, err)
+	}
+}
+
+// seedAuthors создаёт команду backend с пользователями 1, 2, 3
+func testIdempotency(t *testing.T, r repository.Repos) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	rec := &model.IdempotencyRecord{Key: "k1", RequestHash: "h1", CreatedAt: base, ExpiresAt: base.Add(time.Hour)}
+	if err := r.Idempotency.Reserve(rec); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	again := &model.IdempotencyRecord{Key: "k1", RequestHash: "h2", CreatedAt: base.Add(time.Minute), ExpiresAt: base.Add(2 * time.Hour)}
+	if err := r.Idempotency.Reserve(again); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Fatalf("Reserve taken key: want ErrAlreadyExists, got %v", err)
+	}
+
+	got, err := r.Idempotency.Get("k1")
+	if err != nil || got.RequestHash != "h1" || got.Completed() {
+		t.Fatalf("Get pending: got %+v, %v", got, err)
+	}
+	if err := r.Idempotency.Complete("k1", 201, []byte(`{"ok":true}`), base.Add(time.Hour)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	got, _ = r.Idempotency.Get("k1")
+	if got.StatusCode != 201 || string(got.Response) != `{"ok":true}` || !got.ExpiresAt.Equal(base.Add(time.Hour)) {
+		t.Fatalf("Get completed: got %+v", got)
+	}
+	if err := r.Idempotency.Complete("missing", 200, nil, base); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Complete missing: want ErrNotFound, got %v", err)
+	}
+
+	// истёкший ключ можно занять заново
+	late := &model.IdempotencyRecord{Key: "k1", RequestHash: "h3", CreatedAt: base.Add(time.Hour), ExpiresAt: base.Add(3 * time.Hour)}
+	if err := r.Idempotency.Reserve(late); err != nil {
+		t.Fatalf("Reserve expired key: %v", err)
+	}
+	got, _ = r.Idempotency.Get("k1")
+	if got.RequestHash != "h3" || got.Completed() {
+		t.Fatalf("Get after re-reserve: got %+v", got)
+	}
+
+	if err := r.Idempotency.Delete("k1"); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if _, err := r.Idempotency.Get("k1"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get deleted: want ErrNotFound, got %v", err)
+	}
+
+	for i, key := range []string{"a", "b", "c"} {
+		exp := base.Add(time.Duration(i+1) * time.Hour)
+		if err := r.Idempotency.Reserve(&model.IdempotencyRecord{Key: key, RequestHash: "h", CreatedAt: base, ExpiresAt: exp}); err != nil {
+			t.Fatalf("Reserve %s: %v", key, err)
+		}
+	}
+	if n, err := r.Idempotency.DeleteExpired(base.Add(2 * time.Hour)); err != nil || n != 2 {
+		t.Fatalf("DeleteExpired: got %d, %v", n, err)
+	}
+	if _, err := r.Idempotency.Get("c"); err != nil {
+		t.Fatalf("DeleteExpired removed live key: %v", err)
+	}
+}
+
+func testTokens(t *testing.T, r repository.Repos) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lead := &model.APIToken{Name: "lead", Role: model.RoleTeamLead, TeamName: "backend", TokenHash: "h1", CreatedAt: base}
+	bot := &model.APIToken{Name: "ci", Role: model.RoleBot, TokenHash: "h2", CreatedAt: base}
+	for _, tok := range []*model.APIToken{lead, bot} {
+		if err := r.Tokens.Create(tok); err != nil {
+			t.Fatalf("Create %s: %v", tok.Name, err)
+		}
+	}
+	if lead.ID == 0 || bot.ID <= lead.ID {
+		t.Fatalf("Create must assign increasing IDs: got %d, %d", lead.ID, bot.ID)
+	}
+	if err := r.Tokens.Create(&model.APIToken{Name: "dup", Role: model.RoleBot, TokenHash: "h1", CreatedAt: base}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Fatalf("duplicate hash: want ErrAlreadyExists, got %v", err)
+	}
+
+	got, err := r.Tokens.GetByHash("h1")
+	if err != nil || got.ID != lead.ID || got.Role != model.RoleTeamLead || got.TeamName != "backend" || got.RevokedAt != nil {
+		t.Fatalf("GetByHash: got %+v, %v", got, err)
+	}
+	if _, err := r.Tokens.GetByHash("missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("GetByHash missing: want ErrNotFound, got %v", err)
+	}
+
+	revokedAt := base.Add(time.Hour)
+	revoked, err := r.Tokens.Revoke(bot.ID, revokedAt)
+	if err != nil || revoked.RevokedAt == nil || !revoked.RevokedAt.Equal(revokedAt) {
+		t.Fatalf("Revoke: got %+v, %v", revoked, err)
+	}
+	again, _ := r.Tokens.Revoke(bot.ID, revokedAt.Add(time.Hour))
+	if !again.RevokedAt.Equal(revokedAt) {
+		t.Fatalf("second Revoke must keep the first time, got %v", again.RevokedAt)
+	}
+	if _, err := r.Tokens.Revoke(999, revokedAt); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Revoke missing: want ErrNotFound, got %v", err)
+	}
+
+	tokens, err := r.Tokens.List()
+	if err != nil || len(tokens) != 2 || tokens[0].ID != lead.ID || tokens[1].RevokedAt == nil {
+		t.Fatalf("List: got %+v, %v", tokens, err)
+	}
+}
+
+func testAudit(t *testing.T, r repository.Repos) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	events := []*model.AuditEvent{
+		{Action: model.AuditPRMerged, Actor: "1", ActorRole: model.RoleBot, TargetType: "pull_request", TargetID: "pr-1", CreatedAt: base},
+		{Action: model.AuditUserActivity, Actor: "2", TargetType: "user", TargetID: "3", Data: map[string]string{"is_active": "false"}, CreatedAt: base.Add(time.Minute)},
+		{Action: model.AuditPRMerged, Actor: "2", TargetType: "pull_request", TargetID: "pr-2", CreatedAt: base.Add(2 * time.Minute)},
+	}
+	for _, e := range events {
+		if err := r.Audit.Add(e); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if events[0].ID == 0 || events[2].ID <= events[1].ID {
+		t.Fatalf("Add must assign increasing IDs")
+	}
+
+	all, err := r.Audit.List(repository.AuditFilter{Limit: 10})
+	if err != nil || len(all) != 3 || all[0].ID != events[2].ID || all[0].Data == nil {
+		t.Fatalf("List: got %+v, %v", all, err)
+	}
+	merged, _ := r.Audit.List(repository.AuditFilter{Action: model.AuditPRMerged, Limit: 10})
+	if len(merged) != 2 {
+		t.Fatalf("List by action: got %+v", merged)
+	}
+	byActor, _ := r.Audit.List(repository.AuditFilter{Actor: "2", TargetType: "user", Limit: 10})
+	if len(byActor) != 1 || byActor[0].Data["is_active"] != "false" {
+		t.Fatalf("List by actor and target: got %+v", byActor)
+	}
+	page, _ := r.Audit.List(repository.AuditFilter{BeforeID: events[2].ID, Limit: 1})
+	if len(page) != 1 || page[0].ID != events[1].ID {
+		t.Fatalf("List before id: got %+v", page)
+	}
+}
+
+func testOrganizations(t *testing.T, r repository.Repos) {
+	if _, err := r.Orgs.Get(repository.DefaultTenant); err != nil {
+		t.Fatalf("default organization must exist: %v", err)
+	}
+	acme := &model.Organization{ID: "acme", Name: "Acme", CreatedAt: base}
+	if err := r.Orgs.Create(acme); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := r.Orgs.Create(&model.Organization{ID: "acme", Name: "Dup", CreatedAt: base}); !errors.Is(err, repository.ErrAlreadyExists) {
+		t.Fatalf("duplicate Create: want ErrAlreadyExists, got %v", err)
+	}
+	if _, err := r.Orgs.Get("missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Get missing: want ErrNotFound, got %v", err)
+	}
+
+	two := 2
+	updated, err := r.Orgs.UpdateSettings("acme", model.OrgSettings{ReviewerCount: &two, Strategy: "round_robin"})
+	if err != nil || updated.Settings.ReviewerCount == nil || *updated.Settings.ReviewerCount != 2 || updated.Settings.Strategy != "round_robin" {
+		t.Fatalf("UpdateSettings: got %+v, %v", updated, err)
+	}
+	two = 5
+	got, err := r.Orgs.Get("acme")
+	if err != nil || *got.Settings.ReviewerCount != 2 || got.Settings.MaxOpenReviews != nil || got.Name != "Acme" {
+		t.Fatalf("Get after UpdateSettings: got %+v, %v", got, err)
+	}
+	if _, err := r.Orgs.UpdateSettings("missing", model.OrgSettings{}); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("UpdateSettings missing: want ErrNotFound, got %v", err)
+	}
+
+	orgs, err := r.Orgs.List()
+	if err != nil || len(orgs) != 2 || orgs[0].ID != "acme" || orgs[1].ID != repository.DefaultTenant {
+		t.Fatalf("List: got %+v, %v", orgs, err)
+	}
+}
+
+// testTenantIsolation проверяет, что одинаковые ключи в разных организациях не конфликтуют,
+// а репозитории одной организации не видят и не меняют данные другой
+func testTenantIsolation(t *testing.T, uow repository.UnitOfWork) {
+	ctx := context.Background()
+	if err := uow.Repos(ctx).Orgs.Create(&model.Organization{ID: "acme", Name: "Acme", CreatedAt: base}); err != nil {
+		t.Fatalf("Create organization: %v", err)
+	}
+	a := uow.Repos(ctx)
+	b := uow.Repos(repository.WithTenant(ctx, "acme"))
+
+	for _, r := range []repository.Repos{a, b} {
+		mustCreateTeam(t, r, "backend")
+		mustCreateUser(t, r, model.User{ID: 1, Username: "alice", TeamName: "backend", IsActive: true})
+		mustCreatePR(t, r, "pr-1", "1", "OPEN", nil, nil, base)
+	}
+
+	if _, err := b.Users.SetIsActive("1", false); err != nil {
+		t.Fatalf("SetIsActive: %v", err)
+	}
+	if u, err := a.Users.GetByID("1"); err != nil || !u.IsActive {
+		t.Fatalf("update in another tenant leaked: got %+v, %v", u, err)
+	}
+
+	mustCreateUser(t, b, model.User{ID: 2, Username: "bob", TeamName: "backend", IsActive: true})
+	if _, err := a.Users.GetByID("2"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("user of another tenant: want ErrNotFound, got %v", err)
+	}
+	if members, _ := a.Users.GetByTeam("backend"); len(members) != 1 {
+		t.Fatalf("GetByTeam must see only own tenant, got %+v", members)
+	}
+	if prs, _, err := a.PRs.List(repository.PRFilter{SortBy: repository.PRSortID, Limit: 10}); err != nil || len(prs) != 1 {
+		t.Fatalf("PRs.List must see only own tenant, got %+v, %v", prs, err)
+	}
+	if err := b.History.Add(&model.PREvent{PRID: "pr-1", Event: model.PREventCreated, CreatedAt: base}); err != nil {
+		t.Fatalf("History.Add: %v", err)
+	}
+	if events, _ := a.History.ListByPR("pr-1"); len(events) != 0 {
+		t.Fatalf("history of another tenant leaked: %+v", events)
+	}
+
+	// чужие команды и PR не видны и не изменяются
+	mustCreateTeam(t, b, "platform")
+	if _, err := a.Teams.GetByName("platform"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("team of another tenant: want ErrNotFound, got %v", err)
+	}
+	if err := a.Teams.Rename("platform", "stolen"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Rename team of another tenant: want ErrNotFound, got %v", err)
+	}
+	if err := a.Teams.Delete("platform"); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Delete team of another tenant: %v", err)
+	}
+	if _, err := b.Teams.GetByName("platform"); err != nil {
+		t.Fatalf("team deleted through another tenant: %v", err)
+	}
+	if teams, _, _ := a.Teams.List(10, ""); len(teams) != 1 {
+		t.Fatalf("Teams.List must see only own tenant, got %+v", teams)
+	}
+
+	mustCreatePR(t, b, "pr-b", "1", "OPEN", []string{"2"}, nil, base)
+	if _, err := a.PRs.GetByID("pr-b"); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("PR of another tenant: want ErrNotFound, got %v", err)
+	}
+	foreign, _ := b.PRs.GetByID("pr-b")
+	merged := *foreign
+	merged.Status = "MERGED"
+	if err := a.PRs.Update(&merged); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Update PR of another tenant: want ErrNotFound, got %v", err)
+	}
+	if got, _ := b.PRs.GetByID("pr-b"); got.Status != "OPEN" {
+		t.Fatalf("PR updated through another tenant: %+v", got)
+	}
+	if prs, _ := a.PRs.GetByReviewer("2", ""); len(prs) != 0 {
+		t.Fatalf("GetByReviewer must see only own tenant, got %+v", prs)
+	}
+
+	tok := &model.APIToken{Name: "ci", Role: model.RoleBot, TokenHash: "acme-hash", CreatedAt: base}
+	if err := b.Tokens.Create(tok); err != nil {
+		t.Fatalf("Tokens.Create: %v", err)
+	}
+	if got, err := a.Tokens.GetByHash("acme-hash"); err != nil || got.TenantID != "acme" {
+		t.Fatalf("GetByHash must find the token with its tenant, got %+v, %v", got, err)
+	}
+	if tokens, _ := a.Tokens.List(); len(tokens) != 0 {
+		t.Fatalf("tokens of another tenant leaked: %+v", tokens)
+	}
+	if _, err := a.Tokens.Revoke(tok.ID, base); !errors.Is(err, repository.ErrNotFound) {
+		t.Fatalf("Revoke in another tenant: want ErrNotFound, got %v", err)
+	}
+
+	err := uow.Do(repository.WithTenant(ctx, "acme"), func(r repository.Repos) error {
+		if _, err := r.Users.GetByID("2"); err != nil {
+			return err
+		}
+		_, err := r.Users.SetIsActive("1", true)
+		return err
+	})
+	if err != nil {
+		t.Fatalf("Do in tenant: %v", err)
+	}
+	if u, _ := b.Users.GetByID("1"); !u.IsActive {
+		t.Fatalf("Do must work on the tenant from ctx")
+	}
+}
+
+func seedAuthors(t *testing.T, r repository.Repos) {
+	t.Helper()
+	mustCreateTeam(t, r, "backend")
+	for i, name := range []string{"alice", "bob", "carol"} {
+		mustCreateUser(t, r, model.User{ID: int64(i + 1), Username: name, TeamName: "backend", IsActive: true})
+	}
+}
+
+func mustCreateTeam(t *testing.T, r repository.Repos, name string) {
+	t.Helper()
+	if err := r.Teams.Create(&model.Team{Name: name}); err != nil {
+		t.Fatalf("create team %s: %v", name, err)
+	}
+}
+
+// mustCreateUser создаёт пользователя и, если задан TeamName, его полное активное членство в команде
+func mustCreateUser(t *testing.T, r repository.Repos, u model.User) {
+	t.Helper()
+	if err := r.Users.CreateOrUpdate(&u); err != nil {
+		t.Fatalf("create user %d: %v", u.ID, err)
+	}
+	if u.TeamName != "" {
+		mustAddMembership(t, r, u.TeamName, strconv.FormatInt(u.ID, 10), true, model.DefaultMembershipWeight)
+	}
+}
+
+func mustAddMembership(t *testing.T, r repository.Repos, team, userID string, active bool, weight int) {
+	t.Helper()
+	if err := r.Memberships.Upsert(&model.TeamMembership{TeamName: team, UserID: userID, IsActive: active, Weight: weight}); err != nil {
+		t.Fatalf("add membership %s/%s: %v", team, userID, err)
+	}
+}
+
+func mustCreatePR(t *testing.T, r repository.Repos, id, author, status string, reviewers, labels []string, createdAt time.Time) {
+	t.Helper()
+	pr := &model.PullRequest{
+		ID:                id,
+		Name:              "PR " + id,
+		AuthorID:          author,
+		Status:            status,
+		AssignedReviewers: reviewers,
+		Labels:            labels,
+		CreatedAt:         createdAt,
+	}
+	if err := r.PRs.Create(pr); err != nil {
+		t.Fatalf("create PR %s: %v", id, err)
+	}
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"pr-reviewer/internal/model"
 )
@@ -11,40 +12,142 @@ var ErrNotFound = errors.New("record not found")
 
 // TeamRepo работает с командами
 type TeamRepo struct {
-	db *sql.DB
+	db     DBTX
+	tenant string
 }
 
-// NewTeamRepo создаёт новый TeamRepo
-func NewTeamRepo(db *sql.DB) *TeamRepo {
-	return &TeamRepo{db: db}
+// NewTeamRepo создаёт TeamRepo, работающий с командами организации tenant
+func NewTeamRepo(db DBTX, tenant string) *TeamRepo {
+	return &TeamRepo{db: db, tenant: tenant}
 }
 
-// Create создаёт новую команду
+// Create создаёт новую команду с родителем и настройками
 func (r *TeamRepo) Create(team *model.Team) error {
-	query := `INSERT INTO teams (name) VALUES ($1)`
-	_, err := r.db.Exec(query, team.Name)
+	settingsJSON, _ := json.Marshal(team.Settings)
+	query := `INSERT INTO teams (tenant_id, name, parent_name, settings) VALUES ($1, $2, NULLIF($3, ''), $4)`
+	_, err := r.db.Exec(query, r.tenant, team.Name, team.Parent, settingsJSON)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
 	return err
 }
 
-// GetByName возвращает команду по имени
+// GetByName возвращает команду по имени без участников
 func (r *TeamRepo) GetByName(name string) (*model.Team, error) {
-	query := `SELECT name FROM teams WHERE name=$1`
-	row := r.db.QueryRow(query, name)
+	query := `SELECT name, COALESCE(parent_name, ''), settings FROM teams WHERE name=$1 AND tenant_id=$2`
+	row := r.db.QueryRow(query, name, r.tenant)
 
 	var team model.Team
-	if err := row.Scan(&team.Name); err != nil {
+	var settingsJSON []byte
+	if err := row.Scan(&team.Name, &team.Parent, &settingsJSON); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrNotFound
 		}
 		return nil, err
 	}
+	json.Unmarshal(settingsJSON, &team.Settings)
 
 	return &team, nil
 }
 
+// Update сохраняет родителя и настройки команды
+func (r *TeamRepo) Update(team *model.Team) error {
+	settingsJSON, _ := json.Marshal(team.Settings)
+	query := `UPDATE teams SET parent_name=NULLIF($1, ''), settings=$2 WHERE name=$3 AND tenant_id=$4`
+	res, err := r.db.Exec(query, team.Parent, settingsJSON, team.Name, r.tenant)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Children возвращает имена дочерних команд, упорядоченные по имени
+func (r *TeamRepo) Children(name string) ([]string, error) {
+	rows, err := r.db.Query(`SELECT name FROM teams WHERE parent_name=$1 AND tenant_id=$2 ORDER BY name`, name, r.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var n string
+		if err := rows.Scan(&n); err != nil {
+			return nil, err
+		}
+		names = append(names, n)
+	}
+	return names, rows.Err()
+}
+
 // Delete удаляет команду (для тестов или администрирования)
 func (r *TeamRepo) Delete(name string) error {
-	query := `DELETE FROM teams WHERE name=$1`
-	_, err := r.db.Exec(query, name)
+	query := `DELETE FROM teams WHERE name=$1 AND tenant_id=$2`
+	_, err := r.db.Exec(query, name, r.tenant)
 	return err
 }
+
+// Rename переименовывает команду; членства и ссылки дочерних команд переходят к новому имени каскадно
+func (r *TeamRepo) Rename(oldName, newName string) error {
+	res, err := r.db.Exec(`UPDATE teams SET name=$1 WHERE name=$2 AND tenant_id=$3`, newName, oldName, r.tenant)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrAlreadyExists
+		}
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List возвращает страницу команд, упорядоченных по имени, и курсор следующей страницы
+func (r *TeamRepo) List(limit int, after string) ([]model.TeamSummary, string, error) {
+	var afterName string
+	if after != "" {
+		c, err := DecodeCursor(after, "team_name")
+		if err != nil {
+			return nil, "", err
+		}
+		afterName = c.ID
+	}
+
+	query := `
+	SELECT t.name, COALESCE(t.parent_name, ''), COUNT(u.user_id), COUNT(u.user_id) FILTER (WHERE u.is_active AND m.is_active)
+	FROM teams t
+	LEFT JOIN team_memberships m ON m.tenant_id = t.tenant_id AND m.team_name = t.name
+	LEFT JOIN users u ON u.tenant_id = m.tenant_id AND u.user_id = m.user_id
+	WHERE t.name > $1 AND t.tenant_id = $3
+	GROUP BY t.name, t.parent_name
+	ORDER BY t.name
+	LIMIT $2
+	`
+	rows, err := r.db.Query(query, afterName, limit+1, r.tenant)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	teams := []model.TeamSummary{}
+	for rows.Next() {
+		var t model.TeamSummary
+		if err := rows.Scan(&t.Name, &t.Parent, &t.MemberCount, &t.ActiveCount); err != nil {
+			return nil, "", err
+		}
+		teams = append(teams, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(teams) > limit {
+		teams = teams[:limit]
+		next = EncodeCursor(Cursor{Sort: "team_name", ID: teams[limit-1].Name})
+	}
+	return teams, next, nil
+}
@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"context"
+	"regexp"
+)
+
+// DefaultTenant — организация однотенантной установки; в неё перенесены данные,
+// созданные до появления организаций
+const DefaultTenant = "default"
+
+// tenantIDPattern ограничивает ID организации: он попадает в заголовки, токены и логи
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]{0,49}$`)
+
+// ValidTenantID проверяет формат ID организации
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
+
+type tenantKey struct{}
+
+// WithTenant привязывает контекст к организации. Репозитории, полученные через
+// UnitOfWork с этим контекстом, видят и изменяют только данные этой организации.
+func WithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFrom возвращает организацию контекста; без привязки — DefaultTenant
+func TenantFrom(ctx context.Context) string {
+	if t, ok := ctx.Value(tenantKey{}).(string); ok && t != "" {
+		return t
+	}
+	return DefaultTenant
+}
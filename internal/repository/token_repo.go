@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"database/sql"
+	"errors"
+	"pr-reviewer/internal/model"
+	"time"
+)
+
+// tokenColumns — список колонок, читаемых scanToken
+const tokenColumns = `id, tenant_id, name, role, user_id, team_name, token_hash, created_at, revoked_at`
+
+// TokenRepo хранит API-токены
+type TokenRepo struct {
+	db     DBTX
+	tenant string
+}
+
+// NewTokenRepo создаёт новый TokenRepo
+func NewTokenRepo(db DBTX, tenant string) *TokenRepo {
+	return &TokenRepo{db: db, tenant: tenant}
+}
+
+// scanToken читает токен из строки результата
+func scanToken(row rowScanner) (*model.APIToken, error) {
+	var t model.APIToken
+	if err := row.Scan(&t.ID, &t.TenantID, &t.Name, &t.Role, &t.UserID, &t.TeamName, &t.TokenHash, &t.CreatedAt, &t.RevokedAt); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Create сохраняет токен организации и присваивает ему ID
+func (r *TokenRepo) Create(t *model.APIToken) error {
+	query := `
+	INSERT INTO api_tokens (name, role, user_id, team_name, token_hash, created_at, tenant_id)
+	VALUES ($1,$2,$3,$4,$5,$6,$7)
+	RETURNING id
+	`
+	t.TenantID = r.tenant
+	err := r.db.QueryRow(query, t.Name, t.Role, t.UserID, t.TeamName, t.TokenHash, t.CreatedAt, r.tenant).Scan(&t.ID)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// GetByHash возвращает токен по SHA-256, в том числе отозванный. Поиск идёт по всем организациям:
+// организация вызывающего становится известна только из найденного токена.
+func (r *TokenRepo) GetByHash(hash string) (*model.APIToken, error) {
+	query := `SELECT ` + tokenColumns + ` FROM api_tokens WHERE token_hash=$1`
+	return scanToken(r.db.QueryRow(query, hash))
+}
+
+// List возвращает токены организации по возрастанию ID
+func (r *TokenRepo) List() ([]model.APIToken, error) {
+	rows, err := r.db.Query(`SELECT `+tokenColumns+` FROM api_tokens WHERE tenant_id=$1 ORDER BY id`, r.tenant)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []model.APIToken{}
+	for rows.Next() {
+		t, err := scanToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, *t)
+	}
+	return tokens, rows.Err()
+}
+
+// Revoke отзывает токен; повторный отзыв сохраняет исходное время
+func (r *TokenRepo) Revoke(id int64, at time.Time) (*model.APIToken, error) {
+	query := `
+	UPDATE api_tokens SET revoked_at=COALESCE(revoked_at, $1)
+	WHERE id=$2 AND tenant_id=$3
+	RETURNING ` + tokenColumns
+	return scanToken(r.db.QueryRow(query, at, id, r.tenant))
+}
+
+// RenameTeam переносит токены лидов команды oldName на команду newName
+func (r *TokenRepo) RenameTeam(oldName, newName string) error {
+	_, err := r.db.Exec(`UPDATE api_tokens SET team_name=$1 WHERE team_name=$2 AND tenant_id=$3`, newName, oldName, r.tenant)
+	return err
+}
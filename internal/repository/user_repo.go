@@ -2,50 +2,100 @@ package repository
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"pr-reviewer/internal/model"
+	"time"
 )
 
+// userColumns — список колонок, читаемых scanUser; членства собираются в JSON, основная команда первой
+const userColumns = `user_id, username, COALESCE((
+		SELECT json_agg(json_build_object('team_name', m.team_name, 'is_active', m.is_active, 'weight', m.weight, 'role', m.role)
+			ORDER BY m.weight DESC, m.team_name)
+		FROM team_memberships m
+		WHERE m.tenant_id = users.tenant_id AND m.user_id = users.user_id
+	), '[]'), is_active, skills, out_of_office_until`
+
+// rowScanner объединяет *sql.Row и *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
 // UserRepo работает с пользователями
 type UserRepo struct {
-	db *sql.DB
+	db     DBTX
+	tenant string
 }
 
 // NewUserRepo создаёт новый UserRepo
-func NewUserRepo(db *sql.DB) *UserRepo {
-	return &UserRepo{db: db}
+func NewUserRepo(db DBTX, tenant string) *UserRepo {
+	return &UserRepo{db: db, tenant: tenant}
 }
 
-// CreateOrUpdate создаёт или обновляет пользователя
+// scanUser читает пользователя из строки результата
+func scanUser(row rowScanner) (*model.User, error) {
+	var u model.User
+	var teamsJSON, skillsJSON []byte
+	if err := row.Scan(&u.ID, &u.Username, &teamsJSON, &u.IsActive, &skillsJSON, &u.OutOfOfficeUntil); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	json.Unmarshal(teamsJSON, &u.Teams)
+	json.Unmarshal(skillsJSON, &u.Skills)
+	if len(u.Teams) > 0 {
+		u.TeamName = u.Teams[0].TeamName
+	}
+	return &u, nil
+}
+
+// Create создаёт нового пользователя; занятый ID — ErrAlreadyExists
+func (r *UserRepo) Create(user *model.User) error {
+	query := `INSERT INTO users (user_id, username, is_active, tenant_id) VALUES ($1, $2, $3, $4)`
+	_, err := r.db.Exec(query, user.ID, user.Username, user.IsActive, r.tenant)
+	if isUniqueViolation(err) {
+		return ErrAlreadyExists
+	}
+	return err
+}
+
+// CreateOrUpdate создаёт или обновляет пользователя; команды задаются членствами
 func (r *UserRepo) CreateOrUpdate(user *model.User) error {
 	query := `
-	INSERT INTO users (user_id, username, team_name, is_active)
+	INSERT INTO users (user_id, username, is_active, tenant_id)
 	VALUES ($1, $2, $3, $4)
-	ON CONFLICT (user_id) DO UPDATE SET username=$2, team_name=$3, is_active=$4
+	ON CONFLICT (tenant_id, user_id) DO UPDATE SET username=$2, is_active=$3
 	`
-	_, err := r.db.Exec(query, user.ID, user.Username, user.TeamName, user.IsActive)
+	_, err := r.db.Exec(query, user.ID, user.Username, user.IsActive, r.tenant)
 	return err
 }
 
 // GetByID возвращает пользователя по ID
 func (r *UserRepo) GetByID(userID string) (*model.User, error) {
-	query := `SELECT user_id, username, team_name, is_active FROM users WHERE user_id=$1`
-	row := r.db.QueryRow(query, userID)
-
-	var u model.User
-	if err := row.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
-		}
-		return nil, err
-	}
-	return &u, nil
+	query := `SELECT ` + userColumns + ` FROM users WHERE user_id=$1 AND tenant_id=$2`
+	return scanUser(r.db.QueryRow(query, userID, r.tenant))
 }
 
-// GetByTeam возвращает всех пользователей команды
+// GetByTeam возвращает всех участников команды, в том числе с неактивным членством
 func (r *UserRepo) GetByTeam(teamName string) ([]model.User, error) {
-	query := `SELECT user_id, username, team_name, is_active FROM users WHERE team_name=$1`
-	rows, err := r.db.Query(query, teamName)
+	query := `
+	SELECT ` + userColumns + ` FROM users
+	WHERE tenant_id=$2 AND user_id IN (SELECT user_id FROM team_memberships WHERE team_name=$1 AND tenant_id=$2)
+	ORDER BY user_id
+	`
+	return r.queryUsers(query, teamName, r.tenant)
+}
+
+// List возвращает всех пользователей организации, упорядоченных по ID
+func (r *UserRepo) List() ([]model.User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE tenant_id=$1 ORDER BY user_id`
+	return r.queryUsers(query, r.tenant)
+}
+
+// queryUsers выполняет запрос, возвращающий колонки userColumns
+func (r *UserRepo) queryUsers(query string, args ...interface{}) ([]model.User, error) {
+	rows, err := r.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -53,26 +103,30 @@ func (r *UserRepo) GetByTeam(teamName string) ([]model.User, error) {
 
 	var users []model.User
 	for rows.Next() {
-		var u model.User
-		if err := rows.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive); err != nil {
+		u, err := scanUser(rows)
+		if err != nil {
 			return nil, err
 		}
-		users = append(users, u)
+		users = append(users, *u)
 	}
-	return users, nil
+	return users, rows.Err()
 }
 
 // SetIsActive обновляет флаг активности пользователя
 func (r *UserRepo) SetIsActive(userID string, isActive bool) (*model.User, error) {
-	query := `UPDATE users SET is_active=$1 WHERE user_id=$2 RETURNING user_id, username, team_name, is_active`
-	row := r.db.QueryRow(query, isActive, userID)
+	query := `UPDATE users SET is_active=$1 WHERE user_id=$2 AND tenant_id=$3 RETURNING ` + userColumns
+	return scanUser(r.db.QueryRow(query, isActive, userID, r.tenant))
+}
 
-	var u model.User
-	if err := row.Scan(&u.ID, &u.Username, &u.TeamName, &u.IsActive); err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, ErrNotFound
-		}
-		return nil, err
-	}
-	return &u, nil
+// SetSkills заменяет набор навыков пользователя
+func (r *UserRepo) SetSkills(userID string, skills []string) (*model.User, error) {
+	skillsJSON, _ := json.Marshal(nonNil(skills))
+	query := `UPDATE users SET skills=$1 WHERE user_id=$2 AND tenant_id=$3 RETURNING ` + userColumns
+	return scanUser(r.db.QueryRow(query, skillsJSON, userID, r.tenant))
+}
+
+// SetOutOfOffice задаёт момент, до которого пользователь отсутствует (nil — снять отметку)
+func (r *UserRepo) SetOutOfOffice(userID string, until *time.Time) (*model.User, error) {
+	query := `UPDATE users SET out_of_office_until=$1 WHERE user_id=$2 AND tenant_id=$3 RETURNING ` + userColumns
+	return scanUser(r.db.QueryRow(query, until, userID, r.tenant))
 }
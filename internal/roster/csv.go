@@ -0,0 +1,112 @@
+package roster
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"pr-reviewer/internal/model"
+)
+
+// csvColumns сопоставляет допустимые заголовки CSV полям реестра
+var csvColumns = map[string]string{
+	"team":      "team_name",
+	"team_name": "team_name",
+	"user_id":   "user_id",
+	"id":        "user_id",
+	"username":  "username",
+	"name":      "username",
+	"is_active": "is_active",
+	"active":    "is_active",
+	"role":      "role",
+	"roles":     "role",
+}
+
+// parseCSV разбирает CSV с заголовком: team_name, user_id, username и необязательные is_active, role
+func parseCSV(data []byte) ([]model.RosterEntry, []model.ImportLineError) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\ufeff"))))
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+	r.Comment = '#'
+
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, []model.ImportLineError{lineError(1, "empty file: header row required")}
+	}
+	if err != nil {
+		return nil, []model.ImportLineError{csvError(err)}
+	}
+	headerLine, _ := r.FieldPos(0)
+
+	index := make(map[string]int, len(header))
+	var errs []model.ImportLineError
+	for i, h := range header {
+		col, ok := csvColumns[strings.ToLower(strings.TrimSpace(h))]
+		if !ok {
+			errs = append(errs, lineError(headerLine, "unknown column %q", h))
+			continue
+		}
+		if _, dup := index[col]; dup {
+			errs = append(errs, lineError(headerLine, "duplicate column %q", h))
+			continue
+		}
+		index[col] = i
+	}
+	for _, col := range []string{"team_name", "user_id", "username"} {
+		if _, ok := index[col]; !ok {
+			errs = append(errs, lineError(headerLine, "missing required column %q", col))
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	var entries []model.RosterEntry
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// после синтаксической ошибки CSV позиции следующих строк недостоверны
+			return entries, append(errs, csvError(err))
+		}
+		line, _ := r.FieldPos(0)
+		if len(record) > len(header) {
+			errs = append(errs, lineError(line, "expected %d fields, got %d", len(header), len(record)))
+			continue
+		}
+		field := func(col string) string {
+			i, ok := index[col]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		active, err := parseActive(field("is_active"))
+		if err != nil {
+			errs = append(errs, lineError(line, "%v", err))
+			continue
+		}
+		entries = append(entries, model.RosterEntry{
+			Line:     line,
+			TeamName: field("team_name"),
+			UserID:   field("user_id"),
+			Username: field("username"),
+			IsActive: active,
+			Role:     strings.ToLower(field("role")),
+		})
+	}
+	return entries, errs
+}
+
+// csvError переводит ошибку разбора CSV в ошибку строки
+func csvError(err error) model.ImportLineError {
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return lineError(pe.StartLine, "%v", pe.Err)
+	}
+	return lineError(0, "%v", err)
+}
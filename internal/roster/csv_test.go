@@ -0,0 +1,138 @@
+package roster
+
+import (
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/model"
+)
+
+func TestParseCSV(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []model.RosterEntry
+	}{
+		{"all columns", "team_name,user_id,username,is_active,role\n" +
+			"backend,1,alice,true,lead\n" +
+			"backend,2,bob,false,\n" +
+			"frontend,3,carol,,Member\n", []model.RosterEntry{
+			{Line: 2, TeamName: "backend", UserID: "1", Username: "alice", IsActive: true, Role: "lead"},
+			{Line: 3, TeamName: "backend", UserID: "2", Username: "bob", IsActive: false},
+			{Line: 4, TeamName: "frontend", UserID: "3", Username: "carol", IsActive: true, Role: "member"},
+		}},
+		{"aliases, order and spaces", "\ufeffName, Team , ID,Active\r\n" +
+			"alice, backend, 1, FALSE\r\n", []model.RosterEntry{
+			{Line: 2, TeamName: "backend", UserID: "1", Username: "alice", IsActive: false},
+		}},
+		{"short rows, comments and blank lines", "# выгрузка из HR\n" +
+			"team_name,user_id,username,is_active\n" +
+			"\n" +
+			"backend,1,alice\n" +
+			"# уволенные\n" +
+			"backend,2,bob,0\n", []model.RosterEntry{
+			{Line: 4, TeamName: "backend", UserID: "1", Username: "alice", IsActive: true},
+			{Line: 6, TeamName: "backend", UserID: "2", Username: "bob", IsActive: false},
+		}},
+		{"quoted fields", "team_name,user_id,username\n" +
+			"\"platform, core\",1,\"alice \"\"al\"\" smith\"\n" +
+			"backend,2,\"multi\nline\"\n" +
+			"backend,3,carol\n", []model.RosterEntry{
+			{Line: 2, TeamName: "platform, core", UserID: "1", Username: `alice "al" smith`, IsActive: true},
+			{Line: 3, TeamName: "backend", UserID: "2", Username: "multi\nline", IsActive: true},
+			{Line: 5, TeamName: "backend", UserID: "3", Username: "carol", IsActive: true},
+		}},
+		{"header only", "team_name,user_id,username\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := parseCSV([]byte(tt.in))
+			if len(errs) > 0 {
+				t.Fatalf("errors: %+v", errs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCSVErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		entries int
+		want    []model.ImportLineError
+	}{
+		{"empty", "", 0, []model.ImportLineError{{Line: 1, Message: "empty file: header row required"}}},
+		{"bad header", "# комментарий\nteam_name,user,user_id,ID\n", 0, []model.ImportLineError{
+			{Line: 2, Message: `unknown column "user"`},
+			{Line: 2, Message: `duplicate column "ID"`},
+			{Line: 2, Message: `missing required column "username"`},
+		}},
+		{"row errors", "team_name,user_id,username,is_active\n" +
+			"backend,1,alice,yes\n" +
+			"backend,2,bob,true,lead\n" +
+			"backend,3,carol,f\n", 1, []model.ImportLineError{
+			{Line: 2, Message: `is_active must be true or false, got "yes"`},
+			{Line: 3, Message: "expected 4 fields, got 5"},
+		}},
+		{"syntax error stops parsing", "team_name,user_id,username\n" +
+			"backend,1,alice\n" +
+			"backend,2,\"bob\n" +
+			"backend,3,carol\n", 1, []model.ImportLineError{
+			{Line: 3, Message: `extraneous or missing " in quoted-field`},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, errs := parseCSV([]byte(tt.in))
+			if len(entries) != tt.entries {
+				t.Fatalf("entries: got %+v, want %d", entries, tt.entries)
+			}
+			if !reflect.DeepEqual(errs, tt.want) {
+				t.Fatalf("errors:\n got %+v\nwant %+v", errs, tt.want)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	csvEntries, _, err := Parse(FormatCSV, []byte("team_name,user_id,username\nbackend,1,alice\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	yamlEntries, _, err := Parse(FormatYAML, []byte("teams:\n  - name: backend\n    members:\n      - user_id: 1\n        username: alice\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// строки отличаются только номером, по которому сообщаются ошибки
+	yamlEntries[0].Line = csvEntries[0].Line
+	if !reflect.DeepEqual(csvEntries, yamlEntries) {
+		t.Fatalf("csv %+v, yaml %+v", csvEntries, yamlEntries)
+	}
+	if _, _, err := Parse("json", nil); err != ErrUnknownFormat {
+		t.Fatalf("unknown format: got %v", err)
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"csv", FormatCSV},
+		{"YAML", FormatYAML},
+		{"yml", FormatYAML},
+		{"text/csv; charset=utf-8", FormatCSV},
+		{"application/x-yaml", FormatYAML},
+		{"roster.CSV", FormatCSV},
+		{"teams.yml", FormatYAML},
+		{"application/json", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat(tt.in); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package roster разбирает реестр команд и пользователей в форматах CSV и YAML для массового импорта.
+// YAML поддерживается в подмножестве, достаточном для реестра, и разбирается без сторонних библиотек.
+package roster
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"pr-reviewer/internal/model"
+)
+
+// Поддерживаемые форматы реестра
+const (
+	FormatCSV  = "csv"
+	FormatYAML = "yaml"
+)
+
+// ErrUnknownFormat — формат реестра не поддерживается
+var ErrUnknownFormat = errors.New("roster format must be csv or yaml")
+
+// Parse разбирает реестр в формате format. Ошибки в содержимом не прерывают разбор, а возвращаются
+// по строкам, чтобы вызывающий мог показать их все сразу; ошибкой возвращается только
+// неизвестный формат.
+func Parse(format string, data []byte) ([]model.RosterEntry, []model.ImportLineError, error) {
+	switch format {
+	case FormatCSV:
+		entries, errs := parseCSV(data)
+		return entries, errs, nil
+	case FormatYAML:
+		entries, errs := parseYAML(data)
+		return entries, errs, nil
+	}
+	return nil, nil, ErrUnknownFormat
+}
+
+// DetectFormat определяет формат по расширению файла или типу содержимого; пусто, если не удалось
+func DetectFormat(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if i := strings.IndexByte(name, ';'); i >= 0 {
+		name = strings.TrimSpace(name[:i])
+	}
+	switch name {
+	case "text/csv", "application/csv", FormatCSV:
+		return FormatCSV
+	case "application/yaml", "application/x-yaml", "text/yaml", "text/x-yaml", FormatYAML, "yml":
+		return FormatYAML
+	}
+	switch filepath.Ext(name) {
+	case ".csv":
+		return FormatCSV
+	case ".yaml", ".yml":
+		return FormatYAML
+	}
+	return ""
+}
+
+// parseActive разбирает флаг активности; пустое значение означает активного пользователя
+func parseActive(v string) (bool, error) {
+	if v == "" {
+		return true, nil
+	}
+	b, err := strconv.ParseBool(strings.ToLower(v))
+	if err != nil {
+		return false, fmt.Errorf("is_active must be true or false, got %q", v)
+	}
+	return b, nil
+}
+
+// lineError формирует ошибку строки
+func lineError(line int, format string, args ...interface{}) model.ImportLineError {
+	return model.ImportLineError{Line: line, Message: fmt.Sprintf(format, args...)}
+}
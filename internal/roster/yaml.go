@@ -0,0 +1,419 @@
+package roster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pr-reviewer/internal/model"
+)
+
+// Реестр в YAML:
+//
+//	teams:
+//	  - name: backend
+//	    members:
+//	      - user_id: "1"
+//	        username: alice
+//	        is_active: true
+//	        role: lead
+//
+// Поддерживается блочный стиль: отображения, последовательности, скаляры без кавычек и
+// в одинарных или двойных кавычках, комментарии. Потоковый стиль ([...], {...}), якоря,
+// теги и многострочные скаляры не поддерживаются.
+
+// yamlLine — значимая строка YAML-документа
+type yamlLine struct {
+	num    int    // номер строки в файле
+	indent int    // отступ в пробелах
+	text   string // содержимое без отступа и комментария
+}
+
+// Виды узлов YAML
+const (
+	yamlScalar = iota
+	yamlMapping
+	yamlSequence
+)
+
+// yamlNode — узел разобранного документа
+type yamlNode struct {
+	kind  int
+	line  int
+	value string               // для скаляра
+	keys  []string             // ключи отображения в порядке появления
+	props map[string]*yamlNode // значения отображения
+	items []*yamlNode          // элементы последовательности
+}
+
+// yamlError — ошибка разбора в строке line
+type yamlError struct {
+	line int
+	msg  string
+}
+
+func (e *yamlError) Error() string { return fmt.Sprintf("line %d: %s", e.line, e.msg) }
+
+// parseYAML разбирает реестр в YAML
+func parseYAML(data []byte) ([]model.RosterEntry, []model.ImportLineError) {
+	root, err := parseYAMLDocument(string(data))
+	if err != nil {
+		return nil, []model.ImportLineError{lineError(err.line, "%s", err.msg)}
+	}
+	if root == nil {
+		return nil, []model.ImportLineError{lineError(1, "empty file: teams required")}
+	}
+
+	var errs []model.ImportLineError
+	if root.kind != yamlMapping {
+		return nil, []model.ImportLineError{lineError(root.line, "top level must be a mapping with teams")}
+	}
+	for _, k := range root.keys {
+		if k != "teams" {
+			errs = append(errs, lineError(root.props[k].line, "unknown key %q", k))
+		}
+	}
+	teams := root.props["teams"]
+	if teams == nil || teams.kind != yamlSequence {
+		return nil, append(errs, lineError(root.line, "teams must be a list"))
+	}
+
+	var entries []model.RosterEntry
+	for _, t := range teams.items {
+		if t.kind != yamlMapping {
+			errs = append(errs, lineError(t.line, "team must be a mapping with name and members"))
+			continue
+		}
+		name, teamErrs := scalarField(t, "name", "team_name")
+		errs = append(errs, teamErrs...)
+		for _, k := range t.keys {
+			if k != "name" && k != "team_name" && k != "members" {
+				errs = append(errs, lineError(t.props[k].line, "unknown key %q", k))
+			}
+		}
+		members := t.props["members"]
+		if members == nil {
+			continue
+		}
+		if members.kind != yamlSequence {
+			errs = append(errs, lineError(members.line, "members must be a list"))
+			continue
+		}
+		for _, m := range members.items {
+			entry, memberErrs := yamlMember(name, m)
+			if len(memberErrs) > 0 {
+				errs = append(errs, memberErrs...)
+				continue
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, errs
+}
+
+// yamlMember превращает элемент members в строку реестра
+func yamlMember(team string, m *yamlNode) (model.RosterEntry, []model.ImportLineError) {
+	if m.kind != yamlMapping {
+		return model.RosterEntry{}, []model.ImportLineError{lineError(m.line, "member must be a mapping")}
+	}
+	var errs []model.ImportLineError
+	field := func(names ...string) string {
+		v, fieldErrs := scalarField(m, names...)
+		errs = append(errs, fieldErrs...)
+		return v
+	}
+	entry := model.RosterEntry{
+		Line:     m.line,
+		TeamName: team,
+		UserID:   field("user_id", "id"),
+		Username: field("username", "name"),
+		Role:     strings.ToLower(field("role", "roles")),
+	}
+	active, err := parseActive(field("is_active", "active"))
+	if err != nil {
+		errs = append(errs, lineError(m.line, "%v", err))
+	}
+	entry.IsActive = active
+	for _, k := range m.keys {
+		if _, ok := csvColumns[k]; !ok {
+			errs = append(errs, lineError(m.props[k].line, "unknown key %q", k))
+		}
+	}
+	return entry, errs
+}
+
+// scalarField возвращает скалярное значение первого из ключей names, заданного в отображении
+func scalarField(n *yamlNode, names ...string) (string, []model.ImportLineError) {
+	for _, name := range names {
+		v, ok := n.props[name]
+		if !ok {
+			continue
+		}
+		if v.kind != yamlScalar {
+			return "", []model.ImportLineError{lineError(v.line, "%s must be a scalar", name)}
+		}
+		return v.value, nil
+	}
+	return "", nil
+}
+
+// parseYAMLDocument разбирает документ в дерево узлов; nil — пустой документ
+func parseYAMLDocument(src string) (*yamlNode, *yamlError) {
+	lines, err := splitYAMLLines(src)
+	if err != nil {
+		return nil, err
+	}
+	if len(lines) == 0 {
+		return nil, nil
+	}
+	p := &yamlParser{lines: lines}
+	node, err := p.parseBlock(lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		return nil, &yamlError{line: l.num, msg: "unexpected indentation"}
+	}
+	return node, nil
+}
+
+// splitYAMLLines отбрасывает пустые строки, комментарии и маркеры документа и вычисляет отступы
+func splitYAMLLines(src string) ([]yamlLine, *yamlError) {
+	src = strings.TrimPrefix(src, "\ufeff")
+	var lines []yamlLine
+	for i, raw := range strings.Split(src, "\n") {
+		num := i + 1
+		raw = strings.TrimRight(raw, "\r")
+		text := strings.TrimLeft(raw, " ")
+		indent := len(raw) - len(text)
+		if strings.HasPrefix(text, "\t") {
+			return nil, &yamlError{line: num, msg: "tabs are not allowed for indentation"}
+		}
+		text = strings.TrimRight(stripComment(text), " \t")
+		if text == "" {
+			continue
+		}
+		if indent == 0 && (text == "---" || text == "...") {
+			if len(lines) > 0 && text == "---" {
+				return nil, &yamlError{line: num, msg: "multiple documents are not supported"}
+			}
+			continue
+		}
+		lines = append(lines, yamlLine{num: num, indent: indent, text: text})
+	}
+	return lines, nil
+}
+
+// stripComment убирает комментарий: # в начале строки или после пробела, вне кавычек
+func stripComment(s string) string {
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			// \" в двойных и '' в одинарных кавычках не закрывают строку
+			if c == '\\' && quote == '"' || c == '\'' && quote == '\'' && i+1 < len(s) && s[i+1] == '\'' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"':
+			if i == 0 || s[i-1] == ' ' || s[i-1] == ':' || s[i-1] == '-' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || s[i-1] == ' ' || s[i-1] == '\t'):
+			return s[:i]
+		}
+	}
+	return s
+}
+
+// yamlParser — рекурсивный разбор блочного YAML по отступам
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+// parseBlock разбирает узел, строки которого начинаются с отступом indent
+func (p *yamlParser) parseBlock(indent int) (*yamlNode, *yamlError) {
+	l := p.lines[p.pos]
+	if isSeqItem(l.text) {
+		return p.parseSequence(indent)
+	}
+	if _, _, ok := splitKey(l.text); ok {
+		return p.parseMapping(indent)
+	}
+	p.pos++
+	value, err := parseScalar(l.text, l.num)
+	if err != nil {
+		return nil, err
+	}
+	return &yamlNode{kind: yamlScalar, line: l.num, value: value}, nil
+}
+
+// parseSequence разбирает последовательность элементов «- » с отступом indent
+func (p *yamlParser) parseSequence(indent int) (*yamlNode, *yamlError) {
+	node := &yamlNode{kind: yamlSequence, line: p.lines[p.pos].num}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent != indent || !isSeqItem(l.text) {
+			break
+		}
+		rest := strings.TrimLeft(l.text[1:], " ")
+		if rest == "" {
+			// содержимое элемента — вложенный блок на следующих строках
+			p.pos++
+			if p.pos >= len(p.lines) || p.lines[p.pos].indent <= indent {
+				node.items = append(node.items, &yamlNode{kind: yamlScalar, line: l.num})
+				continue
+			}
+			item, err := p.parseBlock(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, item)
+			continue
+		}
+		// «- key: value»: содержимое элемента начинается в той же строке, его отступ — позиция после «- »
+		p.lines[p.pos] = yamlLine{num: l.num, indent: indent + len(l.text) - len(rest), text: rest}
+		item, err := p.parseBlock(p.lines[p.pos].indent)
+		if err != nil {
+			return nil, err
+		}
+		node.items = append(node.items, item)
+	}
+	return node, nil
+}
+
+// parseMapping разбирает отображение «key: value» с отступом indent
+func (p *yamlParser) parseMapping(indent int) (*yamlNode, *yamlError) {
+	node := &yamlNode{kind: yamlMapping, line: p.lines[p.pos].num, props: make(map[string]*yamlNode)}
+	for p.pos < len(p.lines) {
+		l := p.lines[p.pos]
+		if l.indent < indent {
+			break
+		}
+		if l.indent > indent {
+			return nil, &yamlError{line: l.num, msg: "unexpected indentation"}
+		}
+		if isSeqItem(l.text) {
+			break
+		}
+		key, rest, ok := splitKey(l.text)
+		if !ok {
+			return nil, &yamlError{line: l.num, msg: "expected key: value"}
+		}
+		if _, dup := node.props[key]; dup {
+			return nil, &yamlError{line: l.num, msg: fmt.Sprintf("duplicate key %q", key)}
+		}
+		p.pos++
+
+		var value *yamlNode
+		switch {
+		case rest != "":
+			s, err := parseScalar(rest, l.num)
+			if err != nil {
+				return nil, err
+			}
+			value = &yamlNode{kind: yamlScalar, line: l.num, value: s}
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			var err *yamlError
+			if value, err = p.parseBlock(p.lines[p.pos].indent); err != nil {
+				return nil, err
+			}
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isSeqItem(p.lines[p.pos].text):
+			// последовательность может стоять на том же отступе, что и её ключ
+			var err *yamlError
+			if value, err = p.parseSequence(indent); err != nil {
+				return nil, err
+			}
+		default:
+			value = &yamlNode{kind: yamlScalar, line: l.num}
+		}
+		node.keys = append(node.keys, key)
+		node.props[key] = value
+	}
+	return node, nil
+}
+
+// isSeqItem сообщает, что строка — элемент последовательности
+func isSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitKey делит «key: value» на ключ и значение; ключ может быть в кавычках
+func splitKey(text string) (string, string, bool) {
+	if text[0] == '{' || text[0] == '[' {
+		return "", "", false
+	}
+	if text[0] == '"' || text[0] == '\'' {
+		end := closingQuote(text)
+		if end < 0 || end+1 >= len(text) || text[end+1] != ':' {
+			return "", "", false
+		}
+		rest := text[end+2:]
+		if rest != "" && rest[0] != ' ' {
+			return "", "", false
+		}
+		key, err := parseScalar(text[:end+1], 0)
+		if err != nil {
+			return "", "", false
+		}
+		return key, strings.TrimSpace(rest), true
+	}
+	for i := 0; i < len(text); i++ {
+		if text[i] == ':' && (i+1 == len(text) || text[i+1] == ' ') {
+			key := strings.TrimSpace(text[:i])
+			if key == "" {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+// closingQuote возвращает позицию закрывающей кавычки строки, начинающейся с кавычки; -1, если её нет
+func closingQuote(text string) int {
+	q := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case q == '"' && text[i] == '\\':
+			i++
+		case q == '\'' && text[i] == '\'' && i+1 < len(text) && text[i+1] == '\'':
+			i++
+		case text[i] == q:
+			return i
+		}
+	}
+	return -1
+}
+
+// parseScalar разбирает скаляр: в двойных кавычках с экранированием, в одинарных или без кавычек
+func parseScalar(text string, line int) (string, *yamlError) {
+	switch text[0] {
+	case '"':
+		if closingQuote(text) != len(text)-1 {
+			return "", &yamlError{line: line, msg: "unterminated or trailing text after quoted string"}
+		}
+		s, err := strconv.Unquote(text)
+		if err != nil {
+			return "", &yamlError{line: line, msg: "invalid escape in quoted string"}
+		}
+		return s, nil
+	case '\'':
+		if closingQuote(text) != len(text)-1 {
+			return "", &yamlError{line: line, msg: "unterminated or trailing text after quoted string"}
+		}
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	case '[', '{':
+		return "", &yamlError{line: line, msg: "flow collections are not supported"}
+	case '&', '*', '!', '|', '>':
+		return "", &yamlError{line: line, msg: "anchors, tags and block scalars are not supported"}
+	}
+	if text == "~" || text == "null" {
+		return "", nil
+	}
+	return text, nil
+}
@@ -0,0 +1,156 @@
+package roster
+
+import (
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/model"
+)
+
+func TestParseYAML(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []model.RosterEntry
+	}{
+		{"nested lists", `teams:
+  - name: backend
+    members:
+      - user_id: "1"
+        username: alice
+        role: lead
+      - user_id: 2
+        username: bob
+        is_active: false
+
+  - name: frontend
+    members:
+      - user_id: 3
+        username: carol
+`, []model.RosterEntry{
+			{Line: 4, TeamName: "backend", UserID: "1", Username: "alice", IsActive: true, Role: "lead"},
+			{Line: 7, TeamName: "backend", UserID: "2", Username: "bob", IsActive: false},
+			{Line: 13, TeamName: "frontend", UserID: "3", Username: "carol", IsActive: true},
+		}},
+		{"sequence at key indent and aliases", `teams:
+- team_name: backend
+  members:
+  - id: 1
+    name: alice
+    active: "False"
+    roles: Member
+`, []model.RosterEntry{
+			{Line: 4, TeamName: "backend", UserID: "1", Username: "alice", IsActive: false, Role: "member"},
+		}},
+		{"item on its own line", `teams:
+  -
+    name: backend
+    members:
+      -
+        user_id: 1
+        username: alice
+`, []model.RosterEntry{
+			{Line: 6, TeamName: "backend", UserID: "1", Username: "alice", IsActive: true},
+		}},
+		{"quoting", `"teams":
+  - name: 'it''s # not a comment'
+    members:
+      - 'user_id': '1'
+        username: "al\"ice #1é"
+        role: LEAD
+`, []model.RosterEntry{
+			{Line: 4, TeamName: "it's # not a comment", UserID: "1", Username: "al\"ice #1é", IsActive: true, Role: "lead"},
+		}},
+		{"comments, null and document markers", `---
+# реестр команд
+teams: # все команды
+
+  - name: backend   # основная
+    # участники
+    members:
+      - user_id: 1  # alice
+        username: alice#1
+        is_active: ~
+      - user_id: 2
+        username: bob
+        is_active: null
+...
+`, []model.RosterEntry{
+			{Line: 8, TeamName: "backend", UserID: "1", Username: "alice#1", IsActive: true},
+			{Line: 11, TeamName: "backend", UserID: "2", Username: "bob", IsActive: true},
+		}},
+		{"CRLF and BOM", "\ufeffteams:\r\n  - name: backend\r\n    members:\r\n      - user_id: 1\r\n        username: alice\r\n", []model.RosterEntry{
+			{Line: 4, TeamName: "backend", UserID: "1", Username: "alice", IsActive: true},
+		}},
+		{"team without members", "teams:\n  - name: backend\n", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, errs := parseYAML([]byte(tt.in))
+			if len(errs) > 0 {
+				t.Fatalf("errors: %+v", errs)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want []model.ImportLineError
+	}{
+		{"empty", "# только комментарий\n\n", []model.ImportLineError{{Line: 1, Message: "empty file: teams required"}}},
+		{"top level list", "- teams\n", []model.ImportLineError{{Line: 1, Message: "top level must be a mapping with teams"}}},
+		{"teams not a list", "teams: backend\n", []model.ImportLineError{{Line: 1, Message: "teams must be a list"}}},
+		{"unknown top level key", "version: 1\nteams:\n  - name: backend\n", []model.ImportLineError{{Line: 1, Message: `unknown key "version"`}}},
+		{"tab indentation", "teams:\n\t- name: backend\n", []model.ImportLineError{{Line: 2, Message: "tabs are not allowed for indentation"}}},
+		{"multiple documents", "teams: []\n---\nteams: []\n", []model.ImportLineError{{Line: 2, Message: "multiple documents are not supported"}}},
+		{"flow collection", "teams: [backend]\n", []model.ImportLineError{{Line: 1, Message: "flow collections are not supported"}}},
+		{"anchor", "teams:\n  - name: &b backend\n", []model.ImportLineError{{Line: 2, Message: "anchors, tags and block scalars are not supported"}}},
+		{"block scalar", "teams:\n  - name: |\n      backend\n", []model.ImportLineError{{Line: 2, Message: "anchors, tags and block scalars are not supported"}}},
+		{"unterminated quote", "teams:\n  - name: \"backend\n", []model.ImportLineError{{Line: 2, Message: "unterminated or trailing text after quoted string"}}},
+		{"trailing text after quote", "teams:\n  - name: 'back'end\n", []model.ImportLineError{{Line: 2, Message: "unterminated or trailing text after quoted string"}}},
+		{"invalid escape", "teams:\n  - name: \"back\\qend\"\n", []model.ImportLineError{{Line: 2, Message: "invalid escape in quoted string"}}},
+		{"duplicate key", "teams:\n  - name: backend\n    name: frontend\n", []model.ImportLineError{{Line: 3, Message: `duplicate key "name"`}}},
+		{"unexpected indentation", "teams:\n  - name: backend\n      members:\n", []model.ImportLineError{{Line: 3, Message: "unexpected indentation"}}},
+		{"not a key", "teams:\n  - name: backend\n    members\n", []model.ImportLineError{{Line: 3, Message: "expected key: value"}}},
+		{"member errors keep line numbers", `teams:
+  - name: backend
+    owner: alice
+    members:
+      - user_id: 1
+        username: alice
+        is_active: maybe
+      - user_id: 2
+        username:
+          first: bob
+        email: bob@example.org
+      - carol
+      - user_id: 4
+        username: dave
+  - backend
+  - name: frontend
+    members: dave
+`, []model.ImportLineError{
+			{Line: 3, Message: `unknown key "owner"`},
+			{Line: 5, Message: `is_active must be true or false, got "maybe"`},
+			{Line: 10, Message: "username must be a scalar"},
+			{Line: 11, Message: `unknown key "email"`},
+			{Line: 12, Message: "member must be a mapping"},
+			{Line: 15, Message: "team must be a mapping with name and members"},
+			{Line: 17, Message: "members must be a list"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errs := parseYAML([]byte(tt.in))
+			if !reflect.DeepEqual(errs, tt.want) {
+				t.Fatalf("errors:\n got %+v\nwant %+v", errs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// Ошибки выгрузки и восстановления
+var (
+	ErrInvalidArchive  = errors.New("invalid archive")
+	ErrRestoreNotEmpty = errors.New("organization already has teams, users or pull requests")
+)
+
+// maxArchiveProblems ограничивает число проблем архива в сообщении об ошибке
+const maxArchiveProblems = 20
+
+// ArchiveService выгружает данные организации в архив и восстанавливает их из него
+type ArchiveService struct {
+	uow repository.UnitOfWork
+	now Clock
+}
+
+// NewArchiveService создаёт новый ArchiveService
+func NewArchiveService(uow repository.UnitOfWork) *ArchiveService {
+	return &ArchiveService{uow: uow, now: time.Now}
+}
+
+// Export выгружает команды, пользователей с членствами, PR с ревьюверами, историей и отказами
+// организации из ctx. API-токены, журнал аудита и ключи идемпотентности не выгружаются.
+func (s *ArchiveService) Export(ctx context.Context) (*model.Archive, error) {
+	tenant := repository.TenantFrom(ctx)
+	archive := &model.Archive{
+		Format:     model.ArchiveFormat,
+		Version:    model.ArchiveVersion,
+		ExportedAt: s.now().UTC(),
+		TenantID:   tenant,
+	}
+	// выгружаем из одного снимка, чтобы PR не ссылались на пользователей и команды, которых нет в архиве
+	err := s.uow.View(ctx, func(repos repository.Repos) error {
+		data, err := exportData(repos, tenant)
+		if err != nil {
+			return err
+		}
+		archive.Data = *data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if archive.Checksum, err = archiveChecksum(&archive.Data); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// exportData собирает содержимое архива
+func exportData(repos repository.Repos, tenant string) (*model.ArchiveData, error) {
+	data := &model.ArchiveData{
+		Teams:        []model.ArchiveTeam{},
+		Users:        []model.ArchiveUser{},
+		PullRequests: []model.ArchivePR{},
+	}
+	switch org, err := repos.Orgs.Get(tenant); err {
+	case nil:
+		data.Settings = org.Settings
+	case repository.ErrNotFound:
+	default:
+		return nil, err
+	}
+
+	for cursor := ""; ; {
+		page, next, err := repos.Teams.List(maxPageSize, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, summary := range page {
+			team, err := repos.Teams.GetByName(summary.Name)
+			if err != nil {
+				return nil, err
+			}
+			data.Teams = append(data.Teams, model.ArchiveTeam{Name: team.Name, Parent: team.Parent, Settings: team.Settings})
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	ordered, cyclic := orderTeams(data.Teams)
+	if len(cyclic) > 0 {
+		return nil, fmt.Errorf("team hierarchy has a cycle: %s", strings.Join(cyclic, ", "))
+	}
+	data.Teams = ordered
+
+	users, err := repos.Users.List()
+	if err != nil {
+		return nil, err
+	}
+	var declines []model.ReviewDecline
+	for _, u := range users {
+		teams := make([]model.TeamMembership, 0, len(u.Teams))
+		for _, m := range u.Teams {
+			m.UserID = ""
+			teams = append(teams, m)
+		}
+		skills := u.Skills
+		if skills == nil {
+			skills = []string{}
+		}
+		data.Users = append(data.Users, model.ArchiveUser{
+			ID:               u.ID,
+			Username:         u.Username,
+			IsActive:         u.IsActive,
+			Skills:           skills,
+			OutOfOfficeUntil: u.OutOfOfficeUntil,
+			Teams:            teams,
+		})
+		data.Counts.Memberships += len(teams)
+
+		userDeclines, err := repos.Declines.ListByUser(strconv.FormatInt(u.ID, 10), time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		declines = append(declines, userDeclines...)
+	}
+	sort.SliceStable(declines, func(i, j int) bool {
+		if !declines[i].CreatedAt.Equal(declines[j].CreatedAt) {
+			return declines[i].CreatedAt.Before(declines[j].CreatedAt)
+		}
+		return declines[i].ID < declines[j].ID
+	})
+	declinesByPR := make(map[string][]model.ArchiveDecline)
+	for _, d := range declines {
+		declinesByPR[d.PRID] = append(declinesByPR[d.PRID], model.ArchiveDecline{
+			UserID:     d.UserID,
+			Reason:     d.Reason,
+			Comment:    d.Comment,
+			ReplacedBy: d.ReplacedBy,
+			CreatedAt:  d.CreatedAt,
+		})
+	}
+
+	filter := repository.PRFilter{SortBy: repository.PRSortCreatedAt, Limit: maxPageSize}
+	for {
+		prs, next, err := repos.PRs.List(filter)
+		if err != nil {
+			return nil, err
+		}
+		for _, pr := range prs {
+			events, err := repos.History.ListByPR(pr.ID)
+			if err != nil {
+				return nil, err
+			}
+			history := make([]model.ArchiveEvent, 0, len(events))
+			for _, e := range events {
+				history = append(history, model.ArchiveEvent{Event: e.Event, Assignment: e.Assignment, Data: e.Data, CreatedAt: e.CreatedAt})
+			}
+			prDeclines := declinesByPR[pr.ID]
+			if prDeclines == nil {
+				prDeclines = []model.ArchiveDecline{}
+			}
+			data.PullRequests = append(data.PullRequests, model.ArchivePR{PullRequest: pr, History: history, Declines: prDeclines})
+			data.Counts.Events += len(history)
+			data.Counts.Declines += len(prDeclines)
+		}
+		if next == "" {
+			break
+		}
+		filter.Cursor = next
+	}
+
+	data.Counts.Teams = len(data.Teams)
+	data.Counts.Users = len(data.Users)
+	data.Counts.PullRequests = len(data.PullRequests)
+	return data, nil
+}
+
+// Restore восстанавливает архив в организацию из ctx в одной транзакции. Организация должна быть
+// пустой (ErrRestoreNotEmpty): без команд, пользователей и PR. Перед записью архив проверяется
+// целиком (ErrInvalidArchive): формат и версия, контрольная сумма, число записей и ссылочная
+// целостность — родители команд, команды членств, авторы, ревьюверы и отказавшиеся пользователи PR.
+func (s *ArchiveService) Restore(ctx context.Context, archive *model.Archive) (*model.ArchiveCounts, error) {
+	teams, problems := validateArchive(archive)
+	if len(problems) > 0 {
+		if len(problems) > maxArchiveProblems {
+			problems = append(problems[:maxArchiveProblems], fmt.Sprintf("and %d more", len(problems)-maxArchiveProblems))
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidArchive, strings.Join(problems, "; "))
+	}
+	data := &archive.Data
+
+	err := s.uow.Do(ctx, func(repos repository.Repos) error {
+		if err := ensureEmpty(repos); err != nil {
+			return err
+		}
+		tenant := repository.TenantFrom(ctx)
+		if data.Settings != (model.OrgSettings{}) {
+			if _, err := repos.Orgs.UpdateSettings(tenant, data.Settings); err != nil {
+				return err
+			}
+		}
+
+		for _, t := range teams {
+			if err := repos.Teams.Create(&model.Team{Name: t.Name, Parent: t.Parent, Settings: t.Settings}); err != nil {
+				return err
+			}
+		}
+		for _, u := range data.Users {
+			id := strconv.FormatInt(u.ID, 10)
+			if err := repos.Users.CreateOrUpdate(&model.User{ID: u.ID, Username: u.Username, IsActive: u.IsActive}); err != nil {
+				return err
+			}
+			if len(u.Skills) > 0 {
+				if _, err := repos.Users.SetSkills(id, u.Skills); err != nil {
+					return err
+				}
+			}
+			if u.OutOfOfficeUntil != nil {
+				if _, err := repos.Users.SetOutOfOffice(id, u.OutOfOfficeUntil); err != nil {
+					return err
+				}
+			}
+			for _, m := range u.Teams {
+				m.UserID = id
+				if err := repos.Memberships.Upsert(&m); err != nil {
+					return err
+				}
+			}
+		}
+		for _, apr := range data.PullRequests {
+			pr := apr.PullRequest
+			if err := repos.PRs.Restore(&pr); err != nil {
+				return err
+			}
+			for _, e := range apr.History {
+				if err := repos.History.Add(&model.PREvent{PRID: pr.ID, Event: e.Event, Assignment: e.Assignment, Data: e.Data, CreatedAt: e.CreatedAt}); err != nil {
+					return err
+				}
+			}
+			for _, d := range apr.Declines {
+				if err := repos.Declines.Add(&model.ReviewDecline{PRID: pr.ID, UserID: d.UserID, Reason: d.Reason, Comment: d.Comment, ReplacedBy: d.ReplacedBy, CreatedAt: d.CreatedAt}); err != nil {
+					return err
+				}
+			}
+		}
+		return recordAudit(ctx, repos, model.AuditDataRestored, model.AuditTargetOrg, tenant, map[string]string{
+			"source_tenant": archive.TenantID,
+			"exported_at":   archive.ExportedAt.Format(time.RFC3339),
+			"teams":         strconv.Itoa(data.Counts.Teams),
+			"users":         strconv.Itoa(data.Counts.Users),
+			"pull_requests": strconv.Itoa(data.Counts.PullRequests),
+		}, s.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	counts := data.Counts
+	return &counts, nil
+}
+
+// ensureEmpty проверяет, что в организации нет команд, пользователей и PR
+func ensureEmpty(repos repository.Repos) error {
+	teams, _, err := repos.Teams.List(1, "")
+	if err != nil {
+		return err
+	}
+	users, err := repos.Users.List()
+	if err != nil {
+		return err
+	}
+	prs, _, err := repos.PRs.List(repository.PRFilter{SortBy: repository.PRSortCreatedAt, Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(teams) > 0 || len(users) > 0 || len(prs) > 0 {
+		return ErrRestoreNotEmpty
+	}
+	return nil
+}
+
+// archiveChecksum возвращает SHA-256 от JSON-представления содержимого архива
+func archiveChecksum(data *model.ArchiveData) (string, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// orderTeams упорядочивает команды так, чтобы родитель шёл раньше дочерних, сохраняя исходный
+// порядок среди готовых к созданию. Команды, попавшие в цикл, возвращаются вторым значением.
+func orderTeams(teams []model.ArchiveTeam) ([]model.ArchiveTeam, []string) {
+	placed := make(map[string]bool, len(teams))
+	known := make(map[string]bool, len(teams))
+	for _, t := range teams {
+		known[t.Name] = true
+	}
+	ordered := make([]model.ArchiveTeam, 0, len(teams))
+	pending := teams
+	for len(pending) > 0 {
+		var rest []model.ArchiveTeam
+		for _, t := range pending {
+			if t.Parent == "" || placed[t.Parent] || !known[t.Parent] {
+				ordered = append(ordered, t)
+				placed[t.Name] = true
+			} else {
+				rest = append(rest, t)
+			}
+		}
+		if len(rest) == len(pending) {
+			cyclic := make([]string, 0, len(rest))
+			for _, t := range rest {
+				cyclic = append(cyclic, t.Name)
+			}
+			return ordered, cyclic
+		}
+		pending = rest
+	}
+	return ordered, nil
+}
+
+// validateArchive проверяет архив без обращения к хранилищу и возвращает команды в порядке
+// создания и список найденных проблем
+func validateArchive(a *model.Archive) ([]model.ArchiveTeam, []string) {
+	var problems []string
+	fail := func(format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, args...))
+	}
+	if a.Format != model.ArchiveFormat {
+		return nil, []string{fmt.Sprintf("format must be %q, got %q", model.ArchiveFormat, a.Format)}
+	}
+	if a.Version != model.ArchiveVersion {
+		return nil, []string{fmt.Sprintf("unsupported archive version %d, want %d", a.Version, model.ArchiveVersion)}
+	}
+	data := &a.Data
+	if sum, err := archiveChecksum(data); err != nil || sum != a.Checksum {
+		fail("checksum mismatch: archive is damaged or was edited")
+	}
+
+	counts := model.ArchiveCounts{Teams: len(data.Teams), Users: len(data.Users), PullRequests: len(data.PullRequests)}
+	if data.Settings.Strategy != "" && !ValidStrategy(data.Settings.Strategy) {
+		fail("organization: %v: %s", ErrUnknownStrategy, data.Settings.Strategy)
+	}
+	if data.Settings.MergePolicy != "" && !ValidMergePolicy(data.Settings.MergePolicy) {
+		fail("organization: %v: %s", ErrUnknownMergePolicy, data.Settings.MergePolicy)
+	}
+
+	teams := make(map[string]bool, len(data.Teams))
+	for _, t := range data.Teams {
+		switch {
+		case t.Name == "":
+			fail("team with empty name")
+		case teams[t.Name]:
+			fail("team %s: duplicate", t.Name)
+		}
+		teams[t.Name] = true
+		if t.Settings.Strategy != "" && !ValidStrategy(t.Settings.Strategy) {
+			fail("team %s: %v: %s", t.Name, ErrUnknownStrategy, t.Settings.Strategy)
+		}
+		if t.Settings.MergePolicy != "" && !ValidMergePolicy(t.Settings.MergePolicy) {
+			fail("team %s: %v: %s", t.Name, ErrUnknownMergePolicy, t.Settings.MergePolicy)
+		}
+	}
+	for _, t := range data.Teams {
+		if t.Parent != "" && !teams[t.Parent] {
+			fail("team %s: parent team %s is missing", t.Name, t.Parent)
+		}
+	}
+	ordered, cyclic := orderTeams(data.Teams)
+	if len(cyclic) > 0 {
+		fail("team hierarchy has a cycle: %s", strings.Join(cyclic, ", "))
+	}
+
+	users := make(map[string]bool, len(data.Users))
+	for _, u := range data.Users {
+		id := strconv.FormatInt(u.ID, 10)
+		if users[id] {
+			fail("user %s: duplicate", id)
+		}
+		users[id] = true
+		if u.Username == "" {
+			fail("user %s: empty username", id)
+		}
+		seen := make(map[string]bool, len(u.Teams))
+		for _, m := range u.Teams {
+			switch {
+			case !teams[m.TeamName]:
+				fail("user %s: team %s is missing", id, m.TeamName)
+			case seen[m.TeamName]:
+				fail("user %s: duplicate membership in team %s", id, m.TeamName)
+			}
+			seen[m.TeamName] = true
+			if !model.ValidMembershipWeight(m.Weight) {
+				fail("user %s: team %s: %v", id, m.TeamName, ErrInvalidWeight)
+			}
+			if !model.ValidTeamRole(m.Role) {
+				fail("user %s: team %s: %v", id, m.TeamName, ErrInvalidTeamRole)
+			}
+		}
+		counts.Memberships += len(u.Teams)
+	}
+
+	prs := make(map[string]bool, len(data.PullRequests))
+	for _, pr := range data.PullRequests {
+		switch {
+		case pr.ID == "":
+			fail("pull request with empty ID")
+		case prs[pr.ID]:
+			fail("pull request %s: duplicate", pr.ID)
+		}
+		prs[pr.ID] = true
+		if !users[pr.AuthorID] {
+			fail("pull request %s: author %s is missing", pr.ID, pr.AuthorID)
+		}
+		switch pr.Status {
+		case "OPEN":
+		case "MERGED":
+			if pr.MergedAt == nil {
+				fail("pull request %s: merged without mergedAt", pr.ID)
+			}
+		default:
+			fail("pull request %s: unknown status %q", pr.ID, pr.Status)
+		}
+		if pr.Version < 1 {
+			fail("pull request %s: version must be positive", pr.ID)
+		}
+		reviewers := make(map[string]bool, len(pr.AssignedReviewers))
+		for _, r := range pr.AssignedReviewers {
+			switch {
+			case !users[r]:
+				fail("pull request %s: reviewer %s is missing", pr.ID, r)
+			case reviewers[r]:
+				fail("pull request %s: reviewer %s assigned twice", pr.ID, r)
+			}
+			reviewers[r] = true
+		}
+		for _, e := range pr.History {
+			if e.Event == "" {
+				fail("pull request %s: history event without type", pr.ID)
+			}
+		}
+		for _, d := range pr.Declines {
+			if !users[d.UserID] {
+				fail("pull request %s: declined by missing user %s", pr.ID, d.UserID)
+			}
+			if !model.ValidDeclineReason(d.Reason) {
+				fail("pull request %s: unknown decline reason %q", pr.ID, d.Reason)
+			}
+		}
+		counts.Events += len(pr.History)
+		counts.Declines += len(pr.Declines)
+	}
+
+	if counts != data.Counts {
+		fail("counts do not match contents: archive says %+v, found %+v", data.Counts, counts)
+	}
+	return ordered, problems
+}
@@ -0,0 +1,46 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// systemActor записывается в журнал, если действие выполнено без вызывающего (фоновые задачи)
+const systemActor = "system"
+
+// recordAudit пишет в журнал действие вызывающего из ctx в рамках транзакции repos
+func recordAudit(ctx context.Context, repos repository.Repos, action, targetType, targetID string, data map[string]string, at time.Time) error {
+	e := &model.AuditEvent{
+		Action:     action,
+		Actor:      systemActor,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Data:       data,
+		CreatedAt:  at,
+	}
+	if p := PrincipalFrom(ctx); p != nil {
+		e.Actor = p.Actor()
+		e.ActorRole = p.Role
+		e.AuthMethod = p.AuthMethod
+	}
+	return repos.Audit.Add(e)
+}
+
+// AuditService отдаёт журнал аудита
+type AuditService struct {
+	uow repository.UnitOfWork
+}
+
+// NewAuditService создаёт новый AuditService
+func NewAuditService(uow repository.UnitOfWork) *AuditService {
+	return &AuditService{uow: uow}
+}
+
+// List возвращает записи журнала от новых к старым
+func (s *AuditService) List(ctx context.Context, f repository.AuditFilter) ([]model.AuditEvent, error) {
+	f.Limit = pageLimit(f.Limit)
+	return s.uow.Repos(ctx).Audit.List(f)
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"pr-reviewer/internal/jwt"
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+var (
+	ErrUnauthenticated = errors.New("missing or invalid credentials")
+	ErrInvalidRole     = errors.New("invalid role")
+	ErrTokenScope      = errors.New("team-lead tokens need team_name, member tokens need user_id")
+	ErrTokenNotFound   = errors.New("token not found")
+)
+
+// tokenPrefix помогает узнать токен сервиса в логах и сканерах секретов
+const tokenPrefix = "prr_"
+
+// AuthService выдаёт, отзывает и проверяет API-токены и JWT
+type AuthService struct {
+	uow       repository.UnitOfWork
+	adminHash []byte
+	now       Clock
+
+	jwt        *jwt.Verifier
+	jwtMapping JWTMapping
+}
+
+// AuthOption настраивает AuthService
+type AuthOption func(*AuthService)
+
+// JWTMapping описывает, как claims JWT превращаются в пользователя и роль
+type JWTMapping struct {
+	// UserClaim — claim с ID пользователя сервиса (по умолчанию "sub")
+	UserClaim string
+	// GroupsClaim — claim со списком групп (по умолчанию "groups")
+	GroupsClaim string
+	// TenantClaim — claim с ID организации; без него или при пустом claim — организация по умолчанию
+	TenantClaim string
+	// RoleGroups сопоставляет группу SSO с ролью; из нескольких ролей берётся старшая.
+	// Без подходящей группы вызывающий получает роль member.
+	RoleGroups map[string]string
+}
+
+// WithJWT включает вход по JWT, проверяемым verifier
+func WithJWT(verifier *jwt.Verifier, mapping JWTMapping) AuthOption {
+	return func(s *AuthService) {
+		if mapping.UserClaim == "" {
+			mapping.UserClaim = "sub"
+		}
+		if mapping.GroupsClaim == "" {
+			mapping.GroupsClaim = "groups"
+		}
+		s.jwt = verifier
+		s.jwtMapping = mapping
+	}
+}
+
+// NewAuthService создаёт сервис токенов. Непустой adminToken принимается как токен
+// администратора без записи в БД — им создаются первые токены.
+func NewAuthService(uow repository.UnitOfWork, adminToken string, opts ...AuthOption) *AuthService {
+	s := &AuthService{uow: uow, now: time.Now}
+	if adminToken != "" {
+		sum := sha256.Sum256([]byte(adminToken))
+		s.adminHash = sum[:]
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// roleRank упорядочивает роли по объёму прав
+var roleRank = map[string]int{
+	model.RoleMember:   1,
+	model.RoleBot:      2,
+	model.RoleTeamLead: 3,
+	model.RoleAdmin:    4,
+}
+
+// authenticateJWT проверяет JWT и сопоставляет его claims пользователю, роли и организации.
+// Команда лида и команды, где пользователь назначен лидом, берутся из профиля пользователя в сервисе.
+func (s *AuthService) authenticateJWT(ctx context.Context, token string) (*model.Principal, error) {
+	claims, err := s.jwt.Verify(token)
+	if err != nil {
+		if errors.Is(err, jwt.ErrInvalid) {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+
+	userID := claims.String(s.jwtMapping.UserClaim)
+	if userID == "" {
+		return nil, ErrUnauthenticated
+	}
+	tenant := repository.DefaultTenant
+	if s.jwtMapping.TenantClaim != "" {
+		if t := claims.String(s.jwtMapping.TenantClaim); t != "" {
+			tenant = t
+		}
+	}
+	if !repository.ValidTenantID(tenant) {
+		return nil, ErrUnauthenticated
+	}
+	role := model.RoleMember
+	for _, group := range claims.Strings(s.jwtMapping.GroupsClaim) {
+		if r, ok := s.jwtMapping.RoleGroups[group]; ok && roleRank[r] > roleRank[role] {
+			role = r
+		}
+	}
+
+	p := &model.Principal{
+		Name:       claims.String("sub"),
+		Role:       role,
+		UserID:     userID,
+		TenantID:   tenant,
+		AuthMethod: model.AuthMethodJWT,
+	}
+	user, err := s.uow.Repos(repository.WithTenant(ctx, tenant)).Users.GetByID(userID)
+	switch err {
+	case nil:
+		p.TeamName = user.TeamName
+		applyLeadTeams(p, user)
+	case repository.ErrNotFound:
+		// пользователь SSO ещё не заведён в сервисе: права роли без привязки к команде
+	default:
+		return nil, err
+	}
+	return p, nil
+}
+
+// hashToken возвращает SHA-256 токена в hex — в таком виде токены хранятся в БД
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate возвращает вызывающего по предъявленному токену. API-токены и JWT привязаны
+// к организации (Principal.TenantID); токен из ADMIN_TOKEN глобальный.
+func (s *AuthService) Authenticate(ctx context.Context, token string) (*model.Principal, error) {
+	if token == "" {
+		return nil, ErrUnauthenticated
+	}
+	if s.adminHash != nil {
+		sum := sha256.Sum256([]byte(token))
+		if subtle.ConstantTimeCompare(sum[:], s.adminHash) == 1 {
+			return &model.Principal{Name: "bootstrap-admin", Role: model.RoleAdmin, AuthMethod: model.AuthMethodBootstrap}, nil
+		}
+	}
+	if s.jwt != nil && jwt.LooksLikeJWT(token) {
+		return s.authenticateJWT(ctx, token)
+	}
+
+	t, err := s.uow.Repos(ctx).Tokens.GetByHash(hashToken(token))
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrUnauthenticated
+		}
+		return nil, err
+	}
+	if t.RevokedAt != nil {
+		return nil, ErrUnauthenticated
+	}
+	p := &model.Principal{
+		TokenID:    t.ID,
+		Name:       t.Name,
+		Role:       t.Role,
+		UserID:     t.UserID,
+		TeamName:   t.TeamName,
+		TenantID:   t.TenantID,
+		AuthMethod: model.AuthMethodToken,
+	}
+	if t.UserID != "" && (t.Role == model.RoleMember || t.Role == model.RoleTeamLead) {
+		user, err := s.uow.Repos(repository.WithTenant(ctx, t.TenantID)).Users.GetByID(t.UserID)
+		switch err {
+		case nil:
+			applyLeadTeams(p, user)
+		case repository.ErrNotFound:
+		default:
+			return nil, err
+		}
+	}
+	return p, nil
+}
+
+// applyLeadTeams дополняет вызывающего командами, где его пользователь назначен лидом.
+// Участник, ставший лидом хотя бы одной команды, получает права лида в этих командах.
+func applyLeadTeams(p *model.Principal, user *model.User) {
+	for _, m := range user.Teams {
+		if m.Role == model.TeamRoleLead {
+			p.LeadTeams = append(p.LeadTeams, m.TeamName)
+		}
+	}
+	if len(p.LeadTeams) > 0 && p.Role == model.RoleMember {
+		p.Role = model.RoleTeamLead
+	}
+}
+
+// CreateToken выпускает токен организации из ctx и возвращает его открытое значение — оно показывается только один раз
+func (s *AuthService) CreateToken(ctx context.Context, t *model.APIToken) (string, error) {
+	t.Name = strings.TrimSpace(t.Name)
+	if !model.ValidRole(t.Role) {
+		return "", ErrInvalidRole
+	}
+	if (t.Role == model.RoleTeamLead && t.TeamName == "") || (t.Role == model.RoleMember && t.UserID == "") {
+		return "", ErrTokenScope
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := tokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+
+	t.TokenHash = hashToken(token)
+	t.CreatedAt = s.now()
+	t.RevokedAt = nil
+	if err := s.uow.Repos(ctx).Tokens.Create(t); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// ListTokens возвращает токены организации из ctx без их значений
+func (s *AuthService) ListTokens(ctx context.Context) ([]model.APIToken, error) {
+	return s.uow.Repos(ctx).Tokens.List()
+}
+
+// RevokeToken отзывает токен; запросы с ним сразу перестают проходить
+func (s *AuthService) RevokeToken(ctx context.Context, id int64) (*model.APIToken, error) {
+	t, err := s.uow.Repos(ctx).Tokens.Revoke(id, s.now())
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrTokenNotFound
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+type principalKey struct{}
+
+// WithPrincipal сохраняет вызывающего в контексте запроса
+func WithPrincipal(ctx context.Context, p *model.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom возвращает вызывающего из контекста или nil
+func PrincipalFrom(ctx context.Context) *model.Principal {
+	p, _ := ctx.Value(principalKey{}).(*model.Principal)
+	return p
+}
@@ -0,0 +1,381 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// Операции синхронизации с внешним каталогом пользователей (SCIM, LDAP): пользователи
+// находятся по имени, команды — по имени группы, состав команды задаётся списком ID целиком.
+
+// Ошибки синхронизации каталога
+var (
+	ErrUsernameTaken   = errors.New("username already taken")
+	ErrInvalidUsername = errors.New("username required")
+	ErrInvalidTeamName = errors.New("team name required")
+)
+
+// maxCreateAttempts — сколько раз CreateUser повторяет выбор ID при параллельном создании
+const maxCreateAttempts = 3
+
+// ListUsers возвращает пользователей организации по ID; непустой username оставляет только
+// пользователей с этим именем без учёта регистра
+func (s *UserService) ListUsers(ctx context.Context, username string) ([]model.User, error) {
+	users, err := s.uow.Repos(ctx).Users.List()
+	if err != nil {
+		return nil, err
+	}
+	if username == "" {
+		return users, nil
+	}
+	found := []model.User{}
+	for _, u := range users {
+		if strings.EqualFold(u.Username, username) {
+			found = append(found, u)
+		}
+	}
+	return found, nil
+}
+
+// CreateUser создаёт пользователя вне команд со следующим свободным ID. Имя должно быть
+// уникальным без учёта регистра (ErrUsernameTaken).
+func (s *UserService) CreateUser(ctx context.Context, username string, isActive bool) (*model.User, error) {
+	if username == "" {
+		return nil, ErrInvalidUsername
+	}
+	var user *model.User
+	for attempt := 0; ; attempt++ {
+		err := s.uow.Do(ctx, func(repos repository.Repos) error {
+			users, err := repos.Users.List()
+			if err != nil {
+				return err
+			}
+			var maxID int64
+			for _, u := range users {
+				if strings.EqualFold(u.Username, username) {
+					return ErrUsernameTaken
+				}
+				if u.ID > maxID {
+					maxID = u.ID
+				}
+			}
+			user = &model.User{ID: maxID + 1, Username: username, IsActive: isActive, Teams: []model.TeamMembership{}, Skills: []string{}}
+			if err := repos.Users.Create(user); err != nil {
+				return err
+			}
+			return recordAudit(ctx, repos, model.AuditUserCreated, model.AuditTargetUser, strconv.FormatInt(user.ID, 10),
+				map[string]string{"username": username, "is_active": strconv.FormatBool(isActive)}, s.now())
+		})
+		// ID занял параллельный запрос — выбираем следующий
+		if err == repository.ErrAlreadyExists && attempt+1 < maxCreateAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return user, nil
+	}
+}
+
+// UpdateUser меняет имя и флаг активности пользователя; nil оставляет значение без изменений.
+// Деактивация переназначает открытые ревью, как SetIsActive.
+func (s *UserService) UpdateUser(ctx context.Context, userID string, username *string, isActive *bool) (*model.User, []model.ReviewHandoff, error) {
+	if username != nil && *username == "" {
+		return nil, nil, ErrInvalidUsername
+	}
+	var user *model.User
+	handoffs := []model.ReviewHandoff{}
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		user, err = repos.Users.GetByID(userID)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if username != nil && *username != user.Username {
+			users, err := repos.Users.List()
+			if err != nil {
+				return err
+			}
+			for _, u := range users {
+				if u.ID != user.ID && strings.EqualFold(u.Username, *username) {
+					return ErrUsernameTaken
+				}
+			}
+			old := user.Username
+			user.Username = *username
+			if err := repos.Users.CreateOrUpdate(user); err != nil {
+				return err
+			}
+			if err := recordAudit(ctx, repos, model.AuditUserRenamed, model.AuditTargetUser, userID, map[string]string{"old_username": old, "username": *username}, s.now()); err != nil {
+				return err
+			}
+		}
+		if isActive != nil && *isActive != user.IsActive {
+			if _, handoffs, err = s.prs.setUserActive(ctx, repos, userID, *isActive); err != nil {
+				return err
+			}
+		}
+		user, err = repos.Users.GetByID(userID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return user, handoffs, nil
+}
+
+// TeamNames возвращает имена всех команд организации по алфавиту
+func (s *TeamService) TeamNames(ctx context.Context) ([]string, error) {
+	repos := s.uow.Repos(ctx)
+	names := []string{}
+	for cursor := ""; ; {
+		page, next, err := repos.Teams.List(maxPageSize, cursor)
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range page {
+			names = append(names, t.Name)
+		}
+		if next == "" {
+			return names, nil
+		}
+		cursor = next
+	}
+}
+
+// ProvisionTeam создаёт корневую команду с существующими пользователями userIDs в одной транзакции.
+// Существующая команда не меняется (ErrTeamExists).
+func (s *TeamService) ProvisionTeam(ctx context.Context, teamName string, userIDs []string) (*model.Team, error) {
+	if teamName == "" {
+		return nil, ErrInvalidTeamName
+	}
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if err := repos.Teams.Create(&model.Team{Name: teamName}); err != nil {
+			if err == repository.ErrAlreadyExists {
+				return ErrTeamExists
+			}
+			return err
+		}
+		_, err := s.changeMembers(ctx, repos, teamName, userIDs, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTeam(ctx, teamName)
+}
+
+// SetMembers делает состав команды равным userIDs: недостающие пользователи добавляются участниками,
+// лишние убираются, как в RemoveMember. Роли и веса оставшихся участников не меняются.
+func (s *TeamService) SetMembers(ctx context.Context, teamName string, userIDs []string) (*model.Team, []model.ReviewHandoff, error) {
+	var handoffs []model.ReviewHandoff
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if _, err := repos.Teams.GetByName(teamName); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		var err error
+		handoffs, err = s.setMembers(ctx, repos, teamName, userIDs)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	team, err := s.GetTeam(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return team, handoffs, nil
+}
+
+// ChangeMembers добавляет пользователей add в команду и убирает из неё remove в одной транзакции.
+// Уже состоящие в команде из add и не состоящие из remove пропускаются.
+func (s *TeamService) ChangeMembers(ctx context.Context, teamName string, add, remove []string) (*model.Team, []model.ReviewHandoff, error) {
+	var handoffs []model.ReviewHandoff
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if _, err := repos.Teams.GetByName(teamName); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		var err error
+		handoffs, err = s.changeMembers(ctx, repos, teamName, add, remove)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	team, err := s.GetTeam(ctx, teamName)
+	if err != nil {
+		return nil, nil, err
+	}
+	return team, handoffs, nil
+}
+
+// GroupChange — изменение команды, которую ведёт внешний каталог
+type GroupChange struct {
+	// NewName — новое имя команды; пустое или равное текущему не переименовывает её
+	NewName string
+	// Members — новый состав целиком, как в SetMembers; nil — состав меняют только Add и Remove
+	Members []string
+	Add     []string
+	Remove  []string
+}
+
+// UpdateGroup переименовывает команду и меняет её состав в одной транзакции: если состав
+// изменить нельзя, команда сохраняет и прежнее имя. Возвращает команду под итоговым именем.
+func (s *TeamService) UpdateGroup(ctx context.Context, teamName string, change GroupChange) (*model.Team, []model.ReviewHandoff, error) {
+	name := teamName
+	if change.NewName != "" {
+		name = change.NewName
+	}
+	var handoffs []model.ReviewHandoff
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if name != teamName {
+			if err := s.renameTeam(ctx, repos, teamName, name); err != nil {
+				return err
+			}
+		} else if _, err := repos.Teams.GetByName(teamName); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		var err error
+		switch {
+		case change.Members != nil:
+			handoffs, err = s.setMembers(ctx, repos, name, change.Members)
+		case len(change.Add) > 0 || len(change.Remove) > 0:
+			handoffs, err = s.changeMembers(ctx, repos, name, change.Add, change.Remove)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	team, err := s.GetTeam(ctx, name)
+	if err != nil {
+		return nil, nil, err
+	}
+	return team, handoffs, nil
+}
+
+// DisbandTeam убирает из команды всех участников, переназначая их открытые ревью, и удаляет её.
+// Команду с дочерними командами удалить нельзя (ErrTeamHasChildren).
+func (s *TeamService) DisbandTeam(ctx context.Context, teamName string) ([]model.ReviewHandoff, error) {
+	var handoffs []model.ReviewHandoff
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if _, err := repos.Teams.GetByName(teamName); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		children, err := repos.Teams.Children(teamName)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return ErrTeamHasChildren
+		}
+		members, err := repos.Users.GetByTeam(teamName)
+		if err != nil {
+			return err
+		}
+		remove := make([]string, 0, len(members))
+		for _, u := range members {
+			remove = append(remove, strconv.FormatInt(u.ID, 10))
+		}
+		if handoffs, err = s.changeMembers(ctx, repos, teamName, nil, remove); err != nil {
+			return err
+		}
+		if err := repos.Teams.Delete(teamName); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditTeamDeleted, model.AuditTargetTeam, teamName, nil, s.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return handoffs, nil
+}
+
+// setMembers делает состав команды равным userIDs внутри транзакции
+func (s *TeamService) setMembers(ctx context.Context, repos repository.Repos, teamName string, userIDs []string) ([]model.ReviewHandoff, error) {
+	current, err := repos.Users.GetByTeam(teamName)
+	if err != nil {
+		return nil, err
+	}
+	keep := make(map[string]bool, len(userIDs))
+	for _, id := range userIDs {
+		keep[id] = true
+	}
+	var remove []string
+	for _, u := range current {
+		if id := strconv.FormatInt(u.ID, 10); !keep[id] {
+			remove = append(remove, id)
+		}
+	}
+	return s.changeMembers(ctx, repos, teamName, userIDs, remove)
+}
+
+// changeMembers меняет состав команды внутри транзакции. Открытые ревью убранных участников
+// переназначаются после всех изменений, чтобы замена выбиралась из итогового состава.
+func (s *TeamService) changeMembers(ctx context.Context, repos repository.Repos, teamName string, add, remove []string) ([]model.ReviewHandoff, error) {
+	for _, id := range add {
+		user, err := repos.Users.GetByID(id)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return nil, fmt.Errorf("%w: %s", ErrUserNotFound, id)
+			}
+			return nil, err
+		}
+		if _, ok := user.Membership(teamName); ok {
+			continue
+		}
+		m := &model.TeamMembership{TeamName: teamName, UserID: id, IsActive: true, Weight: model.DefaultMembershipWeight, Role: model.TeamRoleMember}
+		if err := repos.Memberships.Upsert(m); err != nil {
+			return nil, err
+		}
+		if err := recordAudit(ctx, repos, model.AuditMemberAdded, model.AuditTargetTeam, teamName, map[string]string{"user_id": id}, s.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	var removed []string
+	for _, id := range remove {
+		switch err := repos.Memberships.Remove(teamName, id); err {
+		case nil:
+			removed = append(removed, id)
+		case repository.ErrNotFound:
+			continue
+		default:
+			return nil, err
+		}
+		if err := recordAudit(ctx, repos, model.AuditMemberRemoved, model.AuditTargetTeam, teamName, map[string]string{"user_id": id}, s.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	handoffs := []model.ReviewHandoff{}
+	for _, id := range removed {
+		released, err := s.prs.releaseReviews(ctx, repos, id, ReleaseMemberRemoved)
+		if err != nil {
+			return nil, err
+		}
+		handoffs = append(handoffs, released...)
+	}
+	return handoffs, nil
+}
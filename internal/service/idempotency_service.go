@@ -0,0 +1,90 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+var (
+	ErrIdempotencyKeyReused  = errors.New("idempotency key reused with a different request")
+	ErrIdempotencyInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+// DefaultIdempotencyTTL — срок хранения ответов по умолчанию
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// DefaultIdempotencyLockTimeout — через сколько незавершённая резервация ключа считается брошенной
+const DefaultIdempotencyLockTimeout = 5 * time.Minute
+
+// IdempotencyService запоминает ответы на запросы с Idempotency-Key,
+// чтобы повтор запроса не выполнял операцию второй раз
+type IdempotencyService struct {
+	uow repository.UnitOfWork
+	ttl time.Duration
+	// lockTimeout — срок резервации ключа до сохранения ответа. Если процесс упал,
+	// не успев сохранить ответ или освободить ключ, по истечении срока ключ можно занять снова.
+	lockTimeout time.Duration
+	now         Clock
+}
+
+// NewIdempotencyService создаёт сервис, хранящий ответы ttl; выполняющийся запрос держит ключ
+// не дольше lockTimeout
+func NewIdempotencyService(uow repository.UnitOfWork, ttl, lockTimeout time.Duration) *IdempotencyService {
+	return &IdempotencyService{uow: uow, ttl: ttl, lockTimeout: lockTimeout, now: time.Now}
+}
+
+// Begin занимает ключ под запрос с хешем requestHash. Возвращает nil, если запрос нужно выполнить,
+// или сохранённый ответ первого такого запроса. Тот же ключ с другим запросом даёт
+// ErrIdempotencyKeyReused, ключ выполняющегося запроса — ErrIdempotencyInProgress.
+// Резервация без ответа истекает через lockTimeout, после чего ключ занимает новый запрос.
+func (s *IdempotencyService) Begin(ctx context.Context, key, requestHash string) (*model.IdempotencyRecord, error) {
+	repo := s.uow.Repos(ctx).Idempotency
+	now := s.now()
+	err := repo.Reserve(&model.IdempotencyRecord{
+		Key:         key,
+		RequestHash: requestHash,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(s.lockTimeout),
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if err != repository.ErrAlreadyExists {
+		return nil, err
+	}
+
+	existing, err := repo.Get(key)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			// ключ освободили между Reserve и Get — клиенту стоит повторить запрос
+			return nil, ErrIdempotencyInProgress
+		}
+		return nil, err
+	}
+	if existing.RequestHash != requestHash {
+		return nil, ErrIdempotencyKeyReused
+	}
+	if !existing.Completed() {
+		return nil, ErrIdempotencyInProgress
+	}
+	return existing, nil
+}
+
+// Complete сохраняет ответ на запрос, для которого Begin вернул nil, на срок ttl
+func (s *IdempotencyService) Complete(ctx context.Context, key string, statusCode int, response []byte) error {
+	return s.uow.Repos(ctx).Idempotency.Complete(key, statusCode, response, s.now().Add(s.ttl))
+}
+
+// Release освобождает ключ, не сохраняя ответ, чтобы запрос можно было повторить
+func (s *IdempotencyService) Release(ctx context.Context, key string) error {
+	return s.uow.Repos(ctx).Idempotency.Delete(key)
+}
+
+// PurgeExpired удаляет сохранённые ответы с истёкшим сроком
+func (s *IdempotencyService) PurgeExpired(ctx context.Context) (int64, error) {
+	return s.uow.Repos(ctx).Idempotency.DeleteExpired(s.now())
+}
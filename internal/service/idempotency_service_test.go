@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/repository/memory"
+)
+
+func TestIdempotencyStaleReservation(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	svc := NewIdempotencyService(memory.NewStore(), time.Hour, time.Minute)
+	svc.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if saved, err := svc.Begin(ctx, "k", "h"); saved != nil || err != nil {
+		t.Fatalf("Begin: got %v, %v", saved, err)
+	}
+	now = now.Add(30 * time.Second)
+	if _, err := svc.Begin(ctx, "k", "h"); err != ErrIdempotencyInProgress {
+		t.Fatalf("Begin within lock timeout: want ErrIdempotencyInProgress, got %v", err)
+	}
+
+	// первый запрос так и не завершился — по истечении срока ключ занимает повтор
+	now = now.Add(time.Minute)
+	if saved, err := svc.Begin(ctx, "k", "h"); saved != nil || err != nil {
+		t.Fatalf("Begin after lock timeout: got %v, %v", saved, err)
+	}
+	if err := svc.Complete(ctx, "k", 201, []byte(`{}`)); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	// сохранённый ответ живёт ttl, а не lockTimeout
+	now = now.Add(30 * time.Minute)
+	saved, err := svc.Begin(ctx, "k", "h")
+	if err != nil || saved == nil || saved.StatusCode != 201 {
+		t.Fatalf("Begin after Complete: got %+v, %v", saved, err)
+	}
+	now = now.Add(time.Hour)
+	if saved, err := svc.Begin(ctx, "k", "h"); saved != nil || err != nil {
+		t.Fatalf("Begin after ttl: got %v, %v", saved, err)
+	}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// ErrInvalidRoster — в реестре есть ошибки, ничего не применено; подробности — в ImportReport.Errors
+var ErrInvalidRoster = errors.New("roster has errors")
+
+// ImportRoster проверяет реестр целиком и строит план изменений: какие команды и пользователи
+// будут созданы, какие данные пользователей и роли изменятся, какие членства добавятся.
+// Импорт только добавляет и обновляет: участники, которых нет в реестре, не удаляются.
+// Открытые ревью деактивированных импортом пользователей переназначаются, как при SetIsActive.
+// parseErrors — ошибки разбора файла: с ними реестр проверяется, но не применяется.
+// При dryRun или ошибках (ErrInvalidRoster) возвращается только отчёт, иначе план применяется
+// в одной транзакции.
+func (s *TeamService) ImportRoster(ctx context.Context, entries []model.RosterEntry, parseErrors []model.ImportLineError, dryRun bool) (*model.ImportReport, error) {
+	report := &model.ImportReport{DryRun: dryRun, Entries: len(entries) + len(parseErrors), Changes: []model.ImportChange{}}
+	report.Errors = append(append([]model.ImportLineError{}, parseErrors...), validateRoster(entries)...)
+	sort.SliceStable(report.Errors, func(i, j int) bool { return report.Errors[i].Line < report.Errors[j].Line })
+	if len(report.Errors) > 0 {
+		return report, ErrInvalidRoster
+	}
+
+	if dryRun {
+		changes, unchanged, err := planImport(s.uow.Repos(ctx), entries)
+		if err != nil {
+			return nil, err
+		}
+		report.Changes, report.Unchanged = changes, unchanged
+		return report, nil
+	}
+
+	err := s.update(ctx, func(repos repository.Repos) error {
+		changes, unchanged, err := planImport(repos, entries)
+		if err != nil {
+			return err
+		}
+		report.Changes, report.Unchanged = changes, unchanged
+		if err := s.applyImport(ctx, repos, entries, changes); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditRosterImported, model.AuditTargetOrg, repository.TenantFrom(ctx), map[string]string{
+			"entries": strconv.Itoa(len(entries)),
+			"changes": strconv.Itoa(len(changes)),
+		}, s.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// validateRoster проверяет строки реестра без обращения к хранилищу: обязательные поля, роль,
+// повторы членства и противоречивые данные одного пользователя в разных строках
+func validateRoster(entries []model.RosterEntry) []model.ImportLineError {
+	var errs []model.ImportLineError
+	fail := func(e model.RosterEntry, format string, args ...interface{}) {
+		errs = append(errs, model.ImportLineError{Line: e.Line, Message: fmt.Sprintf(format, args...)})
+	}
+	type membership struct{ team, user string }
+	seenMember := make(map[membership]int)
+	seenUser := make(map[string]model.RosterEntry)
+
+	for _, e := range entries {
+		switch {
+		case e.TeamName == "":
+			fail(e, "team_name required")
+			continue
+		case e.UserID == "":
+			fail(e, "user_id required")
+			continue
+		case e.Username == "":
+			fail(e, "username required")
+			continue
+		}
+		if _, err := parseID(e.UserID); err != nil {
+			fail(e, "user_id must be an integer, got %q", e.UserID)
+			continue
+		}
+		if e.Role != "" && !model.ValidTeamRole(e.Role) {
+			fail(e, "role must be member or lead, got %q", e.Role)
+			continue
+		}
+		key := membership{e.TeamName, e.UserID}
+		if line, dup := seenMember[key]; dup {
+			fail(e, "user %s is already listed in team %s on line %d", e.UserID, e.TeamName, line)
+			continue
+		}
+		seenMember[key] = e.Line
+		if first, ok := seenUser[e.UserID]; ok {
+			if first.Username != e.Username || first.IsActive != e.IsActive {
+				fail(e, "user %s has different username or is_active on line %d", e.UserID, first.Line)
+			}
+			continue
+		}
+		seenUser[e.UserID] = e
+	}
+	return errs
+}
+
+// planImport сравнивает проверенный реестр с текущим состоянием и возвращает изменения в порядке
+// строк и число строк, которые ничего не меняют
+func planImport(repos repository.Repos, entries []model.RosterEntry) ([]model.ImportChange, int, error) {
+	changes := []model.ImportChange{}
+	teams := make(map[string]bool)
+	users := make(map[string]*model.User)
+	unchanged := 0
+
+	for _, e := range entries {
+		before := len(changes)
+
+		if _, seen := teams[e.TeamName]; !seen {
+			_, err := repos.Teams.GetByName(e.TeamName)
+			switch err {
+			case nil:
+				teams[e.TeamName] = true
+			case repository.ErrNotFound:
+				teams[e.TeamName] = false
+				changes = append(changes, model.ImportChange{Line: e.Line, Action: model.ImportCreateTeam, TeamName: e.TeamName})
+			default:
+				return nil, 0, err
+			}
+		}
+
+		user, seen := users[e.UserID]
+		if !seen {
+			u, err := repos.Users.GetByID(e.UserID)
+			switch err {
+			case nil:
+				user = u
+				if u.Username != e.Username {
+					changes = append(changes, model.ImportChange{Line: e.Line, Action: model.ImportUpdateUser, UserID: e.UserID, Field: "username", Old: u.Username, New: e.Username})
+				}
+				if u.IsActive != e.IsActive {
+					changes = append(changes, model.ImportChange{Line: e.Line, Action: model.ImportUpdateUser, UserID: e.UserID, Field: "is_active", Old: strconv.FormatBool(u.IsActive), New: strconv.FormatBool(e.IsActive)})
+				}
+			case repository.ErrNotFound:
+				changes = append(changes, model.ImportChange{Line: e.Line, Action: model.ImportCreateUser, UserID: e.UserID, New: e.Username})
+			default:
+				return nil, 0, err
+			}
+			users[e.UserID] = user
+		}
+
+		var current *model.TeamMembership
+		if user != nil {
+			if m, ok := user.Membership(e.TeamName); ok {
+				current = &m
+			}
+		}
+		switch {
+		case current == nil:
+			role := e.Role
+			if role == "" {
+				role = model.TeamRoleMember
+			}
+			changes = append(changes, model.ImportChange{Line: e.Line, Action: model.ImportAddMember, TeamName: e.TeamName, UserID: e.UserID, Field: "role", New: role})
+		case e.Role != "" && e.Role != current.Role:
+			changes = append(changes, model.ImportChange{Line: e.Line, Action: model.ImportSetRole, TeamName: e.TeamName, UserID: e.UserID, Field: "role", Old: current.Role, New: e.Role})
+		}
+
+		if len(changes) == before {
+			unchanged++
+		}
+	}
+	return changes, unchanged, nil
+}
+
+// applyImport вносит изменения плана внутри транзакции
+func (s *TeamService) applyImport(ctx context.Context, repos repository.Repos, entries []model.RosterEntry, changes []model.ImportChange) error {
+	byUser := make(map[string]model.RosterEntry, len(entries))
+	for _, e := range entries {
+		if _, ok := byUser[e.UserID]; !ok {
+			byUser[e.UserID] = e
+		}
+	}
+	savedUsers := make(map[string]bool)
+
+	for _, c := range changes {
+		switch c.Action {
+		case model.ImportCreateTeam:
+			if err := repos.Teams.Create(&model.Team{Name: c.TeamName}); err != nil {
+				return err
+			}
+		case model.ImportCreateUser, model.ImportUpdateUser:
+			if !savedUsers[c.UserID] {
+				savedUsers[c.UserID] = true
+				e := byUser[c.UserID]
+				id, _ := parseID(e.UserID)
+				if err := repos.Users.CreateOrUpdate(&model.User{ID: id, Username: e.Username, IsActive: e.IsActive}); err != nil {
+					return err
+				}
+			}
+			if c.Action == model.ImportUpdateUser && c.Field == "is_active" {
+				if _, _, err := s.prs.setUserActive(ctx, repos, c.UserID, c.New == "true"); err != nil {
+					return err
+				}
+			}
+		case model.ImportAddMember:
+			m := &model.TeamMembership{TeamName: c.TeamName, UserID: c.UserID, IsActive: true, Weight: model.DefaultMembershipWeight, Role: c.New}
+			if err := repos.Memberships.Upsert(m); err != nil {
+				return err
+			}
+			if err := recordAudit(ctx, repos, model.AuditMemberAdded, model.AuditTargetTeam, c.TeamName, map[string]string{"user_id": c.UserID}, s.now()); err != nil {
+				return err
+			}
+		case model.ImportSetRole:
+			user, err := repos.Users.GetByID(c.UserID)
+			if err != nil {
+				return err
+			}
+			m, _ := user.Membership(c.TeamName)
+			m.UserID = c.UserID
+			m.Role = c.New
+			if err := repos.Memberships.Upsert(&m); err != nil {
+				return err
+			}
+			action := model.AuditLeadRemoved
+			if c.New == model.TeamRoleLead {
+				action = model.AuditLeadAdded
+			}
+			if err := recordAudit(ctx, repos, action, model.AuditTargetTeam, c.TeamName, map[string]string{"user_id": c.UserID}, s.now()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository/memory"
+	"pr-reviewer/internal/roster"
+)
+
+func TestImportRosterDryRun(t *testing.T) {
+	st := memory.NewStore()
+	prs := NewPRService(st, DefaultAssignmentPolicy())
+	teams := NewTeamService(st, prs)
+	ctx := context.Background()
+	_, err := teams.CreateOrUpdateTeam(ctx, &model.Team{Name: "backend", Members: []model.TeamMember{
+		{UserID: "1", Username: "alice", IsActive: true, Role: model.TeamRoleLead},
+		{UserID: "2", Username: "bob", IsActive: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries, parseErrs, err := roster.Parse(roster.FormatYAML, []byte(`teams:
+  - name: backend
+    members:
+      - user_id: 1
+        username: alice
+      - user_id: 2
+        username: robert
+        is_active: false
+        role: lead
+      - user_id: 3
+        username: carol
+  - name: frontend
+    members:
+      - user_id: 1
+        username: alice
+`))
+	if err != nil || len(parseErrs) > 0 {
+		t.Fatalf("parse: %v %+v", err, parseErrs)
+	}
+
+	report, err := teams.ImportRoster(ctx, entries, nil, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !report.DryRun || report.Applied || report.Entries != 4 || report.Unchanged != 1 {
+		t.Fatalf("dry run report: %+v", report)
+	}
+	want := []model.ImportChange{
+		{Line: 6, Action: model.ImportUpdateUser, UserID: "2", Field: "username", Old: "bob", New: "robert"},
+		{Line: 6, Action: model.ImportUpdateUser, UserID: "2", Field: "is_active", Old: "true", New: "false"},
+		{Line: 6, Action: model.ImportSetRole, TeamName: "backend", UserID: "2", Field: "role", Old: model.TeamRoleMember, New: model.TeamRoleLead},
+		{Line: 10, Action: model.ImportCreateUser, UserID: "3", New: "carol"},
+		{Line: 10, Action: model.ImportAddMember, TeamName: "backend", UserID: "3", Field: "role", New: model.TeamRoleMember},
+		{Line: 14, Action: model.ImportCreateTeam, TeamName: "frontend"},
+		{Line: 14, Action: model.ImportAddMember, TeamName: "frontend", UserID: "1", Field: "role", New: model.TeamRoleMember},
+	}
+	if !reflect.DeepEqual(report.Changes, want) {
+		t.Fatalf("plan:\n got %+v\nwant %+v", report.Changes, want)
+	}
+
+	// проверка ничего не меняет
+	if _, err := teams.GetTeam(ctx, "frontend"); err != ErrTeamNotFound {
+		t.Fatalf("dry run created a team: %v", err)
+	}
+	backend, _ := teams.GetTeam(ctx, "backend")
+	if len(backend.Members) != 2 || backend.Members[1].Username != "bob" || !backend.Members[1].IsActive {
+		t.Fatalf("dry run changed backend: %+v", backend.Members)
+	}
+
+	// применение выполняет тот же план, повторная проверка изменений не находит
+	applied, err := teams.ImportRoster(ctx, entries, nil, false)
+	if err != nil {
+		t.Fatalf("import: %v", err)
+	}
+	if !applied.Applied || !reflect.DeepEqual(applied.Changes, want) {
+		t.Fatalf("applied report: %+v", applied)
+	}
+	backend, _ = teams.GetTeam(ctx, "backend")
+	if len(backend.Members) != 3 || backend.Members[1].Username != "robert" || backend.Members[1].Role != model.TeamRoleLead {
+		t.Fatalf("backend after import: %+v", backend.Members)
+	}
+	again, err := teams.ImportRoster(ctx, entries, nil, true)
+	if err != nil || len(again.Changes) != 0 || again.Unchanged != 4 {
+		t.Fatalf("dry run after import: %+v, %v", again, err)
+	}
+}
+
+func TestImportRosterErrors(t *testing.T) {
+	st := memory.NewStore()
+	teams := NewTeamService(st, NewPRService(st, DefaultAssignmentPolicy()))
+	ctx := context.Background()
+
+	entries, parseErrs, err := roster.Parse(roster.FormatCSV, []byte("team_name,user_id,username,is_active,role\n"+
+		"backend,1,alice,true,lead\n"+
+		"backend,u2,bob,true,\n"+
+		"backend,3,carol,maybe,\n"+
+		"backend,1,alice,true,\n"+
+		"frontend,1,alicia,true,\n"+
+		"frontend,4,,true,\n"+
+		"frontend,5,eve,true,owner\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := teams.ImportRoster(ctx, entries, parseErrs, true)
+	if err != ErrInvalidRoster {
+		t.Fatalf("want ErrInvalidRoster, got %v", err)
+	}
+	// ошибки разбора и проверки собираются вместе в порядке строк
+	want := []model.ImportLineError{
+		{Line: 3, Message: `user_id must be an integer, got "u2"`},
+		{Line: 4, Message: `is_active must be true or false, got "maybe"`},
+		{Line: 5, Message: "user 1 is already listed in team backend on line 2"},
+		{Line: 6, Message: "user 1 has different username or is_active on line 2"},
+		{Line: 7, Message: "username required"},
+		{Line: 8, Message: `role must be member or lead, got "owner"`},
+	}
+	if !reflect.DeepEqual(report.Errors, want) {
+		t.Fatalf("errors:\n got %+v\nwant %+v", report.Errors, want)
+	}
+	if report.Entries != 7 || len(report.Changes) != 0 {
+		t.Fatalf("report: %+v", report)
+	}
+
+	// с ошибками реестр не применяется и без dry_run
+	if _, err := teams.ImportRoster(ctx, entries, parseErrs, false); err != ErrInvalidRoster {
+		t.Fatalf("import with errors: %v", err)
+	}
+	if _, err := teams.GetTeam(ctx, "backend"); err != ErrTeamNotFound {
+		t.Fatalf("invalid roster created a team: %v", err)
+	}
+}
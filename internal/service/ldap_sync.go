@@ -0,0 +1,567 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"pr-reviewer/internal/ldap"
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// Ошибки синхронизации с LDAP
+var (
+	// ErrLDAPSyncNotConfigured — синхронизация не настроена для организации запроса
+	ErrLDAPSyncNotConfigured = errors.New("ldap sync is not configured for this organization")
+	// ErrLDAPUnavailable — каталог недоступен или отклонил запрос
+	ErrLDAPUnavailable = errors.New("ldap directory unavailable")
+)
+
+// LDAPSyncConfig — где в каталоге искать группы и пользователей и какие атрибуты читать
+type LDAPSyncConfig struct {
+	// URL — адрес каталога: ldap://host:389 или ldaps://host:636
+	URL          string
+	BindDN       string
+	BindPassword string
+
+	// BaseDN и GroupFilter задают группы; каждая группа становится командой с именем
+	// из GroupNameAttr, её участники перечислены в MemberAttr (DN или имя пользователя)
+	BaseDN        string
+	GroupFilter   string
+	GroupNameAttr string
+	MemberAttr    string
+
+	// UserBaseDN (по умолчанию BaseDN) и UserFilter задают пользователей. ID берётся из
+	// числового атрибута UserIDAttr; пустой UserIDAttr сопоставляет пользователей по имени
+	// и выдаёт новым следующий свободный ID.
+	UserBaseDN   string
+	UserFilter   string
+	UserIDAttr   string
+	UsernameAttr string
+
+	// DeactivateMissing делает каталог источником активности: участники синхронизируемых команд,
+	// которых нет среди пользователей каталога, деактивируются, найденные — активируются
+	DeactivateMissing bool
+
+	// Tenant — организация, в которую синхронизируется каталог
+	Tenant   string
+	PageSize int
+	Timeout  time.Duration
+}
+
+// DefaultLDAPSyncConfig возвращает настройки для схемы groupOfNames/inetOrgPerson
+func DefaultLDAPSyncConfig() LDAPSyncConfig {
+	return LDAPSyncConfig{
+		GroupFilter:   "(objectClass=groupOfNames)",
+		GroupNameAttr: "cn",
+		MemberAttr:    "member",
+		UserFilter:    "(objectClass=inetOrgPerson)",
+		UserIDAttr:    "uidNumber",
+		UsernameAttr:  "uid",
+		Tenant:        repository.DefaultTenant,
+		PageSize:      500,
+		Timeout:       30 * time.Second,
+	}
+}
+
+// Validate проверяет, что заданы адрес, базовый DN и атрибуты, а фильтры корректны
+func (c LDAPSyncConfig) Validate() error {
+	switch {
+	case c.URL == "":
+		return errors.New("ldap url required")
+	case c.BaseDN == "":
+		return errors.New("ldap base dn required")
+	case c.GroupNameAttr == "" || c.MemberAttr == "" || c.UsernameAttr == "":
+		return errors.New("ldap group name, member and username attributes required")
+	case c.Tenant == "":
+		return errors.New("ldap sync tenant required")
+	case c.PageSize < 0:
+		return errors.New("ldap page size must not be negative")
+	}
+	if _, err := ldap.ParseFilter(c.GroupFilter); err != nil {
+		return fmt.Errorf("group filter: %w", err)
+	}
+	if _, err := ldap.ParseFilter(c.UserFilter); err != nil {
+		return fmt.Errorf("user filter: %w", err)
+	}
+	return nil
+}
+
+// LDAPSyncService сверяет команды и пользователей организации с группами каталога LDAP.
+// Одновременно выполняется не больше одной синхронизации.
+type LDAPSyncService struct {
+	teams *TeamService
+	cfg   LDAPSyncConfig
+	now   Clock
+
+	// run сериализует синхронизации, mu защищает status
+	run    sync.Mutex
+	mu     sync.Mutex
+	status model.LDAPSyncStatus
+}
+
+// NewLDAPSyncService создаёт новый LDAPSyncService; cfg должна пройти Validate.
+// teams меняет состав команд и переназначает ревью убранных участников.
+func NewLDAPSyncService(teams *TeamService, cfg LDAPSyncConfig) *LDAPSyncService {
+	if cfg.UserBaseDN == "" {
+		cfg.UserBaseDN = cfg.BaseDN
+	}
+	return &LDAPSyncService{teams: teams, cfg: cfg, now: time.Now}
+}
+
+// Tenant возвращает организацию, в которую синхронизируется каталог
+func (s *LDAPSyncService) Tenant() string {
+	return s.cfg.Tenant
+}
+
+// Status возвращает отчёт последней применённой синхронизации и последнюю ошибку
+func (s *LDAPSyncService) Status(ctx context.Context) (*model.LDAPSyncStatus, error) {
+	if repository.TenantFrom(ctx) != s.cfg.Tenant {
+		return nil, ErrLDAPSyncNotConfigured
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	status := s.status
+	return &status, nil
+}
+
+// Sync читает группы и пользователей каталога и строит план: какие пользователи и команды
+// создать, чьи имена и активность изменить, кого добавить в команды и убрать из них.
+// Синхронизируются только команды, соответствующие группам каталога; остальные не меняются.
+// Каталог — источник состава: участники, которых нет в группе, убираются из команды с
+// переназначением открытых ревью. При dryRun возвращается только план, иначе он применяется
+// в одной транзакции.
+func (s *LDAPSyncService) Sync(ctx context.Context, dryRun bool) (*model.LDAPSyncReport, error) {
+	if repository.TenantFrom(ctx) != s.cfg.Tenant {
+		return nil, ErrLDAPSyncNotConfigured
+	}
+	s.run.Lock()
+	defer s.run.Unlock()
+
+	report, err := s.sync(ctx, dryRun)
+	if dryRun {
+		return report, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		at := s.now().UTC()
+		s.status.LastError, s.status.LastErrorAt = err.Error(), &at
+		return nil, err
+	}
+	s.status.LastReport, s.status.LastError, s.status.LastErrorAt = report, "", nil
+	return report, nil
+}
+
+// sync выполняет одну синхронизацию
+func (s *LDAPSyncService) sync(ctx context.Context, dryRun bool) (*model.LDAPSyncReport, error) {
+	report := &model.LDAPSyncReport{
+		DryRun:     dryRun,
+		StartedAt:  s.now().UTC(),
+		Changes:    []model.LDAPChange{},
+		Reassigned: []model.ReviewHandoff{},
+	}
+	// периодическая синхронизация не проходит проверку организации в middleware
+	if _, err := s.teams.uow.Repos(ctx).Orgs.Get(s.cfg.Tenant); err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrOrgNotFound
+		}
+		return nil, err
+	}
+	// каталог читаем до транзакции, чтобы не держать её на время сетевых запросов
+	dir, err := s.fetch()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrLDAPUnavailable, err)
+	}
+	report.Groups, report.Users = dir.groupCount, dir.userCount
+
+	if dryRun {
+		plan, err := s.plan(s.teams.uow.Repos(ctx), dir)
+		if err != nil {
+			return nil, err
+		}
+		report.Changes, report.Warnings = plan.changes, plan.warnings
+		return report, nil
+	}
+
+	err = s.teams.update(ctx, func(repos repository.Repos) error {
+		plan, err := s.plan(repos, dir)
+		if err != nil {
+			return err
+		}
+		report.Changes, report.Warnings = plan.changes, plan.warnings
+		if report.Reassigned, err = s.apply(ctx, repos, plan); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditLDAPSynced, model.AuditTargetOrg, repository.TenantFrom(ctx), map[string]string{
+			"groups":  strconv.Itoa(dir.groupCount),
+			"users":   strconv.Itoa(dir.userCount),
+			"changes": strconv.Itoa(len(plan.changes)),
+		}, s.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Applied = true
+	return report, nil
+}
+
+// ldapUser — пользователь каталога; id пуст, если пользователи сопоставляются по имени
+type ldapUser struct {
+	id       string
+	username string
+}
+
+// ldapGroup — группа каталога с участниками, найденными среди пользователей
+type ldapGroup struct {
+	name    string
+	members []*ldapUser
+}
+
+// ldapDirectory — прочитанный каталог
+type ldapDirectory struct {
+	users      []*ldapUser
+	groups     []ldapGroup
+	userCount  int
+	groupCount int
+	warnings   []string
+}
+
+// fetch читает пользователей и группы и сопоставляет участников групп с пользователями:
+// по DN, а значение без «=» — по имени пользователя (memberUid в posixGroup)
+func (s *LDAPSyncService) fetch() (*ldapDirectory, error) {
+	conn, err := ldap.Dial(s.cfg.URL, s.cfg.Timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if s.cfg.BindDN != "" {
+		if err := conn.Bind(s.cfg.BindDN, s.cfg.BindPassword); err != nil {
+			return nil, err
+		}
+	}
+
+	userAttrs := []string{s.cfg.UsernameAttr}
+	if s.cfg.UserIDAttr != "" {
+		userAttrs = append(userAttrs, s.cfg.UserIDAttr)
+	}
+	userEntries, err := conn.Search(&ldap.SearchRequest{
+		BaseDN:     s.cfg.UserBaseDN,
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     s.cfg.UserFilter,
+		Attributes: userAttrs,
+		PageSize:   s.cfg.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+	groupEntries, err := conn.Search(&ldap.SearchRequest{
+		BaseDN:     s.cfg.BaseDN,
+		Scope:      ldap.ScopeWholeSubtree,
+		Filter:     s.cfg.GroupFilter,
+		Attributes: []string{s.cfg.GroupNameAttr, s.cfg.MemberAttr},
+		PageSize:   s.cfg.PageSize,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	dir := &ldapDirectory{userCount: len(userEntries), groupCount: len(groupEntries)}
+	warn := func(format string, args ...interface{}) {
+		dir.warnings = append(dir.warnings, fmt.Sprintf(format, args...))
+	}
+
+	byDN := make(map[string]*ldapUser, len(userEntries))
+	byName := make(map[string]*ldapUser, len(userEntries))
+	byID := make(map[string]*ldapUser, len(userEntries))
+	for _, e := range userEntries {
+		u := &ldapUser{username: e.Value(s.cfg.UsernameAttr)}
+		if u.username == "" {
+			warn("user %s: no %s", e.DN, s.cfg.UsernameAttr)
+			continue
+		}
+		if s.cfg.UserIDAttr != "" {
+			u.id = e.Value(s.cfg.UserIDAttr)
+			if id, err := parseID(u.id); err != nil || id <= 0 {
+				warn("user %s: %s %q is not a positive integer", e.DN, s.cfg.UserIDAttr, u.id)
+				continue
+			}
+			if byID[u.id] != nil {
+				warn("user %s: %s %s is already used by %s", e.DN, s.cfg.UserIDAttr, u.id, byID[u.id].username)
+				continue
+			}
+		}
+		key := strings.ToLower(u.username)
+		if byName[key] != nil {
+			warn("user %s: username %s is already used", e.DN, u.username)
+			continue
+		}
+		byDN[ldap.NormalizeDN(e.DN)], byName[key] = u, u
+		if u.id != "" {
+			byID[u.id] = u
+		}
+		dir.users = append(dir.users, u)
+	}
+
+	seenGroup := make(map[string]bool, len(groupEntries))
+	for _, e := range groupEntries {
+		g := ldapGroup{name: e.Value(s.cfg.GroupNameAttr)}
+		if g.name == "" {
+			warn("group %s: no %s", e.DN, s.cfg.GroupNameAttr)
+			continue
+		}
+		if seenGroup[g.name] {
+			warn("group %s: team name %s is already used", e.DN, g.name)
+			continue
+		}
+		seenGroup[g.name] = true
+		added := make(map[*ldapUser]bool)
+		for _, m := range e.Values(s.cfg.MemberAttr) {
+			var u *ldapUser
+			if strings.Contains(m, "=") {
+				u = byDN[ldap.NormalizeDN(m)]
+			} else {
+				u = byName[strings.ToLower(m)]
+			}
+			if u == nil {
+				warn("group %s: member %s is not a synchronized user", g.name, m)
+				continue
+			}
+			if !added[u] {
+				added[u] = true
+				g.members = append(g.members, u)
+			}
+		}
+		dir.groups = append(dir.groups, g)
+	}
+	sort.Slice(dir.groups, func(i, j int) bool { return dir.groups[i].name < dir.groups[j].name })
+	return dir, nil
+}
+
+// ldapPlan — изменения синхронизации; участники сгруппированы по командам для changeMembers
+type ldapPlan struct {
+	changes  []model.LDAPChange
+	warnings []string
+	add      map[string][]string
+	remove   map[string][]string
+}
+
+// plan сравнивает каталог с хранилищем. Пользователи и активность сверяются только для
+// участников групп; при DeactivateMissing деактивируются участники синхронизируемых команд,
+// которых нет среди пользователей каталога.
+func (s *LDAPSyncService) plan(repos repository.Repos, dir *ldapDirectory) (*ldapPlan, error) {
+	plan := &ldapPlan{
+		changes:  []model.LDAPChange{},
+		warnings: append([]string{}, dir.warnings...),
+		add:      make(map[string][]string),
+		remove:   make(map[string][]string),
+	}
+	users, err := repos.Users.List()
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*model.User, len(users))
+	byName := make(map[string]*model.User, len(users))
+	var maxID int64
+	for i := range users {
+		u := &users[i]
+		byID[strconv.FormatInt(u.ID, 10)] = u
+		byName[strings.ToLower(u.Username)] = u
+		if u.ID > maxID {
+			maxID = u.ID
+		}
+	}
+
+	// участники групп, по имени для стабильного порядка изменений
+	var members []*ldapUser
+	seen := make(map[*ldapUser]bool)
+	for _, g := range dir.groups {
+		for _, u := range g.members {
+			if !seen[u] {
+				seen[u] = true
+				members = append(members, u)
+			}
+		}
+	}
+	sort.Slice(members, func(i, j int) bool { return members[i].username < members[j].username })
+
+	// ids — ID участников в хранилище; пропущенные из-за конфликта имён в команды не попадают
+	ids := make(map[*ldapUser]string, len(members))
+	var activate []string
+	for _, u := range members {
+		var existing *model.User
+		id := u.id
+		if id != "" {
+			existing = byID[id]
+		} else if existing = byName[strings.ToLower(u.username)]; existing != nil {
+			id = strconv.FormatInt(existing.ID, 10)
+		}
+		holder := byName[strings.ToLower(u.username)]
+		taken := holder != nil && holder != existing
+
+		switch {
+		case existing == nil && taken:
+			plan.warnings = append(plan.warnings, fmt.Sprintf("user %s: username is taken by user %d", u.username, holder.ID))
+			continue
+		case existing == nil:
+			if id == "" {
+				maxID++
+				id = strconv.FormatInt(maxID, 10)
+			}
+			plan.changes = append(plan.changes, model.LDAPChange{Action: model.LDAPCreateUser, UserID: id, New: u.username})
+		case existing.Username != u.username && taken:
+			plan.warnings = append(plan.warnings, fmt.Sprintf("user %s: cannot rename user %s, username is taken by user %d", u.username, id, holder.ID))
+		case existing.Username != u.username:
+			plan.changes = append(plan.changes, model.LDAPChange{Action: model.LDAPUpdateUser, UserID: id, Field: "username", Old: existing.Username, New: u.username})
+		}
+		if existing != nil && !existing.IsActive && s.cfg.DeactivateMissing {
+			activate = append(activate, id)
+		}
+		ids[u] = id
+	}
+
+	synced := make(map[string]bool, len(dir.groups))
+	for _, g := range dir.groups {
+		synced[g.name] = true
+		if _, err := repos.Teams.GetByName(g.name); err == repository.ErrNotFound {
+			plan.changes = append(plan.changes, model.LDAPChange{Action: model.LDAPCreateTeam, TeamName: g.name})
+		} else if err != nil {
+			return nil, err
+		}
+
+		want := make(map[string]bool, len(g.members))
+		for _, u := range g.members {
+			if id, ok := ids[u]; ok {
+				want[id] = true
+			}
+		}
+		var add, remove []string
+		for id := range want {
+			if u := byID[id]; u == nil {
+				add = append(add, id)
+			} else if _, ok := u.Membership(g.name); !ok {
+				add = append(add, id)
+			}
+		}
+		for _, u := range users {
+			if _, ok := u.Membership(g.name); ok && !want[strconv.FormatInt(u.ID, 10)] {
+				remove = append(remove, strconv.FormatInt(u.ID, 10))
+			}
+		}
+		sortIDs(add)
+		sortIDs(remove)
+		for _, id := range add {
+			plan.changes = append(plan.changes, model.LDAPChange{Action: model.LDAPAddMember, TeamName: g.name, UserID: id})
+		}
+		for _, id := range remove {
+			plan.changes = append(plan.changes, model.LDAPChange{Action: model.LDAPRemoveMember, TeamName: g.name, UserID: id})
+		}
+		plan.add[g.name], plan.remove[g.name] = add, remove
+	}
+
+	if !s.cfg.DeactivateMissing {
+		return plan, nil
+	}
+	sortIDs(activate)
+	for _, id := range activate {
+		plan.changes = append(plan.changes, model.LDAPChange{Action: model.LDAPActivateUser, UserID: id, Field: "is_active", Old: "false", New: "true"})
+	}
+	present := make(map[string]bool, len(dir.users))
+	for _, u := range dir.users {
+		if u.id != "" {
+			present[u.id] = true
+		} else if existing := byName[strings.ToLower(u.username)]; existing != nil {
+			present[strconv.FormatInt(existing.ID, 10)] = true
+		}
+	}
+	for _, u := range users {
+		id := strconv.FormatInt(u.ID, 10)
+		if !u.IsActive || present[id] {
+			continue
+		}
+		for _, m := range u.Teams {
+			if synced[m.TeamName] {
+				plan.changes = append(plan.changes, model.LDAPChange{Action: model.LDAPDeactivateUser, UserID: id, Field: "is_active", Old: "true", New: "false"})
+				break
+			}
+		}
+	}
+	return plan, nil
+}
+
+// apply применяет план внутри транзакции: пользователи и команды, затем состав команд,
+// затем активность, чтобы ревью деактивированных переназначались уже по новому составу
+func (s *LDAPSyncService) apply(ctx context.Context, repos repository.Repos, plan *ldapPlan) ([]model.ReviewHandoff, error) {
+	handoffs := []model.ReviewHandoff{}
+	var teams []string
+	for _, c := range plan.changes {
+		switch c.Action {
+		case model.LDAPCreateUser:
+			id, _ := parseID(c.UserID)
+			user := &model.User{ID: id, Username: c.New, IsActive: true, Teams: []model.TeamMembership{}, Skills: []string{}}
+			if err := repos.Users.Create(user); err != nil {
+				return nil, err
+			}
+			if err := recordAudit(ctx, repos, model.AuditUserCreated, model.AuditTargetUser, c.UserID,
+				map[string]string{"username": c.New, "is_active": "true"}, s.now()); err != nil {
+				return nil, err
+			}
+		case model.LDAPUpdateUser:
+			user, err := repos.Users.GetByID(c.UserID)
+			if err != nil {
+				return nil, err
+			}
+			user.Username = c.New
+			if err := repos.Users.CreateOrUpdate(user); err != nil {
+				return nil, err
+			}
+			if err := recordAudit(ctx, repos, model.AuditUserRenamed, model.AuditTargetUser, c.UserID,
+				map[string]string{"old_username": c.Old, "username": c.New}, s.now()); err != nil {
+				return nil, err
+			}
+		case model.LDAPCreateTeam:
+			if err := repos.Teams.Create(&model.Team{Name: c.TeamName}); err != nil {
+				return nil, err
+			}
+		case model.LDAPAddMember, model.LDAPRemoveMember:
+			if len(teams) == 0 || teams[len(teams)-1] != c.TeamName {
+				teams = append(teams, c.TeamName)
+			}
+		}
+	}
+
+	for _, team := range teams {
+		released, err := s.teams.changeMembers(ctx, repos, team, plan.add[team], plan.remove[team])
+		if err != nil {
+			return nil, err
+		}
+		handoffs = append(handoffs, released...)
+	}
+
+	for _, c := range plan.changes {
+		if c.Action != model.LDAPActivateUser && c.Action != model.LDAPDeactivateUser {
+			continue
+		}
+		_, released, err := s.teams.prs.setUserActive(ctx, repos, c.UserID, c.Action == model.LDAPActivateUser)
+		if err != nil {
+			return nil, err
+		}
+		handoffs = append(handoffs, released...)
+	}
+	return handoffs, nil
+}
+
+// sortIDs упорядочивает числовые ID по возрастанию
+func sortIDs(ids []string) {
+	sort.Slice(ids, func(i, j int) bool {
+		a, _ := parseID(ids[i])
+		b, _ := parseID(ids[j])
+		return a < b
+	})
+}
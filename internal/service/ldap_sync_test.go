@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"pr-reviewer/internal/ldap"
+	"pr-reviewer/internal/ldap/ldaptest"
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository/memory"
+)
+
+func ldapUserEntry(uid, uidNumber string) *ldap.Entry {
+	attrs := map[string][]string{"objectClass": {"inetOrgPerson"}, "uid": {uid}}
+	if uidNumber != "" {
+		attrs["uidNumber"] = []string{uidNumber}
+	}
+	return ldap.NewEntry("uid="+uid+",ou=people,dc=example,dc=org", attrs)
+}
+
+func ldapGroupEntry(cn string, members ...string) *ldap.Entry {
+	var dns []string
+	for _, m := range members {
+		dns = append(dns, "uid="+m+",ou=people,dc=example,dc=org")
+	}
+	return ldap.NewEntry("cn="+cn+",ou=groups,dc=example,dc=org", map[string][]string{
+		"objectClass": {"groupOfNames"}, "cn": {cn}, "member": dns,
+	})
+}
+
+func TestLDAPSyncDryRun(t *testing.T) {
+	srv, err := ldaptest.NewServer("cn=sync,dc=example,dc=org", "secret",
+		ldap.NewEntry("dc=example,dc=org", map[string][]string{"objectClass": {"domain"}}),
+		ldapUserEntry("alice", "1"),
+		ldapUserEntry("carol", "3"),
+		ldapUserEntry("dave", "4"),
+		ldapUserEntry("ghost", ""),
+		ldapGroupEntry("backend", "alice", "carol", "nobody"),
+		ldapGroupEntry("frontend", "dave"),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer srv.Close()
+
+	st := memory.NewStore()
+	prs := NewPRService(st, DefaultAssignmentPolicy())
+	teams := NewTeamService(st, prs)
+	ctx := context.Background()
+	_, err = teams.CreateOrUpdateTeam(ctx, &model.Team{Name: "backend", Members: []model.TeamMember{
+		{UserID: "1", Username: "alice", IsActive: true},
+		{UserID: "2", Username: "bob", IsActive: true},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := DefaultLDAPSyncConfig()
+	cfg.URL, cfg.BaseDN = srv.URL(), "dc=example,dc=org"
+	cfg.BindDN, cfg.BindPassword = "cn=sync,dc=example,dc=org", "secret"
+	cfg.PageSize = 2
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	sync := NewLDAPSyncService(teams, cfg)
+
+	report, err := sync.Sync(ctx, true)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if !report.DryRun || report.Applied {
+		t.Fatalf("dry run report flags: %+v", report)
+	}
+	if report.Users != 4 || report.Groups != 2 {
+		t.Fatalf("read from directory: %d users, %d groups", report.Users, report.Groups)
+	}
+	want := []model.LDAPChange{
+		{Action: model.LDAPCreateUser, UserID: "3", New: "carol"},
+		{Action: model.LDAPCreateUser, UserID: "4", New: "dave"},
+		{Action: model.LDAPAddMember, TeamName: "backend", UserID: "3"},
+		{Action: model.LDAPRemoveMember, TeamName: "backend", UserID: "2"},
+		{Action: model.LDAPCreateTeam, TeamName: "frontend"},
+		{Action: model.LDAPAddMember, TeamName: "frontend", UserID: "4"},
+	}
+	if !reflect.DeepEqual(report.Changes, want) {
+		t.Fatalf("plan:\n got %+v\nwant %+v", report.Changes, want)
+	}
+	if len(report.Warnings) != 2 ||
+		!strings.Contains(report.Warnings[0], "uid=ghost") ||
+		!strings.Contains(report.Warnings[1], "uid=nobody") {
+		t.Fatalf("warnings: %q", report.Warnings)
+	}
+	// постраничное чтение: 4 пользователя и 2 группы страницами по 2
+	if got := srv.Searches(); got != 3 {
+		t.Fatalf("search requests: got %d, want 3", got)
+	}
+
+	// проверка ничего не меняет и не попадает в статус
+	if _, err := teams.GetTeam(ctx, "frontend"); err != ErrTeamNotFound {
+		t.Fatalf("dry run created a team: %v", err)
+	}
+	backend, _ := teams.GetTeam(ctx, "backend")
+	if len(backend.Members) != 2 {
+		t.Fatalf("dry run changed backend: %+v", backend.Members)
+	}
+	if status, _ := sync.Status(ctx); status.LastReport != nil {
+		t.Fatalf("dry run recorded in status: %+v", status)
+	}
+
+	// применение выполняет тот же план
+	applied, err := sync.Sync(ctx, false)
+	if err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+	if !applied.Applied || !reflect.DeepEqual(applied.Changes, want) {
+		t.Fatalf("applied report: %+v", applied)
+	}
+	backend, _ = teams.GetTeam(ctx, "backend")
+	var ids []string
+	for _, m := range backend.Members {
+		ids = append(ids, m.UserID)
+	}
+	if !reflect.DeepEqual(ids, []string{"1", "3"}) {
+		t.Fatalf("backend after sync: %v", ids)
+	}
+	if _, err := teams.GetTeam(ctx, "frontend"); err != nil {
+		t.Fatalf("frontend after sync: %v", err)
+	}
+
+	// повторная проверка после применения изменений не находит
+	again, err := sync.Sync(ctx, true)
+	if err != nil || len(again.Changes) != 0 {
+		t.Fatalf("dry run after sync: %+v, %v", again, err)
+	}
+}
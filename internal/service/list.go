@@ -0,0 +1,36 @@
+package service
+
+import (
+	"errors"
+	"pr-reviewer/internal/repository"
+)
+
+// Размер страницы списков
+const (
+	defaultPageSize = 50
+	maxPageSize     = 200
+)
+
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidSort   = errors.New("unsupported sort field")
+)
+
+// pageLimit приводит запрошенный размер страницы к допустимому диапазону
+func pageLimit(limit int) int {
+	if limit <= 0 {
+		return defaultPageSize
+	}
+	if limit > maxPageSize {
+		return maxPageSize
+	}
+	return limit
+}
+
+// mapListError переводит ошибки пагинации репозитория в ошибки сервиса
+func mapListError(err error) error {
+	if err == repository.ErrInvalidCursor {
+		return ErrInvalidCursor
+	}
+	return err
+}
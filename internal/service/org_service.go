@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+var (
+	ErrOrgNotFound     = errors.New("organization not found")
+	ErrOrgExists       = errors.New("organization already exists")
+	ErrInvalidOrgID    = errors.New("organization id must be 1-50 lowercase letters, digits or dashes")
+	ErrInvalidSettings = errors.New("invalid organization settings")
+)
+
+// OrgService управляет организациями и их настройками назначения
+type OrgService struct {
+	uow    repository.UnitOfWork
+	policy AssignmentPolicy
+	now    Clock
+}
+
+// NewOrgService создаёт сервис организаций; policy — политика сервиса, поверх которой
+// применяются настройки организаций
+func NewOrgService(uow repository.UnitOfWork, policy AssignmentPolicy) *OrgService {
+	return &OrgService{uow: uow, policy: policy, now: time.Now}
+}
+
+// CreateOrganization создаёт организацию
+func (s *OrgService) CreateOrganization(ctx context.Context, id, name string, settings model.OrgSettings) (*model.Organization, error) {
+	if !repository.ValidTenantID(id) {
+		return nil, ErrInvalidOrgID
+	}
+	if err := s.validateSettings(settings); err != nil {
+		return nil, err
+	}
+	org := &model.Organization{ID: id, Name: strings.TrimSpace(name), Settings: settings, CreatedAt: s.now()}
+	if org.Name == "" {
+		org.Name = id
+	}
+	if err := s.uow.Repos(ctx).Orgs.Create(org); err != nil {
+		if err == repository.ErrAlreadyExists {
+			return nil, ErrOrgExists
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// ListOrganizations возвращает все организации
+func (s *OrgService) ListOrganizations(ctx context.Context) ([]model.Organization, error) {
+	return s.uow.Repos(ctx).Orgs.List()
+}
+
+// GetOrganization возвращает организацию по ID
+func (s *OrgService) GetOrganization(ctx context.Context, id string) (*model.Organization, error) {
+	org, err := s.uow.Repos(ctx).Orgs.Get(id)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrOrgNotFound
+		}
+		return nil, err
+	}
+	return org, nil
+}
+
+// CurrentOrganization возвращает организацию из ctx и её итоговую политику назначения
+func (s *OrgService) CurrentOrganization(ctx context.Context) (*model.Organization, AssignmentPolicy, error) {
+	org, err := s.GetOrganization(ctx, repository.TenantFrom(ctx))
+	if err != nil {
+		return nil, AssignmentPolicy{}, err
+	}
+	return org, s.policy.Apply(org.Settings), nil
+}
+
+// UpdateSettings заменяет настройки организации из ctx. Незаданные поля снова берутся
+// из политики сервиса.
+func (s *OrgService) UpdateSettings(ctx context.Context, settings model.OrgSettings) (*model.Organization, AssignmentPolicy, error) {
+	if err := s.validateSettings(settings); err != nil {
+		return nil, AssignmentPolicy{}, err
+	}
+	tenant := repository.TenantFrom(ctx)
+
+	var org *model.Organization
+	err := s.uow.Do(ctx, func(repos repository.Repos) error {
+		var err error
+		org, err = repos.Orgs.UpdateSettings(tenant, settings)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return ErrOrgNotFound
+			}
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditOrgSettings, model.AuditTargetOrg, tenant, settingsAuditData(settings), s.now())
+	})
+	if err != nil {
+		return nil, AssignmentPolicy{}, err
+	}
+	return org, s.policy.Apply(org.Settings), nil
+}
+
+// validateSettings проверяет, что настройки вместе с политикой сервиса дают согласованную политику
+func (s *OrgService) validateSettings(settings model.OrgSettings) error {
+	if err := s.policy.Apply(settings).Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidSettings, err)
+	}
+	return nil
+}
+
+// settingsAuditData описывает заданные настройки для журнала аудита
+func settingsAuditData(settings model.OrgSettings) map[string]string {
+	data := map[string]string{}
+	for key, v := range map[string]*int{
+		"reviewer_count":   settings.ReviewerCount,
+		"min_reviewers":    settings.MinReviewers,
+		"max_reviewers":    settings.MaxReviewers,
+		"max_open_reviews": settings.MaxOpenReviews,
+		"review_sla_hours": settings.ReviewSLAHours,
+	} {
+		if v != nil {
+			data[key] = strconv.Itoa(*v)
+		}
+	}
+	if settings.Strategy != "" {
+		data["strategy"] = settings.Strategy
+	}
+	if settings.MergePolicy != "" {
+		data["merge_policy"] = settings.MergePolicy
+	}
+	return data
+}
@@ -0,0 +1,119 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"pr-reviewer/internal/model"
+)
+
+// Стратегии выбора ревьюверов
+const (
+	// StrategyRandom — случайный выбор среди кандидатов
+	StrategyRandom = "random"
+	// StrategySkills — предпочтение кандидатам, чьи навыки совпадают с метками PR
+	StrategySkills = "skills"
+	// StrategyLeastLoaded — предпочтение кандидатам с наименьшим числом открытых ревью
+	StrategyLeastLoaded = "least_loaded"
+	// StrategyManual — замена указана явно, выбор не производился
+	StrategyManual = "manual"
+)
+
+// Политики merge
+const (
+	// MergePolicyAny — PR можно смержить в любой момент
+	MergePolicyAny = "any"
+	// MergePolicyRequireReviewers — PR можно смержить, только если назначено не меньше MinReviewers ревьюверов
+	MergePolicyRequireReviewers = "require_reviewers"
+)
+
+var (
+	ErrUnknownStrategy    = errors.New("unknown assignment strategy")
+	ErrUnknownMergePolicy = errors.New("unknown merge policy")
+)
+
+// AssignmentPolicy описывает правила назначения ревьюверов
+type AssignmentPolicy struct {
+	// ReviewerCount — сколько ревьюверов назначается на новый PR
+	ReviewerCount int `json:"reviewer_count"`
+	// MinReviewers — меньше этого числа ревьюверов нельзя оставить при ручном удалении
+	MinReviewers int `json:"min_reviewers"`
+	// MaxReviewers — больше этого числа ревьюверов нельзя назначить вручную
+	MaxReviewers int `json:"max_reviewers"`
+	// MaxOpenReviews — лимит открытых ревью на одного пользователя, 0 — без лимита
+	MaxOpenReviews int `json:"max_open_reviews"`
+	// Strategy — стратегия выбора по умолчанию
+	Strategy string `json:"strategy"`
+	// MergePolicy — условие, при котором PR можно смержить
+	MergePolicy string `json:"merge_policy"`
+	// ReviewSLAHours — за сколько часов ревью должно быть выполнено, 0 — без SLA
+	ReviewSLAHours int `json:"review_sla_hours"`
+}
+
+// DefaultAssignmentPolicy возвращает политику, совпадающую с исходным поведением сервиса
+func DefaultAssignmentPolicy() AssignmentPolicy {
+	return AssignmentPolicy{
+		ReviewerCount: 2,
+		MinReviewers:  1,
+		MaxReviewers:  3,
+		Strategy:      StrategySkills,
+		MergePolicy:   MergePolicyAny,
+	}
+}
+
+// Validate проверяет согласованность политики
+func (p AssignmentPolicy) Validate() error {
+	if !ValidStrategy(p.Strategy) {
+		return fmt.Errorf("%w: %s", ErrUnknownStrategy, p.Strategy)
+	}
+	if !ValidMergePolicy(p.MergePolicy) {
+		return fmt.Errorf("%w: %s", ErrUnknownMergePolicy, p.MergePolicy)
+	}
+	if p.ReviewerCount < 0 || p.MinReviewers < 0 || p.MaxOpenReviews < 0 || p.ReviewSLAHours < 0 {
+		return errors.New("reviewer limits must not be negative")
+	}
+	if p.MinReviewers > p.MaxReviewers || p.ReviewerCount > p.MaxReviewers {
+		return fmt.Errorf("reviewer count %d and minimum %d must not exceed maximum %d", p.ReviewerCount, p.MinReviewers, p.MaxReviewers)
+	}
+	return nil
+}
+
+// Apply возвращает политику с заданными настройками организации поверх p
+func (p AssignmentPolicy) Apply(settings model.OrgSettings) AssignmentPolicy {
+	if settings.ReviewerCount != nil {
+		p.ReviewerCount = *settings.ReviewerCount
+	}
+	if settings.MinReviewers != nil {
+		p.MinReviewers = *settings.MinReviewers
+	}
+	if settings.MaxReviewers != nil {
+		p.MaxReviewers = *settings.MaxReviewers
+	}
+	if settings.MaxOpenReviews != nil {
+		p.MaxOpenReviews = *settings.MaxOpenReviews
+	}
+	if settings.Strategy != "" {
+		p.Strategy = settings.Strategy
+	}
+	if settings.MergePolicy != "" {
+		p.MergePolicy = settings.MergePolicy
+	}
+	if settings.ReviewSLAHours != nil {
+		p.ReviewSLAHours = *settings.ReviewSLAHours
+	}
+	return p
+}
+
+// ValidStrategy проверяет, что стратегия поддерживается
+func ValidStrategy(strategy string) bool {
+	switch strategy {
+	case StrategyRandom, StrategySkills, StrategyLeastLoaded:
+		return true
+	}
+	return false
+}
+
+// ValidMergePolicy проверяет, что политика merge поддерживается
+func ValidMergePolicy(policy string) bool {
+	return policy == MergePolicyAny || policy == MergePolicyRequireReviewers
+}
@@ -1,8 +1,8 @@
 package service
 
 import (
+	"context"
 	"errors"
-	"math/rand"
 	"pr-reviewer/internal/model"
 	"pr-reviewer/internal/repository"
 	"strconv"
@@ -10,141 +10,885 @@ import (
 )
 
 var (
-	ErrPRNotFound          = errors.New("PR not found")
-	ErrPRAlreadyMerged     = errors.New("PR already merged")
-	ErrNoCandidate         = errors.New("no active replacement candidate in team")
-	ErrReviewerNotAssigned = errors.New("reviewer is not assigned to this PR")
+	ErrPRNotFound           = errors.New("PR not found")
+	ErrPRAlreadyMerged      = errors.New("PR already merged")
+	ErrNoCandidate          = errors.New("no active replacement candidate in team")
+	ErrReviewerNotAssigned  = errors.New("reviewer is not assigned to this PR")
+	ErrReviewerIsAuthor     = errors.New("author cannot review own PR")
+	ErrReviewerInactive     = errors.New("reviewer is not active")
+	ErrAlreadyAssigned      = errors.New("reviewer is already assigned to this PR")
+	ErrReviewerOutOfOffice  = errors.New("reviewer is out of office")
+	ErrReviewerDeclined     = errors.New("reviewer has declined this PR")
+	ErrReviewerNotInPool    = errors.New("reviewer is not in the author's teams")
+	ErrTooManyReviewers     = errors.New("maximum number of reviewers reached")
+	ErrTooFewReviewers      = errors.New("minimum number of reviewers required")
+	ErrInvalidDeclineReason = errors.New("invalid decline reason")
+	ErrPRExists             = errors.New("PR already exists")
+	ErrConcurrentUpdate     = errors.New("resource was modified concurrently")
 )
 
 type PRService struct {
-	prRepo   *repository.PRRepo
-	userRepo *repository.UserRepo
-	teamRepo *repository.TeamRepo
+	uow    repository.UnitOfWork
+	policy AssignmentPolicy
+
+	now        Clock
+	newRand    RandFactory
+	seedSecret []byte
 }
 
-func NewPRService(prRepo *repository.PRRepo, userRepo *repository.UserRepo, teamRepo *repository.TeamRepo) *PRService {
-	return &PRService{
-		prRepo:   prRepo,
-		userRepo: userRepo,
-		teamRepo: teamRepo,
+func NewPRService(uow repository.UnitOfWork, policy AssignmentPolicy, opts ...PRServiceOption) *PRService {
+	s := &PRService{
+		uow:     uow,
+		policy:  policy,
+		now:     time.Now,
+		newRand: defaultRandFactory,
+	}
+	for _, opt := range opts {
+		opt(s)
 	}
+	return s
 }
 
-// CreatePR создает PR и назначает до 2 ревьюверов
-func (s *PRService) CreatePR(pr *model.PullRequest) (*model.PullRequest, error) {
-	existing, _ := s.prRepo.GetByID(pr.ID)
-	if existing != nil {
-		return nil, errors.New("PR_EXISTS")
+// update выполняет fn в транзакции. Если PR изменили параллельно между чтением
+// и записью, транзакция откатывается и возвращается ErrConcurrentUpdate.
+func (s *PRService) update(ctx context.Context, fn func(repos repository.Repos) error) error {
+	err := s.uow.Do(ctx, fn)
+	if errors.Is(err, repository.ErrConflict) {
+		return ErrConcurrentUpdate
 	}
+	return err
+}
+
+// CreatePR создает PR и назначает ревьюверов согласно политике
+func (s *PRService) CreatePR(ctx context.Context, pr *model.PullRequest) (*model.PullRequest, error) {
+	err := s.update(ctx, func(repos repository.Repos) error {
+		existing, _ := repos.PRs.GetByID(pr.ID)
+		if existing != nil {
+			return ErrPRExists
+		}
+
+		pr.Status = "OPEN"
+		pr.CreatedAt = s.now()
+		pr.Labels = normalizeTags(pr.Labels)
 
-	pr.Status = "OPEN"
-	pr.CreatedAt = time.Now()
+		sel, err := s.selectForNewPR(ctx, repos, pr, "")
+		if err != nil {
+			if err == ErrUserNotFound {
+				return ErrPRNotFound
+			}
+			return err
+		}
+		pr.AssignedReviewers = sel.reviewers
+		pr.Assignment = sel.explain(model.AssignmentActionCreate, pr.CreatedAt)
 
-	// получаем команду автора
-	user, err := s.userRepo.GetByID(pr.AuthorID)
+		if err := repos.PRs.Create(pr); err != nil {
+			// параллельный запрос успел создать PR с тем же ID
+			if err == repository.ErrAlreadyExists {
+				return ErrPRExists
+			}
+			return err
+		}
+		return repos.History.Add(&model.PREvent{
+			PRID:       pr.ID,
+			Event:      model.PREventCreated,
+			Assignment: pr.Assignment,
+			CreatedAt:  pr.CreatedAt,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// PreviewAssignment показывает, кого выбрал бы CreatePR, ничего не записывая в БД.
+// Пустая strategy означает стратегию из политики команды автора.
+func (s *PRService) PreviewAssignment(ctx context.Context, pr *model.PullRequest, strategy string) (*model.AssignmentPreview, error) {
+	pr.Labels = normalizeTags(pr.Labels)
+	sel, err := s.selectForNewPR(ctx, s.uow.Repos(ctx), pr, strategy)
+	if err != nil {
+		return nil, err
+	}
+	return sel.preview(), nil
+}
+
+// selectForNewPR выбирает ревьюверов для нового PR из всех команд автора по политике его
+// основной команды; при нехватке кандидатов пул расширяется на родительские команды, если
+// это разрешено. Пустая strategy означает стратегию из политики.
+func (s *PRService) selectForNewPR(ctx context.Context, repos repository.Repos, pr *model.PullRequest, strategy string) (*selection, error) {
+	author, err := repos.Users.GetByID(pr.AuthorID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	policy, _, err := s.teamPolicy(ctx, repos, author.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	if strategy == "" {
+		strategy = policy.Strategy
+	}
+	if !ValidStrategy(strategy) {
+		return nil, ErrUnknownStrategy
+	}
+	ancestors, err := s.poolAncestors(ctx, repos, author.TeamName)
+	if err != nil {
+		return nil, err
+	}
+	load, err := s.openReviewLoad(repos, policy, strategy)
 	if err != nil {
-		return nil, ErrPRNotFound
+		return nil, err
 	}
-	users, _ := s.userRepo.GetByTeam(user.TeamName)
 
-	var reviewers []string
-	for _, u := range users {
-		userIDStr := strconv.FormatInt(u.ID, 10)
-		if userIDStr != pr.AuthorID && u.IsActive {
-			reviewers = append(reviewers, userIDStr)
+	return s.selectFromTeams(ctx, repos, author.TeamNames(), ancestors, func(pool *reviewPool) selectionInput {
+		excluded := pool.excluded()
+		excluded[pr.AuthorID] = model.ExcludedAuthor
+		return selectionInput{
+			users:    pool.users,
+			excluded: excluded,
+			labels:   pr.Labels,
+			load:     load,
+			maxOpen:  policy.MaxOpenReviews,
+			weights:  pool.weights,
+			strategy: strategy,
+			limit:    policy.ReviewerCount,
+			seed:     deriveSeed(s.seedSecret, pr.ID, model.AssignmentActionCreate),
+		}
+	})
+}
+
+// reviewPool — участники команд, из которых выбираются ревьюверы
+type reviewPool struct {
+	users    []model.User
+	weights  map[string]int  // наибольший вес среди активных членств пользователя в этих командах
+	inactive map[string]bool // пользователи, чьи членства в этих командах неактивны
+	leads    map[string]bool // лиды, которых настройки команд не назначают ревьюверами
+}
+
+// teamPool собирает участников команд teams без повторов. Пользователь из нескольких команд
+// получает наибольший вес среди своих активных членств в них. Членство лида не учитывается,
+// если в итоговых настройках его команды leads_review выключен.
+func (s *PRService) teamPool(ctx context.Context, repos repository.Repos, teams []string) (*reviewPool, error) {
+	pool := &reviewPool{weights: make(map[string]int), inactive: make(map[string]bool), leads: make(map[string]bool)}
+	seen := make(map[int64]bool)
+	skippedLead := make(map[string]bool)
+	for _, team := range teams {
+		users, err := repos.Users.GetByTeam(team)
+		if err != nil {
+			return nil, err
+		}
+		leadsReview := true
+		for _, u := range users {
+			if m, _ := u.Membership(team); m.Role == model.TeamRoleLead {
+				_, eff, err := s.teamPolicy(ctx, repos, team)
+				if err != nil {
+					return nil, err
+				}
+				leadsReview = eff.LeadsReview
+				break
+			}
+		}
+		for _, u := range users {
+			if !seen[u.ID] {
+				seen[u.ID] = true
+				pool.users = append(pool.users, u)
+			}
+			id := strconv.FormatInt(u.ID, 10)
+			m, _ := u.Membership(team)
+			if m.Role == model.TeamRoleLead && !leadsReview {
+				skippedLead[id] = true
+				continue
+			}
+			if m.IsActive && m.Weight > pool.weights[id] {
+				pool.weights[id] = m.Weight
+			}
 		}
 	}
-	// выбираем случайно до 2 ревьюверов
-	rand.Shuffle(len(reviewers), func(i, j int) { reviewers[i], reviewers[j] = reviewers[j], reviewers[i] })
-	if len(reviewers) > 2 {
-		reviewers = reviewers[:2]
+	for _, u := range pool.users {
+		id := strconv.FormatInt(u.ID, 10)
+		if _, ok := pool.weights[id]; ok {
+			continue
+		}
+		if skippedLead[id] {
+			pool.leads[id] = true
+		} else {
+			pool.inactive[id] = true
+		}
 	}
-	pr.AssignedReviewers = reviewers
+	return pool, nil
+}
 
-	if err := s.prRepo.Create(pr); err != nil {
+// excluded возвращает исключения пула: пользователей без активного членства и лидов,
+// не участвующих в ревью
+func (p *reviewPool) excluded() map[string]string {
+	excluded := make(map[string]string, len(p.inactive)+len(p.leads))
+	for id := range p.inactive {
+		excluded[id] = model.ExcludedMembershipInactive
+	}
+	for id := range p.leads {
+		excluded[id] = model.ExcludedTeamLead
+	}
+	return excluded
+}
+
+// openReviewLoad загружает число открытых ревью на пользователя, если оно нужно политике или стратегии
+func (s *PRService) openReviewLoad(repos repository.Repos, policy AssignmentPolicy, strategy string) (map[string]int, error) {
+	if policy.MaxOpenReviews == 0 && strategy != StrategyLeastLoaded {
+		return nil, nil
+	}
+	return repos.PRs.CountOpenReviews()
+}
+
+// authorPolicy возвращает политику основной команды автора PR — по ней назначаются ревьюверы
+func (s *PRService) authorPolicy(ctx context.Context, repos repository.Repos, pr *model.PullRequest) (AssignmentPolicy, error) {
+	author, err := repos.Users.GetByID(pr.AuthorID)
+	if err != nil {
+		return AssignmentPolicy{}, err
+	}
+	policy, _, err := s.teamPolicy(ctx, repos, author.TeamName)
+	return policy, err
+}
+
+// MergePR помечает PR как MERGED (идемпотентно), если это разрешает политика merge команды автора
+func (s *PRService) MergePR(ctx context.Context, prID string) (*model.PullRequest, error) {
+	var pr *model.PullRequest
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		pr, err = repos.PRs.GetByID(prID)
+		if err != nil {
+			return ErrPRNotFound
+		}
+		if pr.Status == "MERGED" {
+			return nil
+		}
+		if err := s.checkMergePolicy(ctx, repos, pr); err != nil {
+			return err
+		}
+		now := s.now()
+		pr.Status = "MERGED"
+		pr.MergedAt = &now
+		if err := repos.PRs.Update(pr); err != nil {
+			return err
+		}
+		if err := repos.History.Add(&model.PREvent{
+			PRID:      pr.ID,
+			Event:     model.PREventMerged,
+			CreatedAt: now,
+		}); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditPRMerged, model.AuditTargetPR, pr.ID, nil, now)
+	})
+	if err != nil {
 		return nil, err
 	}
 	return pr, nil
 }
 
-// MergePR помечает PR как MERGED (идемпотентно)
-func (s *PRService) MergePR(prID string) (*model.PullRequest, error) {
-	pr, err := s.prRepo.GetByID(prID)
+// checkMergePolicy проверяет, что политика merge команды автора разрешает смержить PR
+func (s *PRService) checkMergePolicy(ctx context.Context, repos repository.Repos, pr *model.PullRequest) error {
+	policy, err := s.authorPolicy(ctx, repos, pr)
 	if err != nil {
-		return nil, ErrPRNotFound
+		return err
 	}
-	if pr.Status == "MERGED" {
-		return pr, nil
+	if policy.MergePolicy == MergePolicyRequireReviewers && len(pr.AssignedReviewers) < policy.MinReviewers {
+		return ErrMergeBlocked
 	}
-	now := time.Now()
-	pr.Status = "MERGED"
-	pr.MergedAt = &now
-	if err := s.prRepo.Update(pr); err != nil {
-		return nil, err
+	return nil
+}
+
+// ReassignOptions уточняет, как выбрать замену при переназначении
+type ReassignOptions struct {
+	// NewUserID — конкретный пользователь на замену; пустое значение означает автоматический выбор
+	NewUserID string
+	// Strategy — стратегия автоматического выбора; пустое значение означает стратегию из политики
+	Strategy string
+
+	// poolTeams — команды, из которых выбирается замена, вместо команд старого ревьювера
+	poolTeams []string
+}
+
+// ReassignReviewer заменяет ревьювера на указанного пользователя или подбирает замену
+// из общих команд старого ревьювера и автора PR (если общих нет — из команд старого ревьювера).
+// Автор PR, уже назначенные ревьюверы и отказавшиеся от этого PR пользователи в замену не попадают.
+func (s *PRService) ReassignReviewer(ctx context.Context, prID, oldUserID string, opts ReassignOptions) (*model.PullRequest, *model.ChosenReviewer, error) {
+	var pr *model.PullRequest
+	var chosen *model.ChosenReviewer
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		pr, chosen, err = s.replaceReviewer(ctx, repos, prID, oldUserID, opts, model.PREventReassigned, nil)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditReviewerReassign, model.AuditTargetPR, pr.ID, map[string]string{
+			"old_user_id": oldUserID,
+			"new_user_id": chosen.UserID,
+			"strategy":    pr.Assignment.Strategy,
+		}, pr.Assignment.DecidedAt)
+	})
+	if err != nil {
+		return nil, nil, err
 	}
-	return pr, nil
+	return pr, chosen, nil
 }
 
-// ReassignReviewer заменяет ревьювера на случайного активного пользователя из команды
-func (s *PRService) ReassignReviewer(prID, oldUserID string) (*model.PullRequest, string, error) {
-	pr, err := s.prRepo.GetByID(prID)
+// DeclineReview фиксирует отказ назначенного ревьювера и автоматически подбирает замену
+func (s *PRService) DeclineReview(ctx context.Context, prID, userID, reason, comment string) (*model.PullRequest, *model.ChosenReviewer, error) {
+	if !model.ValidDeclineReason(reason) {
+		return nil, nil, ErrInvalidDeclineReason
+	}
+
+	var pr *model.PullRequest
+	var chosen *model.ChosenReviewer
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		pr, chosen, err = s.replaceReviewer(ctx, repos, prID, userID, ReassignOptions{}, model.PREventDeclined, map[string]string{"reason": reason})
+		if err != nil {
+			return err
+		}
+		return repos.Declines.Add(&model.ReviewDecline{
+			PRID:       prID,
+			UserID:     userID,
+			Reason:     reason,
+			Comment:    comment,
+			ReplacedBy: chosen.UserID,
+			CreatedAt:  s.now(),
+		})
+	})
 	if err != nil {
-		return nil, "", ErrPRNotFound
+		return nil, nil, err
+	}
+	return pr, chosen, nil
+}
+
+// GetDeclines возвращает отказы пользователя начиная с since
+func (s *PRService) GetDeclines(ctx context.Context, userID string, since time.Time) ([]model.ReviewDecline, error) {
+	repos := s.uow.Repos(ctx)
+	if _, err := repos.Users.GetByID(userID); err != nil {
+		return nil, ErrUserNotFound
+	}
+	return repos.Declines.ListByUser(userID, since)
+}
+
+// GetDeclineStats возвращает сводку отказов по пользователям с не менее чем minTotal отказами
+func (s *PRService) GetDeclineStats(ctx context.Context, since time.Time, minTotal int) ([]model.DeclineStats, error) {
+	return s.uow.Repos(ctx).Declines.Stats(since, minTotal)
+}
+
+// replaceReviewer заменяет oldUserID на другого ревьювера по политике основной команды автора PR
+// и пишет событие event в историю PR
+func (s *PRService) replaceReviewer(ctx context.Context, repos repository.Repos, prID, oldUserID string, opts ReassignOptions, event string, data map[string]string) (*model.PullRequest, *model.ChosenReviewer, error) {
+	if opts.Strategy != "" && !ValidStrategy(opts.Strategy) {
+		return nil, nil, ErrUnknownStrategy
+	}
+
+	pr, err := repos.PRs.GetByID(prID)
+	if err != nil {
+		return nil, nil, ErrPRNotFound
 	}
 	if pr.Status == "MERGED" {
-		return nil, "", ErrPRAlreadyMerged
+		return nil, nil, ErrPRAlreadyMerged
 	}
 
 	// проверяем что oldUserID назначен
 	found := false
-	for _, r := range pr.AssignedReviewers {
-		if r == oldUserID {
+	for _, id := range pr.AssignedReviewers {
+		if id == oldUserID {
 			found = true
 			break
 		}
 	}
 	if !found {
-		return nil, "", ErrReviewerNotAssigned
+		return nil, nil, ErrReviewerNotAssigned
+	}
+
+	now := s.now()
+	if opts.NewUserID != "" {
+		if err := s.checkCanReview(ctx, repos, pr, opts.NewUserID); err != nil {
+			return nil, nil, err
+		}
+		pr.Assignment = &model.AssignmentExplanation{
+			Action:     model.AssignmentActionReassign,
+			Strategy:   StrategyManual,
+			PoolSize:   1,
+			Candidates: []string{opts.NewUserID},
+			Filters:    []string{},
+			Excluded:   []model.ExcludedCandidate{},
+			Chosen: []model.ChosenReviewer{
+				{UserID: opts.NewUserID, Reason: "requested explicitly"},
+			},
+			DecidedAt: now,
+		}
+	} else {
+		author, err := repos.Users.GetByID(pr.AuthorID)
+		if err != nil {
+			return nil, nil, err
+		}
+		policy, _, err := s.teamPolicy(ctx, repos, author.TeamName)
+		if err != nil {
+			return nil, nil, err
+		}
+		strategy := opts.Strategy
+		if strategy == "" {
+			strategy = policy.Strategy
+		}
+		teams := opts.poolTeams
+		if teams == nil {
+			teams, err = s.reassignTeams(repos, pr, oldUserID)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		ancestors, err := s.poolAncestors(ctx, repos, author.TeamName)
+		if err != nil {
+			return nil, nil, err
+		}
+		load, err := s.openReviewLoad(repos, policy, strategy)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		declined, err := repos.Declines.DeclinedUsers(pr.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		sel, err := s.selectFromTeams(ctx, repos, teams, ancestors, func(pool *reviewPool) selectionInput {
+			excluded := pool.excluded()
+			excluded[pr.AuthorID] = model.ExcludedAuthor
+			for _, id := range declined {
+				excluded[id] = model.ExcludedDeclined
+			}
+			for _, id := range pr.AssignedReviewers {
+				excluded[id] = model.ExcludedCurrentReviewer
+			}
+			return selectionInput{
+				users:    pool.users,
+				excluded: excluded,
+				labels:   pr.Labels,
+				load:     load,
+				maxOpen:  policy.MaxOpenReviews,
+				weights:  pool.weights,
+				strategy: strategy,
+				limit:    1,
+				seed:     deriveSeed(s.seedSecret, pr.ID, model.AssignmentActionReassign, oldUserID),
+			}
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(sel.reviewers) == 0 {
+			return nil, nil, ErrNoCandidate
+		}
+		pr.Assignment = sel.explain(model.AssignmentActionReassign, now)
+	}
+	chosen := pr.Assignment.Chosen[0]
+
+	for i, id := range pr.AssignedReviewers {
+		if id == oldUserID {
+			pr.AssignedReviewers[i] = chosen.UserID
+			break
+		}
+	}
+
+	if err := repos.PRs.Update(pr); err != nil {
+		return nil, nil, err
+	}
+	eventData := map[string]string{"old_user_id": oldUserID, "new_user_id": chosen.UserID}
+	for k, v := range data {
+		eventData[k] = v
+	}
+	if err := repos.History.Add(&model.PREvent{
+		PRID:       pr.ID,
+		Event:      event,
+		Assignment: pr.Assignment,
+		Data:       eventData,
+		CreatedAt:  now,
+	}); err != nil {
+		return nil, nil, err
+	}
+	return pr, &chosen, nil
+}
+
+// reassignTeams возвращает команды, из которых подбирается замена ревьюверу oldUserID:
+// его общие команды с автором PR, а если общих нет — все его команды
+func (s *PRService) reassignTeams(repos repository.Repos, pr *model.PullRequest, oldUserID string) ([]string, error) {
+	oldUser, err := repos.Users.GetByID(oldUserID)
+	if err != nil {
+		return nil, err
+	}
+	author, err := repos.Users.GetByID(pr.AuthorID)
+	if err != nil {
+		return nil, err
+	}
+	var shared []string
+	for _, team := range oldUser.TeamNames() {
+		if _, ok := author.Membership(team); ok {
+			shared = append(shared, team)
+		}
 	}
+	if len(shared) == 0 {
+		return oldUser.TeamNames(), nil
+	}
+	return shared, nil
+}
 
-	oldUser, err := s.userRepo.GetByID(oldUserID)
+// releaseReviews вызывается после изменения команд пользователя и снимает его с открытых ревью
+// PR, с автором которых у него не осталось общих команд: каждое такое ревью переназначается
+// на участника команд автора по политике его команды, а если замены нет — ревьювер снимается
+// без замены. reason пишется в историю PR.
+func (s *PRService) releaseReviews(ctx context.Context, repos repository.Repos, userID, reason string) ([]model.ReviewHandoff, error) {
+	prs, err := repos.PRs.GetByReviewer(userID, "OPEN")
+	if err != nil {
+		return nil, err
+	}
+	user, err := repos.Users.GetByID(userID)
 	if err != nil {
-		return nil, "", err
+		return nil, err
 	}
-	teamUsers, _ := s.userRepo.GetByTeam(oldUser.TeamName)
 
-	var candidates []string
-	for _, u := range teamUsers {
-		userIDStr := strconv.FormatInt(u.ID, 10)
-		if userIDStr != oldUserID && u.IsActive {
-			candidates = append(candidates, userIDStr)
+	handoffs := []model.ReviewHandoff{}
+	for _, pr := range prs {
+		author, err := repos.Users.GetByID(pr.AuthorID)
+		if err != nil {
+			return nil, err
+		}
+		if sharesTeam(user, author) {
+			continue
+		}
+		h, err := s.handOffReview(ctx, repos, pr.ID, userID, ReassignOptions{poolTeams: author.TeamNames()}, reason)
+		if err != nil {
+			return nil, err
 		}
+		handoffs = append(handoffs, h)
 	}
-	if len(candidates) == 0 {
-		return nil, "", ErrNoCandidate
+	return handoffs, nil
+}
+
+// releaseAllReviews снимает деактивированного пользователя со всех его открытых ревью: каждое
+// переназначается, как при ReassignReviewer, а если замены нет — ревьювер снимается без замены
+func (s *PRService) releaseAllReviews(ctx context.Context, repos repository.Repos, userID, reason string) ([]model.ReviewHandoff, error) {
+	prs, err := repos.PRs.GetByReviewer(userID, "OPEN")
+	if err != nil {
+		return nil, err
 	}
+	handoffs := []model.ReviewHandoff{}
+	for _, pr := range prs {
+		h, err := s.handOffReview(ctx, repos, pr.ID, userID, ReassignOptions{}, reason)
+		if err != nil {
+			return nil, err
+		}
+		handoffs = append(handoffs, h)
+	}
+	return handoffs, nil
+}
 
-	rand.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
-	newReviewer := candidates[0]
+// handOffReview переназначает ревью userID в PR prID, а без подходящей замены снимает ревьювера
+func (s *PRService) handOffReview(ctx context.Context, repos repository.Repos, prID, userID string, opts ReassignOptions, reason string) (model.ReviewHandoff, error) {
+	_, chosen, err := s.replaceReviewer(ctx, repos, prID, userID, opts, model.PREventReassigned, map[string]string{"reason": reason})
+	switch err {
+	case nil:
+		return model.ReviewHandoff{PRID: prID, UserID: userID, ReplacedBy: chosen.UserID}, nil
+	case ErrNoCandidate:
+	default:
+		return model.ReviewHandoff{}, err
+	}
+	if err := s.dropReviewer(repos, prID, userID, reason); err != nil {
+		return model.ReviewHandoff{}, err
+	}
+	return model.ReviewHandoff{PRID: prID, UserID: userID}, nil
+}
 
-	for i, r := range pr.AssignedReviewers {
-		if r == oldUserID {
-			pr.AssignedReviewers[i] = newReviewer
-			break
+// setUserActive меняет флаг активности пользователя и пишет изменение в журнал аудита.
+// Деактивированный пользователь снимается со всех открытых ревью (releaseAllReviews).
+func (s *PRService) setUserActive(ctx context.Context, repos repository.Repos, userID string, isActive bool) (*model.User, []model.ReviewHandoff, error) {
+	user, err := repos.Users.SetIsActive(userID, isActive)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := recordAudit(ctx, repos, model.AuditUserActivity, model.AuditTargetUser, userID,
+		map[string]string{"is_active": strconv.FormatBool(isActive)}, s.now()); err != nil {
+		return nil, nil, err
+	}
+	handoffs := []model.ReviewHandoff{}
+	if !isActive {
+		if handoffs, err = s.releaseAllReviews(ctx, repos, userID, ReleaseUserDeactivated); err != nil {
+			return nil, nil, err
+		}
+	}
+	return user, handoffs, nil
+}
+
+// sharesTeam сообщает, состоят ли пользователи хотя бы в одной общей команде
+func sharesTeam(a, b *model.User) bool {
+	for _, m := range a.Teams {
+		if _, ok := b.Membership(m.TeamName); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dropReviewer снимает ревьювера с PR без замены и без проверки минимального числа ревьюверов
+func (s *PRService) dropReviewer(repos repository.Repos, prID, userID, reason string) error {
+	pr, err := repos.PRs.GetByID(prID)
+	if err != nil {
+		return err
+	}
+	kept := make([]string, 0, len(pr.AssignedReviewers))
+	for _, id := range pr.AssignedReviewers {
+		if id != userID {
+			kept = append(kept, id)
+		}
+	}
+	pr.AssignedReviewers = kept
+	if err := repos.PRs.Update(pr); err != nil {
+		return err
+	}
+	return repos.History.Add(&model.PREvent{
+		PRID:      pr.ID,
+		Event:     model.PREventRemoved,
+		Data:      map[string]string{"user_id": userID, "reason": reason},
+		CreatedAt: s.now(),
+	})
+}
+
+// checkCanReview проверяет, что пользователя можно вручную назначить ревьювером PR: как и при
+// автоматическом выборе, он должен участвовать в ревью одной из команд автора (или её предков,
+// если пул расширяется), быть на месте и не отказываться от этого PR. Лимит открытых ревью и
+// настройка leads_review при ручном назначении не действуют.
+func (s *PRService) checkCanReview(ctx context.Context, repos repository.Repos, pr *model.PullRequest, userID string) error {
+	if userID == pr.AuthorID {
+		return ErrReviewerIsAuthor
+	}
+	for _, id := range pr.AssignedReviewers {
+		if id == userID {
+			return ErrAlreadyAssigned
+		}
+	}
+	user, err := repos.Users.GetByID(userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return ErrUserNotFound
+		}
+		return err
+	}
+	if !user.IsActive {
+		return ErrReviewerInactive
+	}
+	if user.IsOutOfOffice(s.now()) {
+		return ErrReviewerOutOfOffice
+	}
+	declined, err := repos.Declines.DeclinedUsers(pr.ID)
+	if err != nil {
+		return err
+	}
+	for _, id := range declined {
+		if id == userID {
+			return ErrReviewerDeclined
+		}
+	}
+
+	author, err := repos.Users.GetByID(pr.AuthorID)
+	if err != nil {
+		return err
+	}
+	ancestors, err := s.poolAncestors(ctx, repos, author.TeamName)
+	if err != nil {
+		return err
+	}
+	for _, team := range append(author.TeamNames(), ancestors...) {
+		if m, ok := user.Membership(team); ok && m.IsActive {
+			return nil
+		}
+	}
+	return ErrReviewerNotInPool
+}
+
+// AddReviewer вручную добавляет ревьювера на открытый PR
+func (s *PRService) AddReviewer(ctx context.Context, prID, userID string) (*model.PullRequest, error) {
+	var pr *model.PullRequest
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		pr, err = repos.PRs.GetByID(prID)
+		if err != nil {
+			return ErrPRNotFound
 		}
+		if pr.Status == "MERGED" {
+			return ErrPRAlreadyMerged
+		}
+		if err := s.checkCanReview(ctx, repos, pr, userID); err != nil {
+			return err
+		}
+		policy, err := s.authorPolicy(ctx, repos, pr)
+		if err != nil {
+			return err
+		}
+		if len(pr.AssignedReviewers) >= policy.MaxReviewers {
+			return ErrTooManyReviewers
+		}
+
+		pr.AssignedReviewers = append(pr.AssignedReviewers, userID)
+		if err := repos.PRs.Update(pr); err != nil {
+			return err
+		}
+		return repos.History.Add(&model.PREvent{
+			PRID:      pr.ID,
+			Event:     model.PREventAdded,
+			Data:      map[string]string{"user_id": userID},
+			CreatedAt: s.now(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// RemoveReviewer вручную снимает ревьювера с открытого PR без подбора замены
+func (s *PRService) RemoveReviewer(ctx context.Context, prID, userID string) (*model.PullRequest, error) {
+	var pr *model.PullRequest
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		pr, err = repos.PRs.GetByID(prID)
+		if err != nil {
+			return ErrPRNotFound
+		}
+		if pr.Status == "MERGED" {
+			return ErrPRAlreadyMerged
+		}
+
+		kept := make([]string, 0, len(pr.AssignedReviewers))
+		for _, id := range pr.AssignedReviewers {
+			if id != userID {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == len(pr.AssignedReviewers) {
+			return ErrReviewerNotAssigned
+		}
+		policy, err := s.authorPolicy(ctx, repos, pr)
+		if err != nil {
+			return err
+		}
+		if len(kept) < policy.MinReviewers {
+			return ErrTooFewReviewers
+		}
+
+		pr.AssignedReviewers = kept
+		if err := repos.PRs.Update(pr); err != nil {
+			return err
+		}
+		return repos.History.Add(&model.PREvent{
+			PRID:      pr.ID,
+			Event:     model.PREventRemoved,
+			Data:      map[string]string{"user_id": userID},
+			CreatedAt: s.now(),
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
+}
+
+// GetPR возвращает PR по ID
+func (s *PRService) GetPR(ctx context.Context, prID string) (*model.PullRequest, error) {
+	pr, err := s.uow.Repos(ctx).PRs.GetByID(prID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrPRNotFound
+		}
+		return nil, err
 	}
+	return pr, nil
+}
 
-	if err := s.prRepo.Update(pr); err != nil {
-		return nil, "", err
+// AuthorTeams возвращает команды пользователя authorID — команды, к которым относятся его PR
+func (s *PRService) AuthorTeams(ctx context.Context, authorID string) ([]string, error) {
+	author, err := s.uow.Repos(ctx).Users.GetByID(authorID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
 	}
-	return pr, newReviewer, nil
+	return author.TeamNames(), nil
 }
 
-// GetPRsForReviewer возвращает PR, где пользователь назначен ревьювером
-func (s *PRService) GetPRsForReviewer(userID string) ([]model.PullRequest, error) {
-	_, err := s.userRepo.GetByID(userID)
+// GetHistory возвращает историю PR
+func (s *PRService) GetHistory(ctx context.Context, prID string) ([]model.PREvent, error) {
+	if _, err := s.GetPR(ctx, prID); err != nil {
+		return nil, err
+	}
+	return s.uow.Repos(ctx).History.ListByPR(prID)
+}
+
+// ListPRs возвращает страницу PR по фильтру и курсор следующей страницы
+func (s *PRService) ListPRs(ctx context.Context, filter repository.PRFilter) ([]model.PullRequest, string, error) {
+	if filter.SortBy == "" {
+		filter.SortBy = repository.PRSortCreatedAt
+	}
+	if !repository.ValidPRSort(filter.SortBy) {
+		return nil, "", ErrInvalidSort
+	}
+	filter.Limit = pageLimit(filter.Limit)
+	prs, next, err := s.uow.Repos(ctx).PRs.List(filter)
+	if err != nil {
+		return nil, "", mapListError(err)
+	}
+	return prs, next, nil
+}
+
+// GetPRsForReviewer возвращает PR, где пользователь назначен ревьювером; пустой status — все PR
+func (s *PRService) GetPRsForReviewer(ctx context.Context, userID, status string) ([]model.PullRequest, error) {
+	repos := s.uow.Repos(ctx)
+	_, err := repos.Users.GetByID(userID)
 	if err != nil {
 		return nil, ErrUserNotFound
 	}
-	return s.prRepo.GetByReviewer(userID)
+	return repos.PRs.GetByReviewer(userID, status)
+}
+
+// AddLabels добавляет метки к PR
+func (s *PRService) AddLabels(ctx context.Context, prID string, labels []string) (*model.PullRequest, error) {
+	return s.updateLabels(ctx, prID, func(current []string) []string {
+		return normalizeTags(append(current, labels...))
+	})
+}
+
+// RemoveLabels удаляет метки из PR
+func (s *PRService) RemoveLabels(ctx context.Context, prID string, labels []string) (*model.PullRequest, error) {
+	remove := make(map[string]bool, len(labels))
+	for _, l := range normalizeTags(labels) {
+		remove[l] = true
+	}
+	return s.updateLabels(ctx, prID, func(current []string) []string {
+		kept := make([]string, 0, len(current))
+		for _, l := range current {
+			if !remove[l] {
+				kept = append(kept, l)
+			}
+		}
+		return kept
+	})
+}
+
+// updateLabels заменяет метки PR результатом change в одной транзакции
+func (s *PRService) updateLabels(ctx context.Context, prID string, change func(current []string) []string) (*model.PullRequest, error) {
+	var pr *model.PullRequest
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		pr, err = repos.PRs.GetByID(prID)
+		if err != nil {
+			return ErrPRNotFound
+		}
+		pr.Labels = change(pr.Labels)
+		return repos.PRs.Update(pr)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return pr, nil
 }
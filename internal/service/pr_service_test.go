@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/repository/memory"
+)
+
+// barrierUoW придерживает фиксацию транзакций, пока n транзакций не выполнят fn,
+// чтобы все они работали с одним и тем же состоянием
+type barrierUoW struct {
+	repository.UnitOfWork
+	mu      sync.Mutex
+	pending int
+	ready   chan struct{}
+}
+
+func newBarrierUoW(uow repository.UnitOfWork, n int) *barrierUoW {
+	return &barrierUoW{UnitOfWork: uow, pending: n, ready: make(chan struct{})}
+}
+
+func (u *barrierUoW) Do(ctx context.Context, fn func(repository.Repos) error) error {
+	return u.UnitOfWork.Do(ctx, func(r repository.Repos) error {
+		err := fn(r)
+		u.mu.Lock()
+		if u.pending--; u.pending == 0 {
+			close(u.ready)
+		}
+		u.mu.Unlock()
+		<-u.ready
+		return err
+	})
+}
+
+// seedPR создаёт команду backend из шести активных участников и PR пользователя 1
+func seedPR(t *testing.T, st *memory.Store) *model.PullRequest {
+	t.Helper()
+	ctx := context.Background()
+	prs := NewPRService(st, DefaultAssignmentPolicy())
+	team := &model.Team{Name: "backend"}
+	for i := 1; i <= 6; i++ {
+		id := strconv.Itoa(i)
+		team.Members = append(team.Members, model.TeamMember{UserID: id, Username: "user" + id, IsActive: true})
+	}
+	if _, err := NewTeamService(st, prs).CreateOrUpdateTeam(ctx, team); err != nil {
+		t.Fatalf("create team: %v", err)
+	}
+	pr, err := prs.CreatePR(ctx, &model.PullRequest{ID: "pr-1", Name: "race", AuthorID: "1"})
+	if err != nil {
+		t.Fatalf("create PR: %v", err)
+	}
+	if len(pr.AssignedReviewers) == 0 {
+		t.Fatal("no reviewers assigned")
+	}
+	return pr
+}
+
+func TestConcurrentPRUpdatesHaveOneWinner(t *testing.T) {
+	const workers = 8
+	tests := []struct {
+		name string
+		// op выполняет изменение PR в i-й горутине; reviewer — назначенный ревьювер
+		op func(s *PRService, i int, reviewer string) error
+	}{
+		{"merge", func(s *PRService, _ int, _ string) error {
+			_, err := s.MergePR(context.Background(), "pr-1")
+			return err
+		}},
+		{"reassign", func(s *PRService, _ int, reviewer string) error {
+			_, _, err := s.ReassignReviewer(context.Background(), "pr-1", reviewer, ReassignOptions{})
+			return err
+		}},
+		{"merge and reassign", func(s *PRService, i int, reviewer string) error {
+			if i%2 == 0 {
+				_, err := s.MergePR(context.Background(), "pr-1")
+				return err
+			}
+			_, _, err := s.ReassignReviewer(context.Background(), "pr-1", reviewer, ReassignOptions{})
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := memory.NewStore()
+			created := seedPR(t, st)
+			prs := NewPRService(newBarrierUoW(st, workers), DefaultAssignmentPolicy())
+
+			errs := make([]error, workers)
+			var wg sync.WaitGroup
+			for i := 0; i < workers; i++ {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+					errs[i] = tt.op(prs, i, created.AssignedReviewers[0])
+				}(i)
+			}
+			wg.Wait()
+
+			winners := 0
+			for i, err := range errs {
+				switch err {
+				case nil:
+					winners++
+				case ErrConcurrentUpdate:
+				default:
+					t.Errorf("worker %d: want nil or ErrConcurrentUpdate, got %v", i, err)
+				}
+			}
+			if winners != 1 {
+				t.Fatalf("winners: got %d, want 1 (errors %v)", winners, errs)
+			}
+
+			pr, err := NewPRService(st, DefaultAssignmentPolicy()).GetPR(context.Background(), "pr-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pr.Version != created.Version+1 {
+				t.Fatalf("version: got %d, want %d", pr.Version, created.Version+1)
+			}
+			history, err := NewPRService(st, DefaultAssignmentPolicy()).GetHistory(context.Background(), "pr-1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			// событие создания и одно изменение победителя
+			if len(history) != 2 {
+				t.Fatalf("history: got %d events, want 2: %+v", len(history), history)
+			}
+		})
+	}
+}
+
+func TestManualReviewerLimitsUseAuthorTeamPolicy(t *testing.T) {
+	st := memory.NewStore()
+	prs := NewPRService(st, DefaultAssignmentPolicy())
+	teams := NewTeamService(st, prs)
+	ctx := context.Background()
+
+	// число ревьюверов наследуется от родителя, лимиты ручных изменений — из политики
+	three := 3
+	if _, err := teams.CreateOrUpdateTeam(ctx, &model.Team{Name: "eng", Settings: model.TeamSettings{ReviewerCount: &three}}); err != nil {
+		t.Fatal(err)
+	}
+	squad := &model.Team{Name: "squad", Parent: "eng"}
+	for i := 1; i <= 6; i++ {
+		id := strconv.Itoa(i)
+		squad.Members = append(squad.Members, model.TeamMember{UserID: id, Username: "user" + id, IsActive: true})
+	}
+	if _, err := teams.CreateOrUpdateTeam(ctx, squad); err != nil {
+		t.Fatal(err)
+	}
+	pr, err := prs.CreatePR(ctx, &model.PullRequest{ID: "pr-1", Name: "change", AuthorID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pr.AssignedReviewers) != 3 {
+		t.Fatalf("assigned %v, want 3 reviewers from inherited settings", pr.AssignedReviewers)
+	}
+
+	assigned := make(map[string]bool)
+	for _, id := range pr.AssignedReviewers {
+		assigned[id] = true
+	}
+	var spare string
+	for i := 2; i <= 6 && spare == ""; i++ {
+		if id := strconv.Itoa(i); !assigned[id] {
+			spare = id
+		}
+	}
+	if _, err := prs.AddReviewer(ctx, "pr-1", spare); err != ErrTooManyReviewers {
+		t.Fatalf("add over maximum: got %v", err)
+	}
+
+	reviewers := pr.AssignedReviewers
+	for _, id := range reviewers[:2] {
+		if _, err := prs.RemoveReviewer(ctx, "pr-1", id); err != nil {
+			t.Fatalf("remove %s: %v", id, err)
+		}
+	}
+	if _, err := prs.RemoveReviewer(ctx, "pr-1", reviewers[2]); err != ErrTooFewReviewers {
+		t.Fatalf("remove below minimum: got %v", err)
+	}
+	if _, err := prs.AddReviewer(ctx, "pr-1", spare); err != nil {
+		t.Fatalf("add below maximum: %v", err)
+	}
+}
+
+func TestReplayAssignment(t *testing.T) {
+	decidedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var seeds []int64
+	st := memory.NewStore()
+	prs := NewPRService(st, DefaultAssignmentPolicy(),
+		WithClock(func() time.Time { return decidedAt }),
+		WithRandFactory(func(seed int64) *rand.Rand {
+			seeds = append(seeds, seed)
+			return rand.New(rand.NewSource(seed))
+		}),
+		WithSeedSecret([]byte("replay")),
+	)
+	teams := NewTeamService(st, prs)
+	ctx := context.Background()
+
+	skills := [][]string{nil, {"go"}, {"go", "sql"}, {"frontend"}, {"sql"}, nil, {"go"}, {"frontend", "go"}}
+	team := &model.Team{Name: "backend"}
+	for i, sk := range skills {
+		id := strconv.Itoa(i + 1)
+		team.Members = append(team.Members, model.TeamMember{UserID: id, Username: "user" + id, IsActive: true, Skills: sk})
+	}
+	if _, err := teams.CreateOrUpdateTeam(ctx, team); err != nil {
+		t.Fatal(err)
+	}
+	// открытые PR другого автора создают неравную нагрузку
+	for i := 0; i < 3; i++ {
+		if _, err := prs.CreatePR(ctx, &model.PullRequest{ID: "load-" + strconv.Itoa(i), Name: "load", AuthorID: "2"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	half := 50
+	tests := []struct {
+		strategy string
+		weighted bool
+	}{
+		{StrategyRandom, false},
+		{StrategySkills, false},
+		{StrategyLeastLoaded, false},
+		{StrategyRandom, true},
+	}
+	for i, tt := range tests {
+		t.Run(fmt.Sprintf("%s weighted=%v", tt.strategy, tt.weighted), func(t *testing.T) {
+			if _, err := teams.UpdateSettings(ctx, "backend", model.TeamSettings{Strategy: tt.strategy}); err != nil {
+				t.Fatal(err)
+			}
+			if tt.weighted {
+				if _, err := teams.SetMembership(ctx, "backend", "3", nil, &half); err != nil {
+					t.Fatal(err)
+				}
+			}
+			repos := st.Repos(ctx)
+			load, err := repos.PRs.CountOpenReviews()
+			if err != nil {
+				t.Fatal(err)
+			}
+			labels := []string{"go", "sql"}
+
+			// записываем решение
+			pr, err := prs.CreatePR(ctx, &model.PullRequest{ID: "pr-" + strconv.Itoa(i), Name: "change", AuthorID: "1", Labels: labels})
+			if err != nil {
+				t.Fatal(err)
+			}
+			expl := pr.Assignment
+			if expl == nil || expl.Strategy != tt.strategy || !expl.DecidedAt.Equal(decidedAt) {
+				t.Fatalf("explanation: %+v", expl)
+			}
+			if tt.weighted != (expl.Weights != nil) {
+				t.Fatalf("weights: %v", expl.Weights)
+			}
+
+			// воспроизводим его по сохранённому объяснению и пулу на момент решения
+			var candidates []model.User
+			for _, id := range expl.Candidates {
+				u, err := repos.Users.GetByID(id)
+				if err != nil {
+					t.Fatal(err)
+				}
+				candidates = append(candidates, *u)
+			}
+			seeds = nil
+			got := prs.ReplayAssignment(expl, candidates, labels, load)
+			if !reflect.DeepEqual(got, pr.AssignedReviewers) {
+				t.Fatalf("replay: got %v, assigned %v", got, pr.AssignedReviewers)
+			}
+			if len(seeds) != 1 || seeds[0] != expl.Seed {
+				t.Fatalf("replay seeds: %v, want [%d]", seeds, expl.Seed)
+			}
+		})
+	}
+}
+
+func TestManualReviewerChecks(t *testing.T) {
+	st := memory.NewStore()
+	prs := NewPRService(st, DefaultAssignmentPolicy())
+	teams := NewTeamService(st, prs)
+	ctx := context.Background()
+
+	expand := true
+	members := func(ids ...string) []model.TeamMember {
+		var out []model.TeamMember
+		for _, id := range ids {
+			out = append(out, model.TeamMember{UserID: id, Username: "user" + id, IsActive: true})
+		}
+		return out
+	}
+	for _, team := range []*model.Team{
+		{Name: "eng", Members: members("8")},
+		{Name: "backend", Parent: "eng", Members: members("1", "2", "3", "4", "5", "6"), Settings: model.TeamSettings{ExpandPool: &expand}},
+		{Name: "frontend", Members: members("7")},
+	} {
+		if _, err := teams.CreateOrUpdateTeam(ctx, team); err != nil {
+			t.Fatal(err)
+		}
+	}
+	pr, err := prs.CreatePR(ctx, &model.PullRequest{ID: "pr-1", Name: "change", AuthorID: "1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// decliner отказывается от ревью, из двух оставшихся незанятых один в отпуске,
+	// другой не участвует в ревью команды
+	decliner := pr.AssignedReviewers[0]
+	if pr, _, err = prs.DeclineReview(ctx, "pr-1", decliner, model.DeclineOverloaded, ""); err != nil {
+		t.Fatal(err)
+	}
+	var free []string
+	for i := 2; i <= 6; i++ {
+		id := strconv.Itoa(i)
+		if id != decliner && !containsString(pr.AssignedReviewers, id) {
+			free = append(free, id)
+		}
+	}
+	if len(free) != 2 {
+		t.Fatalf("free members: %v, reviewers %v", free, pr.AssignedReviewers)
+	}
+	away, passive := free[0], free[1]
+	until := time.Now().Add(24 * time.Hour)
+	if _, err := st.Repos(ctx).Users.SetOutOfOffice(away, &until); err != nil {
+		t.Fatal(err)
+	}
+	off := false
+	if _, err := teams.SetMembership(ctx, "backend", passive, &off, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		user string
+		want error
+	}{
+		{"declined this PR", decliner, ErrReviewerDeclined},
+		{"out of office", away, ErrReviewerOutOfOffice},
+		{"membership not reviewing", passive, ErrReviewerNotInPool},
+		{"another team", "7", ErrReviewerNotInPool},
+		{"author", "1", ErrReviewerIsAuthor},
+	}
+	for _, tt := range tests {
+		if _, err := prs.AddReviewer(ctx, "pr-1", tt.user); err != tt.want {
+			t.Errorf("add %s: want %v, got %v", tt.name, tt.want, err)
+		}
+		_, _, err := prs.ReassignReviewer(ctx, "pr-1", pr.AssignedReviewers[0], ReassignOptions{NewUserID: tt.user})
+		if err != tt.want {
+			t.Errorf("reassign to %s: want %v, got %v", tt.name, tt.want, err)
+		}
+	}
+
+	// пул команды с expand_pool включает родительскую команду
+	if _, err := prs.AddReviewer(ctx, "pr-1", "8"); err != nil {
+		t.Fatalf("add member of parent team: %v", err)
+	}
+}
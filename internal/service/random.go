@@ -0,0 +1,48 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"math/rand"
+	"strings"
+	"time"
+)
+
+// Clock возвращает текущее время
+type Clock func() time.Time
+
+// RandFactory создаёт генератор случайных чисел из seed
+type RandFactory func(seed int64) *rand.Rand
+
+// PRServiceOption настраивает PRService
+type PRServiceOption func(*PRService)
+
+// WithClock подменяет источник времени
+func WithClock(clock Clock) PRServiceOption {
+	return func(s *PRService) { s.now = clock }
+}
+
+// WithRandFactory подменяет источник случайности
+func WithRandFactory(factory RandFactory) PRServiceOption {
+	return func(s *PRService) { s.newRand = factory }
+}
+
+// WithSeedSecret задаёт секрет сервера, из которого вместе с ID PR выводится seed назначения
+func WithSeedSecret(secret []byte) PRServiceOption {
+	return func(s *PRService) { s.seedSecret = secret }
+}
+
+// defaultRandFactory использует стандартный генератор math/rand
+func defaultRandFactory(seed int64) *rand.Rand {
+	return rand.New(rand.NewSource(seed))
+}
+
+// deriveSeed выводит seed из секрета сервера и параметров решения (ID PR, действие и т.п.).
+// Одинаковые параметры всегда дают одинаковый seed, поэтому назначение воспроизводимо.
+func deriveSeed(secret []byte, parts ...string) int64 {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(strings.Join(parts, "\x00")))
+	sum := mac.Sum(nil)
+	return int64(binary.BigEndian.Uint64(sum[:8]) >> 1)
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pr-reviewer/internal/model"
+)
+
+// normalizeTags приводит метки и навыки к нижнему регистру, убирает пустые значения и дубли
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool, len(tags))
+	result := make([]string, 0, len(tags))
+	for _, t := range tags {
+		t = strings.ToLower(strings.TrimSpace(t))
+		if t == "" || seen[t] {
+			continue
+		}
+		seen[t] = true
+		result = append(result, t)
+	}
+	return result
+}
+
+// matchedSkills возвращает навыки ревьювера, совпадающие с метками PR
+func matchedSkills(skills, labels []string) []string {
+	if len(skills) == 0 || len(labels) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(labels))
+	for _, l := range labels {
+		set[strings.ToLower(l)] = true
+	}
+	var matched []string
+	for _, s := range skills {
+		if set[strings.ToLower(s)] {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// matchScore возвращает количество навыков ревьювера, совпадающих с метками PR
+func matchScore(skills, labels []string) int {
+	return len(matchedSkills(skills, labels))
+}
+
+// selectionInput — исходные данные для выбора ревьюверов
+type selectionInput struct {
+	users    []model.User
+	excluded map[string]string // заранее исключённые пользователи и причина
+	labels   []string
+	load     map[string]int // число открытых ревью на пользователя
+	maxOpen  int            // лимит открытых ревью, 0 — без лимита
+	weights  map[string]int // вес членства кандидата в команде PR; отсутствующий — 100
+	strategy string
+	limit    int
+	seed     int64
+}
+
+// selection — результат выбора ревьюверов
+type selection struct {
+	strategy   string
+	seed       int64
+	filters    []string
+	candidates []model.User
+	weights    map[string]int // веса кандидатов меньше 100
+	poolTeams  []string       // команды пула, если он расширялся на родительские команды
+	excluded   []model.ExcludedCandidate
+	chosen     []model.ChosenReviewer
+	reviewers  []string
+}
+
+// explain формирует объяснение решения для сохранения вместе с PR
+func (s *selection) explain(action string, decidedAt time.Time) *model.AssignmentExplanation {
+	excluded := s.excluded
+	if excluded == nil {
+		excluded = []model.ExcludedCandidate{}
+	}
+	chosen := s.chosen
+	if chosen == nil {
+		chosen = []model.ChosenReviewer{}
+	}
+	candidates := make([]string, 0, len(s.candidates))
+	for _, u := range s.candidates {
+		candidates = append(candidates, strconv.FormatInt(u.ID, 10))
+	}
+	return &model.AssignmentExplanation{
+		Action:     action,
+		Strategy:   s.strategy,
+		Seed:       s.seed,
+		PoolSize:   len(s.candidates),
+		Candidates: candidates,
+		Filters:    s.filters,
+		Excluded:   excluded,
+		Chosen:     chosen,
+		Weights:    s.weights,
+		PoolTeams:  s.poolTeams,
+		DecidedAt:  decidedAt,
+	}
+}
+
+// preview представляет выбор в виде, пригодном для ответа API
+func (s *selection) preview() *model.AssignmentPreview {
+	candidates := make([]string, 0, len(s.candidates))
+	for _, u := range s.candidates {
+		candidates = append(candidates, strconv.FormatInt(u.ID, 10))
+	}
+	excluded := s.excluded
+	if excluded == nil {
+		excluded = []model.ExcludedCandidate{}
+	}
+	return &model.AssignmentPreview{
+		Strategy:   s.strategy,
+		Seed:       s.seed,
+		Candidates: candidates,
+		Excluded:   excluded,
+		Reviewers:  s.reviewers,
+		PoolTeams:  s.poolTeams,
+	}
+}
+
+// selectReviewers отфильтровывает неподходящих пользователей и выбирает до limit ревьюверов
+// согласно стратегии. Пользователи из in.excluded, неактивные, отсутствующие и
+// достигшие лимита открытых ревью в пул кандидатов не попадают. Лимит открытых ревью
+// уменьшается пропорционально весу членства.
+func (s *PRService) selectReviewers(in selectionInput) *selection {
+	now := s.now()
+	sel := &selection{strategy: in.strategy, seed: in.seed, filters: appliedFilters(in.excluded, in.maxOpen)}
+
+	// порядок пользователей из БД не гарантирован, поэтому для воспроизводимости сортируем по ID
+	users := make([]model.User, len(in.users))
+	copy(users, in.users)
+	sort.Slice(users, func(i, j int) bool { return users[i].ID < users[j].ID })
+
+	for _, u := range users {
+		id := strconv.FormatInt(u.ID, 10)
+		reason := in.excluded[id]
+		switch {
+		case reason != "":
+		case !u.IsActive:
+			reason = model.ExcludedInactive
+		case u.IsOutOfOffice(now):
+			reason = model.ExcludedOutOfOffice
+		case in.maxOpen > 0 && in.load[id] >= weightedLimit(in.maxOpen, weightOf(in.weights, id)):
+			reason = model.ExcludedAtCapacity
+		}
+		if reason != "" {
+			sel.excluded = append(sel.excluded, model.ExcludedCandidate{UserID: id, Reason: reason})
+			continue
+		}
+		sel.candidates = append(sel.candidates, u)
+		if w := weightOf(in.weights, id); w < model.DefaultMembershipWeight {
+			if sel.weights == nil {
+				sel.weights = make(map[string]int)
+			}
+			sel.weights[id] = w
+		}
+	}
+
+	ranked := rankCandidates(sel.candidates, in, s.newRand(in.seed))
+	if len(ranked) > in.limit {
+		ranked = ranked[:in.limit]
+	}
+	sel.reviewers = make([]string, 0, len(ranked))
+	for _, u := range ranked {
+		id := strconv.FormatInt(u.ID, 10)
+		score, reason := scoreCandidate(u, in)
+		sel.reviewers = append(sel.reviewers, id)
+		sel.chosen = append(sel.chosen, model.ChosenReviewer{UserID: id, Score: score, Reason: reason})
+	}
+	return sel
+}
+
+// ReplayAssignment повторяет сохранённое решение офлайн: по seed и стратегии из объяснения
+// заново упорядочивает кандидатов. candidates должны описывать пул на момент решения
+// (навыки, нагрузку), labels — метки PR на тот момент.
+func (s *PRService) ReplayAssignment(expl *model.AssignmentExplanation, candidates []model.User, labels []string, load map[string]int) []string {
+	pool := make([]model.User, len(candidates))
+	copy(pool, candidates)
+	sort.Slice(pool, func(i, j int) bool { return pool[i].ID < pool[j].ID })
+
+	in := selectionInput{labels: labels, load: load, weights: expl.Weights, strategy: expl.Strategy, seed: expl.Seed}
+	ranked := rankCandidates(pool, in, s.newRand(expl.Seed))
+	if len(ranked) > len(expl.Chosen) {
+		ranked = ranked[:len(expl.Chosen)]
+	}
+	reviewers := make([]string, 0, len(ranked))
+	for _, u := range ranked {
+		reviewers = append(reviewers, strconv.FormatInt(u.ID, 10))
+	}
+	return reviewers
+}
+
+// appliedFilters перечисляет фильтры, которыми отсекаются кандидаты
+func appliedFilters(excluded map[string]string, maxOpen int) []string {
+	seen := make(map[string]bool)
+	var filters []string
+	for _, reason := range excluded {
+		if !seen[reason] {
+			seen[reason] = true
+			filters = append(filters, reason)
+		}
+	}
+	sort.Strings(filters)
+	filters = append(filters, model.ExcludedInactive, model.ExcludedOutOfOffice)
+	if maxOpen > 0 {
+		filters = append(filters, model.ExcludedAtCapacity)
+	}
+	return filters
+}
+
+// scoreCandidate возвращает оценку кандидата по стратегии и человекочитаемую причину выбора
+func scoreCandidate(u model.User, in selectionInput) (int, string) {
+	switch in.strategy {
+	case StrategySkills:
+		matched := matchedSkills(u.Skills, in.labels)
+		if len(matched) == 0 {
+			return 0, "no skill matched PR labels, picked at random"
+		}
+		return len(matched), "skills matched PR labels: " + strings.Join(matched, ", ")
+	case StrategyLeastLoaded:
+		n := in.load[strconv.FormatInt(u.ID, 10)]
+		return n, fmt.Sprintf("fewest open reviews (%d)", n)
+	}
+	return 0, "picked at random"
+}
+
+// rankCandidates упорядочивает кандидатов согласно стратегии.
+// Кандидаты сначала перемешиваются, поэтому при равных оценках выбор остаётся случайным;
+// кандидат с меньшим весом членства оказывается впереди реже.
+func rankCandidates(candidates []model.User, in selectionInput, rng *rand.Rand) []model.User {
+	ranked := make([]model.User, len(candidates))
+	copy(ranked, candidates)
+	if hasPartialWeights(ranked, in.weights) {
+		weightedShuffle(ranked, in.weights, rng)
+	} else {
+		rng.Shuffle(len(ranked), func(i, j int) { ranked[i], ranked[j] = ranked[j], ranked[i] })
+	}
+
+	switch in.strategy {
+	case StrategySkills:
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return matchScore(ranked[i].Skills, in.labels) > matchScore(ranked[j].Skills, in.labels)
+		})
+	case StrategyLeastLoaded:
+		sort.SliceStable(ranked, func(i, j int) bool {
+			return in.load[strconv.FormatInt(ranked[i].ID, 10)] < in.load[strconv.FormatInt(ranked[j].ID, 10)]
+		})
+	}
+	return ranked
+}
+
+// weightOf возвращает вес членства пользователя; без записи — полный вес
+func weightOf(weights map[string]int, userID string) int {
+	if w, ok := weights[userID]; ok {
+		return w
+	}
+	return model.DefaultMembershipWeight
+}
+
+// weightedLimit уменьшает лимит открытых ревью пропорционально весу, но не ниже одного ревью
+func weightedLimit(maxOpen, weight int) int {
+	limit := (maxOpen*weight + model.DefaultMembershipWeight - 1) / model.DefaultMembershipWeight
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
+
+// hasPartialWeights сообщает, есть ли среди кандидатов пользователь с весом меньше 100.
+// Без таких кандидатов порядок совпадает с обычным перемешиванием, и старые решения воспроизводятся.
+func hasPartialWeights(candidates []model.User, weights map[string]int) bool {
+	for _, u := range candidates {
+		if weightOf(weights, strconv.FormatInt(u.ID, 10)) < model.DefaultMembershipWeight {
+			return true
+		}
+	}
+	return false
+}
+
+// weightedShuffle перемешивает кандидатов с вероятностью оказаться впереди, пропорциональной
+// весу (алгоритм Efraimidis–Spirakis: ключ u^(1/w), сортировка по убыванию ключа)
+func weightedShuffle(users []model.User, weights map[string]int, rng *rand.Rand) {
+	keys := make(map[int64]float64, len(users))
+	for _, u := range users {
+		w := float64(weightOf(weights, strconv.FormatInt(u.ID, 10)))
+		keys[u.ID] = math.Pow(rng.Float64(), 1/w)
+	}
+	sort.SliceStable(users, func(i, j int) bool { return keys[users[i].ID] > keys[users[j].ID] })
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// SetLead назначает участника команды лидом (lead=true) или возвращает ему роль участника.
+// Лидом можно сделать только участника команды; вес и активность членства не меняются.
+func (s *TeamService) SetLead(ctx context.Context, teamName, userID string, lead bool) (*model.Team, error) {
+	role, action := model.TeamRoleMember, model.AuditLeadRemoved
+	if lead {
+		role, action = model.TeamRoleLead, model.AuditLeadAdded
+	}
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if _, err := repos.Teams.GetByName(teamName); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		user, err := repos.Users.GetByID(userID)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		m, ok := user.Membership(teamName)
+		if !ok {
+			return ErrNotTeamMember
+		}
+		if m.Role == role {
+			return nil
+		}
+		m.UserID = userID
+		m.Role = role
+		if err := repos.Memberships.Upsert(&m); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, action, model.AuditTargetTeam, teamName, map[string]string{"user_id": userID}, s.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTeam(ctx, teamName)
+}
+
+// LeadTeams возвращает команды, в которых пользователь — лид; для неизвестного пользователя — пусто
+func (s *TeamService) LeadTeams(ctx context.Context, userID string) ([]string, error) {
+	user, err := s.uow.Repos(ctx).Users.GetByID(userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var teams []string
+	for _, m := range user.Teams {
+		if m.Role == model.TeamRoleLead {
+			teams = append(teams, m.TeamName)
+		}
+	}
+	return teams, nil
+}
+
+// Escalations возвращает открытые PR авторов из команды teamName, ревью которых не уложилось
+// в итоговый review_sla_hours команды, от самого старого. Эскалации адресуются лидам команды,
+// а если их нет — лидам ближайшей родительской команды. Без SLA список пуст.
+func (s *TeamService) Escalations(ctx context.Context, teamName string) ([]model.Escalation, error) {
+	repos := s.uow.Repos(ctx)
+	chain, err := teamChain(repos, teamName)
+	if err != nil {
+		return nil, err
+	}
+	if len(chain) == 0 {
+		return nil, ErrTeamNotFound
+	}
+	policy, _, err := s.prs.teamPolicy(ctx, repos, teamName)
+	if err != nil {
+		return nil, err
+	}
+	escalations := []model.Escalation{}
+	if policy.ReviewSLAHours == 0 {
+		return escalations, nil
+	}
+
+	leads, err := escalationLeads(repos, chain)
+	if err != nil {
+		return nil, err
+	}
+	sla := time.Duration(policy.ReviewSLAHours) * time.Hour
+	deadline := s.now().Add(-sla)
+	filter := repository.PRFilter{
+		Status:    "OPEN",
+		TeamName:  teamName,
+		CreatedTo: &deadline,
+		SortBy:    repository.PRSortCreatedAt,
+		Limit:     maxPageSize,
+	}
+	for {
+		prs, next, err := repos.PRs.List(filter)
+		if err != nil {
+			return nil, mapListError(err)
+		}
+		for _, pr := range prs {
+			if pr.CreatedAt.After(deadline) {
+				continue
+			}
+			escalations = append(escalations, model.Escalation{
+				PRID:              pr.ID,
+				Name:              pr.Name,
+				AuthorID:          pr.AuthorID,
+				AssignedReviewers: pr.AssignedReviewers,
+				CreatedAt:         pr.CreatedAt,
+				DueAt:             pr.CreatedAt.Add(sla),
+				Leads:             leads,
+			})
+		}
+		if next == "" {
+			return escalations, nil
+		}
+		filter.Cursor = next
+	}
+}
+
+// escalationLeads возвращает лидов первой команды цепочки, у которой они есть
+func escalationLeads(repos repository.Repos, chain []model.Team) ([]string, error) {
+	for _, team := range chain {
+		users, err := repos.Users.GetByTeam(team.Name)
+		if err != nil {
+			return nil, err
+		}
+		var leads []string
+		for _, u := range users {
+			if m, _ := u.Membership(team.Name); m.Role == model.TeamRoleLead {
+				leads = append(leads, strconv.FormatInt(u.ID, 10))
+			}
+		}
+		if len(leads) > 0 {
+			return leads, nil
+		}
+	}
+	return []string{}, nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+)
+
+// Ошибки иерархии и настроек команд
+var (
+	ErrParentNotFound      = errors.New("parent team not found")
+	ErrTeamCycle           = errors.New("team cannot be nested under itself or its descendant")
+	ErrTeamHasChildren     = errors.New("team has child teams")
+	ErrInvalidTeamSettings = errors.New("invalid team settings")
+	ErrMergeBlocked        = errors.New("merge policy requires more reviewers")
+)
+
+// maxTeamDepth ограничивает обход иерархии на случай испорченных данных
+const maxTeamDepth = 32
+
+// Ключи настроек в EffectiveTeamSettings.Sources
+const (
+	settingReviewerCount = "reviewer_count"
+	settingStrategy      = "strategy"
+	settingMergePolicy   = "merge_policy"
+	settingReviewSLA     = "review_sla_hours"
+	settingExpandPool    = "expand_pool"
+	settingLeadsReview   = "leads_review"
+)
+
+// teamChain возвращает команду teamName и её предков, начиная с самой команды
+func teamChain(repos repository.Repos, teamName string) ([]model.Team, error) {
+	var chain []model.Team
+	seen := make(map[string]bool)
+	for name := teamName; name != "" && !seen[name] && len(chain) < maxTeamDepth; {
+		seen[name] = true
+		team, err := repos.Teams.GetByName(name)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return chain, nil
+			}
+			return nil, err
+		}
+		chain = append(chain, *team)
+		name = team.Parent
+	}
+	return chain, nil
+}
+
+// teamPolicy возвращает политику команды teamName: её настройки и настройки предков
+// (ближайшая заданная побеждает) поверх настроек организации и политики сервиса.
+// Для пустого teamName возвращается политика организации.
+func (s *PRService) teamPolicy(ctx context.Context, repos repository.Repos, teamName string) (AssignmentPolicy, *model.EffectiveTeamSettings, error) {
+	var orgSettings model.OrgSettings
+	org, err := repos.Orgs.Get(repository.TenantFrom(ctx))
+	switch {
+	case err == nil:
+		orgSettings = org.Settings
+	case err != repository.ErrNotFound:
+		return AssignmentPolicy{}, nil, err
+	}
+	chain, err := teamChain(repos, teamName)
+	if err != nil {
+		return AssignmentPolicy{}, nil, err
+	}
+	policy, eff := s.resolveTeamPolicy(orgSettings, chain)
+	return policy, eff, nil
+}
+
+// resolveTeamPolicy накладывает настройки цепочки команд на политику организации и
+// запоминает источник каждой настройки
+func (s *PRService) resolveTeamPolicy(orgSettings model.OrgSettings, chain []model.Team) (AssignmentPolicy, *model.EffectiveTeamSettings) {
+	policy := s.policy.Apply(orgSettings)
+	sources := map[string]string{
+		settingReviewerCount: model.SettingSourceService,
+		settingStrategy:      model.SettingSourceService,
+		settingMergePolicy:   model.SettingSourceService,
+		settingReviewSLA:     model.SettingSourceService,
+		settingExpandPool:    model.SettingSourceService,
+		settingLeadsReview:   model.SettingSourceService,
+	}
+	if orgSettings.ReviewerCount != nil {
+		sources[settingReviewerCount] = model.SettingSourceOrganization
+	}
+	if orgSettings.Strategy != "" {
+		sources[settingStrategy] = model.SettingSourceOrganization
+	}
+	if orgSettings.MergePolicy != "" {
+		sources[settingMergePolicy] = model.SettingSourceOrganization
+	}
+	if orgSettings.ReviewSLAHours != nil {
+		sources[settingReviewSLA] = model.SettingSourceOrganization
+	}
+
+	// идём от корня к команде, чтобы настройки ближайшей команды применились последними
+	expand, leadsReview := false, true
+	for i := len(chain) - 1; i >= 0; i-- {
+		st := chain[i].Settings
+		source := "team:" + chain[i].Name
+		if st.ReviewerCount != nil {
+			policy.ReviewerCount = *st.ReviewerCount
+			sources[settingReviewerCount] = source
+		}
+		if st.Strategy != "" {
+			policy.Strategy = st.Strategy
+			sources[settingStrategy] = source
+		}
+		if st.MergePolicy != "" {
+			policy.MergePolicy = st.MergePolicy
+			sources[settingMergePolicy] = source
+		}
+		if st.ReviewSLAHours != nil {
+			policy.ReviewSLAHours = *st.ReviewSLAHours
+			sources[settingReviewSLA] = source
+		}
+		if st.ExpandPool != nil {
+			expand = *st.ExpandPool
+			sources[settingExpandPool] = source
+		}
+		if st.LeadsReview != nil {
+			leadsReview = *st.LeadsReview
+			sources[settingLeadsReview] = source
+		}
+	}
+
+	return policy, &model.EffectiveTeamSettings{
+		ReviewerCount:  policy.ReviewerCount,
+		Strategy:       policy.Strategy,
+		MergePolicy:    policy.MergePolicy,
+		ReviewSLAHours: policy.ReviewSLAHours,
+		ExpandPool:     expand,
+		LeadsReview:    leadsReview,
+		Sources:        sources,
+	}
+}
+
+// poolAncestors возвращает родительские команды, из которых можно добрать пул команды teamName:
+// от ближайшей к корню; пусто, если расширение пула для команды выключено
+func (s *PRService) poolAncestors(ctx context.Context, repos repository.Repos, teamName string) ([]string, error) {
+	chain, err := teamChain(repos, teamName)
+	if err != nil {
+		return nil, err
+	}
+	_, eff, err := s.teamPolicy(ctx, repos, teamName)
+	if err != nil {
+		return nil, err
+	}
+	if !eff.ExpandPool || len(chain) < 2 {
+		return nil, nil
+	}
+	ancestors := make([]string, 0, len(chain)-1)
+	for _, t := range chain[1:] {
+		ancestors = append(ancestors, t.Name)
+	}
+	return ancestors, nil
+}
+
+// selectFromTeams выбирает ревьюверов из участников команд teams. Если кандидатов меньше
+// нужного, пул по одной добирается из команд ancestors. build строит вход выбора по пулу.
+func (s *PRService) selectFromTeams(ctx context.Context, repos repository.Repos, teams, ancestors []string, build func(pool *reviewPool) selectionInput) (*selection, error) {
+	teams = append([]string(nil), teams...)
+	expanded := false
+	for {
+		pool, err := s.teamPool(ctx, repos, teams)
+		if err != nil {
+			return nil, err
+		}
+		in := build(pool)
+		sel := s.selectReviewers(in)
+		if len(sel.reviewers) >= in.limit || len(ancestors) == 0 {
+			if expanded {
+				sel.poolTeams = teams
+			}
+			return sel, nil
+		}
+		next := ancestors[0]
+		ancestors = ancestors[1:]
+		if !containsString(teams, next) {
+			teams = append(teams, next)
+			expanded = true
+		}
+	}
+}
+
+// containsString сообщает, есть ли v в срезе
+func containsString(values []string, v string) bool {
+	for _, x := range values {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
+
+// validateTeamSettings проверяет собственные настройки команды и то, что итоговая политика
+// команды остаётся согласованной
+func (s *PRService) validateTeamSettings(ctx context.Context, repos repository.Repos, team *model.Team) error {
+	st := team.Settings
+	if st.Strategy != "" && !ValidStrategy(st.Strategy) {
+		return fmt.Errorf("%w: %v: %s", ErrInvalidTeamSettings, ErrUnknownStrategy, st.Strategy)
+	}
+	if st.MergePolicy != "" && !ValidMergePolicy(st.MergePolicy) {
+		return fmt.Errorf("%w: %v: %s", ErrInvalidTeamSettings, ErrUnknownMergePolicy, st.MergePolicy)
+	}
+
+	var orgSettings model.OrgSettings
+	if org, err := repos.Orgs.Get(repository.TenantFrom(ctx)); err == nil {
+		orgSettings = org.Settings
+	} else if err != repository.ErrNotFound {
+		return err
+	}
+	chain, err := teamChain(repos, team.Parent)
+	if err != nil {
+		return err
+	}
+	policy, _ := s.resolveTeamPolicy(orgSettings, append([]model.Team{*team}, chain...))
+	if err := policy.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidTeamSettings, err)
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"pr-reviewer/internal/model"
 	"pr-reviewer/internal/repository"
@@ -11,22 +13,43 @@ import (
 
 // Ошибки доменной логики
 var (
-	ErrTeamExists   = errors.New("team already exists")
-	ErrTeamNotFound = errors.New("team not found")
+	ErrTeamExists      = errors.New("team already exists")
+	ErrTeamNotFound    = errors.New("team not found")
+	ErrTeamNotEmpty    = errors.New("team still has members")
+	ErrNotTeamMember   = errors.New("user is not a member of the team")
+	ErrInvalidUserID   = errors.New("invalid user ID")
+	ErrInvalidWeight   = errors.New("membership weight must be between 1 and 100")
+	ErrInvalidTeamRole = errors.New("team role must be member or lead")
 )
 
-// TeamService управляет командами
+// Причины снятия ревью, записываемые в историю PR
+const (
+	ReleaseMemberRemoved = "member_removed"
+	ReleaseMemberMoved   = "member_moved"
+	// ReleaseUserDeactivated — пользователь деактивирован вручную, импортом или синхронизацией каталога
+	ReleaseUserDeactivated = "user_deactivated"
+)
+
+// TeamService управляет командами и их составом
 type TeamService struct {
-	teamRepo *repository.TeamRepo
-	userRepo *repository.UserRepo
+	uow repository.UnitOfWork
+	prs *PRService
+	now Clock
 }
 
-// NewTeamService создаёт новый TeamService
-func NewTeamService(teamRepo *repository.TeamRepo, userRepo *repository.UserRepo) *TeamService {
-	return &TeamService{
-		teamRepo: teamRepo,
-		userRepo: userRepo,
+// NewTeamService создаёт новый TeamService. prs переназначает открытые ревью
+// пользователей, покидающих команду.
+func NewTeamService(uow repository.UnitOfWork, prs *PRService) *TeamService {
+	return &TeamService{uow: uow, prs: prs, now: time.Now}
+}
+
+// update выполняет fn в транзакции; параллельное изменение затронутых PR даёт ErrConcurrentUpdate
+func (s *TeamService) update(ctx context.Context, fn func(repos repository.Repos) error) error {
+	err := s.uow.Do(ctx, fn)
+	if errors.Is(err, repository.ErrConflict) {
+		return ErrConcurrentUpdate
 	}
+	return err
 }
 
 // parseID конвертирует строковый UserID в int64 с проверкой ошибки
@@ -34,63 +57,413 @@ func parseID(id string) (int64, error) {
 	return strconv.ParseInt(id, 10, 64)
 }
 
-// CreateOrUpdateTeam создаёт команду или обновляет участников
-func (s *TeamService) CreateOrUpdateTeam(team *model.Team) (*model.Team, error) {
-	existingTeam, err := s.teamRepo.GetByName(team.Name)
-	if err != nil && err != repository.ErrNotFound {
+// CreateOrUpdateTeam создаёт команду с участниками, родителем и настройками в одной транзакции.
+// Если команда уже существует, ничего не меняется и возвращается ErrTeamExists —
+// состав существующей команды меняют AddMembers, RemoveMember и MoveUser.
+func (s *TeamService) CreateOrUpdateTeam(ctx context.Context, team *model.Team) (*model.Team, error) {
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if team.Parent != "" {
+			if _, err := repos.Teams.GetByName(team.Parent); err != nil {
+				if err == repository.ErrNotFound {
+					return ErrParentNotFound
+				}
+				return err
+			}
+		}
+		if err := s.prs.validateTeamSettings(ctx, repos, team); err != nil {
+			return err
+		}
+		if err := repos.Teams.Create(team); err != nil {
+			if err == repository.ErrAlreadyExists {
+				return ErrTeamExists
+			}
+			return err
+		}
+		return s.upsertMembers(repos, team.Name, team.Members)
+	})
+	if err != nil {
 		return nil, err
 	}
+	return s.GetTeam(ctx, team.Name)
+}
 
-	if existingTeam != nil {
-		// команда уже существует, обновляем участников
-		for i := range team.Members {
-			id, err := parseID(team.Members[i].UserID)
-			if err != nil {
-				return nil, fmt.Errorf("invalid user ID %s: %w", team.Members[i].UserID, err)
+// AddMembers добавляет участников в существующую команду или обновляет их данные и членство.
+// Членства пользователей в других командах сохраняются.
+func (s *TeamService) AddMembers(ctx context.Context, teamName string, members []model.TeamMember) (*model.Team, error) {
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if _, err := repos.Teams.GetByName(teamName); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
 			}
-
-			u := &model.User{
-				ID:       id,
-				Username: team.Members[i].Username,
-				IsActive: team.Members[i].IsActive,
+			return err
+		}
+		if err := s.upsertMembers(repos, teamName, members); err != nil {
+			return err
+		}
+		for _, m := range members {
+			if err := recordAudit(ctx, repos, model.AuditMemberAdded, model.AuditTargetTeam, teamName, map[string]string{"user_id": m.UserID}, s.now()); err != nil {
+				return err
 			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTeam(ctx, teamName)
+}
 
-			if err := s.userRepo.CreateOrUpdate(u); err != nil {
-				return nil, err
+// upsertMembers создаёт или обновляет участников команды teamName и их членство в ней.
+// Без явной роли роль уже состоящего в команде участника сохраняется, новый становится member.
+// is_active задаётся только новым пользователям.
+func (s *TeamService) upsertMembers(repos repository.Repos, teamName string, members []model.TeamMember) error {
+	for _, m := range members {
+		id, err := parseID(m.UserID)
+		if err != nil {
+			return fmt.Errorf("%w: %s", ErrInvalidUserID, m.UserID)
+		}
+		weight := m.Weight
+		if weight == 0 {
+			weight = model.DefaultMembershipWeight
+		}
+		if !model.ValidMembershipWeight(weight) {
+			return ErrInvalidWeight
+		}
+		active := true
+		if m.MembershipActive != nil {
+			active = *m.MembershipActive
+		}
+		existing, err := repos.Users.GetByID(m.UserID)
+		if err != nil && err != repository.ErrNotFound {
+			return err
+		}
+		role := m.Role
+		if role == "" {
+			role = model.TeamRoleMember
+			if existing != nil {
+				if cur, ok := existing.Membership(teamName); ok {
+					role = cur.Role
+				}
 			}
 		}
-		return existingTeam, ErrTeamExists
+		if !model.ValidTeamRole(role) {
+			return ErrInvalidTeamRole
+		}
+
+		u := &model.User{
+			ID:       id,
+			Username: m.Username,
+			IsActive: m.IsActive,
+		}
+		if existing != nil {
+			// активность общая для всех команд и меняется только через SetIsActive,
+			// который переназначает открытые ревью
+			u.IsActive = existing.IsActive
+		}
+		if err := repos.Users.CreateOrUpdate(u); err != nil {
+			return err
+		}
+		if err := repos.Memberships.Upsert(&model.TeamMembership{TeamName: teamName, UserID: m.UserID, IsActive: active, Weight: weight, Role: role}); err != nil {
+			return err
+		}
+		if err := setMemberSkills(repos, m); err != nil {
+			return err
+		}
 	}
+	return nil
+}
+
+// RemoveMember убирает пользователя из команды. Членства в других командах сохраняются;
+// пользователь без команд остаётся в сервисе: его PR и история сохраняются, но ревью ему
+// больше не назначаются. Его открытые ревью PR, с автором которых у него не осталось общих
+// команд, переназначаются в командах автора, а без подходящей замены снимаются.
+func (s *TeamService) RemoveMember(ctx context.Context, teamName, userID string) ([]model.ReviewHandoff, error) {
+	var handoffs []model.ReviewHandoff
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if _, err := repos.Users.GetByID(userID); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		if err := repos.Memberships.Remove(teamName, userID); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrNotTeamMember
+			}
+			return err
+		}
 
-	// создаём команду
-	if err := s.teamRepo.Create(team); err != nil {
+		var err error
+		handoffs, err = s.prs.releaseReviews(ctx, repos, userID, ReleaseMemberRemoved)
+		if err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditMemberRemoved, model.AuditTargetTeam, teamName, map[string]string{"user_id": userID}, s.now())
+	})
+	if err != nil {
 		return nil, err
 	}
+	return handoffs, nil
+}
 
-	// создаём пользователей в команде
-	for i := range team.Members {
-		id, err := parseID(team.Members[i].UserID)
+// MoveUser переводит пользователя в команду teamName: из команды fromTeam, а при пустом fromTeam —
+// из всех его команд. Вес членства в fromTeam переносится в новую команду. Открытые ревью
+// переназначаются, как в RemoveMember; PR, где он автор, не меняются. Роль лида не переносится:
+// в новой команде пользователь становится участником. Перевод в команду, в которой пользователь
+// уже состоит, лишь убирает его из fromTeam.
+func (s *TeamService) MoveUser(ctx context.Context, userID, fromTeam, teamName string) (*model.User, []model.ReviewHandoff, error) {
+	var user *model.User
+	handoffs := []model.ReviewHandoff{}
+	err := s.update(ctx, func(repos repository.Repos) error {
+		if _, err := repos.Teams.GetByName(teamName); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		var err error
+		user, err = repos.Users.GetByID(userID)
 		if err != nil {
-			return nil, fmt.Errorf("invalid user ID %s: %w", team.Members[i].UserID, err)
+			if err == repository.ErrNotFound {
+				return ErrUserNotFound
+			}
+			return err
 		}
 
-		u := &model.User{
-			ID:       id,
-			Username: team.Members[i].Username,
-			IsActive: team.Members[i].IsActive,
+		target := model.TeamMembership{TeamName: teamName, UserID: userID, IsActive: true, Weight: model.DefaultMembershipWeight, Role: model.TeamRoleMember}
+		leaving := user.TeamNames()
+		if fromTeam != "" {
+			m, ok := user.Membership(fromTeam)
+			if !ok {
+				return ErrNotTeamMember
+			}
+			leaving = []string{fromTeam}
+			target.Weight = m.Weight
+		}
+		if current, ok := user.Membership(teamName); ok {
+			target = current
+			target.UserID = userID
+		}
+
+		changed := false
+		for _, team := range leaving {
+			if team == teamName {
+				continue
+			}
+			if err := repos.Memberships.Remove(team, userID); err != nil {
+				return err
+			}
+			if err := recordAudit(ctx, repos, model.AuditMemberRemoved, model.AuditTargetTeam, team, map[string]string{"user_id": userID, "moved_to": teamName}, s.now()); err != nil {
+				return err
+			}
+			changed = true
+		}
+		if _, ok := user.Membership(teamName); !ok {
+			if err := repos.Memberships.Upsert(&target); err != nil {
+				return err
+			}
+			if err := recordAudit(ctx, repos, model.AuditMemberAdded, model.AuditTargetTeam, teamName, map[string]string{"user_id": userID}, s.now()); err != nil {
+				return err
+			}
+			changed = true
+		}
+		if !changed {
+			return nil
 		}
 
-		if err := s.userRepo.CreateOrUpdate(u); err != nil {
-			return nil, err
+		handoffs, err = s.prs.releaseReviews(ctx, repos, userID, ReleaseMemberMoved)
+		if err != nil {
+			return err
 		}
+		user, err = repos.Users.GetByID(userID)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
 	}
+	return user, handoffs, nil
+}
 
-	return team, nil
+// SetMembership меняет флаг активности и вес членства пользователя в команде; nil оставляет
+// значение без изменений. Неактивное членство исключает пользователя из пула ревьюверов этой
+// команды, но уже назначенные ревью за ним сохраняются.
+func (s *TeamService) SetMembership(ctx context.Context, teamName, userID string, isActive *bool, weight *int) (*model.User, error) {
+	if weight != nil && !model.ValidMembershipWeight(*weight) {
+		return nil, ErrInvalidWeight
+	}
+
+	var user *model.User
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		user, err = repos.Users.GetByID(userID)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return ErrUserNotFound
+			}
+			return err
+		}
+		m, ok := user.Membership(teamName)
+		if !ok {
+			return ErrNotTeamMember
+		}
+		m.UserID = userID
+		if isActive != nil {
+			m.IsActive = *isActive
+		}
+		if weight != nil {
+			m.Weight = *weight
+		}
+		if err := repos.Memberships.Upsert(&m); err != nil {
+			return err
+		}
+		if err := recordAudit(ctx, repos, model.AuditMemberUpdated, model.AuditTargetTeam, teamName, map[string]string{
+			"user_id":   userID,
+			"is_active": strconv.FormatBool(m.IsActive),
+			"weight":    strconv.Itoa(m.Weight),
+		}, s.now()); err != nil {
+			return err
+		}
+		user, err = repos.Users.GetByID(userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// RenameTeam переименовывает команду. Участники, их открытые ревью и токены лидов
+// переходят к новому имени.
+func (s *TeamService) RenameTeam(ctx context.Context, oldName, newName string) (*model.Team, error) {
+	err := s.update(ctx, func(repos repository.Repos) error {
+		return s.renameTeam(ctx, repos, oldName, newName)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTeam(ctx, newName)
+}
+
+// renameTeam переименовывает команду и токены её лидов внутри транзакции
+func (s *TeamService) renameTeam(ctx context.Context, repos repository.Repos, oldName, newName string) error {
+	if err := repos.Teams.Rename(oldName, newName); err != nil {
+		switch err {
+		case repository.ErrNotFound:
+			return ErrTeamNotFound
+		case repository.ErrAlreadyExists:
+			return ErrTeamExists
+		}
+		return err
+	}
+	if err := repos.Tokens.RenameTeam(oldName, newName); err != nil {
+		return err
+	}
+	return recordAudit(ctx, repos, model.AuditTeamRenamed, model.AuditTargetTeam, newName, map[string]string{"old_team_name": oldName}, s.now())
+}
+
+// SetParent делает команду parent родителем команды teamName; пустой parent делает её корневой.
+// Команду нельзя вложить в саму себя или в своего потомка (ErrTeamCycle).
+func (s *TeamService) SetParent(ctx context.Context, teamName, parent string) (*model.Team, error) {
+	err := s.update(ctx, func(repos repository.Repos) error {
+		team, err := repos.Teams.GetByName(teamName)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		if parent != "" {
+			chain, err := teamChain(repos, parent)
+			if err != nil {
+				return err
+			}
+			if len(chain) == 0 {
+				return ErrParentNotFound
+			}
+			for _, t := range chain {
+				if t.Name == teamName {
+					return ErrTeamCycle
+				}
+			}
+		}
+		old := team.Parent
+		team.Parent = parent
+		if err := s.prs.validateTeamSettings(ctx, repos, team); err != nil {
+			return err
+		}
+		if err := repos.Teams.Update(team); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditTeamParent, model.AuditTargetTeam, teamName, map[string]string{"old_parent": old, "parent": parent}, s.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTeam(ctx, teamName)
 }
 
-// GetTeam возвращает команду по имени
-func (s *TeamService) GetTeam(teamName string) (*model.Team, error) {
-	team, err := s.teamRepo.GetByName(teamName)
+// UpdateSettings заменяет собственные настройки команды; незаданные поля наследуются
+// от родительских команд и организации
+func (s *TeamService) UpdateSettings(ctx context.Context, teamName string, settings model.TeamSettings) (*model.Team, error) {
+	err := s.update(ctx, func(repos repository.Repos) error {
+		team, err := repos.Teams.GetByName(teamName)
+		if err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		team.Settings = settings
+		if err := s.prs.validateTeamSettings(ctx, repos, team); err != nil {
+			return err
+		}
+		if err := repos.Teams.Update(team); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditTeamSettings, model.AuditTargetTeam, teamName, teamSettingsAuditData(settings), s.now())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return s.GetTeam(ctx, teamName)
+}
+
+// DeleteTeam удаляет пустую команду без дочерних команд. Команду с участниками удалить нельзя
+// (ErrTeamNotEmpty): сначала их нужно убрать или перевести, чтобы их открытые ревью были
+// переназначены; дочерние команды нужно сначала перенести (ErrTeamHasChildren).
+func (s *TeamService) DeleteTeam(ctx context.Context, teamName string) error {
+	return s.update(ctx, func(repos repository.Repos) error {
+		if _, err := repos.Teams.GetByName(teamName); err != nil {
+			if err == repository.ErrNotFound {
+				return ErrTeamNotFound
+			}
+			return err
+		}
+		members, err := repos.Users.GetByTeam(teamName)
+		if err != nil {
+			return err
+		}
+		if len(members) > 0 {
+			return ErrTeamNotEmpty
+		}
+		children, err := repos.Teams.Children(teamName)
+		if err != nil {
+			return err
+		}
+		if len(children) > 0 {
+			return ErrTeamHasChildren
+		}
+		if err := repos.Teams.Delete(teamName); err != nil {
+			return err
+		}
+		return recordAudit(ctx, repos, model.AuditTeamDeleted, model.AuditTargetTeam, teamName, nil, s.now())
+	})
+}
+
+// GetTeam возвращает команду по имени вместе с итоговыми настройками и их источниками
+func (s *TeamService) GetTeam(ctx context.Context, teamName string) (*model.Team, error) {
+	repos := s.uow.Repos(ctx)
+	team, err := repos.Teams.GetByName(teamName)
 	if err != nil {
 		if err == repository.ErrNotFound {
 			return nil, ErrTeamNotFound
@@ -99,21 +472,91 @@ func (s *TeamService) GetTeam(teamName string) (*model.Team, error) {
 	}
 
 	// загружаем участников
-	users, err := s.userRepo.GetByTeam(teamName) // []User
+	users, err := repos.Users.GetByTeam(teamName) // []User
 	if err != nil {
 		return nil, err
 	}
 
-	// конвертируем []User -> []TeamMember
+	// конвертируем []User -> []TeamMember вместе с членством в этой команде
 	members := make([]model.TeamMember, len(users))
 	for i, u := range users {
+		m, _ := u.Membership(teamName)
+		active := m.IsActive
 		members[i] = model.TeamMember{
-			UserID:   fmt.Sprintf("%d", u.ID),
-			Username: u.Username,
-			IsActive: u.IsActive,
+			UserID:           fmt.Sprintf("%d", u.ID),
+			Username:         u.Username,
+			IsActive:         u.IsActive,
+			Skills:           u.Skills,
+			MembershipActive: &active,
+			Weight:           m.Weight,
+			Role:             m.Role,
+		}
+		if m.Role == model.TeamRoleLead {
+			team.Leads = append(team.Leads, members[i].UserID)
 		}
 	}
 
 	team.Members = members
+	if team.Leads == nil {
+		team.Leads = []string{}
+	}
+	_, team.Effective, err = s.prs.teamPolicy(ctx, repos, teamName)
+	if err != nil {
+		return nil, err
+	}
 	return team, nil
 }
+
+// UserTeams возвращает команды пользователя; для неизвестного пользователя — пустой список
+func (s *TeamService) UserTeams(ctx context.Context, userID string) ([]string, error) {
+	user, err := s.uow.Repos(ctx).Users.GetByID(userID)
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return user.TeamNames(), nil
+}
+
+// ListTeams возвращает страницу команд и курсор следующей страницы
+func (s *TeamService) ListTeams(ctx context.Context, limit int, cursor string) ([]model.TeamSummary, string, error) {
+	teams, next, err := s.uow.Repos(ctx).Teams.List(pageLimit(limit), cursor)
+	if err != nil {
+		return nil, "", mapListError(err)
+	}
+	return teams, next, nil
+}
+
+// setMemberSkills сохраняет навыки участника, если они переданы в запросе
+func setMemberSkills(repos repository.Repos, m model.TeamMember) error {
+	if m.Skills == nil {
+		return nil
+	}
+	_, err := repos.Users.SetSkills(m.UserID, normalizeTags(m.Skills))
+	return err
+}
+
+// teamSettingsAuditData описывает заданные настройки команды для журнала аудита
+func teamSettingsAuditData(settings model.TeamSettings) map[string]string {
+	data := map[string]string{}
+	if settings.ReviewerCount != nil {
+		data["reviewer_count"] = strconv.Itoa(*settings.ReviewerCount)
+	}
+	if settings.Strategy != "" {
+		data["strategy"] = settings.Strategy
+	}
+	if settings.MergePolicy != "" {
+		data["merge_policy"] = settings.MergePolicy
+	}
+	if settings.ReviewSLAHours != nil {
+		data["review_sla_hours"] = strconv.Itoa(*settings.ReviewSLAHours)
+	}
+	if settings.ExpandPool != nil {
+		data["expand_pool"] = strconv.FormatBool(*settings.ExpandPool)
+	}
+	if settings.LeadsReview != nil {
+		data["leads_review"] = strconv.FormatBool(*settings.LeadsReview)
+	}
+	return data
+}
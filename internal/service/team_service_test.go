@@ -0,0 +1,100 @@
+package service
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"pr-reviewer/internal/model"
+	"pr-reviewer/internal/repository"
+	"pr-reviewer/internal/repository/memory"
+)
+
+// conflictUoW выполняет fn, но не фиксирует транзакцию, как при параллельном изменении PR
+type conflictUoW struct {
+	repository.UnitOfWork
+}
+
+func (u conflictUoW) Do(ctx context.Context, fn func(repository.Repos) error) error {
+	return u.UnitOfWork.Do(ctx, func(r repository.Repos) error {
+		if err := fn(r); err != nil {
+			return err
+		}
+		return repository.ErrConflict
+	})
+}
+
+func TestTeamChangesReportConcurrentUpdate(t *testing.T) {
+	st := memory.NewStore()
+	prs := NewPRService(st, DefaultAssignmentPolicy())
+	ctx := context.Background()
+	if _, err := NewTeamService(st, prs).CreateOrUpdateTeam(ctx, &model.Team{Name: "backend", Members: []model.TeamMember{
+		{UserID: "1", Username: "alice", IsActive: true},
+	}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewTeamService(st, prs).CreateOrUpdateTeam(ctx, &model.Team{Name: "eng"}); err != nil {
+		t.Fatal(err)
+	}
+	teams := NewTeamService(conflictUoW{st}, prs)
+
+	two := 2
+	tests := []struct {
+		name string
+		op   func() error
+	}{
+		{"rename", func() error { _, err := teams.RenameTeam(ctx, "backend", "platform"); return err }},
+		{"set parent", func() error { _, err := teams.SetParent(ctx, "backend", "eng"); return err }},
+		{"update settings", func() error {
+			_, err := teams.UpdateSettings(ctx, "backend", model.TeamSettings{ReviewerCount: &two})
+			return err
+		}},
+		{"delete", func() error { return teams.DeleteTeam(ctx, "eng") }},
+		{"set membership", func() error { _, err := teams.SetMembership(ctx, "backend", "1", nil, &two); return err }},
+		{"set lead", func() error { _, err := teams.SetLead(ctx, "backend", "1", true); return err }},
+	}
+	for _, tt := range tests {
+		if err := tt.op(); err != ErrConcurrentUpdate {
+			t.Errorf("%s: want ErrConcurrentUpdate, got %v", tt.name, err)
+		}
+	}
+}
+
+func TestAddMembersKeepsUserActivity(t *testing.T) {
+	st := memory.NewStore()
+	pr := seedPR(t, st)
+	prs := NewPRService(st, DefaultAssignmentPolicy())
+	teams := NewTeamService(st, prs)
+	ctx := context.Background()
+	if _, err := teams.CreateOrUpdateTeam(ctx, &model.Team{Name: "frontend"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// is_active не передан и разбирается как false: это не должно деактивировать ревьювера
+	reviewer := pr.AssignedReviewers[0]
+	if _, err := teams.AddMembers(ctx, "frontend", []model.TeamMember{{UserID: reviewer, Username: "user" + reviewer}}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := st.Repos(ctx).Users.GetByID(reviewer)
+	if err != nil || !user.IsActive {
+		t.Fatalf("reviewer after adding to a second team: %+v, %v", user, err)
+	}
+	if _, ok := user.Membership("backend"); !ok {
+		t.Fatalf("membership in backend lost: %+v", user.Teams)
+	}
+	got, err := prs.GetPR(ctx, pr.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.AssignedReviewers, pr.AssignedReviewers) {
+		t.Fatalf("reviewers changed: %v, was %v", got.AssignedReviewers, pr.AssignedReviewers)
+	}
+
+	// новый пользователь получает активность из запроса
+	if _, err := teams.AddMembers(ctx, "frontend", []model.TeamMember{{UserID: "42", Username: "newbie", IsActive: false}}); err != nil {
+		t.Fatal(err)
+	}
+	if u, err := st.Repos(ctx).Users.GetByID("42"); err != nil || u.IsActive {
+		t.Fatalf("new inactive user: %+v, %v", u, err)
+	}
+}
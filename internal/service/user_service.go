@@ -1,28 +1,70 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"pr-reviewer/internal/model"
 	"pr-reviewer/internal/repository"
+	"time"
 )
 
 var ErrUserNotFound = errors.New("user not found")
 
 type UserService struct {
-	userRepo *repository.UserRepo
-	teamRepo *repository.TeamRepo
+	uow repository.UnitOfWork
+	prs *PRService
+	now Clock
 }
 
-func NewUserService(userRepo *repository.UserRepo, teamRepo *repository.TeamRepo) *UserService {
-	return &UserService{
-		userRepo: userRepo,
-		teamRepo: teamRepo,
+// NewUserService создаёт новый UserService. prs переназначает открытые ревью
+// деактивированных пользователей.
+func NewUserService(uow repository.UnitOfWork, prs *PRService) *UserService {
+	return &UserService{uow: uow, prs: prs, now: time.Now}
+}
+
+// update выполняет fn в транзакции; параллельное изменение затронутых PR даёт ErrConcurrentUpdate
+func (s *UserService) update(ctx context.Context, fn func(repos repository.Repos) error) error {
+	err := s.uow.Do(ctx, fn)
+	if errors.Is(err, repository.ErrConflict) {
+		return ErrConcurrentUpdate
 	}
+	return err
+}
+
+// SetIsActive устанавливает флаг активности пользователя и пишет изменение в журнал аудита.
+// Открытые ревью деактивированного пользователя переназначаются, а без замены снимаются.
+func (s *UserService) SetIsActive(ctx context.Context, userID string, isActive bool) (*model.User, []model.ReviewHandoff, error) {
+	var user *model.User
+	var handoffs []model.ReviewHandoff
+	err := s.update(ctx, func(repos repository.Repos) error {
+		var err error
+		user, handoffs, err = s.prs.setUserActive(ctx, repos, userID, isActive)
+		return err
+	})
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, nil, ErrUserNotFound
+		}
+		return nil, nil, err
+	}
+	return user, handoffs, nil
+}
+
+// SetSkills заменяет набор навыков пользователя
+func (s *UserService) SetSkills(ctx context.Context, userID string, skills []string) (*model.User, error) {
+	user, err := s.uow.Repos(ctx).Users.SetSkills(userID, normalizeTags(skills))
+	if err != nil {
+		if err == repository.ErrNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+	return user, nil
 }
 
-// SetIsActive устанавливает флаг активности пользователя
-func (s *UserService) SetIsActive(userID string, isActive bool) (*model.User, error) {
-	user, err := s.userRepo.SetIsActive(userID, isActive)
+// SetOutOfOffice отмечает пользователя отсутствующим до until (nil — снять отметку)
+func (s *UserService) SetOutOfOffice(ctx context.Context, userID string, until *time.Time) (*model.User, error) {
+	user, err := s.uow.Repos(ctx).Users.SetOutOfOffice(userID, until)
 	if err != nil {
 		if err == repository.ErrNotFound {
 			return nil, ErrUserNotFound
@@ -33,8 +75,8 @@ func (s *UserService) SetIsActive(userID string, isActive bool) (*model.User, er
 }
 
 // GetUserByID возвращает пользователя по ID
-func (s *UserService) GetUserByID(userID string) (*model.User, error) {
-	user, err := s.userRepo.GetByID(userID)
+func (s *UserService) GetUserByID(ctx context.Context, userID string) (*model.User, error) {
+	user, err := s.uow.Repos(ctx).Users.GetByID(userID)
 	if err != nil {
 		if err == repository.ErrNotFound {
 			return nil, ErrUserNotFound
@@ -45,6 +87,6 @@ func (s *UserService) GetUserByID(userID string) (*model.User, error) {
 }
 
 // GetUsersByTeam возвращает всех пользователей команды
-func (s *UserService) GetUsersByTeam(teamName string) ([]model.User, error) {
-	return s.userRepo.GetByTeam(teamName)
+func (s *UserService) GetUsersByTeam(ctx context.Context, teamName string) ([]model.User, error) {
+	return s.uow.Repos(ctx).Users.GetByTeam(teamName)
 }
@@ -0,0 +1,2 @@
+ALTER TABLE users DROP COLUMN IF EXISTS skills;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS labels;
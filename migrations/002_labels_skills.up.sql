@@ -0,0 +1,3 @@
+-- Метки PR и навыки пользователей
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS labels JSONB DEFAULT '[]';
+ALTER TABLE users ADD COLUMN IF NOT EXISTS skills JSONB DEFAULT '[]';
@@ -0,0 +1,2 @@
+-- Отсутствие пользователя (отпуск, больничный): до указанного момента он не получает ревью
+ALTER TABLE users ADD COLUMN IF NOT EXISTS out_of_office_until TIMESTAMP NULL;
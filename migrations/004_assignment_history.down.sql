@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS pr_history;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS assignment;
@@ -0,0 +1,14 @@
+-- Объяснение последнего решения о назначении ревьюверов
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS assignment JSONB NULL;
+
+-- История PR: создание, переназначения, слияние
+CREATE TABLE IF NOT EXISTS pr_history (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(50) NOT NULL REFERENCES pull_requests(pull_request_id),
+    event VARCHAR(30) NOT NULL,
+    assignment JSONB NULL,
+    data JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS pr_history_pr_idx ON pr_history (pull_request_id, id);
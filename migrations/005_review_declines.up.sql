@@ -0,0 +1,13 @@
+-- Отказы ревьюверов от назначенных PR
+CREATE TABLE IF NOT EXISTS review_declines (
+    id BIGSERIAL PRIMARY KEY,
+    pull_request_id VARCHAR(50) NOT NULL REFERENCES pull_requests(pull_request_id),
+    user_id VARCHAR(50) NOT NULL REFERENCES users(user_id),
+    reason VARCHAR(30) NOT NULL, -- no_context|overloaded|conflict_of_interest
+    comment TEXT NOT NULL DEFAULT '',
+    replaced_by VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS review_declines_pr_idx ON review_declines (pull_request_id);
+CREATE INDEX IF NOT EXISTS review_declines_user_idx ON review_declines (user_id, created_at);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS pull_requests_status_created_idx;
+DROP INDEX IF EXISTS pull_requests_labels_gin;
+DROP INDEX IF EXISTS pull_requests_reviewers_gin;
@@ -0,0 +1,4 @@
+-- Индексы для поиска PR по ревьюверу и меткам через оператор @>
+CREATE INDEX IF NOT EXISTS pull_requests_reviewers_gin ON pull_requests USING GIN (assigned_reviewers jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS pull_requests_labels_gin ON pull_requests USING GIN (labels jsonb_path_ops);
+CREATE INDEX IF NOT EXISTS pull_requests_status_created_idx ON pull_requests (status, created_at);
@@ -0,0 +1,2 @@
+-- Версия PR для оптимистичной блокировки при обновлении
+ALTER TABLE pull_requests ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
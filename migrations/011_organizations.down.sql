@@ -0,0 +1,46 @@
+-- Откат возможен, только если в базе осталась одна организация: иначе ключи пересекутся
+DELETE FROM idempotency_keys WHERE tenant_id <> 'default';
+
+DROP INDEX IF EXISTS pr_history_pr_idx;
+DROP INDEX IF EXISTS review_declines_pr_idx;
+DROP INDEX IF EXISTS review_declines_user_idx;
+DROP INDEX IF EXISTS pull_requests_status_created_idx;
+DROP INDEX IF EXISTS users_team_idx;
+DROP INDEX IF EXISTS api_tokens_tenant_idx;
+DROP INDEX IF EXISTS audit_events_target_idx;
+DROP INDEX IF EXISTS audit_events_actor_idx;
+
+ALTER TABLE review_declines DROP CONSTRAINT IF EXISTS review_declines_user_fkey;
+ALTER TABLE review_declines DROP CONSTRAINT IF EXISTS review_declines_pr_fkey;
+ALTER TABLE pr_history DROP CONSTRAINT IF EXISTS pr_history_pr_fkey;
+ALTER TABLE pull_requests DROP CONSTRAINT IF EXISTS pull_requests_author_fkey;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_fkey;
+
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey, ADD PRIMARY KEY (idempotency_key);
+ALTER TABLE pull_requests DROP CONSTRAINT pull_requests_pkey, ADD PRIMARY KEY (pull_request_id);
+ALTER TABLE users DROP CONSTRAINT users_pkey, ADD PRIMARY KEY (user_id);
+ALTER TABLE teams DROP CONSTRAINT teams_pkey, ADD PRIMARY KEY (name);
+
+ALTER TABLE users ADD CONSTRAINT users_team_name_fkey FOREIGN KEY (team_name) REFERENCES teams(name);
+ALTER TABLE pull_requests ADD CONSTRAINT pull_requests_author_id_fkey FOREIGN KEY (author_id) REFERENCES users(user_id);
+ALTER TABLE pr_history ADD CONSTRAINT pr_history_pull_request_id_fkey FOREIGN KEY (pull_request_id) REFERENCES pull_requests(pull_request_id);
+ALTER TABLE review_declines ADD CONSTRAINT review_declines_pull_request_id_fkey FOREIGN KEY (pull_request_id) REFERENCES pull_requests(pull_request_id);
+ALTER TABLE review_declines ADD CONSTRAINT review_declines_user_id_fkey FOREIGN KEY (user_id) REFERENCES users(user_id);
+
+ALTER TABLE teams DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE pull_requests DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE pr_history DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE review_declines DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE idempotency_keys DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE api_tokens DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE audit_events DROP COLUMN IF EXISTS tenant_id;
+
+CREATE INDEX IF NOT EXISTS pr_history_pr_idx ON pr_history (pull_request_id, id);
+CREATE INDEX IF NOT EXISTS review_declines_pr_idx ON review_declines (pull_request_id);
+CREATE INDEX IF NOT EXISTS review_declines_user_idx ON review_declines (user_id, created_at);
+CREATE INDEX IF NOT EXISTS pull_requests_status_created_idx ON pull_requests (status, created_at);
+CREATE INDEX IF NOT EXISTS audit_events_target_idx ON audit_events (target_type, target_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (actor, id);
+
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,69 @@
+-- Организации (тенанты): у каждой свои команды, пользователи, PR и токены
+CREATE TABLE IF NOT EXISTS organizations (
+    id VARCHAR(50) PRIMARY KEY,
+    name TEXT NOT NULL,
+    settings JSONB NOT NULL DEFAULT '{}',
+    created_at TIMESTAMP NOT NULL DEFAULT NOW()
+);
+
+-- Существующие данные переезжают в организацию по умолчанию
+INSERT INTO organizations (id, name) VALUES ('default', 'Default') ON CONFLICT DO NOTHING;
+
+ALTER TABLE teams ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+ALTER TABLE users ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+ALTER TABLE pull_requests ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+ALTER TABLE pr_history ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+ALTER TABLE review_declines ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+ALTER TABLE idempotency_keys ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+ALTER TABLE api_tokens ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+ALTER TABLE audit_events ADD COLUMN tenant_id VARCHAR(50) NOT NULL DEFAULT 'default' REFERENCES organizations(id);
+
+-- Организацию задаёт приложение; значение по умолчанию нужно было только для переноса
+ALTER TABLE teams ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE users ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE pull_requests ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE pr_history ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE review_declines ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE idempotency_keys ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE api_tokens ALTER COLUMN tenant_id DROP DEFAULT;
+ALTER TABLE audit_events ALTER COLUMN tenant_id DROP DEFAULT;
+
+-- Ключи уникальны в пределах организации: одинаковые имена команд и ID в разных организациях не конфликтуют
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_name_fkey;
+ALTER TABLE pull_requests DROP CONSTRAINT IF EXISTS pull_requests_author_id_fkey;
+ALTER TABLE pr_history DROP CONSTRAINT IF EXISTS pr_history_pull_request_id_fkey;
+ALTER TABLE review_declines DROP CONSTRAINT IF EXISTS review_declines_pull_request_id_fkey;
+ALTER TABLE review_declines DROP CONSTRAINT IF EXISTS review_declines_user_id_fkey;
+
+ALTER TABLE teams DROP CONSTRAINT teams_pkey, ADD PRIMARY KEY (tenant_id, name);
+ALTER TABLE users DROP CONSTRAINT users_pkey, ADD PRIMARY KEY (tenant_id, user_id);
+ALTER TABLE pull_requests DROP CONSTRAINT pull_requests_pkey, ADD PRIMARY KEY (tenant_id, pull_request_id);
+ALTER TABLE idempotency_keys DROP CONSTRAINT idempotency_keys_pkey, ADD PRIMARY KEY (tenant_id, idempotency_key);
+
+-- Составные внешние ключи не дают сослаться на строку другой организации
+ALTER TABLE users ADD CONSTRAINT users_team_fkey
+    FOREIGN KEY (tenant_id, team_name) REFERENCES teams(tenant_id, name);
+ALTER TABLE pull_requests ADD CONSTRAINT pull_requests_author_fkey
+    FOREIGN KEY (tenant_id, author_id) REFERENCES users(tenant_id, user_id);
+ALTER TABLE pr_history ADD CONSTRAINT pr_history_pr_fkey
+    FOREIGN KEY (tenant_id, pull_request_id) REFERENCES pull_requests(tenant_id, pull_request_id);
+ALTER TABLE review_declines ADD CONSTRAINT review_declines_pr_fkey
+    FOREIGN KEY (tenant_id, pull_request_id) REFERENCES pull_requests(tenant_id, pull_request_id);
+ALTER TABLE review_declines ADD CONSTRAINT review_declines_user_fkey
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users(tenant_id, user_id);
+
+DROP INDEX IF EXISTS pr_history_pr_idx;
+DROP INDEX IF EXISTS review_declines_pr_idx;
+DROP INDEX IF EXISTS review_declines_user_idx;
+DROP INDEX IF EXISTS pull_requests_status_created_idx;
+DROP INDEX IF EXISTS audit_events_target_idx;
+DROP INDEX IF EXISTS audit_events_actor_idx;
+
+CREATE INDEX IF NOT EXISTS pr_history_pr_idx ON pr_history (tenant_id, pull_request_id, id);
+CREATE INDEX IF NOT EXISTS review_declines_pr_idx ON review_declines (tenant_id, pull_request_id);
+CREATE INDEX IF NOT EXISTS review_declines_user_idx ON review_declines (tenant_id, user_id, created_at);
+CREATE INDEX IF NOT EXISTS pull_requests_status_created_idx ON pull_requests (tenant_id, status, created_at);
+CREATE INDEX IF NOT EXISTS users_team_idx ON users (tenant_id, team_name);
+CREATE INDEX IF NOT EXISTS api_tokens_tenant_idx ON api_tokens (tenant_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_target_idx ON audit_events (tenant_id, target_type, target_id, id);
+CREATE INDEX IF NOT EXISTS audit_events_actor_idx ON audit_events (tenant_id, actor, id);
@@ -0,0 +1,15 @@
+ALTER TABLE users ADD COLUMN team_name VARCHAR(100);
+
+-- Пользователь остаётся в основной команде: членство с наибольшим весом
+UPDATE users u SET team_name = (
+    SELECT m.team_name FROM team_memberships m
+    WHERE m.tenant_id = u.tenant_id AND m.user_id = u.user_id
+    ORDER BY m.weight DESC, m.team_name
+    LIMIT 1
+);
+
+ALTER TABLE users ADD CONSTRAINT users_team_fkey
+    FOREIGN KEY (tenant_id, team_name) REFERENCES teams(tenant_id, name);
+CREATE INDEX IF NOT EXISTS users_team_idx ON users (tenant_id, team_name);
+
+DROP TABLE IF EXISTS team_memberships;
@@ -0,0 +1,21 @@
+-- Членство пользователей в командах: пользователь может состоять в нескольких командах
+CREATE TABLE IF NOT EXISTS team_memberships (
+    tenant_id VARCHAR(50) NOT NULL,
+    team_name VARCHAR(100) NOT NULL,
+    user_id VARCHAR(50) NOT NULL,
+    is_active BOOLEAN NOT NULL DEFAULT TRUE,
+    weight INT NOT NULL DEFAULT 100 CHECK (weight BETWEEN 1 AND 100), -- доля времени в команде, %
+    PRIMARY KEY (tenant_id, team_name, user_id),
+    FOREIGN KEY (tenant_id, team_name) REFERENCES teams(tenant_id, name) ON UPDATE CASCADE,
+    FOREIGN KEY (tenant_id, user_id) REFERENCES users(tenant_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS team_memberships_user_idx ON team_memberships (tenant_id, user_id);
+
+-- Переносим единственную команду пользователя в членство
+INSERT INTO team_memberships (tenant_id, team_name, user_id)
+SELECT tenant_id, team_name, user_id FROM users WHERE team_name IS NOT NULL;
+
+DROP INDEX IF EXISTS users_team_idx;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_team_fkey;
+ALTER TABLE users DROP COLUMN team_name;
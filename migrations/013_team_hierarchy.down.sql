@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS teams_parent_idx;
+ALTER TABLE teams DROP CONSTRAINT IF EXISTS teams_parent_fkey;
+ALTER TABLE teams DROP COLUMN IF EXISTS settings;
+ALTER TABLE teams DROP COLUMN IF EXISTS parent_name;
@@ -0,0 +1,7 @@
+-- Иерархия команд (отдел → команда → группа) и собственные настройки команды
+ALTER TABLE teams ADD COLUMN parent_name VARCHAR(100);
+ALTER TABLE teams ADD COLUMN settings JSONB NOT NULL DEFAULT '{}';
+
+ALTER TABLE teams ADD CONSTRAINT teams_parent_fkey
+    FOREIGN KEY (tenant_id, parent_name) REFERENCES teams(tenant_id, name) ON UPDATE CASCADE;
+CREATE INDEX IF NOT EXISTS teams_parent_idx ON teams (tenant_id, parent_name);
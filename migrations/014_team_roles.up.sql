@@ -0,0 +1,3 @@
+-- Роль участника в команде: лиды управляют командой и получают эскалации
+ALTER TABLE team_memberships ADD COLUMN role VARCHAR(20) NOT NULL DEFAULT 'member'
+    CHECK (role IN ('member', 'lead'));
@@ -0,0 +1,8 @@
+// Package migrations содержит SQL-миграции схемы, встроенные в бинарник.
+// Файлы называются NNN_name.up.sql и NNN_name.down.sql.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS